@@ -0,0 +1,122 @@
+package bench
+
+import (
+	"math/rand"
+	"slices"
+
+	"github.com/nkk36/earth-discretization-benchmark/report"
+)
+
+// Area classes of a Bucket, the quartiles of feature area in the dataset
+var AreaClasses = []string{"tiny", "small", "medium", "large"}
+
+// Vertex classes of a Bucket, below or above the median vertex count
+var VertexClasses = []string{"low", "high"}
+
+// Bucket is the complexity class of a feature relative to the rest of its
+// dataset: the quartile of its area and whether its vertex count (holes
+// included) is above the median
+type Bucket struct {
+	Area     string `json:"area"`
+	Vertices string `json:"vertices"`
+}
+
+// String returns the bucket as "area/vertices", e.g. "tiny/high"
+func (b Bucket) String() string {
+	return b.Area + "/" + b.Vertices
+}
+
+// AllBuckets returns every bucket, smallest and simplest first
+func AllBuckets() []Bucket {
+	var buckets []Bucket
+	for _, area := range AreaClasses {
+		for _, vertices := range VertexClasses {
+			buckets = append(buckets, Bucket{Area: area, Vertices: vertices})
+		}
+	}
+	return buckets
+}
+
+// AreaKm2 is the area of the feature's polygon
+func (f Feature) AreaKm2() float64 {
+	return f.S2Polygon.Area() * EarthRadiusKm * EarthRadiusKm
+}
+
+// NumVertices counts the vertices of every loop of the feature's polygon
+func (f Feature) NumVertices() int {
+	n := 0
+	for _, loop := range f.S2Polygon.Loops() {
+		n += loop.NumVertices()
+	}
+	return n
+}
+
+// Buckets classifies every feature, indexed like ds.Features
+func (d *Dataset) Buckets() []Bucket {
+	areas := make([]float64, len(d.Features))
+	vertices := make([]float64, len(d.Features))
+	for i, f := range d.Features {
+		areas[i] = f.AreaKm2()
+		vertices[i] = float64(f.NumVertices())
+	}
+	sortedAreas := slices.Sorted(slices.Values(areas))
+	sortedVertices := slices.Sorted(slices.Values(vertices))
+	quartiles := []float64{
+		report.Percentile(sortedAreas, 25),
+		report.Percentile(sortedAreas, 50),
+		report.Percentile(sortedAreas, 75),
+	}
+	medianVertices := report.Percentile(sortedVertices, 50)
+
+	buckets := make([]Bucket, len(d.Features))
+	for i := range d.Features {
+		area := len(quartiles)
+		for q, limit := range quartiles {
+			if areas[i] <= limit {
+				area = q
+				break
+			}
+		}
+		b := Bucket{Area: AreaClasses[area], Vertices: VertexClasses[0]}
+		if vertices[i] > medianVertices {
+			b.Vertices = VertexClasses[1]
+		}
+		buckets[i] = b
+	}
+	return buckets
+}
+
+// BucketsByID classifies every feature like Buckets, keyed by feature ID
+func (d *Dataset) BucketsByID() map[int]Bucket {
+	byID := make(map[int]Bucket, len(d.Features))
+	for i, b := range d.Buckets() {
+		byID[d.Features[i].FeatureID] = b
+	}
+	return byID
+}
+
+// StratifiedSample returns a dataset with at most k features drawn at
+// random from each bucket, so a small run still spans the full range of
+// sizes and complexities. The same seed draws the same features; they keep
+// their order in the dataset.
+func (d *Dataset) StratifiedSample(k int, seed int64) *Dataset {
+	rng := rand.New(rand.NewSource(seed))
+	members := make(map[Bucket][]int)
+	for i, b := range d.Buckets() {
+		members[b] = append(members[b], i)
+	}
+
+	var keep []int
+	for _, b := range AllBuckets() {
+		indices := members[b]
+		rng.Shuffle(len(indices), func(i, j int) { indices[i], indices[j] = indices[j], indices[i] })
+		keep = append(keep, indices[:min(k, len(indices))]...)
+	}
+	slices.Sort(keep)
+
+	sample := &Dataset{Path: d.Path}
+	for _, i := range keep {
+		sample.Features = append(sample.Features, d.Features[i])
+	}
+	return sample
+}
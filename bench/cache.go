@@ -0,0 +1,145 @@
+package bench
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+	"github.com/uber/h3-go/v4"
+)
+
+// datasetCacheVersion is part of every cache key; bump it whenever the
+// conversion or the cached layout changes so stale entries are ignored
+const datasetCacheVersion = 9
+
+// cachedDataset is the gob form of a Dataset
+type cachedDataset struct {
+	Features []cachedFeature
+	Issues   []ConversionIssue
+}
+
+// cachedFeature is the gob form of a Feature. s2.Polygon has no
+// exported fields, so it is stored in its own binary encoding, and gob
+// cannot encode arbitrary JSON values, so the properties are kept as JSON.
+type cachedFeature struct {
+	FeatureID  int
+	Geometry   geojson.Geometry
+	Properties []byte
+	H3Polygon  h3.GeoPolygon
+	S2Polygon  []byte
+}
+
+// datasetCacheDir is where converted datasets are cached: $EARTHBENCH_CACHE_DIR
+// if set, otherwise the user cache directory. "off" disables the cache.
+func datasetCacheDir() string {
+	if dir := os.Getenv("EARTHBENCH_CACHE_DIR"); dir != "" {
+		if dir == "off" {
+			return ""
+		}
+		return dir
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "earth-discretization-benchmark")
+}
+
+// datasetCachePath returns the cache file for a GeoJSON document, keyed by
+// the hash of its bytes, the source CRS it is read in and whether
+// degenerate rings are repaired
+func datasetCachePath(dir string, data []byte, sourceCRS string, repair bool) string {
+	hash := sha256.New()
+	hash.Write(data)
+	hash.Write([]byte("\x00" + sourceCRS))
+	if repair {
+		hash.Write([]byte("\x00repair"))
+	}
+	sum := hash.Sum(nil)
+	return filepath.Join(dir, fmt.Sprintf("dataset-v%d-%s.gob", datasetCacheVersion, hex.EncodeToString(sum[:])))
+}
+
+// readDatasetCache decodes a cached dataset, reporting false if there is no
+// usable entry
+func readDatasetCache(path, name string) (*Dataset, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	defer file.Close()
+
+	var cached cachedDataset
+	if err := gob.NewDecoder(file).Decode(&cached); err != nil {
+		log.Printf("Warning: ignoring unreadable dataset cache %s: %v", path, err)
+		return nil, false
+	}
+	ds := &Dataset{Path: name, Features: make([]Feature, len(cached.Features)), Issues: cached.Issues}
+	for i, c := range cached.Features {
+		polygon := &s2.Polygon{}
+		if err := polygon.Decode(bytes.NewReader(c.S2Polygon)); err != nil {
+			log.Printf("Warning: ignoring unreadable dataset cache %s: %v", path, err)
+			return nil, false
+		}
+		var properties map[string]interface{}
+		if err := json.Unmarshal(c.Properties, &properties); err != nil {
+			log.Printf("Warning: ignoring unreadable dataset cache %s: %v", path, err)
+			return nil, false
+		}
+		ds.Features[i] = Feature{
+			FeatureID:  c.FeatureID,
+			Geometry:   c.Geometry,
+			Properties: properties,
+			H3Polygon:  c.H3Polygon,
+			S2Polygon:  polygon,
+		}
+	}
+	return ds, true
+}
+
+// writeDatasetCache stores a converted dataset. The file is written under a
+// temporary name and renamed so concurrent runs never read a partial entry.
+func writeDatasetCache(path string, ds *Dataset) error {
+	cached := cachedDataset{Features: make([]cachedFeature, len(ds.Features)), Issues: ds.Issues}
+	for i, f := range ds.Features {
+		var buf bytes.Buffer
+		if err := f.S2Polygon.Encode(&buf); err != nil {
+			return fmt.Errorf("encoding feature %d: %w", f.FeatureID, err)
+		}
+		properties, err := json.Marshal(f.Properties)
+		if err != nil {
+			return fmt.Errorf("encoding feature %d properties: %w", f.FeatureID, err)
+		}
+		cached.Features[i] = cachedFeature{
+			FeatureID:  f.FeatureID,
+			Geometry:   f.Geometry,
+			Properties: properties,
+			H3Polygon:  f.H3Polygon,
+			S2Polygon:  buf.Bytes(),
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := gob.NewEncoder(tmp).Encode(cached); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
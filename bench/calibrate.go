@@ -0,0 +1,63 @@
+package bench
+
+import (
+	"math"
+	"time"
+
+	"github.com/golang/geo/s2"
+	dh3 "github.com/nkk36/earth-discretization-benchmark/discretize/h3"
+	ds2 "github.com/nkk36/earth-discretization-benchmark/discretize/s2"
+	"github.com/uber/h3-go/v4"
+)
+
+// Calibration scores the machine a run measured on with a small fixed
+// covering workload, so results gathered on different machines can be
+// roughly compared. Score is the reference time of the workload over the
+// time measured here: 2 means this machine ran it twice as fast as the
+// reference machine. Normalized reports that the durations of the run
+// were multiplied by Score, i.e. are estimates of the reference machine's.
+type Calibration struct {
+	Score      float64 `json:"score"`
+	WorkloadNs float64 `json:"workload_ns"`
+	Normalized bool    `json:"normalized,omitempty"`
+}
+
+// calibrationReferenceNs is the time of the calibration workload on the
+// machine the scores are relative to
+const calibrationReferenceNs = 60e6
+
+// calibrationRounds are run and the fastest kept, the one least disturbed
+// by other load
+const calibrationRounds = 5
+
+// Calibrate times the calibration workload: an H3 fill at resolution 6 and
+// S2 coverings at levels 8 to 12 of a fixed 64-vertex polygon. It takes a
+// few tenths of a second.
+func Calibrate() Calibration {
+	ring := make([][2]float64, 0, 65)
+	for i := 0; i <= 64; i++ {
+		angle := 2 * math.Pi * float64(i%64) / 64
+		// A wobbly circle of about 2 degrees around 40N 100W, so the edges
+		// are not all alike
+		radius := 2 + 0.3*math.Sin(5*angle)
+		ring = append(ring, [2]float64{-100 + radius*math.Cos(angle)/math.Cos(40*math.Pi/180), 40 + radius*math.Sin(angle)})
+	}
+	h3Polygon := h3.GeoPolygon{GeoLoop: dh3.LoopFromRing(ring)}
+	s2Polygon := s2.PolygonFromLoops([]*s2.Loop{ds2.LoopFromRing(ring)})
+
+	best := time.Duration(math.MaxInt64)
+	for range calibrationRounds {
+		start := time.Now()
+		dh3.Cover(h3Polygon, 6)
+		for level := 8; level <= 12; level++ {
+			ds2.Cover(s2Polygon, level, 8)
+		}
+		best = min(best, time.Since(start))
+	}
+	return Calibration{Score: calibrationReferenceNs / float64(best), WorkloadNs: float64(best)}
+}
+
+// Normalize returns d as it would be on the reference machine
+func (c Calibration) Normalize(d time.Duration) time.Duration {
+	return time.Duration(float64(d) * c.Score)
+}
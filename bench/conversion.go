@@ -0,0 +1,140 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+)
+
+// The conversion modes selected by $EARTHBENCH_CONVERSION
+const (
+	ConversionLenient = "lenient"
+	ConversionStrict  = "strict"
+	ConversionRepair  = "repair" // lenient, repairing the degenerate rings that can be
+)
+
+// ConversionIssue is a problem found while converting one feature of a
+// FeatureCollection. Ring is the offending ring (0 the exterior, 1 and up
+// the holes) or -1 when the issue concerns the feature as a whole; Dropped
+// says what was left out of the dataset because of it. Degenerate rings
+// carry the geojson reason code (see geojson.ClassifyRing) in Code, and are
+// left out before any timing; a repaired one drops nothing.
+type ConversionIssue struct {
+	Index     int // position in the FeatureCollection
+	FeatureID int
+	Ring      int
+	Dropped   string // "feature", "hole" or "none"
+	Code      string
+	Reason    string
+}
+
+// ConversionReport collects the issues of one dataset conversion. Lenient
+// mode drops what cannot be converted and carries on; strict mode fails the
+// load if there is any issue, after collecting all of them.
+type ConversionReport struct {
+	Mode   string
+	Issues []ConversionIssue
+}
+
+// ConversionMode reads the mode from $EARTHBENCH_CONVERSION, lenient by
+// default
+func ConversionMode() (string, error) {
+	switch mode := os.Getenv("EARTHBENCH_CONVERSION"); mode {
+	case "", ConversionLenient:
+		return ConversionLenient, nil
+	case ConversionStrict, ConversionRepair:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("EARTHBENCH_CONVERSION must be %s, %s or %s, got %q", ConversionLenient, ConversionStrict, ConversionRepair, mode)
+	}
+}
+
+// DropFeature records a feature that was left out entirely
+func (r *ConversionReport) DropFeature(index, featureID int, reason string) {
+	r.Issues = append(r.Issues, ConversionIssue{Index: index, FeatureID: featureID, Ring: -1, Dropped: "feature", Reason: reason})
+}
+
+// RecordDefects records the degenerate rings SanitizeGeometry found in one
+// feature and reports whether the feature must be dropped, as it is when
+// its exterior ring was not repaired
+func (r *ConversionReport) RecordDefects(index, featureID int, defects []geojson.RingDefect) bool {
+	dropFeature := false
+	for _, d := range defects {
+		issue := ConversionIssue{Index: index, FeatureID: featureID, Ring: d.Ring, Code: d.Code, Reason: d.Detail}
+		switch {
+		case d.Repaired:
+			issue.Dropped = "none"
+			issue.Reason = "repaired, " + d.Detail
+		case d.Ring == 0:
+			issue.Dropped = "feature"
+			dropFeature = true
+		default:
+			issue.Dropped = "hole"
+		}
+		r.Issues = append(r.Issues, issue)
+	}
+	return dropFeature
+}
+
+// SkipRing returns the geojson.SkipRingFunc recording the holes dropped
+// from one feature
+func (r *ConversionReport) SkipRing(index, featureID int) geojson.SkipRingFunc {
+	return func(ring int, reason string) error {
+		r.Issues = append(r.Issues, ConversionIssue{Index: index, FeatureID: featureID, Ring: ring, Dropped: "hole", Reason: reason})
+		return nil
+	}
+}
+
+// Err summarizes the issues as an error in strict mode
+func (r *ConversionReport) Err() error {
+	if r.Mode != ConversionStrict || len(r.Issues) == 0 {
+		return nil
+	}
+	return fmt.Errorf("strict conversion: %d issue(s), first: %s", len(r.Issues), r.Issues[0])
+}
+
+// Print writes the per-feature report to stdout; it prints nothing when
+// there are no issues
+func (r *ConversionReport) Print() {
+	r.Fprint(os.Stdout)
+}
+
+// Fprint writes the per-feature report to w like Print
+func (r *ConversionReport) Fprint(w io.Writer) {
+	if len(r.Issues) == 0 {
+		return
+	}
+	dropped := make(map[string]int)
+	for _, issue := range r.Issues {
+		dropped[issue.Dropped]++
+	}
+	fmt.Fprintf(w, "Conversion issues (%s mode): %d feature(s) and %d hole(s) dropped", r.Mode, dropped["feature"], dropped["hole"])
+	if dropped["none"] > 0 {
+		fmt.Fprintf(w, ", %d ring(s) repaired", dropped["none"])
+	}
+	fmt.Fprintln(w)
+	for _, issue := range r.Issues {
+		fmt.Fprintf(w, "  %s\n", issue)
+	}
+}
+
+func (i ConversionIssue) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "feature %d (index %d)", i.FeatureID, i.Index)
+	if i.Ring >= 0 {
+		fmt.Fprintf(&b, " ring %d", i.Ring)
+	}
+	if i.Dropped == "none" {
+		b.WriteString(": kept")
+	} else {
+		fmt.Fprintf(&b, ": %s dropped", i.Dropped)
+	}
+	if i.Code != "" {
+		fmt.Fprintf(&b, " [%s]", i.Code)
+	}
+	fmt.Fprintf(&b, ": %s", i.Reason)
+	return b.String()
+}
@@ -0,0 +1,440 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/golang/geo/s2"
+	dh3 "github.com/nkk36/earth-discretization-benchmark/discretize/h3"
+	ds2 "github.com/nkk36/earth-discretization-benchmark/discretize/s2"
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+	"github.com/nkk36/earth-discretization-benchmark/report"
+	"github.com/uber/h3-go/v4"
+)
+
+// CoveringMeasurement holds the per-feature covering durations of one
+// system/resolution pair, the measurement h3Experiments and s2VaryLevels
+// record, for callers that need it in memory rather than as CSV. Features
+// whose covering exceeded the per-operation timeout are listed in TimedOut,
+// those not covered because of the H3 cell cap in OverCap and those whose
+// covering failed verification in Violations; none contributes a duration
+// or cells. Repetition numbers the repeated runs of a Runner from 0.
+//
+// FeatureIDs names the feature of each duration, and AreaKm2 is the total
+// polygon area of those features. Empty lists the features whose covering
+// came back without cells, as H3's does for a polygon smaller than a cell
+// that misses every cell center; they are measured like the rest, with
+// zero cells or, with CoveringOptions.CentroidFallback, their centroid cell.
+//
+// With CoveringOptions.Precision set, each duration is the mean of several
+// samples of the feature's covering; Samples holds their count per feature,
+// parallel to Durations, and Unconverged the features whose confidence
+// interval was still too wide after MaxSamples.
+//
+// LevelCells counts the cells of the S2 coverings at each level when
+// CoveringOptions.S2LevelSpan lets the coverer mix levels, showing how it
+// spends its MaxCells budget; it is nil otherwise.
+//
+// Quality flags a measurement a Runner's NoiseMonitor saw disturbed by
+// load, frequency scaling or throttling; Results.Summary leaves flagged
+// repetitions out when the sweep point has clean ones.
+type CoveringMeasurement struct {
+	System      string              `json:"system"`
+	Resolution  int                 `json:"resolution"`
+	Repetition  int                 `json:"repetition"`
+	Cells       int                 `json:"cells"`
+	AreaKm2     float64             `json:"area_km2"`
+	Durations   []time.Duration     `json:"durations_ns"`
+	FeatureIDs  []int               `json:"feature_ids,omitempty"`
+	Samples     []int               `json:"samples,omitempty"`
+	Unconverged []int               `json:"unconverged,omitempty"` // feature IDs
+	Empty       []int               `json:"empty,omitempty"`       // feature IDs
+	LevelCells  map[int]int         `json:"level_cells,omitempty"` // S2 level to cells
+	TimedOut    []int               `json:"timed_out,omitempty"`   // feature IDs
+	OverCap     []CappedFeature     `json:"over_cap,omitempty"`
+	Violations  []CoveringViolation `json:"violations,omitempty"`
+	Quality     []string            `json:"quality,omitempty"`
+}
+
+// CappedFeature is a feature skipped because its estimated H3 cell count
+// exceeded the cap
+type CappedFeature struct {
+	FeatureID      int `json:"feature_id"`
+	EstimatedCells int `json:"estimated_cells"`
+}
+
+// SweepPoint is a single system/resolution pair of a sweep
+type SweepPoint struct {
+	System     string
+	Resolution int
+}
+
+// CoveringOptions configures BenchmarkCoverings
+type CoveringOptions struct {
+	MaxCells  int           `json:"max_cells"`   // S2 RegionCoverer MaxCells
+	Timeout   time.Duration `json:"timeout_ns"`  // per covering; 0 for no limit
+	H3CellCap int           `json:"h3_cell_cap"` // skip H3 coverings estimated to exceed this many cells; 0 for no cap
+	Verify    int           `json:"verify"`      // interior sample points per covering for VerifyCovering; 0 to skip verification
+
+	// H3Containment selects the H3 cells by a containment mode of
+	// dh3.Containment through PolygonToCellsExperimental; empty uses
+	// PolygonToCells
+	H3Containment string `json:"h3_containment,omitempty"`
+
+	// Precision, when positive, samples each covering until the 95%
+	// confidence interval of its mean duration is within this fraction of
+	// the mean (0.05 for ±5%), or MaxSamples samples were taken
+	Precision  float64 `json:"precision,omitempty"`
+	MaxSamples int     `json:"max_samples,omitempty"`
+
+	// CentroidFallback replaces an empty covering with the cell containing
+	// the polygon's centroid (CentroidCell), timed as part of the covering,
+	// so every polygon is indexed by at least one cell
+	CentroidFallback bool `json:"centroid_fallback,omitempty"`
+
+	// S2LevelSpan, when positive, covers with RegionCoverer MinLevel that
+	// many levels below the sweep level (MaxLevel), not lower than 0, instead
+	// of fixing both at the sweep level
+	S2LevelSpan int `json:"s2_level_span,omitempty"`
+}
+
+// S2MinLevel is the RegionCoverer MinLevel of S2 coverings at a level
+func (o CoveringOptions) S2MinLevel(level int) int {
+	return max(level-max(o.S2LevelSpan, 0), 0)
+}
+
+// AverageDurationNs is the mean covering duration per feature
+func (m CoveringMeasurement) AverageDurationNs() float64 {
+	if len(m.Durations) == 0 {
+		return 0
+	}
+	return float64(m.TotalDuration().Nanoseconds()) / float64(len(m.Durations))
+}
+
+// NsPerKm2 is the covering time per km² of polygon covered, comparable
+// across resolutions and systems
+func (m CoveringMeasurement) NsPerKm2() float64 {
+	if m.AreaKm2 == 0 {
+		return 0
+	}
+	return float64(m.TotalDuration().Nanoseconds()) / m.AreaKm2
+}
+
+// CellsPerSecond is the rate at which the coverings produced cells
+func (m CoveringMeasurement) CellsPerSecond() float64 {
+	if m.TotalDuration() == 0 {
+		return 0
+	}
+	return float64(m.Cells) / m.TotalDuration().Seconds()
+}
+
+// TotalSamples is the number of timed coverings behind the durations
+func (m CoveringMeasurement) TotalSamples() int {
+	if m.Samples == nil {
+		return len(m.Durations)
+	}
+	total := 0
+	for _, n := range m.Samples {
+		total += n
+	}
+	return total
+}
+
+// TotalDuration is the sum of the per-feature covering durations
+func (m CoveringMeasurement) TotalDuration() time.Duration {
+	var total time.Duration
+	for _, d := range m.Durations {
+		total += d
+	}
+	return total
+}
+
+// BenchmarkCoverings times the covering call of every feature for each
+// sweep point. With a positive timeout, a covering that runs longer is
+// recorded in TimedOut and the run moves on to the next feature; with an H3
+// cell cap, fills estimated (EstimateH3Cells) to exceed it are recorded in
+// OverCap and never started, since a single oversized fill can run out of
+// memory. With Verify set, each covering is checked after it is timed (the
+// check is not part of the duration) and dropped from the measurement if it
+// is wrong; the centroid fallback is applied after the check. With
+// Precision set, a verified covering is timed again until its mean duration
+// is known to that precision. When ctx is cancelled the measurements
+// completed so far are returned with ctx.Err().
+//
+// Neither PolygonToCells (cgo) nor RegionCoverer can be interrupted, so a
+// timed-out covering keeps running in the background. At most one is left
+// running: on a second timeout the run waits for the first to finish, which
+// bounds the memory held by runaway coverings when every feature is slow.
+func BenchmarkCoverings(ctx context.Context, ds *Dataset, sweepPoints []SweepPoint, opts CoveringOptions) ([]CoveringMeasurement, error) {
+	return benchmarkCoverings(ctx, ds, sweepPoints, opts, nil)
+}
+
+// benchmarkCoverings is BenchmarkCoverings calling progress, if not nil,
+// with the measurement in progress before each feature and once more when
+// the sweep point is complete; done counts the features handled so far
+func benchmarkCoverings(ctx context.Context, ds *Dataset, sweepPoints []SweepPoint, opts CoveringOptions, progress func(done int, m *CoveringMeasurement)) ([]CoveringMeasurement, error) {
+	if opts.H3Containment != "" {
+		if _, ok := dh3.Containment[opts.H3Containment]; !ok {
+			return nil, fmt.Errorf("unknown H3 containment mode %q", opts.H3Containment)
+		}
+		if opts.Verify > 0 && opts.H3Containment != "center" {
+			return nil, fmt.Errorf("verification checks the center containment contract, not %q", opts.H3Containment)
+		}
+	}
+	if opts.Precision > 0 && opts.MaxSamples < 2 {
+		return nil, fmt.Errorf("sampling to a precision needs at least 2 samples per covering, got %d", opts.MaxSamples)
+	}
+
+	var measurements []CoveringMeasurement
+	c := &coverer{}
+	for _, sp := range sweepPoints {
+		m := CoveringMeasurement{System: sp.System, Resolution: sp.Resolution}
+		for i, f := range ds.Features {
+			if progress != nil {
+				progress(i, &m)
+			}
+			if sp.System == SystemH3 && opts.H3CellCap > 0 {
+				estimate, err := EstimateH3Cells(f.S2Polygon, sp.Resolution)
+				if err != nil {
+					return nil, fmt.Errorf("%s resolution %d, feature %d: %w", sp.System, sp.Resolution, f.FeatureID, err)
+				}
+				if estimate > opts.H3CellCap {
+					m.OverCap = append(m.OverCap, CappedFeature{FeatureID: f.FeatureID, EstimatedCells: estimate})
+					continue
+				}
+			}
+
+			covering, duration, timedOut, err := c.cover(ctx, f, sp, opts)
+			if ctx.Err() != nil {
+				return measurements, ctx.Err()
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s resolution %d, feature %d: %w", sp.System, sp.Resolution, f.FeatureID, err)
+			}
+			if timedOut {
+				m.TimedOut = append(m.TimedOut, f.FeatureID)
+				continue
+			}
+			if ok, err := recordVerified(&m, opts, f, covering); err != nil || !ok {
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			var fallback time.Duration
+			if len(covering) == 0 {
+				m.Empty = append(m.Empty, f.FeatureID)
+				if opts.CentroidFallback {
+					start := time.Now()
+					cell, err := CentroidCell(f, sp.System, sp.Resolution)
+					fallback = time.Since(start)
+					if err != nil {
+						return nil, fmt.Errorf("%s resolution %d, feature %d: centroid cell: %w", sp.System, sp.Resolution, f.FeatureID, err)
+					}
+					covering = []uint64{cell}
+					duration += fallback
+				}
+			}
+
+			if opts.Precision > 0 {
+				samples := []float64{float64(duration)}
+				for len(samples) < opts.MaxSamples && !preciseEnough(samples, opts.Precision) {
+					_, d, timedOut, err := c.cover(ctx, f, sp, opts)
+					if ctx.Err() != nil {
+						return measurements, ctx.Err()
+					}
+					if err != nil {
+						return nil, fmt.Errorf("%s resolution %d, feature %d: %w", sp.System, sp.Resolution, f.FeatureID, err)
+					}
+					if timedOut {
+						break
+					}
+					samples = append(samples, float64(d+fallback))
+				}
+				if !preciseEnough(samples, opts.Precision) {
+					m.Unconverged = append(m.Unconverged, f.FeatureID)
+				}
+				duration = time.Duration(report.NewDistribution(samples).Mean)
+				m.Samples = append(m.Samples, len(samples))
+			}
+			m.Durations = append(m.Durations, duration)
+			m.FeatureIDs = append(m.FeatureIDs, f.FeatureID)
+			m.Cells += len(covering)
+			m.AreaKm2 += f.AreaKm2()
+			if sp.System == SystemS2 && opts.S2MinLevel(sp.Resolution) < sp.Resolution {
+				if m.LevelCells == nil {
+					m.LevelCells = make(map[int]int)
+				}
+				for _, c := range covering {
+					m.LevelCells[s2.CellID(c).Level()]++
+				}
+			}
+		}
+		if progress != nil {
+			progress(len(ds.Features), &m)
+		}
+		measurements = append(measurements, m)
+	}
+	return measurements, nil
+}
+
+// preciseEnough reports whether the 95% confidence interval of the mean of
+// samples is within precision of the mean on either side
+func preciseEnough(samples []float64, precision float64) bool {
+	mean := report.NewDistribution(slices.Clone(samples)).Mean
+	return report.ConfidenceHalfWidth95(samples) <= precision*mean
+}
+
+// coverer times coverings one at a time, keeping track of the one
+// abandoned after a timeout
+type coverer struct {
+	abandoned *coveringRun
+}
+
+// cover times the covering of f at sp, reporting timedOut instead of
+// waiting longer than opts.Timeout
+func (c *coverer) cover(ctx context.Context, f Feature, sp SweepPoint, opts CoveringOptions) (cells []uint64, duration time.Duration, timedOut bool, err error) {
+	if opts.Timeout <= 0 && ctx.Done() == nil {
+		// Nothing can interrupt the call, so skip the goroutine
+		start := time.Now()
+		cells, err := coverWithOptions(f, sp, opts)
+		return cells, time.Since(start), false, err
+	}
+
+	run := startCovering(f, sp, opts)
+	if err := run.wait(ctx, opts.Timeout); err != nil {
+		if ctx.Err() != nil {
+			return nil, 0, false, ctx.Err()
+		}
+		if c.abandoned != nil {
+			if err := c.abandoned.wait(ctx, 0); err != nil {
+				return nil, 0, false, err
+			}
+		}
+		c.abandoned = run
+		return nil, 0, true, nil
+	}
+	return run.cells, run.duration, false, run.err
+}
+
+// recordVerified runs VerifyCovering when enabled, recording a violation in
+// m and reporting false if the covering is wrong
+func recordVerified(m *CoveringMeasurement, opts CoveringOptions, f Feature, cells []uint64) (bool, error) {
+	if opts.Verify <= 0 {
+		return true, nil
+	}
+	v, err := VerifyCovering(f, m.System, m.Resolution, cells, opts.Verify, int64(f.FeatureID))
+	if err != nil {
+		return false, fmt.Errorf("%s resolution %d, feature %d: verifying: %w", m.System, m.Resolution, f.FeatureID, err)
+	}
+	if v.Count > 0 {
+		m.Violations = append(m.Violations, v)
+		return false, nil
+	}
+	return true, nil
+}
+
+// coverWithOptions is CoverFeature with the H3 containment mode and the S2
+// level span of opts
+func coverWithOptions(f Feature, sp SweepPoint, opts CoveringOptions) ([]uint64, error) {
+	if sp.System == SystemH3 && opts.H3Containment != "" {
+		return dh3.CoverContainment(f.H3Polygon, sp.Resolution, dh3.Containment[opts.H3Containment])
+	}
+	if minLevel := opts.S2MinLevel(sp.Resolution); sp.System == SystemS2 && minLevel < sp.Resolution {
+		return ds2.CoverLevels(f.S2Polygon, minLevel, sp.Resolution, opts.MaxCells), nil
+	}
+	return CoverFeature(f, sp.System, sp.Resolution, opts.MaxCells)
+}
+
+// coveringRun is a CoverFeature call running in its own goroutine; its
+// results are valid once done is closed
+type coveringRun struct {
+	done     chan struct{}
+	cells    []uint64
+	duration time.Duration
+	err      error
+}
+
+func startCovering(f Feature, sp SweepPoint, opts CoveringOptions) *coveringRun {
+	run := &coveringRun{done: make(chan struct{})}
+	go func() {
+		defer close(run.done)
+		start := time.Now()
+		run.cells, run.err = coverWithOptions(f, sp, opts)
+		run.duration = time.Since(start)
+	}()
+	return run
+}
+
+// wait blocks until the covering finishes, ctx is done or the timeout (if
+// positive) passes
+func (r *coveringRun) wait(ctx context.Context, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// CoverGeometry converts a single Polygon geometry for one system and covers
+// it, timing the two stages separately
+func CoverGeometry(geometry geojson.Geometry, system string, resolution int, maxCells int) (cells []uint64, convert time.Duration, cover time.Duration, err error) {
+	start := time.Now()
+	if system == SystemH3 {
+		polygon, err := dh3.FromGeometry(geometry, geojson.LogSkippedRing)
+		convert = time.Since(start)
+		if err != nil {
+			return nil, convert, 0, err
+		}
+		start = time.Now()
+		cells, err = dh3.Cover(polygon, resolution)
+		return cells, convert, time.Since(start), err
+	}
+
+	polygon, err := ds2.FromGeometry(geometry, geojson.LogSkippedRing)
+	convert = time.Since(start)
+	if err != nil {
+		return nil, convert, 0, err
+	}
+	start = time.Now()
+	cells = ds2.Cover(polygon, resolution, maxCells)
+	return cells, convert, time.Since(start), nil
+}
+
+// EarthRadiusKm is the mean Earth radius used to turn steradians and
+// radians into km² and km
+const EarthRadiusKm = 6371.0088
+
+// EstimateH3Cells estimates how many cells PolygonToCells returns for a
+// polygon at a resolution without running it: the polygon area in average
+// cells plus one cell per average edge length of boundary. h3-go does not
+// export maxPolygonToCellsSize, and its bounding-box bound is far too loose
+// for a guard anyway. H3 cell areas vary by about 2x over the globe, so the
+// estimate can be off by that factor either way; leave headroom in caps.
+func EstimateH3Cells(polygon *s2.Polygon, resolution int) (int, error) {
+	cellArea, err := h3.HexagonAreaAvgKm2(resolution)
+	if err != nil {
+		return 0, err
+	}
+	edge, err := h3.HexagonEdgeLengthAvgKm(resolution)
+	if err != nil {
+		return 0, err
+	}
+	var perimeter float64
+	for _, loop := range polygon.Loops() {
+		for i := 0; i < loop.NumVertices(); i++ {
+			perimeter += loop.Vertex(i).Distance(loop.Vertex(i + 1)).Radians()
+		}
+	}
+	area := polygon.Area() * EarthRadiusKm * EarthRadiusKm
+	return int(math.Ceil(area/cellArea+perimeter*EarthRadiusKm/edge)) + 1, nil
+}
@@ -0,0 +1,105 @@
+package bench
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"golang.org/x/sys/cpu"
+)
+
+// armCores names the Arm cores found in servers and laptops by the
+// implementer and part numbers of /proc/cpuinfo
+var armCores = map[string]string{
+	"0x41/0xd03": "cortex-a53",
+	"0x41/0xd07": "cortex-a57",
+	"0x41/0xd08": "cortex-a72",
+	"0x41/0xd0b": "cortex-a76",
+	"0x41/0xd0c": "neoverse-n1",
+	"0x41/0xd40": "neoverse-v1",
+	"0x41/0xd49": "neoverse-n2",
+	"0x41/0xd4f": "neoverse-v2",
+	"0x41/0xd84": "neoverse-v3",
+	"0x41/0xd8e": "neoverse-n3",
+	"0xc0/0xac3": "ampere-1",
+	"0xc0/0xac4": "ampere-1a",
+}
+
+// cpuModel returns the CPU model name and microarchitecture of the
+// machine, as far as the OS tells them. The microarchitecture is the Arm
+// core, or on x86 the x86-64 microarchitecture level whose instructions
+// the CPU has, since model names do not sort x86 CPUs by what they run.
+func cpuModel() (model, microarch string) {
+	switch runtime.GOOS {
+	case "linux":
+		model, microarch = linuxCPU()
+	case "darwin":
+		if out, err := exec.Command("sysctl", "-n", "machdep.cpu.brand_string").Output(); err == nil {
+			model = strings.TrimSpace(string(out))
+		}
+		if strings.HasPrefix(model, "Apple ") {
+			microarch = strings.ToLower(strings.ReplaceAll(model, " ", "-"))
+		}
+	}
+	if microarch == "" && (runtime.GOARCH == "amd64" || runtime.GOARCH == "386") {
+		microarch = x86Level()
+	}
+	if model == "" {
+		model = microarch
+	}
+	return model, microarch
+}
+
+// linuxCPU reads the first processor of /proc/cpuinfo
+func linuxCPU() (model, microarch string) {
+	data, err := os.ReadFile("/proc/cpuinfo")
+	if err != nil {
+		return "", ""
+	}
+	var implementer, part string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			if model != "" || part != "" {
+				break // the end of the first processor
+			}
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "model name":
+			model = value
+		case "CPU implementer":
+			implementer = value
+		case "CPU part":
+			part = value
+		}
+	}
+	if part != "" {
+		microarch = armCores[implementer+"/"+part]
+		if microarch == "" {
+			microarch = "arm " + implementer + "/" + part
+		}
+	}
+	return model, microarch
+}
+
+// x86Level returns the x86-64 microarchitecture level (v1 to v4) whose
+// instructions the CPU supports, leaving out the few x/sys/cpu does not
+// report
+func x86Level() string {
+	x := cpu.X86
+	switch {
+	case !(x.HasCX16 && x.HasPOPCNT && x.HasSSE3 && x.HasSSE41 && x.HasSSE42 && x.HasSSSE3):
+		return "x86-64-v1"
+	case !(x.HasAVX && x.HasAVX2 && x.HasBMI1 && x.HasBMI2 && x.HasFMA && x.HasOSXSAVE):
+		return "x86-64-v2"
+	case !(x.HasAVX512F && x.HasAVX512BW && x.HasAVX512CD && x.HasAVX512DQ && x.HasAVX512VL):
+		return "x86-64-v3"
+	}
+	return "x86-64-v4"
+}
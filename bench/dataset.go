@@ -0,0 +1,318 @@
+// Package bench converts GeoJSON datasets for both H3 and S2 and measures
+// their coverings.
+package bench
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	dh3 "github.com/nkk36/earth-discretization-benchmark/discretize/h3"
+	ds2 "github.com/nkk36/earth-discretization-benchmark/discretize/s2"
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+	"github.com/uber/h3-go/v4"
+)
+
+// The systems a covering can be computed in
+const (
+	SystemH3 = "H3"
+	SystemS2 = "S2"
+)
+
+// Feature holds a single polygon feature converted for both systems
+type Feature struct {
+	FeatureID  int
+	Geometry   geojson.Geometry
+	Properties map[string]interface{} // of the GeoJSON feature, for Filter
+	H3Polygon  h3.GeoPolygon
+	S2Polygon  *s2.Polygon
+}
+
+// Dataset is a GeoJSON file converted once for both H3 and S2 so scenarios
+// can compare the systems on exactly the same features
+type Dataset struct {
+	Path     string // file the dataset was read from, or a caller-supplied name
+	Features []Feature
+	Issues   []ConversionIssue // what was dropped during conversion
+}
+
+// LoadDataset reads a GeoJSON FeatureCollection and converts every polygon
+// feature to both an H3 GeoPolygon and an S2 polygon. Converted datasets are
+// cached by content hash (see datasetCacheDir), so repeated runs over the
+// same file skip parsing and conversion.
+//
+// The features dropped during conversion are always printed; in strict mode
+// (see ConversionMode) any of them fails the load.
+func LoadDataset(filePath string) (*Dataset, error) {
+	return LoadDatasetCRS(filePath, "")
+}
+
+// LoadDatasetCRS loads a dataset like LoadDataset, reading its coordinates
+// in the named CRS (see geojson.LookupCRS) whatever the file's crs member
+// says. An empty sourceCRS uses the crs member, and WGS84 without one.
+func LoadDatasetCRS(filePath, sourceCRS string) (*Dataset, error) {
+	return LoadDatasetCRSTo(os.Stdout, filePath, sourceCRS)
+}
+
+// LoadDatasetCRSTo loads a dataset like LoadDatasetCRS, writing its
+// conversion report to w instead of stdout
+func LoadDatasetCRSTo(w io.Writer, filePath, sourceCRS string) (*Dataset, error) {
+	mode, err := ConversionMode()
+	if err != nil {
+		return nil, err
+	}
+	data, err := geojson.ReadData(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var cachePath string
+	ds, cached := (*Dataset)(nil), false
+	if dir := datasetCacheDir(); dir != "" {
+		cachePath = datasetCachePath(dir, data, sourceCRS, mode == ConversionRepair)
+		ds, cached = readDatasetCache(cachePath, filePath)
+	}
+	if !cached {
+		if ds, err = parseDataset(filePath, data, sourceCRS, mode == ConversionRepair); err != nil {
+			return nil, err
+		}
+		if cachePath != "" {
+			if err := writeDatasetCache(cachePath, ds); err != nil {
+				log.Printf("Warning: could not cache dataset: %v", err)
+			}
+		}
+	}
+
+	report := &ConversionReport{Mode: mode, Issues: ds.Issues}
+	report.Fprint(w)
+	if err := report.Err(); err != nil {
+		return nil, err
+	}
+	return ds, nil
+}
+
+// ParseDataset converts an in-memory GeoJSON FeatureCollection document,
+// reprojected to WGS84 if it has a crs member
+func ParseDataset(name string, data []byte) (*Dataset, error) {
+	return parseDataset(name, data, "", false)
+}
+
+func parseDataset(name string, data []byte, sourceCRS string, repair bool) (*Dataset, error) {
+	fc, err := geojson.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := fc.Reproject(sourceCRS); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return newDataset(name, fc, repair)
+}
+
+// NewDataset converts the polygon features of a parsed FeatureCollection,
+// recording what it drops in ds.Issues. Degenerate rings (see
+// geojson.SanitizeGeometry) are left out before conversion, so they never
+// reach the timed covering calls. Features are converted in parallel (see
+// geojson.ParallelFor); the dataset and its issues keep the order of the
+// collection.
+func NewDataset(name string, fc geojson.FeatureCollection) (*Dataset, error) {
+	return newDataset(name, fc, false)
+}
+
+// newDataset is NewDataset, repairing the degenerate rings that can be in
+// the repair conversion mode
+func newDataset(name string, fc geojson.FeatureCollection, repair bool) (*Dataset, error) {
+	featureIDs := geojson.FeatureIDs(fc)
+	features := make([]*Feature, len(fc.Features))
+	reports := make([]ConversionReport, len(fc.Features))
+	geojson.ParallelFor(len(fc.Features), func(i int) {
+		feature, report := fc.Features[i], &reports[i]
+		featureID := featureIDs[i]
+		if feature.Geometry.Type != "Polygon" {
+			report.DropFeature(i, featureID, fmt.Sprintf("not a Polygon (%s)", feature.Geometry.Type))
+			return
+		}
+
+		geometry := feature.Geometry
+		if geometry.Err() == nil {
+			var defects []geojson.RingDefect
+			geometry, defects = geojson.SanitizeGeometry(geometry, repair)
+			if report.RecordDefects(i, featureID, defects) {
+				return
+			}
+		}
+
+		f, err := ConvertFeature(featureID, geometry, report.SkipRing(i, featureID))
+		if err != nil {
+			report.DropFeature(i, featureID, err.Error())
+			return
+		}
+		f.Properties = feature.Properties
+		features[i] = &f
+	})
+
+	ds := &Dataset{Path: name}
+	for i, f := range features {
+		ds.Issues = append(ds.Issues, reports[i].Issues...)
+		if f != nil {
+			ds.Features = append(ds.Features, *f)
+		}
+	}
+
+	if len(ds.Features) == 0 {
+		return nil, fmt.Errorf("no polygon features in %s", name)
+	}
+	return ds, nil
+}
+
+// ConvertFeature converts a single Polygon geometry for both systems. The
+// S2 pass validates the rings and reports the holes it drops to skip; the
+// H3 polygon and the feature's Geometry leave out the same holes, so both
+// systems cover the same shape.
+func ConvertFeature(featureID int, geometry geojson.Geometry, skip geojson.SkipRingFunc) (Feature, error) {
+	dropped := make(map[int]bool)
+	s2Polygon, err := ds2.FromGeometry(geometry, func(ring int, reason string) error {
+		dropped[ring] = true
+		return skip(ring, reason)
+	})
+	if err != nil {
+		return Feature{}, err
+	}
+	if len(dropped) > 0 {
+		kept := geojson.Geometry{Type: geometry.Type}
+		for r, ring := range geometry.Coordinates {
+			if !dropped[r] {
+				kept.Coordinates = append(kept.Coordinates, ring)
+			}
+		}
+		geometry = kept
+	}
+	h3Polygon, err := dh3.FromGeometry(geometry, geojson.IgnoreSkippedRing)
+	if err != nil {
+		return Feature{}, err
+	}
+	return Feature{
+		FeatureID: featureID,
+		Geometry:  geometry,
+		H3Polygon: h3Polygon,
+		S2Polygon: s2Polygon,
+	}, nil
+}
+
+// Bounds returns the latitude/longitude bounding rectangle of all features
+func (d *Dataset) Bounds() s2.Rect {
+	rect := s2.EmptyRect()
+	for _, f := range d.Features {
+		for _, ll := range f.H3Polygon.GeoLoop {
+			rect = rect.AddPoint(s2.LatLngFromDegrees(ll.Lat, ll.Lng))
+		}
+	}
+	return rect
+}
+
+// RandomPointsInRect draws n points uniformly (in degrees) from rect
+func RandomPointsInRect(rect s2.Rect, n int, seed int64) []s2.LatLng {
+	rng := rand.New(rand.NewSource(seed))
+	points := make([]s2.LatLng, n)
+	for i := range points {
+		lat := rect.Lat.Lo + rng.Float64()*rect.Lat.Length()
+		lng := rect.Lng.Lo + rng.Float64()*rect.Lng.Length()
+		points[i] = s2.LatLng{Lat: s1.Angle(lat), Lng: s1.Angle(math.Remainder(lng, 2*math.Pi))}
+	}
+	return points
+}
+
+// PointCell returns the cell of the given system containing a point
+func PointCell(system string, ll s2.LatLng, resolution int) (uint64, error) {
+	if system == SystemH3 {
+		return dh3.PointCell(ll.Lat.Degrees(), ll.Lng.Degrees(), resolution)
+	}
+	return ds2.PointCell(ll, resolution), nil
+}
+
+// CentroidCell returns the cell of the given system containing the
+// feature's centroid, the usual stand-in for a polygon smaller than a cell
+func CentroidCell(f Feature, system string, resolution int) (uint64, error) {
+	centroid := f.S2Polygon.Centroid()
+	if centroid.Norm() == 0 {
+		// A polygon without area has no centroid; use its first vertex
+		if f.S2Polygon.NumLoops() == 0 || f.S2Polygon.Loop(0).NumVertices() == 0 {
+			return 0, fmt.Errorf("feature %d has no vertices", f.FeatureID)
+		}
+		centroid = f.S2Polygon.Loop(0).Vertex(0)
+	}
+	return PointCell(system, s2.LatLngFromPoint(centroid), resolution)
+}
+
+// CoverFeature returns the covering of a feature for the given system
+func CoverFeature(f Feature, system string, resolution int, maxCells int) ([]uint64, error) {
+	if system == SystemH3 {
+		return dh3.Cover(f.H3Polygon, resolution)
+	}
+	return ds2.Cover(f.S2Polygon, resolution, maxCells), nil
+}
+
+// ComputeCoverings covers every feature of the dataset, indexed like ds.Features
+func ComputeCoverings(ds *Dataset, system string, resolution int, maxCells int) ([][]uint64, error) {
+	coverings := make([][]uint64, len(ds.Features))
+	for i, f := range ds.Features {
+		var err error
+		coverings[i], err = CoverFeature(f, system, resolution, maxCells)
+		if err != nil {
+			return nil, fmt.Errorf("covering feature %d: %w", f.FeatureID, err)
+		}
+	}
+	return coverings, nil
+}
+
+// Round returns the dataset with every coordinate rounded to the given
+// number of decimal places and converted again, keeping the feature IDs.
+// Rounding merges close vertices into repeated positions and spikes, which
+// are repaired; rings it collapses altogether are dropped and recorded in
+// the rounded dataset's Issues.
+func (d *Dataset) Round(decimals int) *Dataset {
+	features := make([]*Feature, len(d.Features))
+	reports := make([]ConversionReport, len(d.Features))
+	geojson.ParallelFor(len(d.Features), func(i int) {
+		src, report := d.Features[i], &reports[i]
+		geometry, defects := geojson.SanitizeGeometry(geojson.RoundGeometry(src.Geometry, decimals), true)
+		if report.RecordDefects(i, src.FeatureID, defects) {
+			return
+		}
+		f, err := ConvertFeature(src.FeatureID, geometry, report.SkipRing(i, src.FeatureID))
+		if err != nil {
+			report.DropFeature(i, src.FeatureID, err.Error())
+			return
+		}
+		f.Properties = src.Properties
+		features[i] = &f
+	})
+
+	rounded := &Dataset{Path: d.Path}
+	for i, f := range features {
+		rounded.Issues = append(rounded.Issues, reports[i].Issues...)
+		if f != nil {
+			rounded.Features = append(rounded.Features, *f)
+		}
+	}
+	return rounded
+}
+
+// Subset returns a dataset holding only the features with the given IDs
+func (d *Dataset) Subset(featureIDs []int) *Dataset {
+	keep := make(map[int]bool, len(featureIDs))
+	for _, id := range featureIDs {
+		keep[id] = true
+	}
+	subset := &Dataset{Path: d.Path}
+	for _, f := range d.Features {
+		if keep[f.FeatureID] {
+			subset.Features = append(subset.Features, f)
+		}
+	}
+	return subset
+}
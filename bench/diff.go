@@ -0,0 +1,144 @@
+package bench
+
+import (
+	"maps"
+	"math"
+	"slices"
+
+	"github.com/nkk36/earth-discretization-benchmark/report"
+)
+
+// PointDiff compares the measurements of one sweep point in two runs, A the
+// baseline and B the candidate
+type PointDiff struct {
+	SweepPoint
+	InA, InB bool
+
+	// MeanNsA and MeanNsB are the mean covering durations per feature.
+	// Ratio is the geometric mean of B/A over the features measured in
+	// both runs, with the 95% confidence interval RatioLow-RatioHigh;
+	// Paired counts those features. Runs without feature IDs are compared
+	// by their means instead, with an interval from their standard errors.
+	MeanNsA, MeanNsB    float64
+	Ratio               float64
+	RatioLow, RatioHigh float64
+	Paired              int
+
+	CellsA, CellsB           int
+	ViolationsA, ViolationsB int
+	SkippedA, SkippedB       int // timed out or over the cell cap
+
+	// Slower and Faster report a duration change beyond noise: the whole
+	// confidence interval is past the threshold given to DiffResults
+	Slower, Faster bool
+}
+
+// Changed reports whether anything but noise differs: the duration beyond
+// the threshold, the number of cells, the violations or the skipped
+// coverings, or the sweep point is missing from one run
+func (d PointDiff) Changed() bool {
+	return d.Slower || d.Faster || d.CellsA != d.CellsB || d.ViolationsA != d.ViolationsB ||
+		d.SkippedA != d.SkippedB || d.InA != d.InB
+}
+
+// DiffResults compares every sweep point of a and b, in the order of a's
+// sweep points followed by those only in b. A duration counts as changed
+// when its ratio's confidence interval lies entirely above 1+threshold or
+// below 1/(1+threshold), e.g. threshold 0.05 for a 5% change.
+func DiffResults(a, b *Results, threshold float64) []PointDiff {
+	points := a.SweepPoints()
+	for _, sp := range b.SweepPoints() {
+		if !slices.Contains(points, sp) {
+			points = append(points, sp)
+		}
+	}
+
+	diffs := make([]PointDiff, 0, len(points))
+	for _, sp := range points {
+		d := PointDiff{SweepPoint: sp}
+		perFeatureA, unpairedA := d.collect(a, sp, true)
+		perFeatureB, unpairedB := d.collect(b, sp, false)
+		if d.InA && d.InB {
+			d.compareDurations(perFeatureA, perFeatureB, unpairedA, unpairedB)
+			d.Slower = d.RatioLow > 1+threshold
+			d.Faster = d.RatioHigh < 1/(1+threshold)
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs
+}
+
+// collect reads the measurements of sp from r into d's A or B side,
+// returning the mean duration of each feature over the repetitions and all
+// durations of measurements without feature IDs
+func (d *PointDiff) collect(r *Results, sp SweepPoint, isA bool) (map[int]float64, []float64) {
+	sums := make(map[int]float64)
+	counts := make(map[int]int)
+	var all, unpaired []float64
+	found, cells, violations, skipped := false, 0, 0, 0
+	for _, m := range r.Measurements {
+		if m.System != sp.System || m.Resolution != sp.Resolution {
+			continue
+		}
+		if !found {
+			cells = m.Cells
+		}
+		found = true
+		violations += len(m.Violations)
+		skipped += len(m.TimedOut) + len(m.OverCap)
+		for i, duration := range m.Durations {
+			all = append(all, float64(duration))
+			if len(m.FeatureIDs) != len(m.Durations) {
+				unpaired = append(unpaired, float64(duration))
+				continue
+			}
+			sums[m.FeatureIDs[i]] += float64(duration)
+			counts[m.FeatureIDs[i]]++
+		}
+	}
+	perFeature := make(map[int]float64, len(sums))
+	for id, sum := range sums {
+		perFeature[id] = sum / float64(counts[id])
+	}
+	mean := report.NewDistribution(all).Mean
+	if isA {
+		d.InA, d.MeanNsA, d.CellsA, d.ViolationsA, d.SkippedA = found, mean, cells, violations, skipped
+	} else {
+		d.InB, d.MeanNsB, d.CellsB, d.ViolationsB, d.SkippedB = found, mean, cells, violations, skipped
+	}
+	return perFeature, unpaired
+}
+
+// compareDurations sets the ratio and its interval, from the per-feature
+// log ratios when both runs identify their features and from the two
+// means otherwise
+func (d *PointDiff) compareDurations(a, b map[int]float64, unpairedA, unpairedB []float64) {
+	var logRatios []float64
+	for id, durationA := range a {
+		if durationB, ok := b[id]; ok && durationA > 0 && durationB > 0 {
+			logRatios = append(logRatios, math.Log(durationB/durationA))
+		}
+	}
+	d.Paired = len(logRatios)
+
+	if len(logRatios) >= 2 && len(unpairedA) == 0 && len(unpairedB) == 0 {
+		mean := report.NewDistribution(slices.Clone(logRatios)).Mean
+		half := report.ConfidenceHalfWidth95(logRatios)
+		d.Ratio, d.RatioLow, d.RatioHigh = math.Exp(mean), math.Exp(mean-half), math.Exp(mean+half)
+		return
+	}
+
+	// Unpaired: the log of the ratio of means has a standard error of about
+	// the two relative standard errors combined
+	d.Paired = 0
+	if d.MeanNsA <= 0 || d.MeanNsB <= 0 {
+		d.Ratio, d.RatioLow, d.RatioHigh = math.NaN(), math.NaN(), math.NaN()
+		return
+	}
+	durationsA, durationsB := append(unpairedA, slices.Collect(maps.Values(a))...), append(unpairedB, slices.Collect(maps.Values(b))...)
+	relA := report.StandardError(durationsA) / d.MeanNsA
+	relB := report.StandardError(durationsB) / d.MeanNsB
+	half := 1.96 * math.Sqrt(relA*relA+relB*relB)
+	logRatio := math.Log(d.MeanNsB / d.MeanNsA)
+	d.Ratio, d.RatioLow, d.RatioHigh = math.Exp(logRatio), math.Exp(logRatio-half), math.Exp(logRatio+half)
+}
@@ -0,0 +1,156 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang/geo/r1"
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+)
+
+// PropertyCondition compares a GeoJSON property with a value, as parsed
+// from KEY=VALUE, KEY!=VALUE, KEY<VALUE, KEY<=VALUE, KEY>VALUE or
+// KEY>=VALUE by ParseCondition
+type PropertyCondition struct {
+	Key   string
+	Op    string
+	Value string
+}
+
+// conditionOps are the operators of a condition, two-character ones first
+// so "<=" is not read as "<" followed by "=VALUE"
+var conditionOps = []string{"!=", "<=", ">=", "=", "<", ">"}
+
+// ParseCondition parses a condition such as "ADMIN=France" or
+// "POP_EST>=1000000". The ordering operators compare numerically, so their
+// value must be a number.
+func ParseCondition(s string) (PropertyCondition, error) {
+	at, op := -1, ""
+	for _, candidate := range conditionOps {
+		if i := strings.Index(s, candidate); i >= 0 && (at < 0 || i < at) {
+			at, op = i, candidate
+		}
+	}
+	if at < 0 {
+		return PropertyCondition{}, fmt.Errorf("condition %q has no operator (=, !=, <, <=, >, >=)", s)
+	}
+	c := PropertyCondition{Key: strings.TrimSpace(s[:at]), Op: op, Value: strings.TrimSpace(s[at+len(op):])}
+	if c.Key == "" {
+		return PropertyCondition{}, fmt.Errorf("condition %q has no property name", s)
+	}
+	if op != "=" && op != "!=" {
+		if _, err := strconv.ParseFloat(c.Value, 64); err != nil {
+			return PropertyCondition{}, fmt.Errorf("condition %q compares with %s, which needs a number", s, op)
+		}
+	}
+	return c, nil
+}
+
+// Match reports whether a feature's properties satisfy the condition. A
+// missing property only satisfies !=. Equality compares numerically when
+// both sides are numbers, so "POP=1e6" matches 1000000, and as text
+// otherwise.
+func (c PropertyCondition) Match(properties map[string]interface{}) bool {
+	value, ok := properties[c.Key]
+	if !ok {
+		return c.Op == "!="
+	}
+	text := propertyText(value)
+	number, numErr := strconv.ParseFloat(text, 64)
+	want, wantErr := strconv.ParseFloat(c.Value, 64)
+	numeric := numErr == nil && wantErr == nil
+
+	switch c.Op {
+	case "=":
+		return text == c.Value || numeric && number == want
+	case "!=":
+		return text != c.Value && !(numeric && number == want)
+	case "<":
+		return numeric && number < want
+	case "<=":
+		return numeric && number <= want
+	case ">":
+		return numeric && number > want
+	case ">=":
+		return numeric && number >= want
+	}
+	return false
+}
+
+// propertyText formats a decoded JSON property the way it would be written
+// in a condition
+func propertyText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return "null"
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// ParseBBox parses a bounding box "minLng,minLat,maxLng,maxLat" in degrees.
+// A minLng greater than maxLng crosses the antimeridian.
+func ParseBBox(s string) (s2.Rect, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return s2.Rect{}, fmt.Errorf("bbox %q is not minLng,minLat,maxLng,maxLat", s)
+	}
+	var v [4]float64
+	for i, part := range parts {
+		var err error
+		if v[i], err = strconv.ParseFloat(strings.TrimSpace(part), 64); err != nil {
+			return s2.Rect{}, fmt.Errorf("bbox %q: %w", s, err)
+		}
+	}
+	if v[1] > v[3] || v[1] < -90 || v[3] > 90 || v[0] < -180 || v[0] > 180 || v[2] < -180 || v[2] > 180 {
+		return s2.Rect{}, fmt.Errorf("bbox %q is outside [-180, 180] x [-90, 90] or has minLat above maxLat", s)
+	}
+	degrees := func(d float64) float64 { return (s1.Angle(d) * s1.Degree).Radians() }
+	return s2.Rect{
+		Lat: r1.Interval{Lo: degrees(v[1]), Hi: degrees(v[3])},
+		Lng: s1.IntervalFromEndpoints(degrees(v[0]), degrees(v[2])),
+	}, nil
+}
+
+// FeatureFilter selects the features of a dataset. A feature is kept when
+// it satisfies every condition and, with a bounding box, its bounds
+// intersect it.
+type FeatureFilter struct {
+	Where []PropertyCondition
+	BBox  *s2.Rect
+}
+
+// Empty reports whether the filter keeps every feature
+func (f FeatureFilter) Empty() bool {
+	return len(f.Where) == 0 && f.BBox == nil
+}
+
+// Filter returns a dataset holding only the features selected by filter
+func (d *Dataset) Filter(filter FeatureFilter) *Dataset {
+	filtered := &Dataset{Path: d.Path}
+	for _, f := range d.Features {
+		if filter.BBox != nil && !filter.BBox.Intersects(f.S2Polygon.RectBound()) {
+			continue
+		}
+		keep := true
+		for _, c := range filter.Where {
+			if !c.Match(f.Properties) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			filtered.Features = append(filtered.Features, f)
+		}
+	}
+	return filtered
+}
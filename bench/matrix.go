@@ -0,0 +1,283 @@
+package bench
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	dh3 "github.com/nkk36/earth-discretization-benchmark/discretize/h3"
+)
+
+// Matrix is the parameter axes of an experiment, usually read from a JSON
+// config file with LoadMatrix:
+//
+//	{
+//	  "systems": ["H3", "S2"],
+//	  "resolutions": {"H3": [5, 6, 7], "S2": [9, 10, 11]},
+//	  "max_cells": [8, 64],
+//	  "containment": ["center", "overlapping"],
+//	  "workers": [1, 4]
+//	}
+//
+// Experiments enumerates the cross product. MaxCells only applies to S2 and
+// Containment only to H3, so each system is crossed with its own axis
+// rather than with both. Empty axes take the defaults of NewRunner.
+type Matrix struct {
+	Systems     []string         `json:"systems"`
+	Resolutions map[string][]int `json:"resolutions"`
+	MaxCells    []int            `json:"max_cells"`
+	Containment []string         `json:"containment"`
+	Workers     []int            `json:"workers"`
+}
+
+// Experiment is one cell of a Matrix: a sweep point with the parameters it
+// is covered with. MaxCells is 0 for H3 and Containment empty for S2.
+type Experiment struct {
+	System      string `json:"system"`
+	Resolution  int    `json:"resolution"`
+	MaxCells    int    `json:"max_cells,omitempty"`
+	Containment string `json:"containment,omitempty"`
+	Workers     int    `json:"workers"`
+}
+
+// LoadMatrix reads a Matrix from a JSON file, rejecting unknown fields so a
+// misspelled axis does not silently fall back to its default
+func LoadMatrix(path string) (*Matrix, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var m Matrix
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&m); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// Experiments returns the cross product of the axes, ordered by workers,
+// then system, then MaxCells or containment, then resolution, so the
+// experiments sharing covering options and concurrency are adjacent
+func (m *Matrix) Experiments() ([]Experiment, error) {
+	systems := m.Systems
+	if len(systems) == 0 {
+		systems = []string{SystemH3, SystemS2}
+	}
+	maxCells := m.MaxCells
+	if len(maxCells) == 0 {
+		maxCells = []int{8}
+	}
+	containment := m.Containment
+	if len(containment) == 0 {
+		containment = []string{"center"}
+	}
+	workers := m.Workers
+	if len(workers) == 0 {
+		workers = []int{1}
+	}
+
+	for _, c := range containment {
+		if _, ok := dh3.Containment[c]; !ok {
+			return nil, fmt.Errorf("unknown containment mode %q", c)
+		}
+	}
+	for _, n := range maxCells {
+		if n < 1 {
+			return nil, fmt.Errorf("max_cells must be at least 1, got %d", n)
+		}
+	}
+	for _, n := range workers {
+		if n < 1 {
+			return nil, fmt.Errorf("workers must be at least 1, got %d", n)
+		}
+	}
+
+	var experiments []Experiment
+	for _, w := range workers {
+		for _, system := range systems {
+			resolutions, ok := m.Resolutions[system]
+			switch {
+			case system != SystemH3 && system != SystemS2:
+				return nil, fmt.Errorf("unknown system %q", system)
+			case !ok && system == SystemH3:
+				resolutions = DefaultH3Resolutions
+			case !ok:
+				resolutions = DefaultS2Levels
+			}
+			if system == SystemH3 {
+				for _, c := range containment {
+					for _, res := range resolutions {
+						experiments = append(experiments, Experiment{System: system, Resolution: res, Containment: c, Workers: w})
+					}
+				}
+				continue
+			}
+			for _, n := range maxCells {
+				for _, res := range resolutions {
+					experiments = append(experiments, Experiment{System: system, Resolution: res, MaxCells: n, Workers: w})
+				}
+			}
+		}
+	}
+	if len(experiments) == 0 {
+		return nil, fmt.Errorf("no resolutions selected")
+	}
+	return experiments, nil
+}
+
+// MatrixRow is a measurement tagged with the experiment it belongs to
+type MatrixRow struct {
+	Experiment  Experiment          `json:"experiment"`
+	Measurement CoveringMeasurement `json:"measurement"`
+}
+
+// MatrixResults are the measurements of every experiment of a matrix, in
+// the order of Matrix.Experiments and then by repetition
+type MatrixResults struct {
+	Dataset string          `json:"dataset"`
+	Options CoveringOptions `json:"options"` // shared by all experiments; MaxCells and H3Containment come from each
+	Rows    []MatrixRow     `json:"rows"`
+}
+
+// RunMatrix measures every experiment of m over ds. Experiments sharing
+// covering options and concurrency run together through one Runner built
+// from opts and then configured for them, so opts can set the base
+// covering options (timeout, H3 cell cap, verification), repetitions or
+// budget and sinks; its sweep points and concurrency are replaced, and a
+// budget is shared between the groups by their number of sweep points. When ctx is
+// cancelled or a run fails, the rows completed so far are returned with
+// the error.
+func RunMatrix(ctx context.Context, ds *Dataset, m *Matrix, opts ...Option) (*MatrixResults, error) {
+	runs, err := m.runs(opts)
+	if err != nil {
+		return nil, err
+	}
+	results := &MatrixResults{Dataset: ds.Path, Options: NewRunner(opts...).options}
+	for _, mr := range runs {
+		run, err := mr.runner.Run(ctx, ds)
+		if run != nil {
+			for _, measurement := range run.Measurements {
+				tagged := mr.experiment
+				tagged.Resolution = measurement.Resolution
+				results.Rows = append(results.Rows, MatrixRow{Experiment: tagged, Measurement: measurement})
+			}
+		}
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// PlanMatrix calibrates every experiment of m like Runner.Plan without
+// running it, returning one plan per group of experiments that RunMatrix
+// would run together
+func PlanMatrix(ctx context.Context, ds *Dataset, m *Matrix, sample int, opts ...Option) ([]*Plan, error) {
+	runs, err := m.runs(opts)
+	if err != nil {
+		return nil, err
+	}
+	var plans []*Plan
+	for _, mr := range runs {
+		plan, err := mr.runner.Plan(ctx, ds, sample)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, plan)
+	}
+	return plans, nil
+}
+
+// matrixRun is a Runner over the experiments that share covering options
+// and concurrency; experiment is the first of them
+type matrixRun struct {
+	experiment Experiment
+	runner     *Runner
+}
+
+// runs groups the experiments of m into Runners built from opts
+func (m *Matrix) runs(opts []Option) ([]matrixRun, error) {
+	experiments, err := m.Experiments()
+	if err != nil {
+		return nil, err
+	}
+	base := NewRunner(opts...)
+
+	var runs []matrixRun
+	for start := 0; start < len(experiments); {
+		e := experiments[start]
+		end := start
+		var points []SweepPoint
+		for end < len(experiments) && sameRun(experiments[end], e) {
+			points = append(points, SweepPoint{System: experiments[end].System, Resolution: experiments[end].Resolution})
+			end++
+		}
+
+		options := base.options
+		options.H3Containment = ""
+		if e.System == SystemH3 && e.Containment != "center" {
+			options.H3Containment = e.Containment
+		}
+		if e.System == SystemS2 {
+			options.MaxCells = e.MaxCells
+		}
+		runnerOpts := append(slices.Clone(opts),
+			WithSweepPoints(points...),
+			WithCoveringOptions(options),
+			WithConcurrency(e.Workers),
+		)
+		if base.budget > 0 {
+			// Each group gets its share of the budget by sweep points
+			runnerOpts = append(runnerOpts, WithBudget(base.budget*time.Duration(len(points))/time.Duration(len(experiments))))
+		}
+		runner := NewRunner(runnerOpts...)
+		runs = append(runs, matrixRun{experiment: e, runner: runner})
+		start = end
+	}
+	return runs, nil
+}
+
+// sameRun reports whether two experiments differ only in resolution
+func sameRun(a, b Experiment) bool {
+	a.Resolution = b.Resolution
+	return a == b
+}
+
+// WriteCSV writes one row per measurement, led by the parameters of its
+// experiment
+func (r *MatrixResults) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	headers := append([]string{"System", "Resolution", "MaxCells", "Containment", "Workers"}, measurementCSVHeader[2:]...)
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, row := range r.Rows {
+		e := row.Experiment
+		record := append([]string{e.System, strconv.Itoa(e.Resolution), strconv.Itoa(e.MaxCells), e.Containment, strconv.Itoa(e.Workers)},
+			measurementCSVRow(row.Measurement)[2:]...)
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteJSON writes the results as one indented JSON document
+func (r *MatrixResults) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
@@ -0,0 +1,217 @@
+package bench
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Quality flags of a CoveringMeasurement taken while the machine was
+// disturbed
+const (
+	QualityLoad      = "load"      // other processes kept CPUs busy
+	QualityFrequency = "frequency" // the CPU clock dropped below its peak
+	QualityThrottled = "throttled" // the CPU was thermally throttled
+)
+
+// NoiseThresholds set when a NoiseMonitor flags a measurement. Either is
+// disabled by a value of 0 or less; thermal throttling is always flagged.
+type NoiseThresholds struct {
+	OtherCPUs     float64 // average CPUs kept busy by other processes
+	FrequencyDrop float64 // fraction of the peak clock lost, e.g. 0.1
+}
+
+// NoiseMonitor watches the machine for what makes timings unreliable: load
+// from other processes, CPU frequency scaling and thermal throttling. It
+// reads /proc and /sys, so it only sees anything on Linux, and only what
+// the kernel exposes there (virtual machines often have no cpufreq).
+type NoiseMonitor struct {
+	thresholds NoiseThresholds
+
+	mu      sync.Mutex
+	clocks  []clockSample
+	peakKHz float64
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// clockSample is the mean clock of all CPUs at a time
+type clockSample struct {
+	at  time.Time
+	kHz float64
+}
+
+// noiseWindow is the state of the machine when a measurement started
+type noiseWindow struct {
+	at        time.Time
+	busyTicks int64 // of all CPUs, excluding idle and iowait
+	selfTicks int64 // of this process
+	throttles int64
+}
+
+// noiseSampleInterval is how often the monitor reads the CPU clocks
+const noiseSampleInterval = 250 * time.Millisecond
+
+// clockTicks is USER_HZ, the unit of /proc/stat, 100 on every Linux
+// architecture Go supports
+const clockTicks = 100
+
+// StartNoiseMonitor starts sampling the CPU clocks until Stop
+func StartNoiseMonitor(thresholds NoiseThresholds) *NoiseMonitor {
+	n := &NoiseMonitor{thresholds: thresholds, stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(n.done)
+		ticker := time.NewTicker(noiseSampleInterval)
+		defer ticker.Stop()
+		for {
+			n.sampleClock()
+			select {
+			case <-n.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return n
+}
+
+// Stop ends the sampling
+func (n *NoiseMonitor) Stop() {
+	close(n.stop)
+	<-n.done
+}
+
+func (n *NoiseMonitor) sampleClock() {
+	kHz := cpuClockKHz()
+	if kHz == 0 {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.clocks = append(n.clocks, clockSample{at: time.Now(), kHz: kHz})
+	n.peakKHz = max(n.peakKHz, kHz)
+}
+
+// begin records the start of a measurement; a nil monitor records nothing
+func (n *NoiseMonitor) begin() *noiseWindow {
+	if n == nil {
+		return nil
+	}
+	busy, _ := cpuTicks()
+	return &noiseWindow{at: time.Now(), busyTicks: busy, selfTicks: processTicks(), throttles: throttleCount()}
+}
+
+// flags returns the quality flags of the measurement started at w
+func (n *NoiseMonitor) flags(w *noiseWindow) []string {
+	if w == nil {
+		return nil
+	}
+	var flags []string
+	elapsed := time.Since(w.at).Seconds()
+	busy, ok := cpuTicks()
+	if ok && n.thresholds.OtherCPUs > 0 && elapsed > 0 {
+		other := float64(busy-w.busyTicks-(processTicks()-w.selfTicks)) / clockTicks / elapsed
+		if other > n.thresholds.OtherCPUs {
+			flags = append(flags, QualityLoad)
+		}
+	}
+
+	n.mu.Lock()
+	sum, count := 0.0, 0
+	// The samples taken during the window and the last one before it
+	for i := len(n.clocks) - 1; i >= 0; i-- {
+		sum += n.clocks[i].kHz
+		count++
+		if n.clocks[i].at.Before(w.at) {
+			break
+		}
+	}
+	peak := n.peakKHz
+	n.mu.Unlock()
+	if n.thresholds.FrequencyDrop > 0 && count > 0 && sum/float64(count) < (1-n.thresholds.FrequencyDrop)*peak {
+		flags = append(flags, QualityFrequency)
+	}
+
+	if throttleCount() > w.throttles {
+		flags = append(flags, QualityThrottled)
+	}
+	return flags
+}
+
+// cpuTicks sums the busy time of all CPUs from the first line of
+// /proc/stat: user, nice, system, irq, softirq and steal
+func cpuTicks() (int64, bool) {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return 0, false
+	}
+	line, _, _ := strings.Cut(string(data), "\n")
+	fields := strings.Fields(line)
+	if len(fields) < 9 || fields[0] != "cpu" {
+		return 0, false
+	}
+	var busy int64
+	for _, i := range []int{1, 2, 3, 6, 7, 8} {
+		v, _ := strconv.ParseInt(fields[i], 10, 64)
+		busy += v
+	}
+	return busy, true
+}
+
+// processTicks is the user and system time of this process from
+// /proc/self/stat, whose fields follow the parenthesized command name
+func processTicks() int64 {
+	data, err := os.ReadFile("/proc/self/stat")
+	if err != nil {
+		return 0
+	}
+	s := string(data)
+	fields := strings.Fields(s[strings.LastIndexByte(s, ')')+1:])
+	if len(fields) < 13 {
+		return 0
+	}
+	utime, _ := strconv.ParseInt(fields[11], 10, 64)
+	stime, _ := strconv.ParseInt(fields[12], 10, 64)
+	return utime + stime
+}
+
+// cpuClockKHz is the mean current clock of the CPUs with cpufreq, 0 if none
+func cpuClockKHz() float64 {
+	paths, _ := filepath.Glob("/sys/devices/system/cpu/cpu[0-9]*/cpufreq/scaling_cur_freq")
+	sum, count := 0.0, 0
+	for _, path := range paths {
+		if v, ok := readSysInt(path); ok {
+			sum += float64(v)
+			count++
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return sum / float64(count)
+}
+
+// throttleCount sums the core and package thermal throttling events of
+// all CPUs
+func throttleCount() int64 {
+	paths, _ := filepath.Glob("/sys/devices/system/cpu/cpu[0-9]*/thermal_throttle/*_throttle_count")
+	var total int64
+	for _, path := range paths {
+		if v, ok := readSysInt(path); ok {
+			total += v
+		}
+	}
+	return total
+}
+
+func readSysInt(path string) (int64, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	v, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	return v, err == nil
+}
@@ -0,0 +1,109 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Plan is what a Runner would do over a dataset, with a runtime estimated
+// from covering a small sample of its features once at every sweep point
+type Plan struct {
+	Dataset     string          `json:"dataset"`
+	Features    int             `json:"features"`
+	Sample      int             `json:"sample"` // features covered to calibrate
+	Repetitions int             `json:"repetitions"`
+	Concurrency int             `json:"concurrency"`
+	Options     CoveringOptions `json:"options"`
+	Points      []PlannedPoint  `json:"points"`
+	Calibration time.Duration   `json:"calibration_ns"` // wall time spent calibrating
+}
+
+// PlannedPoint is the estimate for one sweep point. Coverings counts every
+// feature in every repetition; PerCovering is the mean calibration
+// duration. Calibration coverings that timed out count as taking the full
+// timeout, and those skipped by the H3 cell cap as taking nothing, so with
+// either the estimate is a lower bound.
+type PlannedPoint struct {
+	SweepPoint
+	Coverings   int           `json:"coverings"`
+	PerCovering time.Duration `json:"per_covering_ns"`
+	TimedOut    int           `json:"timed_out"`
+	OverCap     int           `json:"over_cap"`
+}
+
+// Estimated is the expected time to cover every feature at the point over
+// all repetitions
+func (p PlannedPoint) Estimated() time.Duration {
+	return p.PerCovering * time.Duration(p.Coverings)
+}
+
+// Coverings is the total number of coverings the plan runs
+func (p *Plan) Coverings() int {
+	total := 0
+	for _, pp := range p.Points {
+		total += pp.Coverings
+	}
+	return total
+}
+
+// Estimated is the expected wall time of the run: the sum over sweep
+// points, divided by the sweep points measured at once. Concurrent sweep
+// points slow each other down, so the estimate is optimistic above one.
+func (p *Plan) Estimated() time.Duration {
+	var total time.Duration
+	for _, pp := range p.Points {
+		total += pp.Estimated()
+	}
+	return total / time.Duration(max(1, min(p.Concurrency, len(p.Points)*p.Repetitions)))
+}
+
+// Plan estimates the run of r over ds without performing it, by timing
+// the coverings of sample features spread evenly over the dataset at every
+// sweep point, once and without verification. Calibration at fine
+// resolutions can itself take a while; a covering timeout bounds it.
+func (r *Runner) Plan(ctx context.Context, ds *Dataset, sample int) (*Plan, error) {
+	points := r.SweepPoints()
+	if len(points) == 0 {
+		return nil, fmt.Errorf("no resolutions selected")
+	}
+	if sample < 1 {
+		return nil, fmt.Errorf("calibration sample must be at least 1 feature, got %d", sample)
+	}
+
+	sample = min(sample, len(ds.Features))
+	ids := make([]int, sample)
+	for i := range ids {
+		ids[i] = ds.Features[i*len(ds.Features)/sample].FeatureID
+	}
+	calibration := ds.Subset(ids)
+
+	plan := &Plan{
+		Dataset:     ds.Path,
+		Features:    len(ds.Features),
+		Sample:      sample,
+		Repetitions: r.repetitions,
+		Concurrency: r.concurrency,
+		Options:     r.options,
+	}
+	options := r.options
+	options.Verify = 0
+	start := time.Now()
+	measurements, err := BenchmarkCoverings(ctx, calibration, points, options)
+	plan.Calibration = time.Since(start)
+	if err != nil {
+		return nil, fmt.Errorf("calibrating: %w", err)
+	}
+	for _, m := range measurements {
+		total := m.TotalDuration() + time.Duration(len(m.TimedOut))*options.Timeout
+		pp := PlannedPoint{
+			SweepPoint:  SweepPoint{System: m.System, Resolution: m.Resolution},
+			Coverings:   len(ds.Features) * r.repetitions,
+			PerCovering: total / time.Duration(sample),
+			TimedOut:    len(m.TimedOut),
+			OverCap:     len(m.OverCap),
+		}
+		plan.Points = append(plan.Points, pp)
+	}
+	return plan, nil
+}
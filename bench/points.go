@@ -0,0 +1,85 @@
+package bench
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+)
+
+// maxPointAttempts bounds the rejection sampling of one point inside a
+// polygon's bounding rectangle
+const maxPointAttempts = 10000
+
+// RandomPointsOnSphere draws n points uniformly over the sphere's surface,
+// so unlike points uniform in degrees they do not crowd at the poles
+func RandomPointsOnSphere(n int, seed int64) []s2.LatLng {
+	rng := rand.New(rand.NewSource(seed))
+	points := make([]s2.LatLng, n)
+	for i := range points {
+		lat := math.Asin(2*rng.Float64() - 1)
+		lng := (2*rng.Float64() - 1) * math.Pi
+		points[i] = s2.LatLng{Lat: s1.Angle(lat), Lng: s1.Angle(lng)}
+	}
+	return points
+}
+
+// ClusteredPoints draws n points around clusters centers placed uniformly
+// (in degrees) in rect, each point offset from a random center by a normal
+// distance with standard deviation radiusKm, as events gather in cities
+func ClusteredPoints(rect s2.Rect, n, clusters int, radiusKm float64, seed int64) []s2.LatLng {
+	rng := rand.New(rand.NewSource(seed))
+	centers := RandomPointsInRect(rect, clusters, rng.Int63())
+	sigma := radiusKm / EarthRadiusKm
+	points := make([]s2.LatLng, n)
+	for i := range points {
+		c := centers[rng.Intn(len(centers))]
+		lat := float64(c.Lat) + rng.NormFloat64()*sigma
+		lng := float64(c.Lng) + rng.NormFloat64()*sigma/math.Max(math.Cos(float64(c.Lat)), 1e-6)
+		lat = math.Max(-math.Pi/2, math.Min(math.Pi/2, lat))
+		points[i] = s2.LatLng{Lat: s1.Angle(lat), Lng: s1.Angle(math.Remainder(lng, 2*math.Pi))}
+	}
+	return points
+}
+
+// PointsInFeatures draws n points inside the dataset's polygons, picking a
+// polygon with probability proportional to its area and a point uniformly
+// (in degrees) inside it, so the density is even over the area the dataset
+// covers and zero elsewhere
+func PointsInFeatures(ds *Dataset, n int, seed int64) ([]s2.LatLng, error) {
+	if len(ds.Features) == 0 {
+		return nil, fmt.Errorf("%s has no features to draw points in", ds.Path)
+	}
+	cumulative := make([]float64, len(ds.Features))
+	total := 0.0
+	for i, f := range ds.Features {
+		total += f.S2Polygon.Area()
+		cumulative[i] = total
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("the features of %s have no area to draw points in", ds.Path)
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	points := make([]s2.LatLng, n)
+	for i := range points {
+		f := ds.Features[sort.SearchFloat64s(cumulative, rng.Float64()*total)]
+		rect := f.S2Polygon.RectBound()
+		found := false
+		for attempt := 0; attempt < maxPointAttempts && !found; attempt++ {
+			lat := rect.Lat.Lo + rng.Float64()*rect.Lat.Length()
+			lng := rect.Lng.Lo + rng.Float64()*rect.Lng.Length()
+			ll := s2.LatLng{Lat: s1.Angle(lat), Lng: s1.Angle(math.Remainder(lng, 2*math.Pi))}
+			if f.S2Polygon.ContainsPoint(s2.PointFromLatLng(ll)) {
+				points[i], found = ll, true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("feature %d: no point inside it in %d draws from its bounding rectangle", f.FeatureID, maxPointAttempts)
+		}
+	}
+	return points, nil
+}
@@ -0,0 +1,412 @@
+package bench
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nkk36/earth-discretization-benchmark/report"
+)
+
+// Results are the measurements of one or more benchmark runs over a
+// dataset with the same covering options, ordered by repetition and then by
+// sweep point
+type Results struct {
+	Dataset      string                `json:"dataset"`
+	Options      CoveringOptions       `json:"options"`
+	Measurements []CoveringMeasurement `json:"measurements"`
+	Calibration  *Calibration          `json:"calibration,omitempty"`
+	Info         *RunInfo              `json:"info,omitempty"` // unset in results written before runs were tagged
+}
+
+// Summary aggregates the measurements of one sweep point over all
+// repetitions. Durations are per feature, pooled across repetitions;
+// TimedOut, OverCap, Violations and Empty are summed. NsPerKm2 and
+// CellsPerSecond are over the pooled durations, cells and polygon areas of
+// all repetitions.
+// Repetitions with quality flags are left out of all of these, and counted
+// in Flagged, unless every repetition is flagged.
+type Summary struct {
+	System      string  `json:"system"`
+	Resolution  int     `json:"resolution"`
+	Repetitions int     `json:"repetitions"`
+	Samples     int     `json:"samples"`
+	Cells       int     `json:"cells"` // of the first repetition; coverings do not change between runs
+	MeanNs      float64 `json:"mean_ns"`
+	MedianNs    float64 `json:"median_ns"`
+	P90Ns       float64 `json:"p90_ns"`
+	MinNs       float64 `json:"min_ns"`
+	MaxNs       float64 `json:"max_ns"`
+	NsPerKm2    float64 `json:"ns_per_km2"`
+	CellsPerSec float64 `json:"cells_per_second"`
+	TimedOut    int     `json:"timed_out"`
+	OverCap     int     `json:"over_cap"`
+	Violations  int     `json:"violations"`
+	Empty       int     `json:"empty"`   // coverings without cells, see CoveringMeasurement.Empty
+	Flagged     int     `json:"flagged"` // repetitions with quality flags
+
+	LevelCells map[int]int `json:"level_cells,omitempty"` // of the first repetition, see CoveringMeasurement.LevelCells
+}
+
+// BucketSummary is the covering duration of the features of one bucket at
+// one sweep point, pooled across repetitions
+type BucketSummary struct {
+	System     string  `json:"system"`
+	Resolution int     `json:"resolution"`
+	Bucket     Bucket  `json:"bucket"`
+	Samples    int     `json:"samples"`
+	MeanNs     float64 `json:"mean_ns"`
+	MedianNs   float64 `json:"median_ns"`
+}
+
+// SweepPoints returns the distinct system/resolution pairs of the
+// measurements in order of first appearance
+func (r *Results) SweepPoints() []SweepPoint {
+	var points []SweepPoint
+	for _, m := range r.Measurements {
+		sp := SweepPoint{System: m.System, Resolution: m.Resolution}
+		if !slices.Contains(points, sp) {
+			points = append(points, sp)
+		}
+	}
+	return points
+}
+
+// summarized returns the measurements of a sweep point that its summary
+// pools: those without quality flags, or all of them if every one is
+// flagged
+func (r *Results) summarized(sp SweepPoint) []CoveringMeasurement {
+	var all, clean []CoveringMeasurement
+	for _, m := range r.Measurements {
+		if m.System == sp.System && m.Resolution == sp.Resolution {
+			all = append(all, m)
+			if len(m.Quality) == 0 {
+				clean = append(clean, m)
+			}
+		}
+	}
+	if len(clean) == 0 {
+		return all
+	}
+	return clean
+}
+
+// durationsNs pools the per-feature durations of the summarized
+// measurements of a sweep point, sorted
+func (r *Results) durationsNs(sp SweepPoint) []float64 {
+	var values []float64
+	for _, m := range r.summarized(sp) {
+		for _, d := range m.Durations {
+			values = append(values, float64(d.Nanoseconds()))
+		}
+	}
+	slices.Sort(values)
+	return values
+}
+
+// Summary returns one row per sweep point, in the order of SweepPoints
+func (r *Results) Summary() []Summary {
+	var summaries []Summary
+	for _, sp := range r.SweepPoints() {
+		s := Summary{System: sp.System, Resolution: sp.Resolution}
+		pooled := CoveringMeasurement{}
+		for _, m := range r.Measurements {
+			if m.System == sp.System && m.Resolution == sp.Resolution && len(m.Quality) > 0 {
+				s.Flagged++
+			}
+		}
+		for _, m := range r.summarized(sp) {
+			if s.Repetitions == 0 {
+				s.Cells, s.LevelCells = m.Cells, m.LevelCells
+			}
+			pooled.Durations = append(pooled.Durations, m.Durations...)
+			pooled.Cells += m.Cells
+			pooled.AreaKm2 += m.AreaKm2
+			s.Repetitions++
+			s.TimedOut += len(m.TimedOut)
+			s.OverCap += len(m.OverCap)
+			s.Violations += len(m.Violations)
+			s.Empty += len(m.Empty)
+		}
+		dist := report.NewDistribution(r.durationsNs(sp))
+		s.Samples = dist.Count
+		s.MeanNs, s.MedianNs, s.P90Ns, s.MinNs, s.MaxNs = dist.Mean, dist.Median, dist.P90, dist.Min, dist.Max
+		s.NsPerKm2, s.CellsPerSec = pooled.NsPerKm2(), pooled.CellsPerSecond()
+		summaries = append(summaries, s)
+	}
+	return summaries
+}
+
+// ByBucket returns one row per sweep point and bucket with any durations,
+// in the order of SweepPoints and AllBuckets. buckets gives the bucket of
+// each feature ID (see Dataset.BucketsByID); durations recorded without
+// feature IDs, or of features missing from buckets, are left out, and so
+// are flagged repetitions as in Summary.
+func (r *Results) ByBucket(buckets map[int]Bucket) []BucketSummary {
+	var summaries []BucketSummary
+	for _, sp := range r.SweepPoints() {
+		values := make(map[Bucket][]float64)
+		for _, m := range r.summarized(sp) {
+			if len(m.FeatureIDs) != len(m.Durations) {
+				continue
+			}
+			for i, id := range m.FeatureIDs {
+				if b, ok := buckets[id]; ok {
+					values[b] = append(values[b], float64(m.Durations[i].Nanoseconds()))
+				}
+			}
+		}
+		for _, b := range AllBuckets() {
+			if len(values[b]) == 0 {
+				continue
+			}
+			dist := report.NewDistribution(values[b])
+			summaries = append(summaries, BucketSummary{
+				System:     sp.System,
+				Resolution: sp.Resolution,
+				Bucket:     b,
+				Samples:    dist.Count,
+				MeanNs:     dist.Mean,
+				MedianNs:   dist.Median,
+			})
+		}
+	}
+	return summaries
+}
+
+// Percentile returns the p-th percentile (0-100) of the per-feature
+// covering duration at each sweep point, pooled across repetitions
+func (r *Results) Percentile(p float64) map[SweepPoint]time.Duration {
+	percentiles := make(map[SweepPoint]time.Duration)
+	for _, sp := range r.SweepPoints() {
+		percentiles[sp] = time.Duration(report.Percentile(r.durationsNs(sp), p))
+	}
+	return percentiles
+}
+
+// Normalized reports whether the durations are scaled to the reference
+// machine of Calibrate rather than as measured
+func (r *Results) Normalized() bool {
+	return r.Calibration != nil && r.Calibration.Normalized
+}
+
+// FilterBySystem returns the results of one system
+func (r *Results) FilterBySystem(system string) *Results {
+	filtered := &Results{Dataset: r.Dataset, Options: r.Options, Calibration: r.Calibration, Info: r.Info}
+	for _, m := range r.Measurements {
+		if m.System == system {
+			filtered.Measurements = append(filtered.Measurements, m)
+		}
+	}
+	return filtered
+}
+
+// Merge combines two sets of results over the same dataset and options,
+// for instance runs made at different times on the same kind of machine.
+// The repetitions of other are renumbered to follow those of r, so no two
+// runs share a number.
+func (r *Results) Merge(other *Results) (*Results, error) {
+	if r.Dataset != other.Dataset {
+		return nil, fmt.Errorf("cannot merge results over %s with results over %s", r.Dataset, other.Dataset)
+	}
+	if r.Options != other.Options {
+		return nil, fmt.Errorf("cannot merge results with different covering options (%+v and %+v)", r.Options, other.Options)
+	}
+	if r.Normalized() != other.Normalized() {
+		return nil, fmt.Errorf("cannot merge normalized results with raw ones")
+	}
+	if r.Info != nil && other.Info != nil && r.Info.Machine() != other.Info.Machine() {
+		return nil, fmt.Errorf("cannot merge results from %s with results from %s", r.Info.Machine(), other.Info.Machine())
+	}
+	merged := &Results{Dataset: r.Dataset, Options: r.Options, Measurements: slices.Clone(r.Measurements), Calibration: r.Calibration, Info: r.Info}
+	offset := 0
+	for _, m := range r.Measurements {
+		offset = max(offset, m.Repetition+1)
+	}
+	for _, m := range other.Measurements {
+		m.Repetition += offset
+		merged.Measurements = append(merged.Measurements, m)
+	}
+	return merged, nil
+}
+
+// WriteCSV writes one row per measurement
+func (r *Results) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(measurementCSVHeader); err != nil {
+		return err
+	}
+
+	for _, m := range r.Measurements {
+		if err := writer.Write(measurementCSVRow(m)); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// DatasetName is the file name of a dataset path without its extension
+// (and .gz), which tells datasets apart in combined reports; "stdin" for a
+// dataset read from "-"
+func DatasetName(path string) string {
+	if path == "-" {
+		return "stdin"
+	}
+	base := strings.TrimSuffix(filepath.Base(path), ".gz")
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// WriteCombinedCSV writes the measurements of results over several
+// datasets as one table, each row led by the DatasetName of its results
+func WriteCombinedCSV(w io.Writer, results []*Results) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(append([]string{"Dataset"}, measurementCSVHeader...)); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		name := DatasetName(r.Dataset)
+		for _, m := range r.Measurements {
+			if err := writer.Write(append([]string{name}, measurementCSVRow(m)...)); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// measurementCSVHeader names the columns of measurementCSVRow
+var measurementCSVHeader = []string{"System", "Resolution", "Features", "Cells", "AverageDurationNs", "TotalDurationNs", "TimedOut", "OverCap", "Violations", "Repetition", "Samples", "Unconverged", "NsPerKm2", "CellsPerSecond", "Quality", "Empty", "LevelCells"}
+
+// measurementCSVRow is the CSV row of one measurement, shared by WriteCSV
+// and CSVSink
+func measurementCSVRow(m CoveringMeasurement) []string {
+	return []string{
+		m.System,
+		strconv.Itoa(m.Resolution),
+		strconv.Itoa(len(m.Durations)),
+		strconv.Itoa(m.Cells),
+		strconv.FormatFloat(m.AverageDurationNs(), 'f', -1, 64),
+		strconv.FormatInt(m.TotalDuration().Nanoseconds(), 10),
+		strconv.Itoa(len(m.TimedOut)),
+		strconv.Itoa(len(m.OverCap)),
+		strconv.Itoa(len(m.Violations)),
+		strconv.Itoa(m.Repetition),
+		strconv.Itoa(m.TotalSamples()),
+		strconv.Itoa(len(m.Unconverged)),
+		strconv.FormatFloat(m.NsPerKm2(), 'f', -1, 64),
+		strconv.FormatFloat(m.CellsPerSecond(), 'f', -1, 64),
+		strings.Join(m.Quality, ";"),
+		strconv.Itoa(len(m.Empty)),
+		FormatLevelCells(m.LevelCells),
+	}
+}
+
+// FormatLevelCells writes a per-level cell count as level:cells pairs in
+// level order, separated by semicolons, e.g. "9:2;10:5;11:1"
+func FormatLevelCells(levelCells map[int]int) string {
+	levels := make([]int, 0, len(levelCells))
+	for level := range levelCells {
+		levels = append(levels, level)
+	}
+	slices.Sort(levels)
+	pairs := make([]string, len(levels))
+	for i, level := range levels {
+		pairs[i] = fmt.Sprintf("%d:%d", level, levelCells[level])
+	}
+	return strings.Join(pairs, ";")
+}
+
+// WriteSkippedCSV lists every covering that was left out of the
+// measurements, with Reason timeout, cell_cap or violation and a detail
+func (r *Results) WriteSkippedCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	headers := []string{"System", "Resolution", "FeatureID", "Reason", "Detail", "Repetition"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, m := range r.Measurements {
+		var rows [][]string
+		for _, id := range m.TimedOut {
+			rows = append(rows, []string{strconv.Itoa(id), "timeout", "ran longer than " + r.Options.Timeout.String()})
+		}
+		for _, c := range m.OverCap {
+			rows = append(rows, []string{strconv.Itoa(c.FeatureID), "cell_cap", fmt.Sprintf("estimated %d cells", c.EstimatedCells)})
+		}
+		for _, v := range m.Violations {
+			rows = append(rows, []string{strconv.Itoa(v.FeatureID), "violation", fmt.Sprintf("%d sample(s) failed, e.g. %s", v.Count, v.Example)})
+		}
+		for _, row := range rows {
+			row = append([]string{m.System, strconv.Itoa(m.Resolution)}, row...)
+			if err := writer.Write(append(row, strconv.Itoa(m.Repetition))); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteBucketsCSV writes the rows of ByBucket
+func (r *Results) WriteBucketsCSV(w io.Writer, buckets map[int]Bucket) error {
+	writer := csv.NewWriter(w)
+
+	headers := []string{"System", "Resolution", "Area", "Vertices", "Samples", "MeanDurationNs", "MedianDurationNs"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, s := range r.ByBucket(buckets) {
+		row := []string{
+			s.System,
+			strconv.Itoa(s.Resolution),
+			s.Bucket.Area,
+			s.Bucket.Vertices,
+			strconv.Itoa(s.Samples),
+			strconv.FormatFloat(s.MeanNs, 'f', -1, 64),
+			strconv.FormatFloat(s.MedianNs, 'f', -1, 64),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteJSON writes the results, with a summary per sweep point, as one
+// indented JSON document
+func (r *Results) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		*Results
+		Summary []Summary `json:"summary"`
+	}{r, r.Summary()})
+}
+
+// ReadJSON decodes results written by WriteJSON; the summary is recomputed
+// rather than read
+func ReadJSON(rd io.Reader) (*Results, error) {
+	var results Results
+	if err := json.NewDecoder(rd).Decode(&results); err != nil {
+		return nil, err
+	}
+	return &results, nil
+}
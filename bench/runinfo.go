@@ -0,0 +1,91 @@
+package bench
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// RunInfo identifies the code a run measured and the machine it ran on:
+// the commit of this repository and the versions of the libraries under
+// test, so results stored over time can be lined up against upgrades and
+// code changes, and the platform and CPU, so runs on ARM and x86 can be
+// told apart (see cpuModel)
+type RunInfo struct {
+	Commit     string `json:"commit,omitempty"` // short hash, with -dirty for uncommitted changes
+	GoVersion  string `json:"go_version"`
+	H3Version  string `json:"h3_go_version,omitempty"`
+	GeoVersion string `json:"golang_geo_version,omitempty"`
+	Host       string `json:"host,omitempty"`
+	OS         string `json:"goos,omitempty"`
+	Arch       string `json:"goarch,omitempty"`
+	CPU        string `json:"cpu,omitempty"`
+	Microarch  string `json:"microarch,omitempty"`
+}
+
+// Machine names the kind of machine a run was on, such as
+// "linux/arm64 neoverse-n1", for telling runs apart in merged reports
+func (info RunInfo) Machine() string {
+	platform := info.OS + "/" + info.Arch
+	if info.OS == "" {
+		platform = "unknown"
+	}
+	switch {
+	case info.CPU == "" && info.Microarch == "":
+		return platform
+	case info.CPU == "" || info.CPU == info.Microarch:
+		return platform + " " + info.Microarch
+	case info.Microarch == "":
+		return platform + " " + info.CPU
+	}
+	return platform + " " + info.CPU + " (" + info.Microarch + ")"
+}
+
+// CurrentRunInfo describes the running binary. The commit comes from the
+// VCS stamp of go build, or from git in the working directory for go run,
+// which does not stamp one.
+func CurrentRunInfo() RunInfo {
+	info := RunInfo{GoVersion: runtime.Version(), OS: runtime.GOOS, Arch: runtime.GOARCH}
+	info.Host, _ = os.Hostname()
+	info.CPU, info.Microarch = cpuModel()
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	for _, dep := range build.Deps {
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		switch dep.Path {
+		case "github.com/uber/h3-go/v4":
+			info.H3Version = dep.Version
+		case "github.com/golang/geo":
+			info.GeoVersion = dep.Version
+		}
+	}
+	dirty := false
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.Commit = setting.Value
+		case "vcs.modified":
+			dirty = setting.Value == "true"
+		}
+	}
+	if info.Commit == "" {
+		if out, err := exec.Command("git", "rev-parse", "HEAD").Output(); err == nil {
+			info.Commit = strings.TrimSpace(string(out))
+			status, err := exec.Command("git", "status", "--porcelain", "--untracked-files=no").Output()
+			dirty = err == nil && len(strings.TrimSpace(string(status))) > 0
+		}
+	}
+	if len(info.Commit) > 12 {
+		info.Commit = info.Commit[:12]
+	}
+	if info.Commit != "" && dirty {
+		info.Commit += "-dirty"
+	}
+	return info
+}
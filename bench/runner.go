@@ -0,0 +1,301 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Default resolutions of a Runner, the ranges of the original experiments
+var (
+	DefaultH3Resolutions = []int{0, 1, 2, 3, 4, 5, 6, 7, 8}
+	DefaultS2Levels      = []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13}
+)
+
+// ResultSink receives each measurement as soon as it is complete, so long
+// runs can stream results instead of waiting for Run to return. A Runner
+// calls its sinks from one goroutine at a time and never closes them; Close
+// flushes whatever the sink buffers and is up to whoever created it.
+type ResultSink interface {
+	WriteMeasurement(m CoveringMeasurement) error
+	Close() error
+}
+
+// SinkFunc adapts a function to a ResultSink with nothing to close
+type SinkFunc func(m CoveringMeasurement) error
+
+// WriteMeasurement calls f(m)
+func (f SinkFunc) WriteMeasurement(m CoveringMeasurement) error {
+	return f(m)
+}
+
+// Close does nothing
+func (f SinkFunc) Close() error {
+	return nil
+}
+
+// Runner benchmarks the coverings of a dataset over a sweep of systems and
+// resolutions. Create one with NewRunner; the zero value is not usable.
+type Runner struct {
+	systems     []string
+	resolutions map[string][]int
+	options     CoveringOptions
+	repetitions int
+	concurrency int
+	budget      time.Duration
+	sinks       []ResultSink
+	progress    func(Progress)
+	calibration *Calibration
+	noise       *NoiseMonitor
+}
+
+// Option configures a Runner
+type Option func(*Runner)
+
+// WithSystems restricts the run to the given systems (SystemH3, SystemS2),
+// in that order. Both run by default, H3 first.
+func WithSystems(systems ...string) Option {
+	return func(r *Runner) { r.systems = systems }
+}
+
+// WithResolutions sets the H3 resolutions or S2 levels run for a system
+// instead of DefaultH3Resolutions or DefaultS2Levels
+func WithResolutions(system string, resolutions ...int) Option {
+	return func(r *Runner) { r.resolutions[system] = resolutions }
+}
+
+// WithSweepPoints sets the systems and resolutions from a list of pairs,
+// systems in order of first appearance
+func WithSweepPoints(points ...SweepPoint) Option {
+	return func(r *Runner) {
+		r.systems = nil
+		r.resolutions = make(map[string][]int)
+		for _, sp := range points {
+			if _, ok := r.resolutions[sp.System]; !ok {
+				r.systems = append(r.systems, sp.System)
+			}
+			r.resolutions[sp.System] = append(r.resolutions[sp.System], sp.Resolution)
+		}
+	}
+}
+
+// WithCoveringOptions sets the S2 MaxCells, per-covering timeout, H3 cell cap
+// and verification of each covering
+func WithCoveringOptions(opts CoveringOptions) Option {
+	return func(r *Runner) { r.options = opts }
+}
+
+// WithRepetitions runs the whole sweep n times; each measurement records
+// the repetition it belongs to
+func WithRepetitions(n int) Option {
+	return func(r *Runner) { r.repetitions = n }
+}
+
+// WithConcurrency measures up to n sweep points at once. Coverings running
+// side by side compete for CPU and memory bandwidth, so durations are only
+// comparable between runs with the same concurrency.
+func WithConcurrency(n int) Option {
+	return func(r *Runner) { r.concurrency = n }
+}
+
+// WithBudget replaces the fixed repetitions with a total time budget:
+// every sweep point is measured once, then the points whose per-repetition
+// mean duration is least certain (highest relative standard error) are
+// measured again, as long as another measurement is expected to fit in
+// what remains. Coverings cannot be interrupted, so a run can overshoot the
+// budget by about one measurement.
+func WithBudget(d time.Duration) Option {
+	return func(r *Runner) { r.budget = d }
+}
+
+// WithSinks adds sinks that receive every measurement as it completes
+func WithSinks(sinks ...ResultSink) Option {
+	return func(r *Runner) { r.sinks = append(r.sinks, sinks...) }
+}
+
+// WithCalibration records c in the results. If c is Normalized, every
+// duration is multiplied by its score as it is measured, so sinks, progress
+// and results all report the reference machine's estimated durations.
+func WithCalibration(c Calibration) Option {
+	return func(r *Runner) { r.calibration = &c }
+}
+
+// WithNoiseMonitor flags each measurement taken while n saw the machine
+// disturbed, in CoveringMeasurement.Quality
+func WithNoiseMonitor(n *NoiseMonitor) Option {
+	return func(r *Runner) { r.noise = n }
+}
+
+// Progress reports how far the measurement of one sweep point of one
+// repetition has got
+type Progress struct {
+	SweepPoint
+	Repetition int
+	Done       int     // features handled so far
+	Total      int     // features in the dataset
+	Cells      int     // cells in the coverings timed so far
+	MeanNs     float64 // mean covering duration so far
+}
+
+// WithProgress calls fn before each feature of every sweep point and when
+// the point is complete, so a display can follow a long measurement. With
+// concurrency above 1, fn is called from several goroutines at once.
+func WithProgress(fn func(Progress)) Option {
+	return func(r *Runner) { r.progress = fn }
+}
+
+// NewRunner returns a Runner over both systems at the default resolutions,
+// one repetition and one sweep point at a time, with S2 MaxCells 8
+func NewRunner(opts ...Option) *Runner {
+	r := &Runner{
+		systems: []string{SystemH3, SystemS2},
+		resolutions: map[string][]int{
+			SystemH3: DefaultH3Resolutions,
+			SystemS2: DefaultS2Levels,
+		},
+		options:     CoveringOptions{MaxCells: 8},
+		repetitions: 1,
+		concurrency: 1,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// progressFunc adapts r's progress callback to the measurement of sp in
+// job j, keeping a running sum of the durations so each call is O(1)
+func (r *Runner) progressFunc(j job, sp SweepPoint, total int) func(int, *CoveringMeasurement) {
+	if r.progress == nil {
+		return nil
+	}
+	var sum time.Duration
+	summed := 0
+	return func(done int, m *CoveringMeasurement) {
+		for ; summed < len(m.Durations); summed++ {
+			sum += m.Durations[summed]
+		}
+		p := Progress{SweepPoint: sp, Repetition: j.repetition, Done: done, Total: total, Cells: m.Cells}
+		if summed > 0 {
+			p.MeanNs = float64(r.normalize(sum)) / float64(summed)
+		}
+		r.progress(p)
+	}
+}
+
+// normalize scales d by the calibration score when durations are normalized
+func (r *Runner) normalize(d time.Duration) time.Duration {
+	if r.calibration == nil || !r.calibration.Normalized {
+		return d
+	}
+	return r.calibration.Normalize(d)
+}
+
+// SweepPoints returns the system/resolution pairs of one repetition, in
+// the order they are reported
+func (r *Runner) SweepPoints() []SweepPoint {
+	var points []SweepPoint
+	for _, system := range r.systems {
+		for _, res := range r.resolutions[system] {
+			points = append(points, SweepPoint{System: system, Resolution: res})
+		}
+	}
+	return points
+}
+
+// Run measures every sweep point of every repetition over ds with
+// BenchmarkCoverings. When ctx is cancelled or a sink fails, the results
+// completed so far are returned with the error.
+func (r *Runner) Run(ctx context.Context, ds *Dataset) (*Results, error) {
+	points := r.SweepPoints()
+	if len(points) == 0 {
+		return nil, fmt.Errorf("no resolutions selected")
+	}
+	for _, system := range r.systems {
+		if system != SystemH3 && system != SystemS2 {
+			return nil, fmt.Errorf("unknown system %q", system)
+		}
+	}
+	if r.repetitions < 1 || r.concurrency < 1 {
+		return nil, fmt.Errorf("repetitions and concurrency must be at least 1, got %d and %d", r.repetitions, r.concurrency)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var sched schedule = &fixedSchedule{points: len(points), repetitions: r.repetitions}
+	workers := min(r.concurrency, len(points)*r.repetitions)
+	if r.budget > 0 {
+		sched = newBudgetSchedule(len(points), r.budget)
+		workers = r.concurrency
+	}
+	var (
+		mu       sync.Mutex
+		done     = make(map[int]CoveringMeasurement)
+		firstErr error
+	)
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				mu.Lock()
+				j, ok := sched.next()
+				mu.Unlock()
+				if !ok {
+					return
+				}
+				sp := points[j.index]
+				start, window := time.Now(), r.noise.begin()
+				measurements, err := benchmarkCoverings(ctx, ds, []SweepPoint{sp}, r.options, r.progressFunc(j, sp, len(ds.Features)))
+				elapsed, quality := time.Since(start), r.noise.flags(window)
+				mu.Lock()
+				if err != nil {
+					fail(err)
+				} else {
+					m := measurements[0]
+					m.Repetition = j.repetition
+					m.Quality = quality
+					for i := range m.Durations {
+						m.Durations[i] = r.normalize(m.Durations[i])
+					}
+					done[j.repetition*len(points)+j.index] = m
+					sched.done(j, m, elapsed)
+					for _, sink := range r.sinks {
+						if err := sink.WriteMeasurement(m); err != nil {
+							fail(fmt.Errorf("writing %s resolution %d: %w", m.System, m.Resolution, err))
+							break
+						}
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	info := CurrentRunInfo()
+	results := &Results{Dataset: ds.Path, Options: r.options, Calibration: r.calibration, Info: &info}
+	keys := make([]int, 0, len(done))
+	for k := range done {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		results.Measurements = append(results.Measurements, done[k])
+	}
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return results, firstErr
+}
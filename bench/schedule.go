@@ -0,0 +1,116 @@
+package bench
+
+import (
+	"math"
+	"time"
+
+	"github.com/nkk36/earth-discretization-benchmark/report"
+)
+
+// job is one measurement of a Runner: the sweep point at index and the
+// repetition it is numbered as
+type job struct{ index, repetition int }
+
+// schedule decides which measurement a Runner makes next. A Runner calls it
+// from one goroutine at a time.
+type schedule interface {
+	// next returns the next job, or false when the run is over
+	next() (job, bool)
+	// done records a completed job and its wall time
+	done(j job, m CoveringMeasurement, elapsed time.Duration)
+}
+
+// fixedSchedule measures every sweep point once per repetition, in order
+type fixedSchedule struct {
+	points, repetitions int
+	sent                int
+}
+
+func (s *fixedSchedule) next() (job, bool) {
+	if s.sent == s.points*s.repetitions {
+		return job{}, false
+	}
+	j := job{index: s.sent % s.points, repetition: s.sent / s.points}
+	s.sent++
+	return j, true
+}
+
+func (s *fixedSchedule) done(job, CoveringMeasurement, time.Duration) {}
+
+// budgetSchedule spends a time budget on the sweep points whose mean
+// duration per repetition is least certain; see WithBudget
+type budgetSchedule struct {
+	budget  time.Duration
+	start   time.Time
+	started []int           // measurements started per point, the next repetition number
+	means   [][]float64     // mean duration (ns) of each completed measurement per point
+	cost    []time.Duration // wall time of the latest measurement per point
+}
+
+func newBudgetSchedule(points int, budget time.Duration) *budgetSchedule {
+	return &budgetSchedule{
+		budget:  budget,
+		start:   time.Now(),
+		started: make([]int, points),
+		means:   make([][]float64, points),
+		cost:    make([]time.Duration, points),
+	}
+}
+
+func (s *budgetSchedule) next() (job, bool) {
+	elapsed := time.Since(s.start)
+	if elapsed >= s.budget {
+		return job{}, false
+	}
+	// Every point is measured once before any is repeated
+	for i, n := range s.started {
+		if n == 0 {
+			s.started[i]++
+			return job{index: i}, true
+		}
+	}
+
+	best, bestNoise := -1, 0.0
+	for i, means := range s.means {
+		if len(means) == 0 || s.started[i] > len(means) && len(means) < 2 {
+			continue // still running, with nothing to judge its noise by
+		}
+		if elapsed+s.cost[i] > s.budget {
+			continue
+		}
+		noise := relativeStandardError(means, s.started[i])
+		if noise > bestNoise {
+			best, bestNoise = i, noise
+		}
+	}
+	if best < 0 {
+		return job{}, false
+	}
+	j := job{index: best, repetition: s.started[best]}
+	s.started[best]++
+	return j, true
+}
+
+func (s *budgetSchedule) done(j job, m CoveringMeasurement, elapsed time.Duration) {
+	if len(m.Durations) > 0 {
+		s.means[j.index] = append(s.means[j.index], m.AverageDurationNs())
+	}
+	s.cost[j.index] = elapsed
+}
+
+// relativeStandardError is the standard error of the mean of means over
+// the mean, as if n measurements (those still running included) had the
+// spread of the completed ones. A single measurement is infinitely
+// uncertain, and one with nothing timed (every covering skipped) never
+// improves, so it scores 0.
+func relativeStandardError(means []float64, n int) float64 {
+	var sum float64
+	for _, v := range means {
+		sum += v
+	}
+	if sum == 0 {
+		return 0
+	}
+	se := report.StandardError(means) * math.Sqrt(float64(len(means))) / math.Sqrt(float64(n))
+	return se / (sum / float64(len(means)))
+}
@@ -0,0 +1,313 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// CSVSink writes one row per measurement with the columns of
+// Results.WriteCSV, flushing after every row so a file can be followed
+// while the run is in progress
+type CSVSink struct {
+	writer *csv.Writer
+	header bool
+}
+
+// NewCSVSink returns a CSVSink writing to w; the header is written with the
+// first measurement
+func NewCSVSink(w io.Writer) *CSVSink {
+	return &CSVSink{writer: csv.NewWriter(w)}
+}
+
+// WriteMeasurement writes the row of m
+func (s *CSVSink) WriteMeasurement(m CoveringMeasurement) error {
+	if !s.header {
+		if err := s.writer.Write(measurementCSVHeader); err != nil {
+			return err
+		}
+		s.header = true
+	}
+	if err := s.writer.Write(measurementCSVRow(m)); err != nil {
+		return err
+	}
+	s.writer.Flush()
+	return s.writer.Error()
+}
+
+// Close flushes the writer; closing the underlying writer is up to the caller
+func (s *CSVSink) Close() error {
+	s.writer.Flush()
+	return s.writer.Error()
+}
+
+// JSONSink writes each measurement as one JSON object per line (JSON Lines)
+type JSONSink struct {
+	encoder *json.Encoder
+}
+
+// NewJSONSink returns a JSONSink writing to w
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{encoder: json.NewEncoder(w)}
+}
+
+// WriteMeasurement writes m on its own line
+func (s *JSONSink) WriteMeasurement(m CoveringMeasurement) error {
+	return s.encoder.Encode(m)
+}
+
+// Close does nothing; every line is written by WriteMeasurement
+func (s *JSONSink) Close() error {
+	return nil
+}
+
+// TableSink prints each measurement as a row of a fixed-width table, for
+// following a run on a terminal
+type TableSink struct {
+	w      io.Writer
+	header bool
+}
+
+// NewTableSink returns a TableSink writing to w, usually os.Stdout
+func NewTableSink(w io.Writer) *TableSink {
+	return &TableSink{w: w}
+}
+
+// tableRowFormat lays out the columns of TableSink
+const tableRowFormat = "%-6s %4v %4v %10v %14v %12v %12v %9v %8v %10v  %v\n"
+
+// WriteMeasurement prints the row of m
+func (s *TableSink) WriteMeasurement(m CoveringMeasurement) error {
+	if !s.header {
+		if _, err := fmt.Fprintf(s.w, tableRowFormat, "SYSTEM", "RES", "RUN", "CELLS", "NS/FEATURE", "NS/KM2", "CELLS/S", "TIMEDOUT", "OVERCAP", "VIOLATIONS", "QUALITY"); err != nil {
+			return err
+		}
+		s.header = true
+	}
+	_, err := fmt.Fprintf(s.w, tableRowFormat, m.System, m.Resolution, m.Repetition+1, m.Cells,
+		fmt.Sprintf("%.0f", m.AverageDurationNs()), fmt.Sprintf("%.1f", m.NsPerKm2()), fmt.Sprintf("%.0f", m.CellsPerSecond()), len(m.TimedOut), len(m.OverCap), len(m.Violations), qualityLabel(m.Quality))
+	return err
+}
+
+// qualityLabel shows the quality flags of a measurement, "ok" for none
+func qualityLabel(flags []string) string {
+	if len(flags) == 0 {
+		return "ok"
+	}
+	return strings.Join(flags, ",")
+}
+
+// Close does nothing; every row is printed by WriteMeasurement
+func (s *TableSink) Close() error {
+	return nil
+}
+
+// SQLiteSink inserts each measurement into the measurements table of a
+// SQLite database through the sqlite3 CLI, so the benchmark needs no cgo
+// driver. The table is created if missing and rows accumulate across runs,
+// told apart by the run column, the time the sink was opened. The runs
+// table records the RunInfo of each run, for ReadTrends.
+type SQLiteSink struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stderr bytes.Buffer
+	run    string
+}
+
+// NewSQLiteSink starts binary (usually "sqlite3") on the database at path
+func NewSQLiteSink(binary, path string) (*SQLiteSink, error) {
+	s := &SQLiteSink{run: time.Now().UTC().Format(time.RFC3339)}
+	s.cmd = exec.Command(binary, "-bail", path)
+	s.cmd.Stderr = &s.stderr
+	stdin, err := s.cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	s.stdin = stdin
+	if err := s.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting %s: %w", binary, err)
+	}
+	_, err = io.WriteString(s.stdin, `CREATE TABLE IF NOT EXISTS measurements (
+	run TEXT NOT NULL,
+	system TEXT NOT NULL,
+	resolution INTEGER NOT NULL,
+	repetition INTEGER NOT NULL,
+	features INTEGER NOT NULL,
+	cells INTEGER NOT NULL,
+	average_duration_ns REAL NOT NULL,
+	total_duration_ns INTEGER NOT NULL,
+	timed_out INTEGER NOT NULL,
+	over_cap INTEGER NOT NULL,
+	violations INTEGER NOT NULL
+);
+`+runsTableSQL)
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	info := CurrentRunInfo()
+	_, err = fmt.Fprintf(s.stdin, "INSERT OR REPLACE INTO runs VALUES (%s, %s, %s, %s, %s, %s);\n",
+		sqlQuote(s.run), sqlQuote(info.Commit), sqlQuote(info.GoVersion), sqlQuote(info.H3Version), sqlQuote(info.GeoVersion), sqlQuote(info.Host))
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	return s, nil
+}
+
+// WriteMeasurement inserts the row of m
+func (s *SQLiteSink) WriteMeasurement(m CoveringMeasurement) error {
+	_, err := fmt.Fprintf(s.stdin, "INSERT INTO measurements VALUES (%s, %s, %d, %d, %d, %d, %g, %d, %d, %d, %d);\n",
+		sqlQuote(s.run), sqlQuote(m.System), m.Resolution, m.Repetition, len(m.Durations), m.Cells,
+		m.AverageDurationNs(), m.TotalDuration().Nanoseconds(), len(m.TimedOut), len(m.OverCap), len(m.Violations))
+	if err != nil {
+		return s.fail(err)
+	}
+	return nil
+}
+
+// runsTableSQL creates the table of RunInfo by run, which databases
+// written before it existed lack
+const runsTableSQL = `CREATE TABLE IF NOT EXISTS runs (
+	run TEXT PRIMARY KEY,
+	git_commit TEXT NOT NULL,
+	go_version TEXT NOT NULL,
+	h3_go_version TEXT NOT NULL,
+	golang_geo_version TEXT NOT NULL,
+	host TEXT NOT NULL
+);
+`
+
+// sqlQuote writes s as an SQL string literal
+func sqlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// Close ends the sqlite3 session and reports any statement it rejected
+func (s *SQLiteSink) Close() error {
+	s.stdin.Close()
+	if err := s.cmd.Wait(); err != nil {
+		return fmt.Errorf("sqlite3: %w: %s", err, strings.TrimSpace(s.stderr.String()))
+	}
+	return nil
+}
+
+// fail stops sqlite3 after a write error, returning its message as well as
+// the broken pipe it most likely caused
+func (s *SQLiteSink) fail(err error) error {
+	s.stdin.Close()
+	s.cmd.Wait()
+	return fmt.Errorf("sqlite3: %w: %s", err, strings.TrimSpace(s.stderr.String()))
+}
+
+// PrometheusSink exposes the latest measurement of every sweep point as
+// Prometheus gauges in the text exposition format. A target starting with
+// http:// or https:// is a Pushgateway URL such as
+// http://localhost:9091/metrics/job/earthbench, which is replaced on every
+// measurement; any other target is a file for node_exporter's textfile
+// collector, rewritten atomically.
+type PrometheusSink struct {
+	target string
+	latest map[SweepPoint]CoveringMeasurement
+}
+
+// NewPrometheusSink returns a PrometheusSink publishing to target
+func NewPrometheusSink(target string) *PrometheusSink {
+	return &PrometheusSink{target: target, latest: make(map[SweepPoint]CoveringMeasurement)}
+}
+
+// WriteMeasurement records m and publishes all sweep points so far
+func (s *PrometheusSink) WriteMeasurement(m CoveringMeasurement) error {
+	s.latest[SweepPoint{System: m.System, Resolution: m.Resolution}] = m
+	return s.publish(s.render())
+}
+
+// Close does nothing; every measurement is published by WriteMeasurement
+func (s *PrometheusSink) Close() error {
+	return nil
+}
+
+// render writes the gauges of every sweep point, in sweep point order so
+// consecutive scrapes diff cleanly
+func (s *PrometheusSink) render() []byte {
+	points := make([]SweepPoint, 0, len(s.latest))
+	for sp := range s.latest {
+		points = append(points, sp)
+	}
+	slices.SortFunc(points, func(a, b SweepPoint) int {
+		if c := strings.Compare(a.System, b.System); c != 0 {
+			return c
+		}
+		return a.Resolution - b.Resolution
+	})
+
+	var buf bytes.Buffer
+	gauge := func(name, help string, value func(m CoveringMeasurement) float64) {
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for _, sp := range points {
+			fmt.Fprintf(&buf, "%s{system=%q,resolution=\"%d\"} %g\n", name, sp.System, sp.Resolution, value(s.latest[sp]))
+		}
+	}
+	gauge("earthbench_covering_duration_seconds", "Average time to cover one feature in the latest repetition.",
+		func(m CoveringMeasurement) float64 { return m.AverageDurationNs() / 1e9 })
+	gauge("earthbench_covering_cells", "Total cells over all coverings in the latest repetition.",
+		func(m CoveringMeasurement) float64 { return float64(m.Cells) })
+	gauge("earthbench_covering_features", "Features measured in the latest repetition.",
+		func(m CoveringMeasurement) float64 { return float64(len(m.Durations)) })
+	gauge("earthbench_covering_skipped", "Coverings left out of the latest repetition (timeout, cell cap or failed verification).",
+		func(m CoveringMeasurement) float64 {
+			return float64(len(m.TimedOut) + len(m.OverCap) + len(m.Violations))
+		})
+	gauge("earthbench_repetition", "Repetition of the latest measurement, counting from 0.",
+		func(m CoveringMeasurement) float64 { return float64(m.Repetition) })
+	return buf.Bytes()
+}
+
+// publish sends the rendered metrics to the Pushgateway or textfile
+func (s *PrometheusSink) publish(metrics []byte) error {
+	if strings.HasPrefix(s.target, "http://") || strings.HasPrefix(s.target, "https://") {
+		req, err := http.NewRequest(http.MethodPut, s.target, bytes.NewReader(metrics))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			return fmt.Errorf("pushgateway returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+		}
+		return nil
+	}
+
+	// node_exporter must never read a half-written file
+	tmp, err := os.CreateTemp(filepath.Dir(s.target), ".earthbench-*.prom")
+	if err != nil {
+		return err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if _, err := tmp.Write(metrics); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.target)
+}
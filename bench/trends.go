@@ -0,0 +1,73 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// TrendMetrics are the columns of the measurements table ReadTrends can
+// follow, averaged over the repetitions of a run
+var TrendMetrics = []string{"average_duration_ns", "total_duration_ns", "cells", "features", "timed_out", "over_cap", "violations"}
+
+// TrendPoint is a metric of one sweep point in one stored run
+type TrendPoint struct {
+	Run         string  `json:"run"` // RFC 3339 start time of the run
+	Commit      string  `json:"commit"`
+	H3Version   string  `json:"h3_go_version"`
+	GeoVersion  string  `json:"golang_geo_version"`
+	System      string  `json:"system"`
+	Resolution  int     `json:"resolution"`
+	Value       float64 `json:"value"`
+	Repetitions int     `json:"repetitions"`
+}
+
+// ReadTrends reads metric (one of TrendMetrics) for every run and sweep
+// point stored by SQLiteSink in the database at path, oldest run first,
+// through the sqlite3 CLI binary. Runs stored before the runs table
+// existed have no commit or versions; the table is added if missing.
+func ReadTrends(binary, path, metric string) ([]TrendPoint, error) {
+	if !slices.Contains(TrendMetrics, metric) {
+		return nil, fmt.Errorf("unknown metric %q (want one of %s)", metric, strings.Join(TrendMetrics, ", "))
+	}
+	query := runsTableSQL + fmt.Sprintf(`SELECT m.run AS run, COALESCE(r.git_commit, '') AS git_commit,
+	COALESCE(r.h3_go_version, '') AS h3_go_version, COALESCE(r.golang_geo_version, '') AS golang_geo_version,
+	m.system AS system, m.resolution AS resolution, AVG(m.%s) AS value, COUNT(*) AS repetitions
+FROM measurements m LEFT JOIN runs r ON r.run = m.run
+GROUP BY m.run, m.system, m.resolution
+ORDER BY m.run, m.system, m.resolution;
+`, metric)
+
+	cmd := exec.Command(binary, "-json", "-bail", path)
+	cmd.Stdin = strings.NewReader(query)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("sqlite3: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if strings.TrimSpace(stdout.String()) == "" {
+		return nil, nil
+	}
+
+	var rows []struct {
+		Run         string  `json:"run"`
+		Commit      string  `json:"git_commit"`
+		H3Version   string  `json:"h3_go_version"`
+		GeoVersion  string  `json:"golang_geo_version"`
+		System      string  `json:"system"`
+		Resolution  int     `json:"resolution"`
+		Value       float64 `json:"value"`
+		Repetitions int     `json:"repetitions"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &rows); err != nil {
+		return nil, fmt.Errorf("reading sqlite3 output: %w", err)
+	}
+	points := make([]TrendPoint, len(rows))
+	for i, r := range rows {
+		points[i] = TrendPoint(r)
+	}
+	return points, nil
+}
@@ -0,0 +1,94 @@
+package bench
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/golang/geo/s2"
+	dh3 "github.com/nkk36/earth-discretization-benchmark/discretize/h3"
+	"github.com/uber/h3-go/v4"
+)
+
+// CoveringViolation is a covering that failed verification: Count sample
+// points broke the system's contract, Example describes the first
+type CoveringViolation struct {
+	FeatureID int    `json:"feature_id"`
+	Count     int    `json:"count"`
+	Example   string `json:"example"`
+}
+
+// VerifyCovering checks a covering against what its system promises:
+//
+//   - S2 RegionCoverer returns a superset of the polygon, so every polygon
+//     vertex, edge midpoint and interior sample must fall in a covering cell
+//   - H3 PolygonToCells returns exactly the cells whose centers lie in the
+//     polygon, so every returned center must be inside and every interior
+//     sample whose cell center is inside must be in the set. H3 tests
+//     containment in planar latitude/longitude, and so does the check.
+//
+// Interior samples are drawn from the polygon's bounding rectangle and kept
+// when inside the polygon. It reports Count 0 for a correct covering.
+func VerifyCovering(f Feature, system string, resolution int, cells []uint64, samples int, seed int64) (CoveringViolation, error) {
+	v := CoveringViolation{FeatureID: f.FeatureID}
+	flag := func(format string, args ...interface{}) {
+		if v.Count == 0 {
+			v.Example = fmt.Sprintf(format, args...)
+		}
+		v.Count++
+	}
+
+	if system == SystemS2 {
+		union := make(s2.CellUnion, len(cells))
+		for i, c := range cells {
+			union[i] = s2.CellID(c)
+		}
+		slices.Sort(union)
+		check := func(kind string, p s2.Point) {
+			if !union.ContainsCellID(s2.CellIDFromLatLng(s2.LatLngFromPoint(p))) {
+				flag("%s %v is not covered", kind, s2.LatLngFromPoint(p))
+			}
+		}
+		for _, loop := range f.S2Polygon.Loops() {
+			for i := 0; i < loop.NumVertices(); i++ {
+				a, b := loop.Vertex(i), loop.Vertex(i+1)
+				check("vertex", a)
+				check("edge midpoint", s2.Interpolate(0.5, a, b))
+			}
+		}
+		for _, ll := range RandomPointsInRect(f.S2Polygon.RectBound(), samples, seed) {
+			if p := s2.PointFromLatLng(ll); f.S2Polygon.ContainsPoint(p) {
+				check("interior point", p)
+			}
+		}
+		return v, nil
+	}
+
+	set := make(map[uint64]bool, len(cells))
+	for _, c := range cells {
+		set[c] = true
+		center, err := h3.Cell(c).LatLng()
+		if err != nil {
+			return v, err
+		}
+		if !f.Geometry.ContainsPlanar(center.Lng, center.Lat) {
+			flag("cell %s has its center outside the polygon", h3.Cell(c))
+		}
+	}
+	for _, ll := range RandomPointsInRect(f.S2Polygon.RectBound(), samples, seed) {
+		if !f.Geometry.ContainsPlanar(ll.Lng.Degrees(), ll.Lat.Degrees()) {
+			continue
+		}
+		cell, err := dh3.PointCell(ll.Lat.Degrees(), ll.Lng.Degrees(), resolution)
+		if err != nil {
+			return v, err
+		}
+		center, err := h3.Cell(cell).LatLng()
+		if err != nil {
+			return v, err
+		}
+		if f.Geometry.ContainsPlanar(center.Lng, center.Lat) && !set[cell] {
+			flag("cell %s has its center inside the polygon but is missing", h3.Cell(cell))
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,57 @@
+//go:build js && wasm && h3
+
+package main
+
+import (
+	"fmt"
+	"syscall/js"
+	"time"
+
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+)
+
+// The h3 tag covers with h3-js, the Emscripten build of the same C library
+// as h3-go, which the page must load as the global h3 before this module
+func init() {
+	coverers["H3"] = coverH3
+}
+
+func coverH3(geometry geojson.Geometry, resolution, _ int) (cells []wasmCell, elapsed time.Duration, err error) {
+	h3 := js.Global().Get("h3")
+	if h3.IsUndefined() {
+		return nil, 0, fmt.Errorf("h3-js is not loaded")
+	}
+	defer func() {
+		// h3-js throws on invalid input, which syscall/js raises as a panic
+		if r := recover(); r != nil {
+			cells, elapsed, err = nil, 0, fmt.Errorf("h3-js: %v", r)
+		}
+	}()
+
+	rings := make([]any, len(geometry.Coordinates))
+	for i, ring := range geometry.Coordinates {
+		positions := make([]any, len(ring))
+		for j, p := range ring {
+			positions[j] = []any{p[0], p[1]}
+		}
+		rings[i] = positions
+	}
+	jsRings := js.ValueOf(rings)
+
+	start := time.Now()
+	indexes := h3.Call("polygonToCells", jsRings, resolution, true)
+	elapsed = time.Since(start)
+
+	cells = make([]wasmCell, indexes.Length())
+	for i := range cells {
+		id := indexes.Index(i)
+		boundary := h3.Call("cellToBoundary", id, true)
+		ring := make([][2]float64, boundary.Length())
+		for j := range ring {
+			p := boundary.Index(j)
+			ring[j] = [2]float64{p.Index(0).Float(), p.Index(1).Float()}
+		}
+		cells[i] = wasmCell{ID: id.String(), Ring: ring}
+	}
+	return cells, elapsed, nil
+}
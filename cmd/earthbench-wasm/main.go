@@ -0,0 +1,121 @@
+//go:build js && wasm
+
+// Command earthbench-wasm is the covering engine compiled to WebAssembly
+// for the browser demo in web/. It registers a global earthbench object:
+//
+//	earthbench.systems                                   // ["S2"], and "H3" with -tags h3
+//	earthbench.cover(geojson, system, resolution, maxCells)
+//
+// cover takes a GeoJSON Polygon, Feature or FeatureCollection as text and
+// covers its first polygon, returning {cells: [{id, ring}], millis} or
+// {error}. Cell IDs are H3 index strings or S2 tokens, since JavaScript
+// numbers cannot hold 64-bit IDs, and rings are closed [lng, lat] arrays.
+// millis times the covering call alone, not the conversion.
+//
+// h3-go is cgo and cannot compile to WebAssembly, so S2 is built in and
+// H3, behind the h3 build tag, calls the h3-js library on the page.
+//
+// Build from the repository root with:
+//
+//	GOOS=js GOARCH=wasm go build -o cmd/earthbench-wasm/web/earthbench.wasm ./cmd/earthbench-wasm
+//	cp "$(go env GOROOT)/lib/wasm/wasm_exec.js" cmd/earthbench-wasm/web/
+package main
+
+import (
+	"fmt"
+	"syscall/js"
+	"time"
+
+	"github.com/golang/geo/s2"
+	ds2 "github.com/nkk36/earth-discretization-benchmark/discretize/s2"
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+)
+
+// wasmCell is a covering cell as handed to JavaScript
+type wasmCell struct {
+	ID   string
+	Ring [][2]float64
+}
+
+// coverFunc covers a polygon, timing the covering call
+type coverFunc func(geometry geojson.Geometry, resolution, maxCells int) ([]wasmCell, time.Duration, error)
+
+// coverers are the systems compiled in, by name
+var coverers = map[string]coverFunc{"S2": coverS2}
+
+// systemOrder lists the systems in the order the demo shows them
+var systemOrder = []string{"H3", "S2"}
+
+func coverS2(geometry geojson.Geometry, level, maxCells int) ([]wasmCell, time.Duration, error) {
+	polygon, err := ds2.FromGeometry(geometry, geojson.LogSkippedRing)
+	if err != nil {
+		return nil, 0, err
+	}
+	start := time.Now()
+	ids := ds2.Cover(polygon, level, maxCells)
+	elapsed := time.Since(start)
+
+	cells := make([]wasmCell, len(ids))
+	for i, id := range ids {
+		c := s2.CellFromCellID(s2.CellID(id))
+		ring := make([][2]float64, 0, 5)
+		for k := 0; k < 4; k++ {
+			ll := s2.LatLngFromPoint(c.Vertex(k))
+			ring = append(ring, [2]float64{ll.Lng.Degrees(), ll.Lat.Degrees()})
+		}
+		cells[i] = wasmCell{ID: s2.CellID(id).ToToken(), Ring: append(ring, ring[0])}
+	}
+	return cells, elapsed, nil
+}
+
+// cover is earthbench.cover
+func cover(_ js.Value, args []js.Value) any {
+	if len(args) != 4 {
+		return map[string]any{"error": "cover(geojson, system, resolution, maxCells) takes 4 arguments"}
+	}
+	cells, elapsed, err := coverText(args[0].String(), args[1].String(), args[2].Int(), args[3].Int())
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+	jsCells := make([]any, len(cells))
+	for i, c := range cells {
+		ring := make([]any, len(c.Ring))
+		for j, p := range c.Ring {
+			ring[j] = []any{p[0], p[1]}
+		}
+		jsCells[i] = map[string]any{"id": c.ID, "ring": ring}
+	}
+	return map[string]any{"cells": jsCells, "millis": float64(elapsed.Nanoseconds()) / 1e6}
+}
+
+func coverText(text, system string, resolution, maxCells int) ([]wasmCell, time.Duration, error) {
+	coverer, ok := coverers[system]
+	if !ok {
+		return nil, 0, fmt.Errorf("system %q is not built in", system)
+	}
+	fc, err := geojson.Parse([]byte(text))
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, f := range fc.Features {
+		if f.Geometry.Type == "Polygon" {
+			return coverer(f.Geometry, resolution, maxCells)
+		}
+	}
+	return nil, 0, fmt.Errorf("no Polygon in the GeoJSON")
+}
+
+func main() {
+	var systems []any
+	for _, name := range systemOrder {
+		if _, ok := coverers[name]; ok {
+			systems = append(systems, name)
+		}
+	}
+	js.Global().Set("earthbench", js.ValueOf(map[string]any{
+		"systems": systems,
+		"cover":   js.FuncOf(cover),
+	}))
+	// Keep the exported functions alive
+	select {}
+}
@@ -0,0 +1,49 @@
+// earthbench.js loads the WebAssembly covering engine built from
+// cmd/earthbench-wasm and wraps it for the page. wasm_exec.js, from the Go
+// distribution, must be loaded first; h3-js too, as the global h3, for H3.
+//
+//   const engine = await loadEarthbench("earthbench.wasm");
+//   const { cells, millis } = engine.cover(polygon, "S2", 10, 8);
+//
+// When the engine was built without the h3 tag, H3 is covered by h3-js
+// directly from JavaScript, timed the same way, so the demo compares both
+// systems either way.
+
+export async function loadEarthbench(url = "earthbench.wasm") {
+  const go = new Go();
+  const { instance } = await WebAssembly.instantiateStreaming(fetch(url), go.importObject);
+  go.run(instance);
+  const engine = globalThis.earthbench;
+
+  const systems = [...engine.systems];
+  const inEngine = new Set(systems);
+  if (!inEngine.has("H3") && globalThis.h3) {
+    systems.unshift("H3");
+  }
+
+  return {
+    systems,
+
+    // cover covers a GeoJSON Polygon geometry or Feature at a resolution
+    // (H3) or level (S2); maxCells applies to S2. It throws on error.
+    cover(polygon, system, resolution, maxCells = 8) {
+      if (system === "H3" && !inEngine.has("H3")) {
+        return coverH3(polygon, resolution);
+      }
+      const result = engine.cover(JSON.stringify(polygon), system, resolution, maxCells);
+      if (result.error) {
+        throw new Error(result.error);
+      }
+      return result;
+    },
+  };
+}
+
+function coverH3(polygon, resolution) {
+  const coordinates = (polygon.geometry ?? polygon).coordinates;
+  const start = performance.now();
+  const ids = h3.polygonToCells(coordinates, resolution, true);
+  const millis = performance.now() - start;
+  const cells = ids.map((id) => ({ id, ring: h3.cellToBoundary(id, true) }));
+  return { cells, millis };
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/nkk36/earth-discretization-benchmark/report"
+)
+
+// AdaptiveResult compares a run where every polygon is covered at its own
+// resolution (Mode "adaptive", Resolution -1) with fixed-resolution runs.
+// CellsPerFeature is the spread of covering sizes across polygons, which
+// adaptive selection is meant to narrow.
+type AdaptiveResult struct {
+	System          string
+	Mode            string
+	Resolution      int
+	Features        int
+	Cells           int
+	Duration        time.Duration
+	CellsPerFeature report.Distribution
+}
+
+// AdaptiveFeature is the resolution picked for one polygon
+type AdaptiveFeature struct {
+	FeatureID  int
+	System     string
+	AreaKm2    float64
+	Resolution int
+	Cells      int
+	Duration   time.Duration
+}
+
+func runAdaptiveCommand(args []string) error {
+	fs := flag.NewFlagSet("adaptive", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "3-7", "6-12")
+	target := fs.Int("target-cells", 100, "cells per polygon the adaptive resolution aims for")
+	output := fs.String("output", "output/adaptive.csv", "CSV file for the summary")
+	detail := fs.String("detail", "", "optional CSV file listing the resolution chosen for every polygon")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *target < 1 {
+		return fmt.Errorf("-target-cells must be at least 1")
+	}
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d features from %s\n", len(ds.Features), ds.Path)
+
+	measurements, err := bench.BenchmarkCoverings(context.Background(), ds, sweepPoints, bench.CoveringOptions{MaxCells: *sweep.MaxCells})
+	if err != nil {
+		return err
+	}
+	var results []AdaptiveResult
+	systems := make(map[string]bool)
+	for _, m := range measurements {
+		systems[m.System] = true
+		coverings, err := bench.ComputeCoverings(ds, m.System, m.Resolution, *sweep.MaxCells)
+		if err != nil {
+			return err
+		}
+		sizes := make([]float64, len(coverings))
+		for i, c := range coverings {
+			sizes[i] = float64(len(c))
+		}
+		results = append(results, AdaptiveResult{
+			System:          m.System,
+			Mode:            "fixed",
+			Resolution:      m.Resolution,
+			Features:        len(m.Durations),
+			Cells:           m.Cells,
+			Duration:        m.TotalDuration(),
+			CellsPerFeature: report.NewDistribution(sizes),
+		})
+	}
+
+	var details []AdaptiveFeature
+	for _, system := range []string{bench.SystemH3, bench.SystemS2} {
+		if !systems[system] {
+			continue
+		}
+		r, d, err := benchmarkAdaptiveCoverings(ds, system, *target, *sweep.MaxCells)
+		if err != nil {
+			return err
+		}
+		results = append(results, r)
+		details = append(details, d...)
+	}
+
+	for _, r := range results {
+		fmt.Printf("%s %-8s res %2d: %9d cells in %12v, cells/feature min %.0f median %.0f max %.0f\n",
+			r.System, r.Mode, r.Resolution, r.Cells, r.Duration,
+			r.CellsPerFeature.Min, r.CellsPerFeature.Median, r.CellsPerFeature.Max)
+	}
+
+	if err := saveAdaptiveResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	if *detail != "" {
+		if err := saveAdaptiveFeaturesToCSV(*detail, details); err != nil {
+			return err
+		}
+		fmt.Printf("Per-feature resolutions saved to %s\n", *detail)
+	}
+	return nil
+}
+
+// adaptiveResolution picks the resolution at which a polygon of areaKm2
+// would be covered by roughly target average-sized cells, comparing on a
+// log scale so 2x too many and 2x too few count the same
+func adaptiveResolution(system string, areaKm2 float64, target int) (int, error) {
+	best, bestDiff := 0, math.Inf(1)
+	for res := 0; res <= maxResolution(system); res++ {
+		cellArea, err := averageCellAreaKm2(system, res)
+		if err != nil {
+			return 0, err
+		}
+		if diff := math.Abs(math.Log(areaKm2 / cellArea / float64(target))); diff < bestDiff {
+			best, bestDiff = res, diff
+		}
+	}
+	return best, nil
+}
+
+func benchmarkAdaptiveCoverings(ds *bench.Dataset, system string, target, maxCells int) (AdaptiveResult, []AdaptiveFeature, error) {
+	result := AdaptiveResult{System: system, Mode: "adaptive", Resolution: -1}
+	var details []AdaptiveFeature
+	sizes := make([]float64, 0, len(ds.Features))
+
+	for _, f := range ds.Features {
+		area := f.S2Polygon.Area() * bench.EarthRadiusKm * bench.EarthRadiusKm
+		res, err := adaptiveResolution(system, area, target)
+		if err != nil {
+			return result, nil, err
+		}
+		start := time.Now()
+		covering, err := bench.CoverFeature(f, system, res, maxCells)
+		duration := time.Since(start)
+		if err != nil {
+			return result, nil, fmt.Errorf("%s resolution %d, feature %d: %w", system, res, f.FeatureID, err)
+		}
+		result.Features++
+		result.Cells += len(covering)
+		result.Duration += duration
+		sizes = append(sizes, float64(len(covering)))
+		details = append(details, AdaptiveFeature{
+			FeatureID:  f.FeatureID,
+			System:     system,
+			AreaKm2:    area,
+			Resolution: res,
+			Cells:      len(covering),
+			Duration:   duration,
+		})
+	}
+	result.CellsPerFeature = report.NewDistribution(sizes)
+	return result, details, nil
+}
+
+func saveAdaptiveResultsToCSV(filename string, results []AdaptiveResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "Mode", "Resolution", "Features", "Cells", "DurationNs", "MinCells", "MedianCells", "P90Cells", "MaxCells"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			r.System,
+			r.Mode,
+			strconv.Itoa(r.Resolution),
+			strconv.Itoa(r.Features),
+			strconv.Itoa(r.Cells),
+			strconv.FormatInt(r.Duration.Nanoseconds(), 10),
+			strconv.FormatFloat(r.CellsPerFeature.Min, 'f', -1, 64),
+			strconv.FormatFloat(r.CellsPerFeature.Median, 'f', -1, 64),
+			strconv.FormatFloat(r.CellsPerFeature.P90, 'f', -1, 64),
+			strconv.FormatFloat(r.CellsPerFeature.Max, 'f', -1, 64),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
+
+func saveAdaptiveFeaturesToCSV(filename string, features []AdaptiveFeature) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"FeatureID", "System", "AreaKm2", "Resolution", "Cells", "DurationNs"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, f := range features {
+		row := []string{
+			strconv.Itoa(f.FeatureID),
+			f.System,
+			strconv.FormatFloat(f.AreaKm2, 'f', -1, 64),
+			strconv.Itoa(f.Resolution),
+			strconv.Itoa(f.Cells),
+			strconv.FormatInt(f.Duration.Nanoseconds(), 10),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
@@ -3,15 +3,17 @@ package main
 import (
 	"bufio"
 	"encoding/csv"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"os"
 	"strconv"
 	"time"
 
 	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	dh3 "github.com/nkk36/earth-discretization-benchmark/discretize/h3"
+	ds2 "github.com/nkk36/earth-discretization-benchmark/discretize/s2"
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
 	"github.com/uber/h3-go/v4"
 )
 
@@ -44,122 +46,41 @@ var S2ResolutionAveragesKm2 = map[int]float64{
 	13: 1.27,
 }
 
-type Measurement struct {
-	Resolution        int
-	AverageAreaKm2    float64
-	AverageDurationNs float64
-	Product           string
-}
-
-// GeoJSONFeature represents a single GeoJSON Feature
-type GeoJSONFeature struct {
-	Type       string                 `json:"type"`
-	Geometry   GeoJSONGeometry        `json:"geometry"`
-	Properties map[string]interface{} `json:"properties"`
-}
-
-// GeoJSONGeometry represents the geometry portion of a GeoJSON Feature
-type GeoJSONGeometry struct {
-	Type        string         `json:"type"`
-	Coordinates [][][2]float64 `json:"coordinates"`
-}
-
-// GeoJSONFeatureCollection represents a GeoJSON FeatureCollection
-type GeoJSONFeatureCollection struct {
-	Type     string           `json:"type"`
-	Features []GeoJSONFeature `json:"features"`
-}
-
 // ConvertGeoJSONToH3Polygons reads a GeoJSON file and converts all polygons to H3 GeoPolygons
 func ConvertGeoJSONToH3Polygons(filePath string) ([]h3.GeoPolygon, error) {
-	// Read the GeoJSON file
-	data, err := ioutil.ReadFile(filePath)
+	fc, err := geojson.ReadFile(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("error reading file: %w", err)
+		return nil, err
 	}
 
-	// Parse the GeoJSON FeatureCollection
-	var fc GeoJSONFeatureCollection
-	if err := json.Unmarshal(data, &fc); err != nil {
-		return nil, fmt.Errorf("error unmarshaling GeoJSON: %w", err)
+	mode, err := bench.ConversionMode()
+	if err != nil {
+		return nil, err
 	}
-
+	report := &bench.ConversionReport{Mode: mode}
 	var h3Polygons []h3.GeoPolygon
 
 	// Convert each feature to an H3 GeoPolygon
+	featureIDs := geojson.FeatureIDs(fc)
 	for i, feature := range fc.Features {
+		featureID := featureIDs[i]
 		if feature.Geometry.Type != "Polygon" {
-			log.Printf("Warning: Feature %d is not a Polygon, skipping", i)
+			report.DropFeature(i, featureID, fmt.Sprintf("not a Polygon (%s)", feature.Geometry.Type))
 			continue
 		}
 
 		// Convert the GeoJSON polygon to H3 GeoPolygon
-		h3Polygon, err := convertGeometryToH3Polygon(feature.Geometry)
+		h3Polygon, err := dh3.FromGeometry(feature.Geometry, report.SkipRing(i, featureID))
 		if err != nil {
-			log.Printf("Warning: Error converting feature %d: %v", i, err)
+			report.DropFeature(i, featureID, err.Error())
 			continue
 		}
 
 		h3Polygons = append(h3Polygons, h3Polygon)
 	}
 
-	return h3Polygons, nil
-}
-
-// convertGeometryToH3Polygon converts a GeoJSON geometry to an H3 GeoPolygon
-func convertGeometryToH3Polygon(geometry GeoJSONGeometry) (h3.GeoPolygon, error) {
-	if geometry.Type != "Polygon" {
-		return h3.GeoPolygon{}, fmt.Errorf("expected Polygon geometry, got %s", geometry.Type)
-	}
-
-	if len(geometry.Coordinates) == 0 {
-		return h3.GeoPolygon{}, fmt.Errorf("polygon has no coordinates")
-	}
-
-	// GeoJSON Polygon coordinates are organized as:
-	// [[exterior ring], [hole1], [hole2], ...]
-	// First ring is the exterior boundary, rest are holes
-
-	// Convert exterior ring (first ring)
-	exterior := convertRingToGeoLoop(geometry.Coordinates[0])
-
-	// Convert holes (if any)
-	var holes []h3.GeoLoop
-	if len(geometry.Coordinates) > 1 {
-		for holeIndex, holeRing := range geometry.Coordinates[1:] {
-			if len(holeRing) < 4 {
-				log.Printf("Warning: Hole %d has fewer than 4 points, skipping", holeIndex)
-				continue
-			}
-			hole := convertRingToGeoLoop(holeRing)
-			holes = append(holes, hole)
-		}
-	}
-
-	// Create H3 GeoPolygon
-	h3Polygon := h3.GeoPolygon{
-		GeoLoop: exterior,
-		Holes:   holes,
-	}
-
-	return h3Polygon, nil
-}
-
-// convertRingToGeoLoop converts a GeoJSON ring to an H3 GeoLoop
-func convertRingToGeoLoop(ring [][2]float64) h3.GeoLoop {
-	var geoLoop h3.GeoLoop
-
-	// Convert each [lon, lat] pair to H3 LatLng
-	// GeoJSON uses [longitude, latitude] order
-	for _, coord := range ring {
-		geoLoc := h3.LatLng{
-			Lat: coord[1], // latitude
-			Lng: coord[0], // longitude
-		}
-		geoLoop = append(geoLoop, geoLoc)
-	}
-
-	return geoLoop
+	report.Print()
+	return h3Polygons, report.Err()
 }
 
 // ProcessPolygonsWithH3 is an example function showing how to use the H3 polygons
@@ -204,121 +125,47 @@ type FeatureRegions struct {
 
 // ConvertGeoJSONToS2Regions reads a GeoJSON file and converts all polygon features to S2 regions
 func ConvertGeoJSONToS2Regions(filePath string) ([]FeatureRegions, error) {
-	// Read the GeoJSON file
-	data, err := ioutil.ReadFile(filePath)
+	fc, err := geojson.ReadFile(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("error reading file: %w", err)
+		return nil, err
 	}
 
-	// Parse the GeoJSON FeatureCollection
-	var fc GeoJSONFeatureCollection
-	if err := json.Unmarshal(data, &fc); err != nil {
-		return nil, fmt.Errorf("error unmarshaling GeoJSON: %w", err)
+	mode, err := bench.ConversionMode()
+	if err != nil {
+		return nil, err
 	}
-
+	report := &bench.ConversionReport{Mode: mode}
 	var featureRegions []FeatureRegions
 
 	// Convert each feature to S2 regions
+	featureIDs := geojson.FeatureIDs(fc)
 	for i, feature := range fc.Features {
+		featureID := featureIDs[i]
 		if feature.Geometry.Type != "Polygon" {
-			log.Printf("Warning: Feature %d is not a Polygon, skipping", i)
+			report.DropFeature(i, featureID, fmt.Sprintf("not a Polygon (%s)", feature.Geometry.Type))
 			continue
 		}
 
-		// Get feature ID from properties if available
-		featureID := i + 1
-		if id, ok := feature.Properties["id"]; ok {
-			if idVal, ok := id.(float64); ok {
-				featureID = int(idVal)
-			}
-		}
-
 		// Convert the GeoJSON polygon to S2 regions
-		regions, err := convertGeometryToS2Regions(feature.Geometry)
+		polygon, err := ds2.FromGeometry(feature.Geometry, report.SkipRing(i, featureID))
 		if err != nil {
-			log.Printf("Warning: Error converting feature %d: %v", i, err)
+			report.DropFeature(i, featureID, err.Error())
 			continue
 		}
 
 		featureRegions = append(featureRegions, FeatureRegions{
 			FeatureID: featureID,
-			Regions:   regions,
+			Regions:   []s2.Region{polygon},
 		})
 	}
 
-	return featureRegions, nil
+	report.Print()
+	return featureRegions, report.Err()
 }
 
-// convertGeometryToS2Regions converts a GeoJSON polygon geometry to S2 regions
-func convertGeometryToS2Regions(geometry GeoJSONGeometry) ([]s2.Region, error) {
-	if geometry.Type != "Polygon" {
-		return nil, fmt.Errorf("expected Polygon geometry, got %s", geometry.Type)
-	}
-
-	if len(geometry.Coordinates) == 0 {
-		return nil, fmt.Errorf("polygon has no coordinates")
-	}
-
-	// Convert exterior ring to S2 loop
-	exteriorLoop := convertRingToS2Loop(geometry.Coordinates[0])
-	if exteriorLoop == nil {
-		return nil, fmt.Errorf("failed to create exterior loop")
-	}
-
-	// Build list of all loops (exterior + holes)
-	loops := []*s2.Loop{exteriorLoop}
-
-	// Add holes as additional loops
-	if len(geometry.Coordinates) > 1 {
-		for holeIndex, holeRing := range geometry.Coordinates[1:] {
-			if len(holeRing) < 4 {
-				log.Printf("Warning: Hole %d has fewer than 4 points, skipping", holeIndex)
-				continue
-			}
-
-			holeLoop := convertRingToS2Loop(holeRing)
-			if holeLoop == nil {
-				log.Printf("Warning: Failed to create hole loop %d", holeIndex)
-				continue
-			}
-
-			loops = append(loops, holeLoop)
-		}
-	}
-
-	// Create polygon from all loops
-	polygon := s2.PolygonFromLoops(loops)
-
-	var regions []s2.Region
-	regions = append(regions, polygon)
-
-	return regions, nil
-}
-
-// convertRingToS2Loop converts a GeoJSON ring to an S2 Loop
-func convertRingToS2Loop(ring [][2]float64) *s2.Loop {
-	if len(ring) < 4 {
-		return nil
-	}
-
-	// Convert each [lon, lat] pair to S2 Point
-	// GeoJSON uses [longitude, latitude] order
-	points := make([]s2.Point, 0, len(ring)-1) // -1 because first and last are the same
-
-	for i := 0; i < len(ring)-1; i++ { // Skip the last point (duplicate of first)
-		coord := ring[i]
-		latLng := s2.LatLngFromDegrees(coord[1], coord[0])
-		point := s2.PointFromLatLng(latLng)
-		points = append(points, point)
-	}
-
-	// Create and return the loop
-	loop := s2.LoopFromPoints(points)
-
-	return loop
-}
-
-func saveFloat64ToCSV(filename string, data map[int]Measurement) error {
+// saveAveragesToCSV writes the average covering duration of each sweep point
+// next to the average cell area at its resolution, the input of main.R
+func saveAveragesToCSV(filename string, results *bench.Results, areasKm2 map[int]float64) error {
 	file, err := os.Create(filename)
 	if err != nil {
 		return err
@@ -331,16 +178,15 @@ func saveFloat64ToCSV(filename string, data map[int]Measurement) error {
 	// Write header
 	headers := []string{"Resolution", "AvgAreaKm2", "AverageDurationNs", "Product"}
 	if err := writer.Write(headers); err != nil {
-		panic(err)
+		return err
 	}
 
-	// Write each float as a row
-	for k, v := range data {
+	for _, s := range results.Summary() {
 		row := []string{
-			strconv.Itoa(k),
-			strconv.FormatFloat(v.AverageAreaKm2, 'f', -1, 64),
-			strconv.FormatFloat(v.AverageDurationNs, 'f', -1, 64),
-			v.Product,
+			strconv.Itoa(s.Resolution),
+			strconv.FormatFloat(areasKm2[s.Resolution], 'f', -1, 64),
+			strconv.FormatFloat(s.MeanNs, 'f', -1, 64),
+			s.System,
 		}
 		if err := writer.Write(row); err != nil {
 			return err
@@ -493,7 +339,7 @@ func h3Experiments(filePath string) {
 
 	fmt.Printf("H3 Experiments ================================================\n")
 	maxResolution := 8 // H3 resolution (0-15, higher = smaller cells)
-	h3averages := make(map[int]Measurement)
+	h3results := &bench.Results{Dataset: filePath}
 	for i := 0; i <= maxResolution; i++ {
 		fmt.Printf("\nResolution: %d\n", i)
 
@@ -509,20 +355,19 @@ func h3Experiments(filePath string) {
 		saveToCSV(output, "duration (ns)", durations)
 		h3avg := averageInt64(durationsToInt64(durations))
 		fmt.Printf("\nAverage: %v\n", h3avg)
-		h3averages[i] = Measurement{
-			Resolution:        i,
-			AverageAreaKm2:    H3ResolutionAveragesKm2[i],
-			AverageDurationNs: h3avg,
-			Product:           "H3",
-		}
+		h3results.Measurements = append(h3results.Measurements, bench.CoveringMeasurement{
+			System:     bench.SystemH3,
+			Resolution: i,
+			Durations:  durations,
+		})
 	}
-	saveFloat64ToCSV("/home/nick898/repos/earth-discretization-benchmark/output/h3-averages.csv", h3averages)
+	saveAveragesToCSV("/home/nick898/repos/earth-discretization-benchmark/output/h3-averages.csv", h3results, H3ResolutionAveragesKm2)
 }
 
 func s2VaryMaxCells(featureRegions []FeatureRegions) {
 	// Fix the level and vary max cells
 	maxCells := 1000
-	s2averages := make(map[int]Measurement)
+	s2results := &bench.Results{}
 	for i := 1; i <= maxCells; i = i + 50 {
 		fmt.Printf("\nMax Cells: %d\n", i)
 
@@ -540,13 +385,14 @@ func s2VaryMaxCells(featureRegions []FeatureRegions) {
 		// saveToCSV(output, "duration (ns)", durations)
 		s2avg := averageInt64(durationsToInt64(durations))
 		fmt.Printf("\nAverage: %v\n", s2avg)
-		s2averages[i] = Measurement{
-			Resolution:        i,
-			AverageAreaKm2:    S2ResolutionAveragesKm2[i],
-			AverageDurationNs: s2avg,
-		}
+		// Resolution holds MaxCells here, the variable of this experiment
+		s2results.Measurements = append(s2results.Measurements, bench.CoveringMeasurement{
+			System:     bench.SystemS2,
+			Resolution: i,
+			Durations:  durations,
+		})
 	}
-	saveFloat64ToCSV("/home/nick898/repos/earth-discretization-benchmark/output/s2-averages-maxcells.csv", s2averages)
+	saveAveragesToCSV("/home/nick898/repos/earth-discretization-benchmark/output/s2-averages-maxcells.csv", s2results, S2ResolutionAveragesKm2)
 }
 
 func s2Caching(featureRegions []FeatureRegions) {
@@ -582,7 +428,7 @@ func s2VaryLevels(featureRegions []FeatureRegions) {
 	fmt.Printf("\nS2 Experiments ================================================\n")
 	// Fix the max cells and set minLevel = maxLevel and vary the levels
 	maxResolution := 13 // Levels 0 - 30; level 13 has average area of 1.27 km^2
-	s2averages := make(map[int]Measurement)
+	s2results := &bench.Results{}
 	for i := 0; i <= maxResolution; i++ {
 		fmt.Printf("\nLevel: %d\n", i)
 
@@ -601,14 +447,13 @@ func s2VaryLevels(featureRegions []FeatureRegions) {
 		saveToCSV(output, "duration (ns)", durations)
 		s2avg := averageInt64(durationsToInt64(durations))
 		fmt.Printf("\nAverage: %v\n", s2avg)
-		s2averages[i] = Measurement{
-			Resolution:        i,
-			AverageAreaKm2:    S2ResolutionAveragesKm2[i],
-			AverageDurationNs: s2avg,
-			Product:           "S2",
-		}
+		s2results.Measurements = append(s2results.Measurements, bench.CoveringMeasurement{
+			System:     bench.SystemS2,
+			Resolution: i,
+			Durations:  durations,
+		})
 	}
-	saveFloat64ToCSV("/home/nick898/repos/earth-discretization-benchmark/output/s2-averages.csv", s2averages)
+	saveAveragesToCSV("/home/nick898/repos/earth-discretization-benchmark/output/s2-averages.csv", s2results, S2ResolutionAveragesKm2)
 }
 
 func s2Experiments(filePath string) {
@@ -625,8 +470,16 @@ func s2Experiments(filePath string) {
 }
 
 func main() {
-	// filePath := "/home/nick898/repos/earth-discretization-benchmark/data/mock_polygons.geojson"
-	// h3Experiments(filePath)
-	// s2Experiments(filePath)
-	h3Intersection("/home/nick898/repos/earth-discretization-benchmark/data/example_polygon_h3_intersection.geojson")
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(2)
+	}
+	cmd, ok := findCommand(os.Args[1])
+	if !ok {
+		printUsage()
+		os.Exit(2)
+	}
+	if err := cmd.Run(os.Args[2:]); err != nil {
+		log.Fatalf("%s: %v", cmd.Name, err)
+	}
 }
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	ds2 "github.com/nkk36/earth-discretization-benchmark/discretize/s2"
+)
+
+// The S2_COVERINGCELLIDS defaults BigQuery covers geographies with when
+// clustering on S2 cells
+const (
+	bigQueryMinLevel = 0
+	bigQueryMaxLevel = 30
+	bigQueryMaxCells = 8
+)
+
+// bigQueryCellRow is one covering cell in the layout BigQuery produces for
+// S2 cells: the cell ID is the signed INT64 returned by
+// S2_CELLIDFROMPOINT and S2_COVERINGCELLIDS, so exported rows join directly
+// against cells computed inside BigQuery
+type bigQueryCellRow struct {
+	FeatureID int    `json:"feature_id"`
+	Level     int    `json:"level"`
+	S2CellID  int64  `json:"s2_cell_id"`
+	Token     string `json:"token"`
+	Geography string `json:"cell_geography"`
+}
+
+// bigQuerySchema is the `bq load --schema` definition for bigQueryCellRow
+var bigQuerySchema = []map[string]string{
+	{"name": "feature_id", "type": "INT64", "mode": "REQUIRED"},
+	{"name": "level", "type": "INT64", "mode": "REQUIRED"},
+	{"name": "s2_cell_id", "type": "INT64", "mode": "REQUIRED"},
+	{"name": "token", "type": "STRING", "mode": "REQUIRED"},
+	{"name": "cell_geography", "type": "GEOGRAPHY", "mode": "REQUIRED"},
+}
+
+// exportS2CoveringsForBigQuery writes the S2 coverings of every feature,
+// with cells from minLevel to maxLevel and at most maxCells of them as
+// S2_COVERINGCELLIDS computes them, as newline-delimited JSON next to a
+// schema file, ready for
+//
+//	bq load --source_format=NEWLINE_DELIMITED_JSON dataset.table <path> <path>.schema.json
+//
+// BigQuery has no native H3 type, so there is no H3 counterpart.
+func exportS2CoveringsForBigQuery(path string, ds *bench.Dataset, minLevel, maxLevel, maxCells int) (int, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	written := 0
+	for _, f := range ds.Features {
+		for _, cell := range ds2.CoverLevels(f.S2Polygon, minLevel, maxLevel, maxCells) {
+			ring, err := cellBoundary(bench.SystemS2, cell)
+			if err != nil {
+				return written, err
+			}
+			row := bigQueryCellRow{
+				FeatureID: f.FeatureID,
+				Level:     s2.CellID(cell).Level(),
+				S2CellID:  int64(cell),
+				Token:     cellToken(bench.SystemS2, cell),
+				Geography: ringToWKT(ring),
+			}
+			if err := encoder.Encode(row); err != nil {
+				return written, err
+			}
+			written++
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return written, err
+	}
+
+	schema, err := json.MarshalIndent(bigQuerySchema, "", "  ")
+	if err != nil {
+		return written, err
+	}
+	schemaPath := strings.TrimSuffix(path, ".ndjson") + ".schema.json"
+	return written, os.WriteFile(schemaPath, schema, 0o644)
+}
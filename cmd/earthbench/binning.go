@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+)
+
+const systemGeohash = "geohash"
+
+// BinningResult is the cost of aggregating points into per-cell counts at
+// one resolution. HeapBytes is the live heap held by the count map once all
+// points are binned.
+type BinningResult struct {
+	System     string
+	Resolution int
+	Points     int
+	Cells      int
+	Duration   time.Duration
+	HeapBytes  uint64
+}
+
+// PointsPerSecond is the binning throughput
+func (r BinningResult) PointsPerSecond() float64 {
+	if r.Duration == 0 {
+		return 0
+	}
+	return float64(r.Points) / r.Duration.Seconds()
+}
+
+func runBinningCommand(args []string) error {
+	fs := flag.NewFlagSet("binning", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "5-8", "8-14")
+	geohash := fs.String("geohash", "4-7", "geohash precisions, e.g. 4-7 (empty to skip)")
+	output := fs.String("output", "output/binning.csv", "CSV file for the results")
+	numPoints := fs.Int("points", 20000000, "number of synthetic points to bin per resolution")
+	chunk := fs.Int("chunk", 1000000, "points generated per batch; generation is not timed")
+	seed := fs.Int64("seed", 1, "seed for the synthetic points")
+	pointsFile := fs.String("points-file", "", "points from gen-points (CSV or GeoJSON) to bin instead of -points synthetic ones, held in memory as one batch")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	if *geohash != "" {
+		precisions, err := parseIntRange(*geohash)
+		if err != nil {
+			return fmt.Errorf("-geohash: %w", err)
+		}
+		for _, p := range precisions {
+			if p < 1 || p > geohashMaxPrecision {
+				return fmt.Errorf("-geohash: precision %d out of range 1-%d", p, geohashMaxPrecision)
+			}
+			sweepPoints = append(sweepPoints, bench.SweepPoint{System: systemGeohash, Resolution: p})
+		}
+	}
+
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	bounds := ds.Bounds()
+	draw := func(batch, n int) []s2.LatLng {
+		return bench.RandomPointsInRect(bounds, n, *seed+int64(batch))
+	}
+	if *pointsFile != "" {
+		points, err := readPoints(*pointsFile)
+		if err != nil {
+			return err
+		}
+		*numPoints, *chunk = len(points), len(points)
+		draw = func(int, int) []s2.LatLng { return points }
+		fmt.Printf("Binning %d points from %s\n", *numPoints, *pointsFile)
+	} else {
+		fmt.Printf("Binning %d points over the bounds of %s\n", *numPoints, ds.Path)
+	}
+
+	var results []BinningResult
+	for _, sp := range sweepPoints {
+		r, err := benchmarkBinning(sp, *numPoints, *chunk, draw)
+		if err != nil {
+			return fmt.Errorf("%s resolution %d: %w", sp.System, sp.Resolution, err)
+		}
+		fmt.Printf("%-7s res %2d: %9d points -> %8d cells in %v (%.0f points/s), heap %.1f MiB\n",
+			r.System, r.Resolution, r.Points, r.Cells, r.Duration, r.PointsPerSecond(), float64(r.HeapBytes)/(1<<20))
+		results = append(results, r)
+	}
+
+	if err := saveBinningResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// binPoint returns the key of the cell a point falls in
+func binPoint(system string, ll s2.LatLng, resolution int) (uint64, error) {
+	if system == systemGeohash {
+		return geohashBits(ll.Lat.Degrees(), ll.Lng.Degrees(), resolution), nil
+	}
+	return bench.PointCell(system, ll, resolution)
+}
+
+// benchmarkBinning counts points per cell. Points are drawn in chunks so
+// tens of millions of them do not have to be held in memory; draw returns
+// the same points for the same batch, so every sweep point sees the same
+// points.
+func benchmarkBinning(sp bench.SweepPoint, numPoints, chunk int, draw func(batch, n int) []s2.LatLng) (BinningResult, error) {
+	result := BinningResult{System: sp.System, Resolution: sp.Resolution}
+
+	runtime.GC()
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	counts := make(map[uint64]uint32)
+	for batch := 0; result.Points < numPoints; batch++ {
+		n := min(chunk, numPoints-result.Points)
+		points := draw(batch, n)
+
+		start := time.Now()
+		for _, ll := range points {
+			cell, err := binPoint(sp.System, ll, sp.Resolution)
+			if err != nil {
+				return result, err
+			}
+			counts[cell]++
+		}
+		result.Duration += time.Since(start)
+		result.Points += n
+	}
+	result.Cells = len(counts)
+
+	runtime.GC()
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	if after.HeapAlloc > before.HeapAlloc {
+		result.HeapBytes = after.HeapAlloc - before.HeapAlloc
+	}
+	runtime.KeepAlive(counts)
+	return result, nil
+}
+
+func saveBinningResultsToCSV(filename string, results []BinningResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "Resolution", "Points", "Cells", "DurationNs", "PointsPerSecond", "HeapBytes"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			r.System,
+			strconv.Itoa(r.Resolution),
+			strconv.Itoa(r.Points),
+			strconv.Itoa(r.Cells),
+			strconv.FormatInt(r.Duration.Nanoseconds(), 10),
+			strconv.FormatFloat(r.PointsPerSecond(), 'f', -1, 64),
+			strconv.FormatUint(r.HeapBytes, 10),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
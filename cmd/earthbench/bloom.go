@@ -0,0 +1,289 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"math"
+	"math/bits"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+)
+
+// BloomResult measures a Bloom filter over the distinct cells of a
+// dataset's coverings as a prefilter for point queries. Negatives are the
+// queries whose cell is in no covering; a false positive is one of them
+// that the filter lets through. The lookup times are for all queries:
+// filter alone, the exact cell index alone, and point-in-polygon answered
+// with and without the filter in front of the index and refinement.
+type BloomResult struct {
+	System         string
+	Resolution     int
+	Cells          int // distinct cells in the filter
+	Bits           int
+	Hashes         int
+	BuildTime      time.Duration
+	Queries        int
+	Negatives      int
+	FalsePositives int
+	Matches        int // queries inside a polygon
+	FilterTime     time.Duration
+	IndexTime      time.Duration
+	RefineTime     time.Duration // index and exact ContainsPoint
+	PrefilterTime  time.Duration // filter, then index and ContainsPoint
+}
+
+// FalsePositiveRate is the share of negatives the filter let through
+func (r BloomResult) FalsePositiveRate() float64 {
+	if r.Negatives == 0 {
+		return 0
+	}
+	return float64(r.FalsePositives) / float64(r.Negatives)
+}
+
+// perQueryNs is a total time over all queries as a mean per query
+func (r BloomResult) perQueryNs(d time.Duration) float64 {
+	if r.Queries == 0 {
+		return 0
+	}
+	return float64(d.Nanoseconds()) / float64(r.Queries)
+}
+
+// bloomFilter is a Bloom filter of cell IDs with k probes derived from two
+// hashes (Kirsch-Mitzenmacher double hashing)
+type bloomFilter struct {
+	words  []uint64
+	bits   uint64
+	hashes int
+}
+
+// newBloomFilter sizes a filter for n entries at a target false-positive
+// rate: n·ln(1/p)/ln²2 bits and bits/n·ln2 probes
+func newBloomFilter(n int, fpr float64) *bloomFilter {
+	m := math.Ceil(float64(max(n, 1)) * math.Log(1/fpr) / (math.Ln2 * math.Ln2))
+	words := (uint64(m) + 63) / 64
+	k := max(1, int(math.Round(float64(words*64)/float64(max(n, 1))*math.Ln2)))
+	return &bloomFilter{words: make([]uint64, words), bits: words * 64, hashes: k}
+}
+
+// mix64 is the splitmix64 finalizer; cell IDs share most of their bits, so
+// they need a strong mix before indexing bits
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+// probe returns the bit of probe i, mapped onto the filter without a
+// division
+func (b *bloomFilter) probe(h1, h2 uint64, i int) uint64 {
+	hi, _ := bits.Mul64(h1+uint64(i)*h2, b.bits)
+	return hi
+}
+
+func (b *bloomFilter) add(cell uint64) {
+	h1 := mix64(cell)
+	h2 := mix64(h1) | 1
+	for i := 0; i < b.hashes; i++ {
+		bit := b.probe(h1, h2, i)
+		b.words[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+func (b *bloomFilter) mayContain(cell uint64) bool {
+	h1 := mix64(cell)
+	h2 := mix64(h1) | 1
+	for i := 0; i < b.hashes; i++ {
+		bit := b.probe(h1, h2, i)
+		if b.words[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func runBloomCommand(args []string) error {
+	fs := flag.NewFlagSet("bloom", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "3-7", "6-12")
+	output := fs.String("output", "output/bloom.csv", "CSV file for the results")
+	queries := fs.Int("queries", 1000000, "number of random query points")
+	seed := fs.Int64("seed", 1, "seed for the random query points")
+	fpr := fs.Float64("fpr", 0.01, "false-positive rate the filter is sized for")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *fpr <= 0 || *fpr >= 1 {
+		return fmt.Errorf("-fpr must be between 0 and 1")
+	}
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d features from %s\n", len(ds.Features), ds.Path)
+
+	points := bench.RandomPointsInRect(ds.Bounds(), *queries, *seed)
+
+	var results []BloomResult
+	for _, sp := range sweepPoints {
+		r, err := benchmarkBloom(ds, sp, *sweep.MaxCells, points, *fpr)
+		if err != nil {
+			return fmt.Errorf("%s resolution %d: %w", sp.System, sp.Resolution, err)
+		}
+		fmt.Printf("%s res %2d: %9d cells in %10s (k=%d), FPR %.4f (%d/%d), ns/query filter %5.1f, index %5.1f, refine %7.1f, filtered refine %7.1f (%.2fx)\n",
+			r.System, r.Resolution, r.Cells, formatBytes(float64(r.Bits)/8), r.Hashes, r.FalsePositiveRate(), r.FalsePositives, r.Negatives,
+			r.perQueryNs(r.FilterTime), r.perQueryNs(r.IndexTime), r.perQueryNs(r.RefineTime), r.perQueryNs(r.PrefilterTime),
+			float64(r.RefineTime)/float64(max(r.PrefilterTime, 1)))
+		results = append(results, r)
+	}
+
+	if err := saveBloomResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// benchmarkBloom indexes the coverings of one sweep point in a cell map and
+// a Bloom filter, then answers the queries with each. The query cells are
+// computed up front so only the lookups are timed.
+func benchmarkBloom(ds *bench.Dataset, sp bench.SweepPoint, maxCells int, points []s2.LatLng, fpr float64) (BloomResult, error) {
+	result := BloomResult{System: sp.System, Resolution: sp.Resolution, Queries: len(points)}
+	index := make(map[uint64][]int)
+	for i, f := range ds.Features {
+		covering, err := bench.CoverFeature(f, sp.System, sp.Resolution, maxCells)
+		if err != nil {
+			return result, fmt.Errorf("feature %d: %w", f.FeatureID, err)
+		}
+		for _, cell := range covering {
+			index[cell] = append(index[cell], i)
+		}
+	}
+
+	start := time.Now()
+	filter := newBloomFilter(len(index), fpr)
+	for cell := range index {
+		filter.add(cell)
+	}
+	result.BuildTime = time.Since(start)
+	result.Cells, result.Bits, result.Hashes = len(index), int(filter.bits), filter.hashes
+
+	cells := make([]uint64, len(points))
+	for i, ll := range points {
+		cell, err := bench.PointCell(sp.System, ll, sp.Resolution)
+		if err != nil {
+			return result, err
+		}
+		cells[i] = cell
+	}
+
+	positives := make([]bool, len(cells))
+	start = time.Now()
+	for i, cell := range cells {
+		positives[i] = filter.mayContain(cell)
+	}
+	result.FilterTime = time.Since(start)
+
+	indexed := make([]bool, len(cells))
+	start = time.Now()
+	for i, cell := range cells {
+		indexed[i] = len(index[cell]) > 0
+	}
+	result.IndexTime = time.Since(start)
+
+	for i := range cells {
+		if !indexed[i] {
+			result.Negatives++
+			if positives[i] {
+				result.FalsePositives++
+			}
+		}
+	}
+
+	refine := func(i int) bool {
+		candidates := index[cells[i]]
+		if len(candidates) == 0 {
+			return false
+		}
+		p := s2.PointFromLatLng(points[i])
+		for _, f := range candidates {
+			if ds.Features[f].S2Polygon.ContainsPoint(p) {
+				return true
+			}
+		}
+		return false
+	}
+	start = time.Now()
+	for i := range cells {
+		if refine(i) {
+			result.Matches++
+		}
+	}
+	result.RefineTime = time.Since(start)
+
+	matches := 0
+	start = time.Now()
+	for i, cell := range cells {
+		if filter.mayContain(cell) && refine(i) {
+			matches++
+		}
+	}
+	result.PrefilterTime = time.Since(start)
+	if matches != result.Matches {
+		return result, fmt.Errorf("the filter dropped %d matching queries", result.Matches-matches)
+	}
+	return result, nil
+}
+
+// saveBloomResultsToCSV writes one row per system and resolution
+func saveBloomResultsToCSV(filename string, results []BloomResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "Resolution", "Cells", "Bits", "Hashes", "BuildNs", "Queries", "Negatives", "FalsePositives",
+		"FalsePositiveRate", "Matches", "FilterNsPerQuery", "IndexNsPerQuery", "RefineNsPerQuery", "PrefilterRefineNsPerQuery"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			r.System,
+			strconv.Itoa(r.Resolution),
+			strconv.Itoa(r.Cells),
+			strconv.Itoa(r.Bits),
+			strconv.Itoa(r.Hashes),
+			strconv.FormatInt(r.BuildTime.Nanoseconds(), 10),
+			strconv.Itoa(r.Queries),
+			strconv.Itoa(r.Negatives),
+			strconv.Itoa(r.FalsePositives),
+			strconv.FormatFloat(r.FalsePositiveRate(), 'f', -1, 64),
+			strconv.Itoa(r.Matches),
+			strconv.FormatFloat(r.perQueryNs(r.FilterTime), 'f', -1, 64),
+			strconv.FormatFloat(r.perQueryNs(r.IndexTime), 'f', -1, 64),
+			strconv.FormatFloat(r.perQueryNs(r.RefineTime), 'f', -1, 64),
+			strconv.FormatFloat(r.perQueryNs(r.PrefilterTime), 'f', -1, 64),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
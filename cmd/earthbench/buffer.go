@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	dh3 "github.com/nkk36/earth-discretization-benchmark/discretize/h3"
+	"github.com/uber/h3-go/v4"
+)
+
+// BufferResult is the cost and accuracy of expanding every covering outward
+// by BufferKm. Accuracy is estimated from sample points around each
+// polygon: InCover samples fall in the expanded covering, InBuffer samples
+// lie within BufferKm of the polygon.
+type BufferResult struct {
+	System      string
+	Resolution  int
+	BufferKm    float64
+	Features    int
+	InputCells  int
+	OutputCells int
+	AreaKm2     float64
+	Duration    time.Duration
+	Samples     int
+	InCover     int
+	InBuffer    int
+	OverCovered int // in the covering but farther than BufferKm
+	Missed      int // within BufferKm but not in the covering
+}
+
+// OverCoverage is the share of the expanded covering lying outside the
+// true buffer
+func (r BufferResult) OverCoverage() float64 {
+	if r.InCover == 0 {
+		return 0
+	}
+	return float64(r.OverCovered) / float64(r.InCover)
+}
+
+// MissRate is the share of the true buffer the expanded covering misses
+func (r BufferResult) MissRate() float64 {
+	if r.InBuffer == 0 {
+		return 0
+	}
+	return float64(r.Missed) / float64(r.InBuffer)
+}
+
+func runBufferCommand(args []string) error {
+	fs := flag.NewFlagSet("buffer", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "4-7", "8-13")
+	output := fs.String("output", "output/buffer.csv", "CSV file for the results")
+	bufferKm := fs.Float64("buffer-km", 5, "distance to expand the coverings by")
+	maxLevelDiff := fs.Int("s2-max-level-diff", 4, "ExpandByRadius maxLevelDiff: how many levels coarser than the input the expansion cells may be")
+	samples := fs.Int("samples", 500, "sample points per feature for the over-coverage estimate")
+	seed := fs.Int64("seed", 1, "seed for the sample points")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d features from %s\n", len(ds.Features), ds.Path)
+
+	radius := s1.Angle(*bufferKm / bench.EarthRadiusKm)
+	idx := newNearestIndex(ds)
+	sampleSets := make([][]s2.LatLng, len(ds.Features))
+	for i, f := range ds.Features {
+		rect := f.S2Polygon.CapBound().Expanded(radius * 1.5).RectBound()
+		sampleSets[i] = bench.RandomPointsInRect(rect, *samples, *seed+int64(i))
+	}
+
+	var results []BufferResult
+	for _, sp := range sweepPoints {
+		r, err := benchmarkBuffering(ds, idx, sp, *sweep.MaxCells, radius, *maxLevelDiff, sampleSets)
+		if err != nil {
+			return fmt.Errorf("%s resolution %d: %w", sp.System, sp.Resolution, err)
+		}
+		r.BufferKm = *bufferKm
+		fmt.Printf("%s res %2d: %8d -> %8d cells in %v, %.0f km², over-coverage %.2f%%, missed %.2f%%\n",
+			r.System, r.Resolution, r.InputCells, r.OutputCells, r.Duration, r.AreaKm2,
+			100*r.OverCoverage(), 100*r.MissRate())
+		results = append(results, r)
+	}
+
+	if err := saveBufferResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+func benchmarkBuffering(ds *bench.Dataset, idx *nearestIndex, sp bench.SweepPoint, maxCells int, radius s1.Angle, maxLevelDiff int, sampleSets [][]s2.LatLng) (BufferResult, error) {
+	result := BufferResult{System: sp.System, Resolution: sp.Resolution}
+	limit := s1.ChordAngleFromAngle(radius)
+
+	for i, f := range ds.Features {
+		covering, err := bench.CoverFeature(f, sp.System, sp.Resolution, maxCells)
+		if err != nil {
+			return result, fmt.Errorf("feature %d: %w", f.FeatureID, err)
+		}
+		result.Features++
+		result.InputCells += len(covering)
+
+		var contains func(ll s2.LatLng) bool
+		if sp.System == bench.SystemH3 {
+			start := time.Now()
+			expanded, err := bufferH3(covering, sp.Resolution, radius)
+			result.Duration += time.Since(start)
+			if err != nil {
+				return result, fmt.Errorf("feature %d: %w", f.FeatureID, err)
+			}
+			result.OutputCells += len(expanded)
+			for cell := range expanded {
+				area, _ := h3.CellAreaKm2(h3.Cell(cell))
+				result.AreaKm2 += area
+			}
+			contains = func(ll s2.LatLng) bool {
+				cell, err := dh3.PointCell(ll.Lat.Degrees(), ll.Lng.Degrees(), sp.Resolution)
+				return err == nil && expanded[cell]
+			}
+		} else {
+			union := make(s2.CellUnion, len(covering))
+			for k, cell := range covering {
+				union[k] = s2.CellID(cell)
+			}
+			start := time.Now()
+			union.Normalize()
+			union.ExpandByRadius(radius, maxLevelDiff)
+			result.Duration += time.Since(start)
+			result.OutputCells += len(union)
+			result.AreaKm2 += union.ExactArea() * bench.EarthRadiusKm * bench.EarthRadiusKm
+			contains = func(ll s2.LatLng) bool { return union.ContainsPoint(s2.PointFromLatLng(ll)) }
+		}
+
+		for _, ll := range sampleSets[i] {
+			inCover := contains(ll)
+			inBuffer := idx.features[i].IsDistanceLess(s2.NewMinDistanceToPointTarget(s2.PointFromLatLng(ll)), limit.Successor())
+			result.Samples++
+			if inCover {
+				result.InCover++
+				if !inBuffer {
+					result.OverCovered++
+				}
+			}
+			if inBuffer {
+				result.InBuffer++
+				if !inCover {
+					result.Missed++
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// bufferH3 grows an H3 covering by adding the grid disk around each of its
+// boundary cells (cells with a neighbour outside the covering). The disk
+// radius k is the number of center-to-center steps, sqrt(3) average edge
+// lengths each, needed to span the buffer distance.
+func bufferH3(covering []uint64, res int, radius s1.Angle) (map[uint64]bool, error) {
+	edgeKm, err := h3.HexagonEdgeLengthAvgKm(res)
+	if err != nil {
+		return nil, err
+	}
+	k := int(math.Ceil(radius.Radians() * bench.EarthRadiusKm / (math.Sqrt(3) * edgeKm)))
+
+	original := make(map[uint64]bool, len(covering))
+	for _, cell := range covering {
+		original[cell] = true
+	}
+	out := make(map[uint64]bool, len(covering))
+	for _, cell := range covering {
+		out[cell] = true
+		neighbours, err := h3.GridDisk(h3.Cell(cell), 1)
+		if err != nil {
+			return nil, err
+		}
+		boundary := false
+		for _, n := range neighbours {
+			if !original[uint64(n)] {
+				boundary = true
+				break
+			}
+		}
+		if !boundary {
+			continue
+		}
+		disk, err := h3.GridDisk(h3.Cell(cell), k)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range disk {
+			out[uint64(n)] = true
+		}
+	}
+	return out, nil
+}
+
+func saveBufferResultsToCSV(filename string, results []BufferResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "Resolution", "BufferKm", "Features", "InputCells", "OutputCells", "AreaKm2",
+		"DurationNs", "Samples", "InCover", "InBuffer", "OverCovered", "Missed", "OverCoverage", "MissRate"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			r.System,
+			strconv.Itoa(r.Resolution),
+			strconv.FormatFloat(r.BufferKm, 'f', -1, 64),
+			strconv.Itoa(r.Features),
+			strconv.Itoa(r.InputCells),
+			strconv.Itoa(r.OutputCells),
+			strconv.FormatFloat(r.AreaKm2, 'f', -1, 64),
+			strconv.FormatInt(r.Duration.Nanoseconds(), 10),
+			strconv.Itoa(r.Samples),
+			strconv.Itoa(r.InCover),
+			strconv.Itoa(r.InBuffer),
+			strconv.Itoa(r.OverCovered),
+			strconv.Itoa(r.Missed),
+			strconv.FormatFloat(r.OverCoverage(), 'f', -1, 64),
+			strconv.FormatFloat(r.MissRate(), 'f', -1, 64),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
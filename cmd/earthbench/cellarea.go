@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/nkk36/earth-discretization-benchmark/report"
+)
+
+// CellAreaResult is the spread of true cell areas at one system and
+// resolution, against the average-area constant the other commands assume.
+// The dataset figures are over the distinct cells of all coverings;
+// CoveringCV and CoveringSpread are distributions over coverings of the
+// coefficient of variation (stddev/mean) and max/min ratio of their cells.
+type CellAreaResult struct {
+	System         string
+	Resolution     int
+	Coverings      int
+	Cells          int
+	NominalKm2     float64
+	MinKm2         float64
+	MaxKm2         float64
+	MeanKm2        float64
+	StdDevKm2      float64
+	CoveringCV     report.Distribution
+	CoveringSpread report.Distribution
+}
+
+// CV is the coefficient of variation of the cell areas
+func (r CellAreaResult) CV() float64 {
+	if r.MeanKm2 == 0 {
+		return 0
+	}
+	return r.StdDevKm2 / r.MeanKm2
+}
+
+// Spread is the largest over the smallest cell area
+func (r CellAreaResult) Spread() float64 {
+	if r.MinKm2 == 0 {
+		return 0
+	}
+	return r.MaxKm2 / r.MinKm2
+}
+
+func runCellAreaCommand(args []string) error {
+	fs := flag.NewFlagSet("cellarea", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "0-6", "2-11")
+	output := fs.String("output", "output/cellarea.csv", "CSV file for the results")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d features from %s\n", len(ds.Features), ds.Path)
+
+	var results []CellAreaResult
+	for _, sp := range sweepPoints {
+		coverings, err := bench.ComputeCoverings(ds, sp.System, sp.Resolution, *sweep.MaxCells)
+		if err != nil {
+			return fmt.Errorf("%s resolution %d: %w", sp.System, sp.Resolution, err)
+		}
+		r, err := measureCellAreas(sp, coverings)
+		if err != nil {
+			return fmt.Errorf("%s resolution %d: %w", sp.System, sp.Resolution, err)
+		}
+		fmt.Printf("%s res %2d: %9d cells, mean %10.4g km² (nominal %10.4g), min %10.4g, max %10.4g, CV %.4f, max/min %.3f; per covering CV median %.4f, max/min p90 %.3f\n",
+			r.System, r.Resolution, r.Cells, r.MeanKm2, r.NominalKm2, r.MinKm2, r.MaxKm2, r.CV(), r.Spread(), r.CoveringCV.Median, r.CoveringSpread.P90)
+		results = append(results, r)
+	}
+
+	if err := saveCellAreaResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// measureCellAreas computes the exact area of every cell of the coverings
+func measureCellAreas(sp bench.SweepPoint, coverings [][]uint64) (CellAreaResult, error) {
+	result := CellAreaResult{System: sp.System, Resolution: sp.Resolution, Coverings: len(coverings), MinKm2: math.Inf(1)}
+	nominal, err := measuredCellAreaKm2(sp.System, sp.Resolution)
+	if err != nil {
+		return result, err
+	}
+	result.NominalKm2 = nominal
+
+	areas := make(map[uint64]float64)
+	var cvs, spreads []float64
+	for _, covering := range coverings {
+		if len(covering) == 0 {
+			continue
+		}
+		var sum, sumSq float64
+		lo, hi := math.Inf(1), 0.0
+		for _, cell := range covering {
+			area, ok := areas[cell]
+			if !ok {
+				if area, err = cellAreaKm2(sp.System, cell); err != nil {
+					return result, err
+				}
+				areas[cell] = area
+			}
+			sum += area
+			sumSq += area * area
+			lo, hi = min(lo, area), max(hi, area)
+		}
+		n := float64(len(covering))
+		mean := sum / n
+		cvs = append(cvs, math.Sqrt(max(0, sumSq/n-mean*mean))/mean)
+		spreads = append(spreads, hi/lo)
+	}
+
+	var sum, sumSq float64
+	for _, area := range areas {
+		sum += area
+		sumSq += area * area
+		result.MinKm2, result.MaxKm2 = min(result.MinKm2, area), max(result.MaxKm2, area)
+	}
+	result.Cells = len(areas)
+	if result.Cells == 0 {
+		result.MinKm2 = 0
+	} else {
+		n := float64(result.Cells)
+		result.MeanKm2 = sum / n
+		result.StdDevKm2 = math.Sqrt(max(0, sumSq/n-result.MeanKm2*result.MeanKm2))
+	}
+	result.CoveringCV = report.NewDistribution(cvs)
+	result.CoveringSpread = report.NewDistribution(spreads)
+	return result, nil
+}
+
+// saveCellAreaResultsToCSV writes one row per system and resolution
+func saveCellAreaResultsToCSV(filename string, results []CellAreaResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "Resolution", "Coverings", "Cells", "NominalKm2", "MinKm2", "MaxKm2", "MeanKm2", "StdDevKm2", "CV", "MaxOverMin",
+		"CoveringCVMedian", "CoveringCVP90", "CoveringCVMax", "CoveringMaxOverMinMedian", "CoveringMaxOverMinP90", "CoveringMaxOverMinMax"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	for _, r := range results {
+		row := []string{
+			r.System,
+			strconv.Itoa(r.Resolution),
+			strconv.Itoa(r.Coverings),
+			strconv.Itoa(r.Cells),
+			f(r.NominalKm2), f(r.MinKm2), f(r.MaxKm2), f(r.MeanKm2), f(r.StdDevKm2), f(r.CV()), f(r.Spread()),
+			f(r.CoveringCV.Median), f(r.CoveringCV.P90), f(r.CoveringCV.Max),
+			f(r.CoveringSpread.Median), f(r.CoveringSpread.P90), f(r.CoveringSpread.Max),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"math"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	dh3 "github.com/nkk36/earth-discretization-benchmark/discretize/h3"
+	ds2 "github.com/nkk36/earth-discretization-benchmark/discretize/s2"
+	"github.com/uber/h3-go/v4"
+)
+
+// cellToken returns the canonical string form of a cell: the H3 index in hex
+// or the S2 cell token
+func cellToken(system string, cell uint64) string {
+	if system == bench.SystemH3 {
+		return h3.CellToString(h3.Cell(cell))
+	}
+	return s2.CellID(cell).ToToken()
+}
+
+// cellBoundary returns the boundary of a cell as a closed GeoJSON ring of
+// [lon, lat] positions
+func cellBoundary(system string, cell uint64) ([][2]float64, error) {
+	var ring [][2]float64
+	if system == bench.SystemH3 {
+		boundary, err := h3.CellToBoundary(h3.Cell(cell))
+		if err != nil {
+			return nil, err
+		}
+		for _, ll := range boundary {
+			ring = append(ring, [2]float64{ll.Lng, ll.Lat})
+		}
+	} else {
+		c := s2.CellFromCellID(s2.CellID(cell))
+		for k := 0; k < 4; k++ {
+			ll := s2.LatLngFromPoint(c.Vertex(k))
+			ring = append(ring, [2]float64{ll.Lng.Degrees(), ll.Lat.Degrees()})
+		}
+	}
+	return append(ring, ring[0]), nil
+}
+
+// maxResolution is the finest H3 resolution or S2 level
+func maxResolution(system string) int {
+	if system == bench.SystemH3 {
+		return dh3.MaxResolution
+	}
+	return ds2.MaxLevel
+}
+
+// averageCellAreaKm2 is the mean cell area at a resolution: the hexagon
+// average for H3, the face area split evenly for S2
+func averageCellAreaKm2(system string, resolution int) (float64, error) {
+	if system == bench.SystemH3 {
+		return h3.HexagonAreaAvgKm2(resolution)
+	}
+	return 4 * math.Pi * bench.EarthRadiusKm * bench.EarthRadiusKm / (6 * math.Pow(4, float64(resolution))), nil
+}
+
+// averageEdgeLengthKm is the mean cell edge length at a resolution
+func averageEdgeLengthKm(system string, resolution int) (float64, error) {
+	if system == bench.SystemH3 {
+		return h3.HexagonEdgeLengthAvgKm(resolution)
+	}
+	return s2.AvgEdgeMetric.Value(resolution) * bench.EarthRadiusKm, nil
+}
+
+// cellAreaKm2 is the exact area of one cell
+func cellAreaKm2(system string, cell uint64) (float64, error) {
+	if system == bench.SystemH3 {
+		return h3.CellAreaKm2(h3.Cell(cell))
+	}
+	return s2.CellFromCellID(s2.CellID(cell)).ExactArea() * bench.EarthRadiusKm * bench.EarthRadiusKm, nil
+}
+
+// coveringAreaKm2 is the total area of a set of cells
+func coveringAreaKm2(system string, cells []uint64) (float64, error) {
+	var total float64
+	for _, cell := range cells {
+		area, err := cellAreaKm2(system, cell)
+		if err != nil {
+			return 0, err
+		}
+		total += area
+	}
+	return total, nil
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+)
+
+// coastlineBytesPerVertex is a generous estimate of the memory a vertex
+// takes across the GeoJSON ring, the H3 loop and the S2 loop and its index,
+// used to refuse polygons too large for -max-alloc-mb before building them
+const coastlineBytesPerVertex = 256
+
+// CoastlineResult is the covering of one very-high-vertex polygon at one
+// system and resolution. Allocated counts the bytes the Go heap allocated
+// during the covering call; H3's C allocations are not included.
+type CoastlineResult struct {
+	System     string
+	Resolution int
+	FeatureID  int
+	Vertices   int
+	Cells      int
+	Duration   time.Duration
+	Allocated  uint64
+}
+
+// NsPerVertex is the covering time per polygon vertex
+func (r CoastlineResult) NsPerVertex() float64 {
+	if r.Vertices == 0 {
+		return 0
+	}
+	return float64(r.Duration.Nanoseconds()) / float64(r.Vertices)
+}
+
+func runCoastlineCommand(args []string) error {
+	fs := flag.NewFlagSet("coastline", flag.ExitOnError)
+	sweep := &sweepFlags{
+		H3Res:    fs.String("h3-res", "4,6", "H3 resolutions, e.g. 0-6 or 3,5,7 (empty to skip H3)"),
+		S2Levels: fs.String("s2-levels", "8,11", "S2 levels, e.g. 0-11 or 4,8 (empty to skip S2)"),
+		MaxCells: fs.Int("s2-max-cells", 8, "S2 RegionCoverer MaxCells"),
+	}
+	input := fs.String("input", "", "GeoJSON of real coastline polygons to cover instead of generated ones")
+	vertices := fs.String("vertices", "1000,10000,100000,300000,1000000", "vertex counts of the generated polygons")
+	sizeKm := fs.Float64("size-km", 300, "mean radius of the generated polygons")
+	seed := fs.Int64("seed", 1, "seed for the generated coastlines")
+	maxTime := fs.Duration("max-time", 30*time.Second, "skip the larger polygons of a system and resolution once a covering is predicted to take longer")
+	maxAllocMB := fs.Int("max-alloc-mb", 2048, "skip polygons whose geometry or covering is predicted to allocate more")
+	output := fs.String("output", "output/coastline.csv", "CSV file for the results")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	if *maxTime <= 0 || *maxAllocMB <= 0 {
+		return fmt.Errorf("-max-time and -max-alloc-mb must be positive")
+	}
+	maxAlloc := uint64(*maxAllocMB) << 20
+
+	var fc geojson.FeatureCollection
+	if *input != "" {
+		if fc, err = geojson.ReadFile(*input); err != nil {
+			return err
+		}
+	} else {
+		counts, err := parseIntRange(*vertices)
+		if err != nil {
+			return fmt.Errorf("-vertices: %w", err)
+		}
+		if *sizeKm <= 0 {
+			return fmt.Errorf("-size-km must be positive")
+		}
+		rng := rand.New(rand.NewSource(*seed))
+		fc.Type = "FeatureCollection"
+		for _, n := range counts {
+			if n < 3 {
+				return fmt.Errorf("-vertices: %d is fewer than 3", n)
+			}
+			if uint64(n)*coastlineBytesPerVertex > maxAlloc {
+				fmt.Printf("Skipping the %d-vertex polygon: about %s of geometry exceeds -max-alloc-mb\n", n, formatBytes(float64(n)*coastlineBytesPerVertex))
+				continue
+			}
+			fc.Features = append(fc.Features, geojson.Feature{
+				Type:       "Feature",
+				Geometry:   geojson.Geometry{Type: "Polygon", Coordinates: [][][2]float64{coastlineRing(rng, n, *sizeKm)}},
+				Properties: map[string]interface{}{"id": len(fc.Features) + 1, "vertices": n},
+			})
+		}
+	}
+	name := *input
+	if name == "" {
+		name = "coastlines"
+	}
+	ds, err := bench.NewDataset(name, fc)
+	if err != nil {
+		return err
+	}
+	if len(ds.Features) == 0 {
+		return fmt.Errorf("no polygons to cover")
+	}
+	features := slices.Clone(ds.Features)
+	slices.SortStableFunc(features, func(a, b bench.Feature) int { return a.NumVertices() - b.NumVertices() })
+	fmt.Printf("Covering %d polygons of %d to %d vertices from %s\n",
+		len(features), features[0].NumVertices(), features[len(features)-1].NumVertices(), name)
+
+	var results []CoastlineResult
+	for _, sp := range sweepPoints {
+		var done []CoastlineResult
+		for _, f := range features {
+			n := f.NumVertices()
+			if len(done) > 0 {
+				// Guard: extrapolate from the polygons covered so far, at least
+				// linearly in the vertex count
+				exponent := math.Max(1, scalingExponent(done))
+				last := done[len(done)-1]
+				growth := math.Pow(float64(n)/float64(last.Vertices), exponent)
+				predicted := time.Duration(float64(last.Duration) * growth)
+				predictedAlloc := uint64(float64(last.Allocated) * growth)
+				if predicted > *maxTime || predictedAlloc > maxAlloc {
+					fmt.Printf("%s res %2d: skipping %d vertices and up, predicted %v and %s over -max-time %v or -max-alloc-mb %d\n",
+						sp.System, sp.Resolution, n, predicted.Round(time.Millisecond), formatBytes(float64(predictedAlloc)), *maxTime, *maxAllocMB)
+					break
+				}
+			}
+
+			runtime.GC()
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			start := time.Now()
+			covering, err := bench.CoverFeature(f, sp.System, sp.Resolution, *sweep.MaxCells)
+			elapsed := time.Since(start)
+			runtime.ReadMemStats(&after)
+			if err != nil {
+				return fmt.Errorf("%s resolution %d, feature %d: %w", sp.System, sp.Resolution, f.FeatureID, err)
+			}
+			r := CoastlineResult{
+				System: sp.System, Resolution: sp.Resolution, FeatureID: f.FeatureID, Vertices: n,
+				Cells: len(covering), Duration: elapsed, Allocated: after.TotalAlloc - before.TotalAlloc,
+			}
+			fmt.Printf("%s res %2d: %8d vertices, %7d cells in %12v (%8.1f ns/vertex), allocated %s\n",
+				r.System, r.Resolution, r.Vertices, r.Cells, r.Duration, r.NsPerVertex(), formatBytes(float64(r.Allocated)))
+			done = append(done, r)
+		}
+		if len(done) > 1 {
+			fmt.Printf("%s res %2d: covering time grows as vertices^%.2f\n", sp.System, sp.Resolution, scalingExponent(done))
+		}
+		results = append(results, done...)
+	}
+
+	if err := saveCoastlineResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// scalingExponent is the least-squares slope of log covering time against
+// log vertex count, 1 for time linear in the vertices
+func scalingExponent(results []CoastlineResult) float64 {
+	var n, sx, sy, sxx, sxy float64
+	for _, r := range results {
+		if r.Vertices == 0 || r.Duration <= 0 {
+			continue
+		}
+		x, y := math.Log(float64(r.Vertices)), math.Log(float64(r.Duration))
+		n++
+		sx += x
+		sy += y
+		sxx += x * x
+		sxy += x * y
+	}
+	if n < 2 || n*sxx == sx*sx {
+		return 1
+	}
+	return (n*sxy - sx*sy) / (n*sxx - sx*sx)
+}
+
+// coastlineRing draws a closed ring of n vertices that wanders like a
+// coastline: the radius around a random center is a sum of sinusoids whose
+// frequency doubles and amplitude shrinks at each octave, up to what n
+// vertices can resolve. Vertices go round in angle order at a positive
+// radius, so the ring never crosses itself.
+func coastlineRing(rng *rand.Rand, n int, sizeKm float64) [][2]float64 {
+	centerLat := rng.Float64()*100 - 50
+	centerLng := rng.Float64()*300 - 150
+	type wave struct{ freq, amp, phase float64 }
+	var waves []wave
+	total := 0.0
+	for freq, amp := 3.0, 1.0; freq <= float64(n)/8; freq, amp = freq*2, amp*0.6 {
+		for k := range 2 {
+			w := wave{freq: freq + float64(k), amp: amp, phase: rng.Float64() * 2 * math.Pi}
+			waves = append(waves, w)
+			total += w.amp
+		}
+	}
+
+	ry := sizeKm / 111.0
+	rx := ry / math.Cos(centerLat*math.Pi/180)
+	ring := make([][2]float64, 0, n+1)
+	for i := range n {
+		angle := 2 * math.Pi * float64(i) / float64(n)
+		noise := 0.0
+		for _, w := range waves {
+			noise += w.amp * math.Sin(w.freq*angle+w.phase)
+		}
+		r := 1.0
+		if total > 0 {
+			r += 0.45 * noise / total
+		}
+		ring = append(ring, [2]float64{centerLng + r*rx*math.Cos(angle), centerLat + r*ry*math.Sin(angle)})
+	}
+	return append(ring, ring[0])
+}
+
+func saveCoastlineResultsToCSV(filename string, results []CoastlineResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "Resolution", "FeatureID", "Vertices", "Cells", "DurationNs", "NsPerVertex", "AllocatedBytes"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			r.System,
+			strconv.Itoa(r.Resolution),
+			strconv.Itoa(r.FeatureID),
+			strconv.Itoa(r.Vertices),
+			strconv.Itoa(r.Cells),
+			strconv.FormatInt(r.Duration.Nanoseconds(), 10),
+			strconv.FormatFloat(r.NsPerVertex(), 'f', -1, 64),
+			strconv.FormatUint(r.Allocated, 10),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
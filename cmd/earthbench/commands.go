@@ -0,0 +1,366 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/nkk36/earth-discretization-benchmark/data"
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+)
+
+// command is a named entry point selected by the first argument to the
+// binary, e.g. `go run ./cmd/earthbench kvstore -h3-res 0-5`. Running the
+// binary without a command lists them.
+type command struct {
+	Name    string
+	Aliases []string // other names selecting the command
+	Summary string
+	Run     func(args []string) error
+}
+
+var commands = []command{
+	{Name: "cover", Summary: "Print the covering cells of a single geometry (GeoJSON file, stdin or WKT)", Run: runCoverCommand},
+	{Name: "inspect", Summary: "Report dataset statistics and invalid geometries before benchmarking", Run: runInspectCommand},
+	{Name: "gen-data", Summary: "Generate a mock polygon dataset reproducibly from a seed and parameters", Run: runGenDataCommand},
+	{Name: "gen-points", Summary: "Generate random points (uniform, on the sphere, clustered or inside polygons) as CSV or GeoJSON", Run: runGenPointsCommand},
+	{Name: "decode", Summary: "Decode H3 indexes or S2 tokens into GeoJSON cell boundaries", Run: runDecodeCommand},
+	{Name: "verify", Summary: "Compare coverings with golden fixtures to detect library behavior changes", Run: runVerifyCommand},
+	{Name: "sweep", Aliases: []string{"benchmark"}, Summary: "Time the covering call over a resolution sweep, with a per-covering timeout", Run: runSweepCommand},
+	{Name: "matrix", Summary: "Run the cross product of parameter axes from a config file and tag every result", Run: runMatrixCommand},
+	{Name: "convert", Summary: "Time GeoJSON-to-polygon conversion stages against the covering call", Run: runConvertCommand},
+	{Name: "crossmap", Summary: "Benchmark translating coverings between H3 and S2", Run: runCrossMapCommand},
+	{Name: "denormalize", Summary: "Expand mixed-level S2 coverings to one level and compare their cell counts with H3 fills", Run: runDenormalizeCommand},
+	{Name: "h3-experimental", Summary: "Compare h3-go's experimental polygon fill in each containment mode with PolygonToCells: speed and cell differences", Run: runH3ExperimentalCommand},
+	{Name: "pip", Summary: "Benchmark point-in-polygon queries against cell indexes and exact ContainsPoint", Run: runPIPCommand},
+	{Name: "bloom", Summary: "Measure a Bloom filter over covering cells as a prefilter: false-positive rate and lookup speed", Run: runBloomCommand},
+	{Name: "overlap", Summary: "Benchmark pairwise polygon overlap detection via coverings", Run: runOverlapCommand},
+	{Name: "join", Summary: "Benchmark a points x polygons spatial join via cell indexes", Run: runJoinCommand},
+	{Name: "nearest", Summary: "Benchmark nearest-feature queries by cell ring expansion", Run: runNearestCommand},
+	{Name: "binning", Summary: "Benchmark aggregating points into per-cell counts (H3, S2, geohash)", Run: runBinningCommand},
+	{Name: "geofence", Summary: "Simulate streaming GPS pings against geofence coverings", Run: runGeofenceCommand},
+	{Name: "trajectory", Summary: "Benchmark encoding vehicle trajectories as cell sequences", Run: runTrajectoryCommand},
+	{Name: "rollup", Summary: "Benchmark rolling per-cell counts up to coarser resolutions", Run: runRollupCommand},
+	{Name: "setops", Summary: "Benchmark union, intersection and difference of coverings", Run: runSetOpsCommand},
+	{Name: "buffer", Summary: "Benchmark expanding coverings outward by a distance", Run: runBufferCommand},
+	{Name: "edit", Summary: "Benchmark re-covering slightly edited geofences and report cell-set churn", Run: runEditCommand},
+	{Name: "adaptive", Summary: "Cover each polygon at a resolution chosen from its area", Run: runAdaptiveCommand},
+	{Name: "recommend", Summary: "Recommend an H3 resolution and S2 level for a target cell size or cell budget", Run: runRecommendCommand},
+	{Name: "cost", Summary: "Estimate index size and monthly storage cost for a fleet of geofences", Run: runCostCommand},
+	{Name: "dedup", Summary: "Measure how many covering cells overlapping features share, unique vs total per system", Run: runDedupCommand},
+	{Name: "serialize", Summary: "Compare wire formats for coverings: JSON, tokens, binary, varint-delta and protobuf sizes and speeds", Run: runSerializeCommand},
+	{Name: "holes", Summary: "Time conversion and covering of generated polygons with up to thousands of holes", Run: runHolesCommand},
+	{Name: "coastline", Summary: "Cover generated or real coastlines of 100k+ vertices and report how time scales with vertex count", Run: runCoastlineCommand},
+	{Name: "memlimit", Summary: "Cover the dataset under soft memory limits (GOMEMLIMIT) and report lost throughput and coverings over the limit", Run: runMemLimitCommand},
+	{Name: "soak", Summary: "Loop the workload for hours, sampling memory, goroutines and latency, and flag leaks and throughput decay", Run: runSoakCommand},
+	{Name: "precision", Summary: "Round coordinates to fewer decimal places and report the effect on covering time, cells and accuracy", Run: runPrecisionCommand},
+	{Name: "tiny", Summary: "Report what each system returns for polygons smaller than a cell: no cell, the centroid cell or others", Run: runTinyCommand},
+	{Name: "latitude", Summary: "Break covering time, cell count and area error down by latitude band from equator to poles", Run: runLatitudeCommand},
+	{Name: "shape", Summary: "Measure the compactness and aspect ratio of the cells coverings use", Run: runShapeCommand},
+	{Name: "cellarea", Summary: "Report the spread of true cell areas within coverings against the average-area constants", Run: runCellAreaCommand},
+	{Name: "pentagons", Summary: "Find the features whose H3 coverings include pentagons or the distorted cells around them", Run: runPentagonsCommand},
+	{Name: "faces", Summary: "Compare S2 coverings that span cube-face boundaries with those on one face", Run: runFacesCommand},
+	{Name: "pareto", Summary: "Measure covering size against coverage error and find the Pareto frontier", Run: runParetoCommand},
+	{Name: "external", Summary: "Time coverings by other implementations (h3-py, s2geometry, DuckDB h3) in subprocesses in the sweep results schema", Run: runExternalCommand},
+	{Name: "kvstore", Summary: "Index coverings in an embedded key-value store and time writes and point lookups", Run: runKVStoreCommand},
+	{Name: "redis", Summary: "Index coverings in Redis sets and time point-lookup round trips", Run: runRedisCommand},
+	{Name: "postgis", Summary: "Time exact ST_Intersects point and overlap queries in PostGIS as a baseline", Run: runPostGISCommand},
+	{Name: "elasticsearch", Summary: "Time geo_shape indexing and queries in Elasticsearch against in-process cell indexes", Run: runElasticsearchCommand},
+	{Name: "duckdb", Summary: "Time DuckDB spatial load and contains/intersects joins against in-process cell indexes", Run: runDuckDBCommand},
+	{Name: "visualize", Summary: "Write covering cells as a GeoJSON FeatureCollection for geojson.io or kepler.gl", Run: runVisualizeCommand},
+	{Name: "render", Summary: "Draw each feature and its covering cells to a PNG", Run: runRenderCommand},
+	{Name: "diff", Summary: "Compare two sweep runs point by point and flag changes beyond noise", Run: runDiffCommand},
+	{Name: "merge", Summary: "Combine sweep runs from different machines (e.g. ARM and x86) into one comparative report", Run: runMergeCommand},
+	{Name: "trends", Summary: "Chart a metric of the runs stored in SQLite over time and commits", Run: runTrendsCommand},
+	{Name: "serve", Summary: "Serve a web dashboard over a results directory", Run: runServeCommand},
+	{Name: "grpc", Summary: "Serve the Discretizer gRPC API (Cover and Benchmark RPCs)", Run: runGRPCCommand},
+	{Name: "api", Summary: "Serve the HTTP/JSON API (POST /cover, POST /benchmark, GET /results/{run})", Run: runAPICommand},
+	{Name: "export", Summary: "Export covering cells for inspection in other tools", Run: runExportCommand},
+}
+
+// findCommand looks up a command by name or alias
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.Name == name || slices.Contains(cmd.Aliases, name) {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// printUsage lists the available commands on stderr
+func printUsage() {
+	name := filepath.Base(os.Args[0])
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", name)
+	for _, cmd := range commands {
+		summary := cmd.Summary
+		if len(cmd.Aliases) > 0 {
+			summary += " (also: " + strings.Join(cmd.Aliases, ", ") + ")"
+		}
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", cmd.Name, summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for the flags of a command.\n", name)
+}
+
+// parseIntRange parses resolution lists such as "5", "0-8" or "3,5,7-9"
+func parseIntRange(s string) ([]int, error) {
+	var values []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if lo, hi, ok := strings.Cut(part, "-"); ok {
+			start, err := strconv.Atoi(strings.TrimSpace(lo))
+			if err != nil {
+				return nil, fmt.Errorf("invalid range %q: %w", part, err)
+			}
+			end, err := strconv.Atoi(strings.TrimSpace(hi))
+			if err != nil {
+				return nil, fmt.Errorf("invalid range %q: %w", part, err)
+			}
+			if end < start {
+				return nil, fmt.Errorf("invalid range %q: end is before start", part)
+			}
+			for v := start; v <= end; v++ {
+				values = append(values, v)
+			}
+			continue
+		}
+		v, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q: %w", part, err)
+		}
+		values = append(values, v)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("no values in %q", s)
+	}
+	return values, nil
+}
+
+// The -input of commands that load a dataset: the mock dataset of the
+// repository by default, and the embedded sample of it by name or when the
+// default is missing, as when the binary runs outside the repository
+const (
+	defaultInput  = "data/mock_polygons.geojson"
+	embeddedInput = "embedded"
+)
+
+// datasetFlags are the input flags of commands that load a dataset, with
+// the filters and sampling selecting which of its features to benchmark
+type datasetFlags struct {
+	Input      *string
+	SourceCRS  *string
+	Where      []bench.PropertyCondition
+	BBox       *string
+	PerBucket  *int
+	SampleSeed *int64
+	Round      *int
+
+	// Out receives the messages of loading the dataset, stdout when nil
+	Out io.Writer
+}
+
+func addDatasetFlags(fs *flag.FlagSet) *datasetFlags {
+	f := &datasetFlags{
+		Input:      fs.String("input", defaultInput, "GeoJSON to benchmark: a FeatureCollection, a single Feature or a bare Polygon ('-' for stdin, '"+embeddedInput+"' for the built-in sample)"),
+		SourceCRS:  fs.String("source-crs", "", "CRS the input coordinates are in, e.g. EPSG:3857 or EPSG:32633 (default: the file's crs member, or WGS84)"),
+		BBox:       fs.String("bbox", "", "only features whose bounds intersect minLng,minLat,maxLng,maxLat"),
+		PerBucket:  fs.Int("sample-per-bucket", 0, "sample this many features from each area quartile x vertex-count half (0 for all features)"),
+		SampleSeed: fs.Int64("sample-seed", 1, "random seed of -sample-per-bucket"),
+		Round:      fs.Int("round", -1, "round input coordinates to this many decimal places before conversion (-1 keeps them)"),
+	}
+	fs.Func("where", "only features whose property matches, e.g. ADMIN=France or POP_EST>=1e6 (repeatable; all must match)", func(s string) error {
+		c, err := bench.ParseCondition(s)
+		if err != nil {
+			return err
+		}
+		f.Where = append(f.Where, c)
+		return nil
+	})
+	return f
+}
+
+// Inputs expands -input into its files: a comma-separated list whose
+// entries may be globs
+func (f *datasetFlags) Inputs() ([]string, error) {
+	var inputs []string
+	for _, entry := range strings.Split(*f.Input, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if entry == "-" && slices.Contains(inputs, "-") {
+			return nil, fmt.Errorf("-input names stdin ('-') more than once")
+		}
+		if !strings.ContainsAny(entry, "*?[") {
+			inputs = append(inputs, entry)
+			continue
+		}
+		matches, err := filepath.Glob(entry)
+		if err != nil {
+			return nil, fmt.Errorf("-input %q: %w", entry, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("-input %q matches no files", entry)
+		}
+		inputs = append(inputs, matches...)
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("-input names no files")
+	}
+	return inputs, nil
+}
+
+// LoadDataset loads the single file of -input, keeps the features selected
+// by -where and -bbox and samples them with -sample-per-bucket
+func (f *datasetFlags) LoadDataset() (*bench.Dataset, error) {
+	inputs, err := f.Inputs()
+	if err != nil {
+		return nil, err
+	}
+	if len(inputs) > 1 {
+		return nil, fmt.Errorf("-input names %d files; this command takes one", len(inputs))
+	}
+	datasets, err := f.LoadDatasets()
+	if err != nil {
+		return nil, err
+	}
+	return datasets[0], nil
+}
+
+// LoadDatasets loads every file of -input like LoadDataset
+func (f *datasetFlags) LoadDatasets() ([]*bench.Dataset, error) {
+	inputs, err := f.Inputs()
+	if err != nil {
+		return nil, err
+	}
+	out := f.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	filter := bench.FeatureFilter{Where: f.Where}
+	if *f.BBox != "" {
+		rect, err := bench.ParseBBox(*f.BBox)
+		if err != nil {
+			return nil, fmt.Errorf("-bbox: %w", err)
+		}
+		filter.BBox = &rect
+	}
+
+	var datasets []*bench.Dataset
+	for _, input := range inputs {
+		ds, err := loadInput(out, input, *f.SourceCRS)
+		if err != nil {
+			return nil, err
+		}
+		if *f.Round >= 0 {
+			rounded := ds.Round(*f.Round)
+			(&bench.ConversionReport{Mode: bench.ConversionRepair, Issues: rounded.Issues}).Fprint(out)
+			if len(rounded.Features) == 0 {
+				return nil, fmt.Errorf("no feature of %s survives -round %d", ds.Path, *f.Round)
+			}
+			fmt.Fprintf(out, "Rounded the coordinates of %s to %d decimal places\n", ds.Path, *f.Round)
+			ds = rounded
+		}
+		if !filter.Empty() {
+			filtered := ds.Filter(filter)
+			if len(filtered.Features) == 0 {
+				return nil, fmt.Errorf("none of the %d features of %s match -where and -bbox", len(ds.Features), ds.Path)
+			}
+			fmt.Fprintf(out, "Selected %d of %d features of %s with -where and -bbox\n", len(filtered.Features), len(ds.Features), ds.Path)
+			ds = filtered
+		}
+		if *f.PerBucket > 0 {
+			sample := ds.StratifiedSample(*f.PerBucket, *f.SampleSeed)
+			fmt.Fprintf(out, "Sampled %d of %d features of %s, up to %d per size and complexity bucket\n", len(sample.Features), len(ds.Features), ds.Path, *f.PerBucket)
+			ds = sample
+		}
+		datasets = append(datasets, ds)
+	}
+	return datasets, nil
+}
+
+// resolveInput returns embeddedInput for the default input when that file
+// is missing, and input otherwise
+func resolveInput(input string) string {
+	if input == defaultInput {
+		if _, err := os.Stat(input); errors.Is(err, os.ErrNotExist) {
+			log.Printf("%s not found, using the embedded sample dataset", input)
+			return embeddedInput
+		}
+	}
+	return input
+}
+
+// loadInput loads one -input file, or the embedded sample dataset, writing
+// its conversion report to out
+func loadInput(out io.Writer, input, sourceCRS string) (*bench.Dataset, error) {
+	if input = resolveInput(input); input != embeddedInput {
+		return bench.LoadDatasetCRSTo(out, input, sourceCRS)
+	}
+	return bench.ParseDataset(embeddedInput, data.SamplePolygons)
+}
+
+// readInput parses one -input file, or the embedded sample dataset,
+// without converting it
+func readInput(input string) (geojson.FeatureCollection, error) {
+	if input = resolveInput(input); input != embeddedInput {
+		return geojson.ReadFile(input)
+	}
+	return geojson.Parse(data.SamplePolygons)
+}
+
+// sweepFlags are the dataset and resolution flags shared by the commands that
+// sweep both systems over a range of resolutions
+type sweepFlags struct {
+	*datasetFlags
+	H3Res    *string
+	S2Levels *string
+	MaxCells *int
+}
+
+func addSweepFlags(fs *flag.FlagSet, h3Default, s2Default string) *sweepFlags {
+	return &sweepFlags{
+		datasetFlags: addDatasetFlags(fs),
+		H3Res:        fs.String("h3-res", h3Default, "H3 resolutions, e.g. 0-6 or 3,5,7 (empty to skip H3)"),
+		S2Levels:     fs.String("s2-levels", s2Default, "S2 levels, e.g. 0-11 or 4,8 (empty to skip S2)"),
+		MaxCells:     fs.Int("s2-max-cells", 8, "S2 RegionCoverer MaxCells"),
+	}
+}
+
+// Points returns the H3 resolutions followed by the S2 levels to run
+func (f *sweepFlags) Points() ([]bench.SweepPoint, error) {
+	var points []bench.SweepPoint
+	if *f.H3Res != "" {
+		resolutions, err := parseIntRange(*f.H3Res)
+		if err != nil {
+			return nil, fmt.Errorf("-h3-res: %w", err)
+		}
+		for _, r := range resolutions {
+			points = append(points, bench.SweepPoint{System: bench.SystemH3, Resolution: r})
+		}
+	}
+	if *f.S2Levels != "" {
+		levels, err := parseIntRange(*f.S2Levels)
+		if err != nil {
+			return nil, fmt.Errorf("-s2-levels: %w", err)
+		}
+		for _, l := range levels {
+			points = append(points, bench.SweepPoint{System: bench.SystemS2, Resolution: l})
+		}
+	}
+	if len(points) == 0 {
+		return nil, fmt.Errorf("no resolutions selected")
+	}
+	return points, nil
+}
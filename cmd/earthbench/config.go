@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// configFileEnv names the environment variable holding the path of the
+// config file read by parseFlags
+const configFileEnv = "EARTHBENCH_CONFIG_FILE"
+
+// parseFlags parses a command's flags and fills every flag left off the
+// command line from, in order of precedence:
+//
+//  1. EARTHBENCH_<COMMAND>_<FLAG>, e.g. EARTHBENCH_SWEEP_H3_RES for sweep's -h3-res
+//  2. EARTHBENCH_<FLAG>, e.g. EARTHBENCH_S2_MAX_CELLS for every command with -s2-max-cells
+//  3. the command's section of the JSON file named by EARTHBENCH_CONFIG_FILE
+//  4. the top level of that file
+//  5. the -preset of commands that have one (see presets)
+//
+// so a container can be configured through its environment, with flags on
+// the command line still overriding both. A config file looks like
+//
+//	{"s2-max-cells": 16, "sweep": {"h3-res": "0-6", "precision": 0.02}}
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	global, section, err := loadConfigFile(fs)
+	if err != nil {
+		return err
+	}
+
+	var setErr error
+	fs.VisitAll(func(f *flag.Flag) {
+		if explicit[f.Name] || setErr != nil {
+			return
+		}
+		source, value, ok := lookupSetting(fs.Name(), f.Name, global, section)
+		if !ok {
+			return
+		}
+		if err := fs.Set(f.Name, value); err != nil {
+			setErr = fmt.Errorf("%s: invalid value %q for -%s: %w", source, value, f.Name, err)
+		}
+	})
+	if setErr != nil {
+		return setErr
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	return applyPreset(fs, set)
+}
+
+// lookupSetting finds the value of a flag left off the command line and
+// where it came from
+func lookupSetting(command, name string, global, section map[string]string) (source, value string, ok bool) {
+	for _, env := range []string{envName(command + "-" + name), envName(name)} {
+		if value, ok := os.LookupEnv(env); ok {
+			return env, value, true
+		}
+	}
+	if value, ok := section[name]; ok {
+		return os.Getenv(configFileEnv), value, true
+	}
+	if value, ok := global[name]; ok {
+		return os.Getenv(configFileEnv), value, true
+	}
+	return "", "", false
+}
+
+// envName turns a flag name into its environment variable, e.g. h3-res
+// into EARTHBENCH_H3_RES
+func envName(name string) string {
+	return "EARTHBENCH_" + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+// loadConfigFile reads the file named by EARTHBENCH_CONFIG_FILE, if set,
+// returning its top-level settings and those of fs's command. Top-level
+// settings that are not flags of the command are left for other commands,
+// but a command section may only name the command's own flags.
+func loadConfigFile(fs *flag.FlagSet) (global, section map[string]string, err error) {
+	path := os.Getenv(configFileEnv)
+	if path == "" {
+		return nil, nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", configFileEnv, err)
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	global = make(map[string]string)
+	for key, raw := range doc {
+		if key == fs.Name() {
+			if section, err = configSection(raw); err != nil {
+				return nil, nil, fmt.Errorf("config file %s: section %q: %w", path, key, err)
+			}
+			continue
+		}
+		// Objects are the sections of other commands
+		if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+			continue
+		}
+		if global[key], err = configValue(raw); err != nil {
+			return nil, nil, fmt.Errorf("config file %s: %q: %w", path, key, err)
+		}
+	}
+	for name := range section {
+		if fs.Lookup(name) == nil {
+			return nil, nil, fmt.Errorf("config file %s: %s has no -%s flag", path, fs.Name(), name)
+		}
+	}
+	return global, section, nil
+}
+
+// configSection decodes a command's section of the config file
+func configSection(raw json.RawMessage) (map[string]string, error) {
+	var settings map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &settings); err != nil {
+		return nil, err
+	}
+	section := make(map[string]string, len(settings))
+	for name, value := range settings {
+		s, err := configValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", name, err)
+		}
+		section[name] = s
+	}
+	return section, nil
+}
+
+// configValue turns a JSON string, number or boolean into the text a flag
+// would be given on the command line
+func configValue(raw json.RawMessage) (string, error) {
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", err
+	}
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case float64, bool:
+		return string(bytes.TrimSpace(raw)), nil
+	}
+	return "", fmt.Errorf("want a string, number or boolean")
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	dh3 "github.com/nkk36/earth-discretization-benchmark/discretize/h3"
+	ds2 "github.com/nkk36/earth-discretization-benchmark/discretize/s2"
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+)
+
+// ConversionResult splits the cost of covering every feature at one
+// resolution into the construction stages and the covering call itself.
+// Convert is GeoJSON to h3.GeoPolygon for H3 and GeoJSON to s2.Loops
+// (including each loop's origin and bound) for S2. Build (PolygonFromLoops
+// nesting) and Index (the polygon's shape index, which s2.Polygon builds
+// lazily on its first query) are S2 only.
+type ConversionResult struct {
+	System     string
+	Resolution int
+	Features   int
+	Cells      int
+	Convert    time.Duration
+	Build      time.Duration
+	Index      time.Duration
+	Cover      time.Duration
+	Dominated  int // features whose construction took longer than their covering
+}
+
+// Construction is the total time spent before the covering call
+func (r ConversionResult) Construction() time.Duration {
+	return r.Convert + r.Build + r.Index
+}
+
+// ConstructionShare is the fraction of the end-to-end time spent on
+// construction
+func (r ConversionResult) ConstructionShare() float64 {
+	total := r.Construction() + r.Cover
+	if total == 0 {
+		return 0
+	}
+	return float64(r.Construction()) / float64(total)
+}
+
+func runConvertCommand(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "0-8", "0-13")
+	output := fs.String("output", "output/convert.csv", "CSV file for the results")
+	explain := fs.String("explain", "", "instead of benchmarking the dataset, print a stage-by-stage timing breakdown of covering the feature with this FeatureID at every sweep point")
+	explainFormat := fs.String("explain-format", "protobuf", "cell ID format of the serialization stage of -explain: json, tokens, binary, varint-delta or protobuf")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d features from %s\n", len(ds.Features), ds.Path)
+	if *explain != "" {
+		featureID, err := strconv.Atoi(*explain)
+		if err != nil {
+			return fmt.Errorf("-explain: %q is not a FeatureID", *explain)
+		}
+		return runExplain(ds, featureID, sweepPoints, *sweep.MaxCells, *explainFormat)
+	}
+
+	var results []ConversionResult
+	for _, sp := range sweepPoints {
+		r, err := benchmarkConversion(ds, sp, *sweep.MaxCells)
+		if err != nil {
+			return fmt.Errorf("%s resolution %d: %w", sp.System, sp.Resolution, err)
+		}
+		fmt.Printf("%s res %2d: construct %12v (convert %v, build %v, index %v), cover %12v, construction %.1f%%, dominates %d/%d features\n",
+			r.System, r.Resolution, r.Construction(), r.Convert, r.Build, r.Index, r.Cover,
+			100*r.ConstructionShare(), r.Dominated, r.Features)
+		results = append(results, r)
+	}
+
+	if err := saveConversionResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// benchmarkConversion converts every feature from its GeoJSON geometry again
+// for each sweep point, so each row carries its own construction timings
+func benchmarkConversion(ds *bench.Dataset, sp bench.SweepPoint, maxCells int) (ConversionResult, error) {
+	result := ConversionResult{System: sp.System, Resolution: sp.Resolution}
+	for _, f := range ds.Features {
+		var convert, build, index, cover time.Duration
+		var cells []uint64
+		if sp.System == bench.SystemH3 {
+			start := time.Now()
+			polygon, err := dh3.FromGeometry(f.Geometry, geojson.IgnoreSkippedRing)
+			convert = time.Since(start)
+			if err != nil {
+				return result, fmt.Errorf("feature %d: %w", f.FeatureID, err)
+			}
+			start = time.Now()
+			cells, err = dh3.Cover(polygon, sp.Resolution)
+			cover = time.Since(start)
+			if err != nil {
+				return result, fmt.Errorf("feature %d: %w", f.FeatureID, err)
+			}
+		} else {
+			start := time.Now()
+			loops, err := ds2.LoopsFromGeometry(f.Geometry, geojson.IgnoreSkippedRing)
+			convert = time.Since(start)
+			if err != nil {
+				return result, fmt.Errorf("feature %d: %w", f.FeatureID, err)
+			}
+			start = time.Now()
+			polygon := s2.PolygonFromLoops(loops)
+			build = time.Since(start)
+			// Any index query forces the pending shape index update
+			start = time.Now()
+			polygon.IntersectsCell(s2.CellFromCellID(s2.CellIDFromFace(0)))
+			index = time.Since(start)
+			start = time.Now()
+			cells = ds2.Cover(polygon, sp.Resolution, maxCells)
+			cover = time.Since(start)
+		}
+
+		result.Features++
+		result.Cells += len(cells)
+		result.Convert += convert
+		result.Build += build
+		result.Index += index
+		result.Cover += cover
+		if convert+build+index > cover {
+			result.Dominated++
+		}
+	}
+	return result, nil
+}
+
+func saveConversionResultsToCSV(filename string, results []ConversionResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "Resolution", "Features", "Cells", "ConvertNs", "BuildNs", "IndexNs",
+		"ConstructionNs", "CoverNs", "ConstructionShare", "ConstructionDominated"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			r.System,
+			strconv.Itoa(r.Resolution),
+			strconv.Itoa(r.Features),
+			strconv.Itoa(r.Cells),
+			strconv.FormatInt(r.Convert.Nanoseconds(), 10),
+			strconv.FormatInt(r.Build.Nanoseconds(), 10),
+			strconv.FormatInt(r.Index.Nanoseconds(), 10),
+			strconv.FormatInt(r.Construction().Nanoseconds(), 10),
+			strconv.FormatInt(r.Cover.Nanoseconds(), 10),
+			strconv.FormatFloat(r.ConstructionShare(), 'f', -1, 64),
+			strconv.Itoa(r.Dominated),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/nkk36/earth-discretization-benchmark/report"
+)
+
+// CostResult is the storage estimate of one (system, resolution) for a
+// fleet of geofences shaped like the dataset's features
+type CostResult struct {
+	System          string
+	Resolution      int
+	CellsPerFeature report.Distribution
+	FleetCells      float64 // mean cells per feature x fleet size
+	IndexBytes      float64
+	MonthlyCost     float64
+}
+
+func runCostCommand(args []string) error {
+	fs := flag.NewFlagSet("cost", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "0-6", "0-11")
+	output := fs.String("output", "output/cost.csv", "CSV file for the estimates")
+	fleet := fs.Int("fleet", 100000, "number of geofences to plan for, assumed shaped like the dataset's features")
+	cellBytes := fs.Int("cell-bytes", 8, "bytes stored per cell ID (8 for a 64-bit H3 index or S2 cell ID)")
+	refBytes := fs.Int("ref-bytes", 8, "bytes stored per index entry to point back at its geofence")
+	overhead := fs.Float64("overhead", 1.5, "multiplier for index structure, page fill and metadata on top of the raw entries")
+	pricePerGB := fs.Float64("price-per-gb", 0.10, "storage price in $ per GB-month")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	if *fleet < 1 || *overhead < 1 {
+		return fmt.Errorf("-fleet must be at least 1 and -overhead at least 1")
+	}
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d features from %s; planning for %d geofences at %d+%d bytes per entry x %.2f, $%.3f/GB-month\n",
+		len(ds.Features), ds.Path, *fleet, *cellBytes, *refBytes, *overhead, *pricePerGB)
+
+	var results []CostResult
+	for _, sp := range sweepPoints {
+		coverings, err := bench.ComputeCoverings(ds, sp.System, sp.Resolution, *sweep.MaxCells)
+		if err != nil {
+			return fmt.Errorf("%s resolution %d: %w", sp.System, sp.Resolution, err)
+		}
+		counts := make([]float64, len(coverings))
+		for i, cells := range coverings {
+			counts[i] = float64(len(cells))
+		}
+		r := CostResult{System: sp.System, Resolution: sp.Resolution, CellsPerFeature: report.NewDistribution(counts)}
+		r.FleetCells = r.CellsPerFeature.Mean * float64(*fleet)
+		r.IndexBytes = r.FleetCells * float64(*cellBytes+*refBytes) * *overhead
+		r.MonthlyCost = r.IndexBytes / 1e9 * *pricePerGB
+		results = append(results, r)
+
+		fmt.Printf("%s res %2d: %9.1f cells/geofence (p90 %9.0f), %14.0f cells, index %10s, $%10.2f/month\n",
+			r.System, r.Resolution, r.CellsPerFeature.Mean, r.CellsPerFeature.P90, r.FleetCells, formatBytes(r.IndexBytes), r.MonthlyCost)
+	}
+
+	if err := saveCostResults(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// formatBytes writes a byte count with a decimal unit, as storage is billed
+func formatBytes(b float64) string {
+	units := []string{"B", "kB", "MB", "GB", "TB", "PB"}
+	i := 0
+	for b >= 1000 && i < len(units)-1 {
+		b /= 1000
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", b, units[i])
+}
+
+// saveCostResults writes one row per system and resolution
+func saveCostResults(filename string, results []CostResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "Resolution", "MeanCellsPerFeature", "P90CellsPerFeature", "FleetCells", "IndexBytes", "MonthlyCost"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			r.System,
+			strconv.Itoa(r.Resolution),
+			strconv.FormatFloat(r.CellsPerFeature.Mean, 'f', -1, 64),
+			strconv.FormatFloat(r.CellsPerFeature.P90, 'f', -1, 64),
+			strconv.FormatFloat(r.FleetCells, 'f', 0, 64),
+			strconv.FormatFloat(r.IndexBytes, 'f', 0, 64),
+			strconv.FormatFloat(r.MonthlyCost, 'f', 2, 64),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
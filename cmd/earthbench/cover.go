@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+)
+
+func runCoverCommand(args []string) error {
+	fs := flag.NewFlagSet("cover", flag.ExitOnError)
+	input := fs.String("input", "-", "GeoJSON file with a Polygon geometry, a Feature or a FeatureCollection ('-' for stdin, optionally gzipped)")
+	wkt := fs.String("wkt", "", "WKT POLYGON to cover instead of -input")
+	system := fs.String("system", "h3", "h3 or s2")
+	resolution := fs.Int("res", 8, "H3 resolution or S2 level")
+	maxCells := fs.Int("s2-max-cells", 8, "S2 RegionCoverer MaxCells")
+	format := fs.String("format", "token", "token (H3 index / S2 token) or id (64-bit cell ID)")
+	timing := fs.Bool("timing", false, "print convert and cover timings to stderr")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sys, err := parseSystem(*system)
+	if err != nil {
+		return err
+	}
+	if *format != "token" && *format != "id" {
+		return fmt.Errorf("-format must be token or id")
+	}
+
+	var fc geojson.FeatureCollection
+	if *wkt != "" {
+		var geometry geojson.Geometry
+		geometry, err = parseWKTPolygon(*wkt)
+		fc.Features = []geojson.Feature{{Type: "Feature", Geometry: geometry}}
+	} else {
+		fc, err = readGeometries(*input)
+	}
+	if err != nil {
+		return err
+	}
+
+	// With several features each line is led by the feature's ID and a tab,
+	// ready for cut, awk or a database COPY
+	featureIDs := geojson.FeatureIDs(fc)
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	for i, feature := range fc.Features {
+		cells, convert, cover, err := bench.CoverGeometry(feature.Geometry, sys, *resolution, *maxCells)
+		if err != nil {
+			if len(fc.Features) > 1 {
+				return fmt.Errorf("feature %d: %w", featureIDs[i], err)
+			}
+			return err
+		}
+		for _, cell := range cells {
+			if len(fc.Features) > 1 {
+				out.WriteString(strconv.Itoa(featureIDs[i]))
+				out.WriteByte('\t')
+			}
+			if *format == "id" {
+				out.WriteString(strconv.FormatUint(cell, 10))
+			} else {
+				out.WriteString(cellToken(sys, cell))
+			}
+			out.WriteByte('\n')
+		}
+		if *timing {
+			label := fmt.Sprintf("%s res %d", sys, *resolution)
+			if len(fc.Features) > 1 {
+				label += fmt.Sprintf(" feature %d", featureIDs[i])
+			}
+			fmt.Fprintf(os.Stderr, "%s: %d cells, convert %v, cover %v\n", label, len(cells), convert, cover)
+		}
+	}
+	return out.Flush()
+}
+
+// parseSystem accepts h3/H3/s2/S2
+func parseSystem(s string) (string, error) {
+	switch strings.ToUpper(s) {
+	case bench.SystemH3:
+		return bench.SystemH3, nil
+	case bench.SystemS2:
+		return bench.SystemS2, nil
+	}
+	return "", fmt.Errorf("unknown system %q (want h3 or s2)", s)
+}
+
+// readGeometries reads a GeoJSON document from a file or stdin ("-"): a
+// bare Polygon geometry, a Feature or a FeatureCollection, reprojected to
+// WGS84 if it has a crs member
+func readGeometries(path string) (geojson.FeatureCollection, error) {
+	data, err := geojson.ReadData(path)
+	if err != nil {
+		return geojson.FeatureCollection{}, err
+	}
+	fc, err := geojson.Parse(data)
+	if err != nil {
+		return geojson.FeatureCollection{}, err
+	}
+	if len(fc.Features) == 0 {
+		return geojson.FeatureCollection{}, fmt.Errorf("no features in %s", path)
+	}
+	return fc, fc.Reproject("")
+}
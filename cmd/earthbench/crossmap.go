@@ -0,0 +1,291 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	dh3 "github.com/nkk36/earth-discretization-benchmark/discretize/h3"
+	ds2 "github.com/nkk36/earth-discretization-benchmark/discretize/s2"
+	"github.com/uber/h3-go/v4"
+)
+
+// CrossMapResult is the cost of translating the coverings of every feature
+// from one system to the other. DirectCells is the size of the covering the
+// target system produces from the original polygons, so TargetCells /
+// DirectCells is the inflation caused by going through the other system.
+type CrossMapResult struct {
+	Source           string
+	SourceResolution int
+	Target           string
+	TargetResolution int
+	Features         int
+	SourceCells      int
+	TargetCells      int
+	DirectCells      int
+	Duration         time.Duration
+}
+
+// Inflation is the number of target cells produced per source cell
+func (r CrossMapResult) Inflation() float64 {
+	if r.SourceCells == 0 {
+		return 0
+	}
+	return float64(r.TargetCells) / float64(r.SourceCells)
+}
+
+// DirectInflation compares the translated covering to a direct covering of
+// the original polygons at the target resolution
+func (r CrossMapResult) DirectInflation() float64 {
+	if r.DirectCells == 0 {
+		return 0
+	}
+	return float64(r.TargetCells) / float64(r.DirectCells)
+}
+
+func runCrossMapCommand(args []string) error {
+	fs := flag.NewFlagSet("crossmap", flag.ExitOnError)
+	dataset := addDatasetFlags(fs)
+	h3Res := fs.String("h3-res", "3-6", "H3 resolutions, e.g. 3-6 or 4,6")
+	s2Levels := fs.String("s2-levels", "", "S2 level paired with each H3 resolution (default: the level with the closest average cell area)")
+	maxCells := fs.Int("s2-max-cells", 8, "S2 RegionCoverer MaxCells")
+	output := fs.String("output", "output/crossmap.csv", "CSV file for the results")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	resolutions, levels, err := pairResolutions(*h3Res, *s2Levels)
+	if err != nil {
+		return err
+	}
+
+	ds, err := dataset.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d features from %s\n", len(ds.Features), ds.Path)
+
+	var results []CrossMapResult
+	for i, res := range resolutions {
+		pair, err := benchmarkCrossMapping(ds, res, levels[i], *maxCells)
+		if err != nil {
+			return fmt.Errorf("H3 resolution %d / S2 level %d: %w", res, levels[i], err)
+		}
+		for _, r := range pair {
+			fmt.Printf("%s res %2d -> %s res %2d: %8d -> %8d cells (x%.2f, x%.2f vs direct), %v\n",
+				r.Source, r.SourceResolution, r.Target, r.TargetResolution,
+				r.SourceCells, r.TargetCells, r.Inflation(), r.DirectInflation(), r.Duration)
+		}
+		results = append(results, pair...)
+	}
+
+	if err := saveCrossMapResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// pairResolutions parses -h3-res and -s2-levels style flags into equal-length
+// lists, filling in area-matched S2 levels when s2Levels is empty
+func pairResolutions(h3Res, s2Levels string) ([]int, []int, error) {
+	resolutions, err := parseIntRange(h3Res)
+	if err != nil {
+		return nil, nil, fmt.Errorf("-h3-res: %w", err)
+	}
+	var levels []int
+	if s2Levels != "" {
+		if levels, err = parseIntRange(s2Levels); err != nil {
+			return nil, nil, fmt.Errorf("-s2-levels: %w", err)
+		}
+		if len(levels) != len(resolutions) {
+			return nil, nil, fmt.Errorf("-s2-levels has %d entries, want one per H3 resolution (%d)", len(levels), len(resolutions))
+		}
+		return resolutions, levels, nil
+	}
+	for _, res := range resolutions {
+		level, err := matchS2Level(res)
+		if err != nil {
+			return nil, nil, err
+		}
+		levels = append(levels, level)
+	}
+	return resolutions, levels, nil
+}
+
+// matchS2Level returns the S2 level whose average cell area is closest (in
+// log scale) to the average H3 hexagon area at res
+func matchS2Level(res int) (int, error) {
+	h3Area, err := averageCellAreaKm2(bench.SystemH3, res)
+	if err != nil {
+		return 0, fmt.Errorf("H3 resolution %d: %w", res, err)
+	}
+	best, bestDiff := 0, math.Inf(1)
+	for level := 0; level <= s2.MaxLevel; level++ {
+		s2Area, _ := averageCellAreaKm2(bench.SystemS2, level)
+		if diff := math.Abs(math.Log(s2Area / h3Area)); diff < bestDiff {
+			best, bestDiff = level, diff
+		}
+	}
+	return best, nil
+}
+
+// benchmarkCrossMapping translates the H3 covering of every feature to S2
+// and the S2 covering to H3, timing only the translation
+func benchmarkCrossMapping(ds *bench.Dataset, res, level, maxCells int) ([]CrossMapResult, error) {
+	toS2 := CrossMapResult{Source: bench.SystemH3, SourceResolution: res, Target: bench.SystemS2, TargetResolution: level}
+	toH3 := CrossMapResult{Source: bench.SystemS2, SourceResolution: level, Target: bench.SystemH3, TargetResolution: res}
+
+	for _, f := range ds.Features {
+		h3Cells, err := dh3.Cover(f.H3Polygon, res)
+		if err != nil {
+			return nil, err
+		}
+		s2Cells := ds2.Cover(f.S2Polygon, level, maxCells)
+
+		start := time.Now()
+		translated, err := h3CellsToS2(h3Cells, level, maxCells)
+		toS2.Duration += time.Since(start)
+		if err != nil {
+			return nil, fmt.Errorf("feature %d: %w", f.FeatureID, err)
+		}
+		toS2.Features++
+		toS2.SourceCells += len(h3Cells)
+		toS2.TargetCells += len(translated)
+		toS2.DirectCells += len(s2Cells)
+
+		start = time.Now()
+		translated, err = s2CellsToH3(s2Cells, res)
+		toH3.Duration += time.Since(start)
+		if err != nil {
+			return nil, fmt.Errorf("feature %d: %w", f.FeatureID, err)
+		}
+		toH3.Features++
+		toH3.SourceCells += len(s2Cells)
+		toH3.TargetCells += len(translated)
+		toH3.DirectCells += len(h3Cells)
+	}
+	return []CrossMapResult{toS2, toH3}, nil
+}
+
+// h3CellsToS2 covers the union of a set of H3 cells with S2 cells at level.
+// The cells are first merged into their outline polygons so the coverer
+// sees a few large loops instead of one hexagon at a time.
+func h3CellsToS2(cells []uint64, level, maxCells int) ([]uint64, error) {
+	h3Cells := make([]h3.Cell, len(cells))
+	for i, c := range cells {
+		h3Cells[i] = h3.Cell(c)
+	}
+	polygons, err := h3.CellsToMultiPolygon(h3Cells)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[uint64]bool)
+	var out []uint64
+	for _, polygon := range polygons {
+		for _, cell := range ds2.Cover(geoPolygonToS2(polygon), level, maxCells) {
+			if !seen[cell] {
+				seen[cell] = true
+				out = append(out, cell)
+			}
+		}
+	}
+	return out, nil
+}
+
+// s2CellsToH3 returns every H3 cell at res overlapping any of the S2 cells.
+// Complete sibling groups are merged into their parent first, so the
+// interior of a covering costs one polygon fill per parent instead of four.
+func s2CellsToH3(cells []uint64, res int) ([]uint64, error) {
+	union := make(s2.CellUnion, len(cells))
+	for i, c := range cells {
+		union[i] = s2.CellID(c)
+	}
+	union.Normalize()
+
+	seen := make(map[uint64]bool)
+	var out []uint64
+	for _, id := range union {
+		c := s2.CellFromCellID(id)
+		loop := make(h3.GeoLoop, 4)
+		for k := range loop {
+			ll := s2.LatLngFromPoint(c.Vertex(k))
+			loop[k] = h3.NewLatLng(ll.Lat.Degrees(), ll.Lng.Degrees())
+		}
+		h3Cells, err := h3.PolygonToCellsExperimental(h3.GeoPolygon{GeoLoop: loop}, res, h3.ContainmentOverlapping)
+		if err != nil {
+			return nil, err
+		}
+		for _, hc := range h3Cells {
+			if !seen[uint64(hc)] {
+				seen[uint64(hc)] = true
+				out = append(out, uint64(hc))
+			}
+		}
+	}
+	return out, nil
+}
+
+// geoPolygonToS2 converts an H3 polygon, normalizing every loop so hole
+// orientation does not matter
+func geoPolygonToS2(polygon h3.GeoPolygon) *s2.Polygon {
+	toLoop := func(geoLoop h3.GeoLoop) *s2.Loop {
+		points := make([]s2.Point, len(geoLoop))
+		for i, ll := range geoLoop {
+			points[i] = s2.PointFromLatLng(s2.LatLngFromDegrees(ll.Lat, ll.Lng))
+		}
+		loop := s2.LoopFromPoints(points)
+		loop.Normalize()
+		return loop
+	}
+	loops := []*s2.Loop{toLoop(polygon.GeoLoop)}
+	for _, hole := range polygon.Holes {
+		loops = append(loops, toLoop(hole))
+	}
+	return s2.PolygonFromLoops(loops)
+}
+
+func saveCrossMapResultsToCSV(filename string, results []CrossMapResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"Source", "SourceResolution", "Target", "TargetResolution", "Features",
+		"SourceCells", "TargetCells", "DirectCells", "Inflation", "DirectInflation", "DurationNs"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			r.Source,
+			strconv.Itoa(r.SourceResolution),
+			r.Target,
+			strconv.Itoa(r.TargetResolution),
+			strconv.Itoa(r.Features),
+			strconv.Itoa(r.SourceCells),
+			strconv.Itoa(r.TargetCells),
+			strconv.Itoa(r.DirectCells),
+			strconv.FormatFloat(r.Inflation(), 'f', -1, 64),
+			strconv.FormatFloat(r.DirectInflation(), 'f', -1, 64),
+			strconv.FormatInt(r.Duration.Nanoseconds(), 10),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"html/template"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+)
+
+// deckLayer is one system/resolution of coverings as consumed by the page
+type deckLayer struct {
+	ID         string        `json:"id"`
+	System     string        `json:"system"`
+	Resolution int           `json:"resolution"`
+	Cells      []deckCellRow `json:"cells"`
+}
+
+// deckCellRow carries either an H3 index for H3HexagonLayer or a cell
+// polygon for PolygonLayer
+type deckCellRow struct {
+	FeatureID int          `json:"feature_id"`
+	Cell      string       `json:"cell"`
+	Polygon   [][2]float64 `json:"polygon,omitempty"`
+}
+
+type deckPage struct {
+	Sources   geojson.FeatureCollection `json:"sources"`
+	Layers    []deckLayer               `json:"layers"`
+	Longitude float64                   `json:"longitude"`
+	Latitude  float64                   `json:"latitude"`
+	Zoom      float64                   `json:"zoom"`
+}
+
+// exportDeckGLHTML writes a single HTML file with the source polygons and
+// every covering of the sweep inlined as JSON. The page loads deck.gl and
+// h3-js from unpkg and renders H3 coverings with H3HexagonLayer and S2
+// coverings as polygons.
+func exportDeckGLHTML(path string, ds *bench.Dataset, sweepPoints []bench.SweepPoint, maxCells int) (int, error) {
+	page := deckPage{Sources: geojson.FeatureCollection{Type: "FeatureCollection"}}
+	for _, f := range ds.Features {
+		page.Sources.Features = append(page.Sources.Features, geojson.Feature{
+			Type:       "Feature",
+			Geometry:   f.Geometry,
+			Properties: map[string]interface{}{"feature_id": f.FeatureID},
+		})
+	}
+
+	cells := 0
+	for _, sp := range sweepPoints {
+		coverings, err := bench.ComputeCoverings(ds, sp.System, sp.Resolution, maxCells)
+		if err != nil {
+			return cells, err
+		}
+		layer := deckLayer{ID: sp.System + "-" + strconv.Itoa(sp.Resolution), System: sp.System, Resolution: sp.Resolution}
+		for i, f := range ds.Features {
+			for _, cell := range coverings[i] {
+				row := deckCellRow{FeatureID: f.FeatureID, Cell: cellToken(sp.System, cell)}
+				if sp.System == bench.SystemS2 {
+					ring, err := cellBoundary(sp.System, cell)
+					if err != nil {
+						return cells, err
+					}
+					row.Polygon = ring
+				}
+				layer.Cells = append(layer.Cells, row)
+			}
+			cells += len(coverings[i])
+		}
+		page.Layers = append(page.Layers, layer)
+	}
+
+	bounds := ds.Bounds()
+	center := bounds.Center()
+	page.Longitude = center.Lng.Degrees()
+	page.Latitude = center.Lat.Degrees()
+	span := math.Max(bounds.Lng.Length(), bounds.Lat.Length()) * 180 / math.Pi
+	page.Zoom = math.Max(0, math.Min(12, math.Log2(360/math.Max(span, 1e-6))))
+
+	file, err := os.Create(path)
+	if err != nil {
+		return cells, err
+	}
+	defer file.Close()
+	return cells, deckTemplate.Execute(file, page)
+}
+
+var deckTemplate = template.Must(template.New("deck").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>earth-discretization-benchmark coverings</title>
+<script src="https://unpkg.com/h3-js@4"></script>
+<script src="https://unpkg.com/deck.gl@9/dist.min.js"></script>
+<style>
+  body { margin: 0; font-family: sans-serif; }
+  #map { position: absolute; inset: 0; }
+  #panel { position: absolute; top: 10px; left: 10px; z-index: 1; background: rgba(255,255,255,0.9);
+           padding: 8px 12px; border-radius: 4px; font-size: 13px; max-height: 90vh; overflow-y: auto; }
+</style>
+</head>
+<body>
+<div id="map"></div>
+<div id="panel"><strong>Layers</strong><div id="toggles"></div></div>
+<script>
+const DATA = {{.}};
+const visible = { sources: true };
+DATA.layers.forEach((l, i) => { visible[l.id] = i === 0; });
+
+function buildLayers() {
+  const layers = [
+    new deck.TileLayer({
+      id: 'osm',
+      data: 'https://tile.openstreetmap.org/{z}/{x}/{y}.png',
+      maxZoom: 19,
+      renderSubLayers: props => {
+        const { boundingBox } = props.tile;
+        return new deck.BitmapLayer(props, {
+          data: null, image: props.data,
+          bounds: [boundingBox[0][0], boundingBox[0][1], boundingBox[1][0], boundingBox[1][1]]
+        });
+      }
+    })
+  ];
+  DATA.layers.forEach(l => {
+    if (!visible[l.id]) return;
+    const common = {
+      id: l.id, data: l.cells, pickable: true, filled: true, stroked: true,
+      lineWidthMinPixels: 1, getLineColor: [30, 80, 170], getFillColor: [66, 133, 244, 90]
+    };
+    if (l.system === 'H3') {
+      layers.push(new deck.H3HexagonLayer({ ...common, extruded: false, getHexagon: d => d.cell }));
+    } else {
+      layers.push(new deck.PolygonLayer({ ...common, getPolygon: d => d.polygon }));
+    }
+  });
+  if (visible.sources) {
+    layers.push(new deck.GeoJsonLayer({
+      id: 'sources', data: DATA.sources, pickable: true, filled: false, stroked: true,
+      lineWidthMinPixels: 2, getLineColor: [220, 40, 40]
+    }));
+  }
+  return layers;
+}
+
+const map = new deck.DeckGL({
+  container: 'map',
+  controller: true,
+  initialViewState: { longitude: DATA.longitude, latitude: DATA.latitude, zoom: DATA.zoom },
+  getTooltip: ({ object }) => object && (object.cell
+    ? 'feature ' + object.feature_id + ' · ' + object.cell
+    : object.properties && 'feature ' + object.properties.feature_id),
+  layers: buildLayers()
+});
+
+const toggles = document.getElementById('toggles');
+[{ id: 'sources' }].concat(DATA.layers).forEach(l => {
+  const label = document.createElement('label');
+  const box = document.createElement('input');
+  box.type = 'checkbox';
+  box.checked = visible[l.id];
+  box.onchange = () => { visible[l.id] = box.checked; map.setProps({ layers: buildLayers() }); };
+  label.appendChild(box);
+  label.appendChild(document.createTextNode(' ' + (l.cells ? l.id + ' (' + l.cells.length + ' cells)' : 'source polygons')));
+  toggles.appendChild(label);
+  toggles.appendChild(document.createElement('br'));
+});
+</script>
+</body>
+</html>
+`))
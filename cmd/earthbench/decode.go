@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+	"github.com/uber/h3-go/v4"
+)
+
+func runDecodeCommand(args []string) error {
+	fs := flag.NewFlagSet("decode", flag.ExitOnError)
+	file := fs.String("file", "", "file with one H3 index or S2 token per line, e.g. from saveAllTokens ('-' for stdin)")
+	system := fs.String("system", "auto", "auto, h3 or s2; auto tries H3 first, so pass s2 for 15-character S2 tokens")
+	output := fs.String("output", "", "GeoJSON file to write (default stdout)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: decode [flags] [token ...]\n")
+		fs.PrintDefaults()
+	}
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	tokens := fs.Args()
+	if *file != "" {
+		fromFile, err := readTokens(*file)
+		if err != nil {
+			return err
+		}
+		tokens = append(tokens, fromFile...)
+	}
+	if len(tokens) == 0 {
+		fs.Usage()
+		return fmt.Errorf("no tokens given")
+	}
+
+	fc := geojson.FeatureCollection{Type: "FeatureCollection"}
+	for _, token := range tokens {
+		feature, err := decodeCell(token, *system)
+		if err != nil {
+			return err
+		}
+		fc.Features = append(fc.Features, feature)
+	}
+
+	if *output != "" {
+		if err := writeGeoJSON(*output, fc); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %d cells to %s\n", len(fc.Features), *output)
+		return nil
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(fc)
+}
+
+// readTokens reads non-empty, whitespace-trimmed lines
+func readTokens(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	var tokens []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			tokens = append(tokens, line)
+		}
+	}
+	return tokens, scanner.Err()
+}
+
+// parseCellToken resolves a token to a system and cell ID. Tokens may be an
+// H3 index in hex, an S2 token, or a decimal 64-bit cell ID of either system.
+// A 15-character hex string can be valid in both systems; auto picks H3.
+func parseCellToken(token, system string) (string, uint64, error) {
+	want := strings.ToUpper(system)
+	if want != "AUTO" && want != bench.SystemH3 && want != bench.SystemS2 {
+		return "", 0, fmt.Errorf("unknown system %q (want auto, h3 or s2)", system)
+	}
+
+	if want != bench.SystemS2 {
+		if c := h3.Cell(h3.IndexFromString(token)); c.IsValid() {
+			return bench.SystemH3, uint64(c), nil
+		}
+	}
+	if want != bench.SystemH3 {
+		if id := s2.CellIDFromToken(token); id.IsValid() {
+			return bench.SystemS2, uint64(id), nil
+		}
+	}
+	if n, err := strconv.ParseUint(token, 10, 64); err == nil {
+		if want != bench.SystemS2 && h3.Cell(n).IsValid() {
+			return bench.SystemH3, n, nil
+		}
+		if want != bench.SystemH3 && s2.CellID(n).IsValid() {
+			return bench.SystemS2, n, nil
+		}
+	}
+	return "", 0, fmt.Errorf("%q is not a valid cell token", token)
+}
+
+// decodeCell returns the cell's boundary as a GeoJSON feature with its
+// resolution, area and center as properties
+func decodeCell(token, system string) (geojson.Feature, error) {
+	sys, cell, err := parseCellToken(token, system)
+	if err != nil {
+		return geojson.Feature{}, err
+	}
+	ring, err := cellBoundary(sys, cell)
+	if err != nil {
+		return geojson.Feature{}, err
+	}
+
+	props := map[string]interface{}{
+		"cell":   cellToken(sys, cell),
+		"id":     strconv.FormatUint(cell, 10),
+		"system": sys,
+	}
+	if sys == bench.SystemH3 {
+		c := h3.Cell(cell)
+		area, err := h3.CellAreaKm2(c)
+		if err != nil {
+			return geojson.Feature{}, err
+		}
+		center, err := h3.CellToLatLng(c)
+		if err != nil {
+			return geojson.Feature{}, err
+		}
+		props["resolution"] = c.Resolution()
+		props["area_km2"] = area
+		props["center"] = [2]float64{center.Lng, center.Lat}
+		props["base_cell"] = c.BaseCellNumber()
+		props["pentagon"] = c.IsPentagon()
+	} else {
+		id := s2.CellID(cell)
+		center := id.LatLng()
+		props["resolution"] = id.Level()
+		props["area_km2"] = s2.CellFromCellID(id).ExactArea() * bench.EarthRadiusKm * bench.EarthRadiusKm
+		props["center"] = [2]float64{center.Lng.Degrees(), center.Lat.Degrees()}
+		props["face"] = id.Face()
+	}
+
+	return geojson.Feature{
+		Type:       "Feature",
+		Geometry:   geojson.Geometry{Type: "Polygon", Coordinates: [][][2]float64{ring}},
+		Properties: props,
+	}, nil
+}
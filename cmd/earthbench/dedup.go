@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/uber/h3-go/v4"
+)
+
+// DedupResult counts how many of the cells in a dataset's coverings are
+// shared between features. An index storing each distinct cell once, with
+// the list of features covering it, holds UniqueCells keys instead of
+// TotalCells. Compacted is the size of the union of all coverings, each
+// complete set of children merged into its parent (H3 CompactCells, S2
+// CellUnion.Normalize) — what an index needs when it only answers whether
+// any feature covers a point.
+type DedupResult struct {
+	System       string
+	Resolution   int
+	Features     int
+	TotalCells   int
+	UniqueCells  int
+	SharedCells  int // distinct cells in two or more coverings
+	MaxSharing   int // most coverings any one cell is in
+	Compacted    int
+	DedupTime    time.Duration
+	CompactTime  time.Duration
+	OverlapPairs int // feature pairs with at least one cell in common
+}
+
+// DedupRatio is the share of the stored cells a deduplicated index keeps
+func (r DedupResult) DedupRatio() float64 {
+	if r.TotalCells == 0 {
+		return 0
+	}
+	return float64(r.UniqueCells) / float64(r.TotalCells)
+}
+
+func runDedupCommand(args []string) error {
+	fs := flag.NewFlagSet("dedup", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "0-7", "0-10")
+	output := fs.String("output", "output/dedup.csv", "CSV file for the results")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d features from %s\n", len(ds.Features), ds.Path)
+
+	var results []DedupResult
+	for _, sp := range sweepPoints {
+		coverings, err := bench.ComputeCoverings(ds, sp.System, sp.Resolution, *sweep.MaxCells)
+		if err != nil {
+			return fmt.Errorf("%s resolution %d: %w", sp.System, sp.Resolution, err)
+		}
+		r, err := measureDedup(coverings, sp)
+		if err != nil {
+			return fmt.Errorf("%s resolution %d: %w", sp.System, sp.Resolution, err)
+		}
+		fmt.Printf("%s res %2d: %10d cells, %10d unique (%5.1f%%), %9d shared by up to %3d features, %9d compacted, %6d overlapping pairs\n",
+			r.System, r.Resolution, r.TotalCells, r.UniqueCells, 100*r.DedupRatio(), r.SharedCells, r.MaxSharing, r.Compacted, r.OverlapPairs)
+		results = append(results, r)
+	}
+
+	if err := saveDedupResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// measureDedup counts the distinct cells of a set of coverings and which
+// features share them. S2 coverings mix levels, so features overlapping
+// through cells of different levels share area but no cell IDs; only the
+// compacted union sees that overlap.
+func measureDedup(coverings [][]uint64, sp bench.SweepPoint) (DedupResult, error) {
+	result := DedupResult{System: sp.System, Resolution: sp.Resolution, Features: len(coverings)}
+
+	start := time.Now()
+	owners := make(map[uint64][]int)
+	for i, covering := range coverings {
+		result.TotalCells += len(covering)
+		for _, cell := range covering {
+			// Coverings hold each cell once, so an owner repeats only as the
+			// last entry
+			if list := owners[cell]; len(list) == 0 || list[len(list)-1] != i {
+				owners[cell] = append(list, i)
+			}
+		}
+	}
+	result.DedupTime = time.Since(start)
+	result.UniqueCells = len(owners)
+
+	pairs := make(map[[2]int]bool)
+	for _, list := range owners {
+		if len(list) < 2 {
+			continue
+		}
+		result.SharedCells++
+		result.MaxSharing = max(result.MaxSharing, len(list))
+		for a := 0; a < len(list); a++ {
+			for b := a + 1; b < len(list); b++ {
+				pairs[[2]int{list[a], list[b]}] = true
+			}
+		}
+	}
+	if result.UniqueCells > 0 {
+		result.MaxSharing = max(result.MaxSharing, 1)
+	}
+	result.OverlapPairs = len(pairs)
+
+	start = time.Now()
+	if sp.System == bench.SystemH3 {
+		cells := make([]h3.Cell, 0, len(owners))
+		for cell := range owners {
+			cells = append(cells, h3.Cell(cell))
+		}
+		compacted, err := h3.CompactCells(cells)
+		if err != nil {
+			return result, err
+		}
+		result.Compacted = len(compacted)
+	} else {
+		union := make(s2.CellUnion, 0, len(owners))
+		for cell := range owners {
+			union = append(union, s2.CellID(cell))
+		}
+		union.Normalize()
+		result.Compacted = len(union)
+	}
+	result.CompactTime = time.Since(start)
+	return result, nil
+}
+
+func saveDedupResultsToCSV(filename string, results []DedupResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "Resolution", "Features", "TotalCells", "UniqueCells", "DedupRatio", "SharedCells",
+		"MaxSharing", "OverlapPairs", "CompactedCells", "DedupNs", "CompactNs"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			r.System,
+			strconv.Itoa(r.Resolution),
+			strconv.Itoa(r.Features),
+			strconv.Itoa(r.TotalCells),
+			strconv.Itoa(r.UniqueCells),
+			strconv.FormatFloat(r.DedupRatio(), 'f', -1, 64),
+			strconv.Itoa(r.SharedCells),
+			strconv.Itoa(r.MaxSharing),
+			strconv.Itoa(r.OverlapPairs),
+			strconv.Itoa(r.Compacted),
+			strconv.FormatInt(r.DedupTime.Nanoseconds(), 10),
+			strconv.FormatInt(r.CompactTime.Nanoseconds(), 10),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
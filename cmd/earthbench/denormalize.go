@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	ds2 "github.com/nkk36/earth-discretization-benchmark/discretize/s2"
+)
+
+// DenormalizeResult compares, over every feature, the mixed-level S2
+// covering at S2Level (RegionCoverer MinLevel to S2Level) expanded to cells
+// of S2Level alone with the H3 fill at the paired resolution, so both count
+// cells of a single size. Duration is the time spent expanding.
+type DenormalizeResult struct {
+	H3Resolution      int
+	S2Level           int
+	MinLevel          int
+	Features          int
+	S2Cells           int
+	DenormalizedCells int
+	H3Cells           int
+	S2CellKm2         float64
+	H3CellKm2         float64
+	Duration          time.Duration
+}
+
+// Inflation is the number of single-level cells per cell of the
+// mixed-level covering
+func (r DenormalizeResult) Inflation() float64 {
+	if r.S2Cells == 0 {
+		return 0
+	}
+	return float64(r.DenormalizedCells) / float64(r.S2Cells)
+}
+
+// VsH3 is the number of single-level S2 cells per H3 cell
+func (r DenormalizeResult) VsH3() float64 {
+	if r.H3Cells == 0 {
+		return 0
+	}
+	return float64(r.DenormalizedCells) / float64(r.H3Cells)
+}
+
+// AreaAdjustedVsH3 is VsH3 weighted by the average cell areas, 1 when
+// both coverings span the same area; the paired level and resolution
+// differ in cell area by up to a factor of two
+func (r DenormalizeResult) AreaAdjustedVsH3() float64 {
+	if r.H3CellKm2 == 0 {
+		return 0
+	}
+	return r.VsH3() * r.S2CellKm2 / r.H3CellKm2
+}
+
+func runDenormalizeCommand(args []string) error {
+	fs := flag.NewFlagSet("denormalize", flag.ExitOnError)
+	dataset := addDatasetFlags(fs)
+	h3Res := fs.String("h3-res", "3-7", "H3 resolutions, e.g. 3-7 or 4,6")
+	s2Levels := fs.String("s2-levels", "", "S2 level paired with each H3 resolution (default: the level with the closest average cell area)")
+	maxCells := fs.Int("s2-max-cells", 8, "S2 RegionCoverer MaxCells of the mixed-level coverings")
+	minLevel := fs.Int("s2-min-level", 0, "S2 RegionCoverer MinLevel of the mixed-level coverings")
+	output := fs.String("output", "output/denormalize.csv", "CSV file for the results")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	resolutions, levels, err := pairResolutions(*h3Res, *s2Levels)
+	if err != nil {
+		return err
+	}
+	if *minLevel < 0 || *minLevel > ds2.MaxLevel {
+		return fmt.Errorf("-s2-min-level must be between 0 and %d", ds2.MaxLevel)
+	}
+	ds, err := dataset.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d features from %s\n", len(ds.Features), ds.Path)
+
+	var results []DenormalizeResult
+	for i, res := range resolutions {
+		r, err := benchmarkDenormalize(ds, res, levels[i], min(*minLevel, levels[i]), *maxCells)
+		if err != nil {
+			return fmt.Errorf("H3 resolution %d / S2 level %d: %w", res, levels[i], err)
+		}
+		fmt.Printf("S2 levels %2d-%2d: %6d cells -> %9d at level %2d (x%.1f); H3 res %2d: %9d cells, x%.2f (x%.2f by area); expanded in %v\n",
+			r.MinLevel, r.S2Level, r.S2Cells, r.DenormalizedCells, r.S2Level, r.Inflation(), r.H3Resolution, r.H3Cells, r.VsH3(), r.AreaAdjustedVsH3(), r.Duration)
+		results = append(results, r)
+	}
+
+	if err := saveDenormalizeResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// benchmarkDenormalize covers every feature with S2 cells from minLevel to
+// level, times their expansion to level alone and fills the feature with
+// H3 cells at res
+func benchmarkDenormalize(ds *bench.Dataset, res, level, minLevel, maxCells int) (DenormalizeResult, error) {
+	r := DenormalizeResult{H3Resolution: res, S2Level: level, MinLevel: minLevel, Features: len(ds.Features)}
+	var err error
+	if r.S2CellKm2, err = averageCellAreaKm2(bench.SystemS2, level); err != nil {
+		return r, err
+	}
+	if r.H3CellKm2, err = averageCellAreaKm2(bench.SystemH3, res); err != nil {
+		return r, err
+	}
+
+	for _, f := range ds.Features {
+		covering := ds2.CoverLevels(f.S2Polygon, minLevel, level, maxCells)
+		start := time.Now()
+		denormalized := ds2.Denormalize(covering, level)
+		r.Duration += time.Since(start)
+
+		fill, err := bench.CoverFeature(f, bench.SystemH3, res, maxCells)
+		if err != nil {
+			return r, fmt.Errorf("feature %d: %w", f.FeatureID, err)
+		}
+		r.S2Cells += len(covering)
+		r.DenormalizedCells += len(denormalized)
+		r.H3Cells += len(fill)
+	}
+	return r, nil
+}
+
+func saveDenormalizeResultsToCSV(filename string, results []DenormalizeResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"H3Resolution", "S2Level", "S2MinLevel", "Features", "S2Cells", "DenormalizedCells", "Inflation",
+		"H3Cells", "DenormalizedVsH3", "S2CellKm2", "H3CellKm2", "AreaAdjustedVsH3", "DurationNs"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			strconv.Itoa(r.H3Resolution),
+			strconv.Itoa(r.S2Level),
+			strconv.Itoa(r.MinLevel),
+			strconv.Itoa(r.Features),
+			strconv.Itoa(r.S2Cells),
+			strconv.Itoa(r.DenormalizedCells),
+			strconv.FormatFloat(r.Inflation(), 'f', -1, 64),
+			strconv.Itoa(r.H3Cells),
+			strconv.FormatFloat(r.VsH3(), 'f', -1, 64),
+			strconv.FormatFloat(r.S2CellKm2, 'f', -1, 64),
+			strconv.FormatFloat(r.H3CellKm2, 'f', -1, 64),
+			strconv.FormatFloat(r.AreaAdjustedVsH3(), 'f', -1, 64),
+			strconv.FormatInt(r.Duration.Nanoseconds(), 10),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
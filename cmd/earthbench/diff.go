@@ -0,0 +1,206 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+)
+
+func runDiffCommand(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	threshold := fs.Float64("threshold", 0.05, "smallest duration change reported, as a fraction (0.05 for 5%); smaller changes count as noise")
+	output := fs.String("output", "", "also write the per-sweep-point deltas to this CSV file")
+	fail := fs.Bool("fail", false, "exit with an error when anything changed beyond noise, e.g. to gate a dependency upgrade in CI")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: earthbench diff [flags] runA runB\n\nEach run is a sweep -json file, a -protobuf .pb file (either optionally .gz) or a directory holding sweep.json or sweep.pb.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("want two runs to compare, got %d", fs.NArg())
+	}
+
+	a, err := loadRun(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	b, err := loadRun(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	if bench.DatasetName(a.Dataset) != bench.DatasetName(b.Dataset) {
+		fmt.Printf("Warning: comparing different datasets (%s and %s)\n", a.Dataset, b.Dataset)
+	}
+	if a.Options != b.Options {
+		fmt.Printf("Warning: the runs used different covering options (%+v and %+v)\n", a.Options, b.Options)
+	}
+
+	switch {
+	case a.Normalized() != b.Normalized():
+		fmt.Println("Warning: only one run has normalized durations (sweep -normalize)")
+	case !a.Normalized() && a.Calibration != nil && b.Calibration != nil &&
+		math.Abs(math.Log(a.Calibration.Score/b.Calibration.Score)) > math.Log(1.1):
+		fmt.Printf("Warning: the runs were on machines scoring %.2f and %.2f; sweep -normalize makes them roughly comparable\n", a.Calibration.Score, b.Calibration.Score)
+	}
+
+	diffs := bench.DiffResults(a, b, *threshold)
+	changed := printDiffs(diffs, *threshold, isTerminal(os.Stdout))
+
+	if *output != "" {
+		if err := writeResultsFile(*output, func(w io.Writer) error { return writeDiffCSV(w, diffs) }); err != nil {
+			return err
+		}
+		fmt.Printf("Deltas saved to %s\n", *output)
+	}
+	if *fail && changed > 0 {
+		return fmt.Errorf("%d of %d sweep points changed", changed, len(diffs))
+	}
+	return nil
+}
+
+// loadRun reads the results of a sweep -json or -protobuf (.pb) file, or of
+// the sweep.json or sweep.pb in a results directory
+func loadRun(path string) (*bench.Results, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		dir := path
+		path = filepath.Join(dir, "sweep.json")
+		for _, name := range []string{"sweep.json", "sweep.json.gz", "sweep.pb", "sweep.pb.gz"} {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				path = filepath.Join(dir, name)
+				break
+			}
+		}
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	read := bench.ReadJSON
+	if strings.HasSuffix(strings.TrimSuffix(path, ".gz"), ".pb") {
+		read = readRunProto
+	}
+	results, err := read(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w (diff reads the output of sweep -json or -protobuf)", path, err)
+	}
+	return results, nil
+}
+
+// printDiffs prints one row per sweep point, marking the changes beyond
+// noise in color on a terminal, and returns how many points changed
+func printDiffs(diffs []bench.PointDiff, threshold float64, color bool) int {
+	paint := func(s, code string) string {
+		if !color {
+			return s
+		}
+		return "\x1b[" + code + "m" + s + "\x1b[0m"
+	}
+
+	fmt.Printf("%-6s %4s %12s %12s %8s %17s %10s %10s %6s %6s  %s\n",
+		"SYSTEM", "RES", "A/FEATURE", "B/FEATURE", "B/A", "95% CI", "CELLS A", "CELLS B", "VIOL", "SKIP", "CHANGE")
+	changed := 0
+	for _, d := range diffs {
+		var notes []string
+		switch {
+		case !d.InA:
+			notes = append(notes, paint("only in B", "33"))
+		case !d.InB:
+			notes = append(notes, paint("only in A", "33"))
+		case d.Slower:
+			notes = append(notes, paint(fmt.Sprintf("slower by %.1f%%", 100*(d.Ratio-1)), "31"))
+		case d.Faster:
+			notes = append(notes, paint(fmt.Sprintf("faster by %.1f%%", 100*(1-d.Ratio)), "32"))
+		}
+		if d.InA && d.InB {
+			if d.CellsA != d.CellsB {
+				notes = append(notes, paint(fmt.Sprintf("cells %+d", d.CellsB-d.CellsA), "35"))
+			}
+			if d.ViolationsA != d.ViolationsB {
+				notes = append(notes, paint(fmt.Sprintf("violations %+d", d.ViolationsB-d.ViolationsA), "31"))
+			}
+			if d.SkippedA != d.SkippedB {
+				notes = append(notes, paint(fmt.Sprintf("skipped %+d", d.SkippedB-d.SkippedA), "33"))
+			}
+		}
+		if d.Changed() {
+			changed++
+		}
+
+		ratio, interval := "-", "-"
+		if d.InA && d.InB && !math.IsNaN(d.Ratio) {
+			ratio = fmt.Sprintf("%.3f", d.Ratio)
+			interval = fmt.Sprintf("%.3f-%.3f", d.RatioLow, d.RatioHigh)
+		}
+		fmt.Printf("%-6s %4d %12v %12v %8s %17s %10d %10d %6s %6s  %s\n",
+			d.System, d.Resolution, time.Duration(d.MeanNsA).Round(time.Microsecond), time.Duration(d.MeanNsB).Round(time.Microsecond),
+			ratio, interval, d.CellsA, d.CellsB,
+			fmt.Sprintf("%d/%d", d.ViolationsA, d.ViolationsB), fmt.Sprintf("%d/%d", d.SkippedA, d.SkippedB), strings.Join(notes, ", "))
+	}
+	fmt.Printf("\n%d of %d sweep points changed beyond noise (durations: 95%% interval past ±%.0f%%)\n", changed, len(diffs), 100*threshold)
+	return changed
+}
+
+// writeDiffCSV writes one row per sweep point with both runs' values
+func writeDiffCSV(w io.Writer, diffs []bench.PointDiff) error {
+	writer := csv.NewWriter(w)
+
+	headers := []string{"System", "Resolution", "InA", "InB", "MeanNsA", "MeanNsB", "Ratio", "RatioLow", "RatioHigh", "PairedFeatures",
+		"CellsA", "CellsB", "ViolationsA", "ViolationsB", "SkippedA", "SkippedB", "Slower", "Faster", "Changed"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, d := range diffs {
+		row := []string{
+			d.System,
+			strconv.Itoa(d.Resolution),
+			strconv.FormatBool(d.InA),
+			strconv.FormatBool(d.InB),
+			strconv.FormatFloat(d.MeanNsA, 'f', -1, 64),
+			strconv.FormatFloat(d.MeanNsB, 'f', -1, 64),
+			strconv.FormatFloat(d.Ratio, 'f', -1, 64),
+			strconv.FormatFloat(d.RatioLow, 'f', -1, 64),
+			strconv.FormatFloat(d.RatioHigh, 'f', -1, 64),
+			strconv.Itoa(d.Paired),
+			strconv.Itoa(d.CellsA),
+			strconv.Itoa(d.CellsB),
+			strconv.Itoa(d.ViolationsA),
+			strconv.Itoa(d.ViolationsB),
+			strconv.Itoa(d.SkippedA),
+			strconv.Itoa(d.SkippedB),
+			strconv.FormatBool(d.Slower),
+			strconv.FormatBool(d.Faster),
+			strconv.FormatBool(d.Changed()),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
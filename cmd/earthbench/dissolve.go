@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/uber/h3-go/v4"
+)
+
+// dissolveCells merges the cells of a covering into their outline: a list
+// of polygons, each its closed exterior [lon, lat] ring, counterclockwise,
+// followed by its holes, clockwise
+func dissolveCells(system string, cells []uint64) ([][][][2]float64, error) {
+	if len(cells) == 0 {
+		return nil, nil
+	}
+	if system == bench.SystemS2 {
+		return dissolveS2Cells(cells)
+	}
+	h3Cells := make([]h3.Cell, len(cells))
+	for i, c := range cells {
+		h3Cells[i] = h3.Cell(c)
+	}
+	polygons, err := h3.CellsToMultiPolygon(h3Cells)
+	if err != nil {
+		return nil, err
+	}
+	toRing := func(loop h3.GeoLoop) [][2]float64 {
+		ring := make([][2]float64, 0, len(loop)+1)
+		for _, ll := range loop {
+			ring = append(ring, [2]float64{ll.Lng, ll.Lat})
+		}
+		return append(ring, ring[0])
+	}
+	var out [][][][2]float64
+	for _, polygon := range polygons {
+		rings := [][][2]float64{toRing(polygon.GeoLoop)}
+		for _, hole := range polygon.Holes {
+			rings = append(rings, toRing(hole))
+		}
+		out = append(out, rings)
+	}
+	return out, nil
+}
+
+// s2VertexKey identifies a cell vertex by its coordinates rounded to about
+// a micrometre, so the corner two neighbouring cells share is one vertex
+// even when it was computed from different faces
+type s2VertexKey [3]int64
+
+func keyOfPoint(p s2.Point) s2VertexKey {
+	return s2VertexKey{int64(math.Round(p.X * 1e12)), int64(math.Round(p.Y * 1e12)), int64(math.Round(p.Z * 1e12))}
+}
+
+// dissolveS2Cells outlines cells of one level, which the S2 coverings of
+// the benchmark always are. Every cell adds its four edges
+// counterclockwise and an edge cancels the opposite one of its neighbour,
+// leaving the boundary with the region on its left; the boundary edges
+// are chained into loops that s2 nests into shells and holes.
+func dissolveS2Cells(cells []uint64) ([][][][2]float64, error) {
+	type edge struct{ from, to s2VertexKey }
+	points := make(map[s2VertexKey]s2.Point)
+	boundary := make(map[edge]bool)
+	var order []edge // boundary edges in the order they were added, for a stable output
+	for _, cell := range cells {
+		c := s2.CellFromCellID(s2.CellID(cell))
+		var keys [4]s2VertexKey
+		for k := range keys {
+			v := c.Vertex(k)
+			keys[k] = keyOfPoint(v)
+			if _, ok := points[keys[k]]; !ok {
+				points[keys[k]] = v
+			}
+		}
+		for k := range keys {
+			e := edge{keys[k], keys[(k+1)%4]}
+			if reverse := (edge{e.to, e.from}); boundary[reverse] {
+				delete(boundary, reverse)
+				continue
+			}
+			boundary[e] = true
+			order = append(order, e)
+		}
+	}
+
+	outgoing := make(map[s2VertexKey][]s2VertexKey)
+	for _, e := range order {
+		if boundary[e] {
+			outgoing[e.from] = append(outgoing[e.from], e.to)
+		}
+	}
+	var loops []*s2.Loop
+	for _, first := range order {
+		if !boundary[first] {
+			continue
+		}
+		vertices := []s2.Point{points[first.from]}
+		prev, at := first.from, first.to
+		delete(boundary, first)
+		for at != first.from {
+			vertices = append(vertices, points[at])
+			// Where two cells touch only at a corner the vertex has two
+			// ways out; turning left keeps each cell's side in a loop of
+			// its own instead of one loop touching itself
+			next, best := s2VertexKey{}, math.Inf(-1)
+			for _, to := range outgoing[at] {
+				if !boundary[edge{at, to}] {
+					continue
+				}
+				if turn := float64(s2.TurnAngle(points[prev], points[at], points[to])); turn > best {
+					next, best = to, turn
+				}
+			}
+			if math.IsInf(best, -1) {
+				return nil, fmt.Errorf("S2 covering outline does not close at %v", s2.LatLngFromPoint(points[at]))
+			}
+			delete(boundary, edge{at, next})
+			prev, at = at, next
+		}
+		loops = append(loops, s2.LoopFromPoints(vertices))
+	}
+
+	polygon := s2.PolygonFromOrientedLoops(loops)
+	toRing := func(vertices []s2.Point, reverse bool) [][2]float64 {
+		ring := make([][2]float64, 0, len(vertices)+1)
+		for i := range vertices {
+			if reverse {
+				i = len(vertices) - 1 - i
+			}
+			ll := s2.LatLngFromPoint(vertices[i])
+			ring = append(ring, [2]float64{ll.Lng.Degrees(), ll.Lat.Degrees()})
+		}
+		return append(ring, ring[0])
+	}
+	var out [][][][2]float64
+	for k, loop := range polygon.Loops() {
+		if loop.IsHole() {
+			continue
+		}
+		rings := [][][2]float64{toRing(loop.Vertices(), false)}
+		// Loops are in pre-order, so the holes of a shell are its
+		// descendants less theirs; s2 keeps holes counterclockwise around
+		// the hole itself
+		for j := k + 1; j <= polygon.LastDescendant(k); j = polygon.LastDescendant(j) + 1 {
+			rings = append(rings, toRing(polygon.Loop(j).Vertices(), true))
+		}
+		out = append(out, rings)
+	}
+	return out, nil
+}
+
+// multiPolygonToWKT formats dissolved polygons as a WKT MULTIPOLYGON
+func multiPolygonToWKT(polygons [][][][2]float64) string {
+	if len(polygons) == 0 {
+		return "MULTIPOLYGON EMPTY"
+	}
+	var b strings.Builder
+	b.WriteString("MULTIPOLYGON(")
+	for i, rings := range polygons {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(strings.TrimPrefix(polygonToWKT(rings), "POLYGON"))
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// The OGC WKB byte order and geometry types written
+const (
+	wkbLittleEndian = 1
+	wkbPolygon      = 3
+	wkbMultiPolygon = 6
+)
+
+// multiPolygonToWKB encodes dissolved polygons as a little-endian OGC WKB
+// MultiPolygon
+func multiPolygonToWKB(polygons [][][][2]float64) []byte {
+	b := []byte{wkbLittleEndian}
+	b = binary.LittleEndian.AppendUint32(b, wkbMultiPolygon)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(polygons)))
+	for _, rings := range polygons {
+		b = appendPolygonWKB(b, rings)
+	}
+	return b
+}
+
+// appendPolygonWKB appends GeoJSON polygon rings as a little-endian OGC
+// WKB Polygon
+func appendPolygonWKB(b []byte, rings [][][2]float64) []byte {
+	b = append(b, wkbLittleEndian)
+	b = binary.LittleEndian.AppendUint32(b, wkbPolygon)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(rings)))
+	for _, ring := range rings {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(ring)))
+		for _, p := range ring {
+			b = binary.LittleEndian.AppendUint64(b, math.Float64bits(p[0]))
+			b = binary.LittleEndian.AppendUint64(b, math.Float64bits(p[1]))
+		}
+	}
+	return b
+}
+
+// exportDissolvedCoverings writes a CSV row per feature and sweep point
+// with the outline of its covering as WKT, or as hex-encoded WKB (the
+// form PostGIS and most WKB readers take from text) when wkb is set
+func exportDissolvedCoverings(path string, wkb bool, ds *bench.Dataset, sweepPoints []bench.SweepPoint, maxCells int) (int, error) {
+	column := "wkt"
+	if wkb {
+		column = "wkb"
+	}
+	rows := 0
+	err := writeResultsFile(path, func(w io.Writer) error {
+		writer := csv.NewWriter(w)
+		if err := writer.Write([]string{"feature_id", "system", "resolution", "cells", "polygons", column}); err != nil {
+			return err
+		}
+		for _, sp := range sweepPoints {
+			coverings, err := bench.ComputeCoverings(ds, sp.System, sp.Resolution, maxCells)
+			if err != nil {
+				return err
+			}
+			for i, f := range ds.Features {
+				polygons, err := dissolveCells(sp.System, coverings[i])
+				if err != nil {
+					return err
+				}
+				geometry := multiPolygonToWKT(polygons)
+				if wkb {
+					geometry = hex.EncodeToString(multiPolygonToWKB(polygons))
+				}
+				row := []string{
+					strconv.Itoa(f.FeatureID),
+					sp.System,
+					strconv.Itoa(sp.Resolution),
+					strconv.Itoa(len(coverings[i])),
+					strconv.Itoa(len(polygons)),
+					geometry,
+				}
+				if err := writer.Write(row); err != nil {
+					return err
+				}
+				rows++
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+	return rows, err
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+)
+
+func runDuckDBCommand(args []string) error {
+	fs := flag.NewFlagSet("duckdb", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "0-6", "0-11")
+	binary := fs.String("duckdb", "duckdb", "path to the duckdb CLI")
+	output := fs.String("output", "output/duckdb.csv", "CSV file for the results")
+	queries := fs.Int("queries", 100000, "number of random query points")
+	seed := fs.Int64("seed", 1, "seed for the random query points")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if _, err := exec.LookPath(*binary); err != nil {
+		return fmt.Errorf("duckdb CLI not found (set -duckdb): %w", err)
+	}
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d features from %s\n", len(ds.Features), ds.Path)
+	points := bench.RandomPointsInRect(ds.Bounds(), *queries, *seed)
+
+	results, err := benchmarkDuckDB(*binary, ds, points)
+	if err != nil {
+		return err
+	}
+	baseline, err := benchmarkInProcessIndex(ds, sweepPoints, *sweep.MaxCells, points)
+	if err != nil {
+		return err
+	}
+	results = append(results, baseline...)
+
+	printOperationResults(results)
+	return saveOperationResultsToCSV(*output, results)
+}
+
+// benchmarkDuckDB loads the dataset and query points into an in-memory
+// DuckDB with the spatial extension and times the load, a point-in-polygon
+// join and a polygon self-overlap join using the CLI's own timer. DuckDB
+// evaluates these planar on lon/lat, unlike S2's spherical edges.
+func benchmarkDuckDB(binary string, ds *bench.Dataset, points []s2.LatLng) ([]OperationResult, error) {
+	dir, err := os.MkdirTemp("", "earthbench-duckdb-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	polygonsPath := filepath.Join(dir, "polygons.csv")
+	pointsPath := filepath.Join(dir, "points.csv")
+	if err := writeDuckDBInputs(polygonsPath, pointsPath, ds, points); err != nil {
+		return nil, err
+	}
+
+	script := fmt.Sprintf(`.bail on
+.mode csv
+.headers off
+INSTALL spatial;
+LOAD spatial;
+CREATE TABLE pts AS SELECT * FROM read_csv('%s', header = true, columns = {'point_id': 'INTEGER', 'lng': 'DOUBLE', 'lat': 'DOUBLE'});
+.timer on
+CREATE TABLE polys AS SELECT feature_id, ST_GeomFromText(wkt) AS geom FROM read_csv('%s', header = true, columns = {'feature_id': 'BIGINT', 'wkt': 'VARCHAR'});
+SELECT count(DISTINCT pts.point_id) FROM pts JOIN polys ON ST_Contains(polys.geom, ST_Point(pts.lng, pts.lat));
+SELECT count(*) FROM polys a JOIN polys b ON a.feature_id <> b.feature_id AND ST_Intersects(a.geom, b.geom);
+`, pointsPath, polygonsPath)
+
+	cmd := exec.Command(binary, ":memory:")
+	cmd.Stdin = strings.NewReader(script)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running duckdb: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	timings, counts, err := parseDuckDBOutput(out)
+	if err != nil {
+		return nil, err
+	}
+	if len(timings) != 3 || len(counts) != 2 {
+		return nil, fmt.Errorf("unexpected duckdb output: %q", out)
+	}
+
+	return []OperationResult{
+		{System: "DuckDB", Resolution: -1, Operation: "load", Operations: len(ds.Features), Duration: timings[0]},
+		{System: "DuckDB", Resolution: -1, Operation: "point_in_polygon", Operations: len(points), Matches: counts[0], Duration: timings[1]},
+		{System: "DuckDB", Resolution: -1, Operation: "polygon_overlap", Operations: len(ds.Features), Matches: counts[1], Duration: timings[2]},
+	}, nil
+}
+
+// parseDuckDBOutput splits CLI output into `.timer on` wall times and the
+// single-value rows printed by the SELECT statements
+func parseDuckDBOutput(out []byte) ([]time.Duration, []int, error) {
+	var timings []time.Duration
+	var counts []int
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "Run Time") {
+			// Run Time (s): real 0.012 user 0.010000 sys 0.001000
+			fields := strings.Fields(line)
+			for i := 0; i+1 < len(fields); i++ {
+				if fields[i] == "real" {
+					seconds, err := strconv.ParseFloat(fields[i+1], 64)
+					if err != nil {
+						return nil, nil, fmt.Errorf("parsing timer line %q: %w", line, err)
+					}
+					timings = append(timings, time.Duration(seconds*float64(time.Second)))
+				}
+			}
+			continue
+		}
+		n, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unexpected duckdb output line %q", line)
+		}
+		counts = append(counts, n)
+	}
+	return timings, counts, scanner.Err()
+}
+
+func writeDuckDBInputs(polygonsPath, pointsPath string, ds *bench.Dataset, points []s2.LatLng) error {
+	polygons, err := os.Create(polygonsPath)
+	if err != nil {
+		return err
+	}
+	defer polygons.Close()
+	writer := csv.NewWriter(polygons)
+	writer.Write([]string{"feature_id", "wkt"})
+	for _, f := range ds.Features {
+		writer.Write([]string{strconv.Itoa(f.FeatureID), polygonToWKT(f.Geometry.Coordinates)})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+
+	pts, err := os.Create(pointsPath)
+	if err != nil {
+		return err
+	}
+	defer pts.Close()
+	writer = csv.NewWriter(pts)
+	writer.Write([]string{"point_id", "lng", "lat"})
+	for i, ll := range points {
+		writer.Write([]string{
+			strconv.Itoa(i),
+			strconv.FormatFloat(ll.Lng.Degrees(), 'f', -1, 64),
+			strconv.FormatFloat(ll.Lat.Degrees(), 'f', -1, 64),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	dh3 "github.com/nkk36/earth-discretization-benchmark/discretize/h3"
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+	"github.com/uber/h3-go/v4"
+)
+
+// The kinds of edit applied to a geofence
+const (
+	editVertex = "vertex" // move one vertex of the outer ring
+	editBump   = "bump"   // insert a vertex pushed outward from an edge's midpoint
+)
+
+// kmPerDegree is the length of one degree of latitude
+const kmPerDegree = 111.32
+
+// EditResult is the cost of keeping the coverings of frequently edited
+// geofences up to date: every feature is edited Edits/Features times in a
+// row and re-covered after each edit. Recover times a full conversion and
+// covering of the edited polygon. For H3, Incremental times patching the
+// previous covering instead, by re-testing only the cells whose centers lie
+// in the bounding box of the edit; Mismatched counts the cells where the
+// patch disagreed with the full fill.
+type EditResult struct {
+	System      string
+	Resolution  int
+	Edit        string
+	DistanceKm  float64
+	Features    int
+	Edits       int
+	CellsBefore int // cells of the coverings before each edit, summed
+	Added       int
+	Removed     int
+	Recover     time.Duration
+	Incremental time.Duration
+	Candidates  int // cells re-tested by the incremental patches
+	Mismatched  int
+}
+
+// Churn is the share of a covering's cells that an edit adds or removes
+func (r EditResult) Churn() float64 {
+	if r.CellsBefore == 0 {
+		return 0
+	}
+	return float64(r.Added+r.Removed) / float64(r.CellsBefore)
+}
+
+// RecoverNs is the mean cost of a full re-covering per edit
+func (r EditResult) RecoverNs() float64 {
+	if r.Edits == 0 {
+		return 0
+	}
+	return float64(r.Recover.Nanoseconds()) / float64(r.Edits)
+}
+
+// IncrementalNs is the mean cost of an incremental patch per edit
+func (r EditResult) IncrementalNs() float64 {
+	if r.Edits == 0 {
+		return 0
+	}
+	return float64(r.Incremental.Nanoseconds()) / float64(r.Edits)
+}
+
+func runEditCommand(args []string) error {
+	fs := flag.NewFlagSet("edit", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "4-6", "8-12")
+	output := fs.String("output", "output/edit.csv", "CSV file for the results")
+	kind := fs.String("edit", editVertex, "edit applied to each geofence: vertex (move one vertex) or bump (add a small outward spike to an edge)")
+	distanceKm := fs.Float64("distance-km", 0.5, "how far an edit moves a vertex or pushes out a bump")
+	edits := fs.Int("edits", 5, "edits applied in a row to each feature, each re-covered")
+	seed := fs.Int64("seed", 1, "seed choosing the edited vertices and edges")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	if *kind != editVertex && *kind != editBump {
+		return fmt.Errorf("unknown -edit %q (want %s or %s)", *kind, editVertex, editBump)
+	}
+	if *edits < 1 || *distanceKm <= 0 {
+		return fmt.Errorf("-edits must be at least 1 and -distance-km positive")
+	}
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d features from %s; %d %s edit(s) of %g km per feature\n", len(ds.Features), ds.Path, *edits, *kind, *distanceKm)
+
+	var results []EditResult
+	for _, sp := range sweepPoints {
+		r, err := benchmarkEdits(ds, sp, *sweep.MaxCells, *kind, *distanceKm, *edits, *seed)
+		if err != nil {
+			return fmt.Errorf("%s resolution %d: %w", sp.System, sp.Resolution, err)
+		}
+		line := fmt.Sprintf("%s res %2d: churn %6.2f%% (+%d -%d cells), re-cover %10.0f ns/edit",
+			r.System, r.Resolution, 100*r.Churn(), r.Added, r.Removed, r.RecoverNs())
+		if r.System == bench.SystemH3 {
+			line += fmt.Sprintf(", incremental %10.0f ns/edit (%d mismatched cells)", r.IncrementalNs(), r.Mismatched)
+		}
+		fmt.Println(line)
+		results = append(results, r)
+	}
+
+	if err := saveEditResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+func benchmarkEdits(ds *bench.Dataset, sp bench.SweepPoint, maxCells int, kind string, distanceKm float64, edits int, seed int64) (EditResult, error) {
+	result := EditResult{System: sp.System, Resolution: sp.Resolution, Edit: kind, DistanceKm: distanceKm}
+
+	for i, f := range ds.Features {
+		// Every sweep point sees the same edits of a feature
+		rng := rand.New(rand.NewSource(seed + int64(i)))
+		geometry := f.Geometry
+		covering, err := bench.CoverFeature(f, sp.System, sp.Resolution, maxCells)
+		if err != nil {
+			return result, fmt.Errorf("feature %d: %w", f.FeatureID, err)
+		}
+		patched := make(map[uint64]bool, len(covering))
+		for _, cell := range covering {
+			patched[cell] = true
+		}
+		result.Features++
+
+		for e := 0; e < edits; e++ {
+			edited, region, err := editGeometry(geometry, kind, distanceKm, rng)
+			if err != nil {
+				return result, fmt.Errorf("feature %d: %w", f.FeatureID, err)
+			}
+			cells, convert, cover, err := bench.CoverGeometry(edited, sp.System, sp.Resolution, maxCells)
+			if err != nil {
+				return result, fmt.Errorf("feature %d edit %d: %w", f.FeatureID, e+1, err)
+			}
+			result.Edits++
+			result.Recover += convert + cover
+			added, removed := cellChurn(covering, cells)
+			result.CellsBefore += len(covering)
+			result.Added += added
+			result.Removed += removed
+
+			if sp.System == bench.SystemH3 {
+				start := time.Now()
+				candidates, err := patchH3Covering(patched, edited, region, sp.Resolution)
+				result.Incremental += time.Since(start)
+				if err != nil {
+					return result, fmt.Errorf("feature %d edit %d: %w", f.FeatureID, e+1, err)
+				}
+				result.Candidates += candidates
+				// Count the disagreements, then carry on from the full fill so
+				// one mismatch is not counted again at every later edit
+				full := make(map[uint64]bool, len(cells))
+				for _, cell := range cells {
+					full[cell] = true
+					if !patched[cell] {
+						result.Mismatched++
+					}
+				}
+				for cell := range patched {
+					if !full[cell] {
+						result.Mismatched++
+					}
+				}
+				patched = full
+			}
+			geometry, covering = edited, cells
+		}
+	}
+	return result, nil
+}
+
+// editGeometry applies one edit to the outer ring of a polygon and returns
+// the edited polygon with the corners of the area the edit can change: the
+// two triangles spanned by the neighbours of a moved vertex and its old and
+// new positions, or the triangle of a bump
+func editGeometry(g geojson.Geometry, kind string, distanceKm float64, rng *rand.Rand) (geojson.Geometry, [][2]float64, error) {
+	ring := g.Coordinates[0]
+	n := len(ring) - 1 // GeoJSON rings repeat their first position at the end
+	if n < 3 {
+		return g, nil, fmt.Errorf("outer ring has %d positions", len(ring))
+	}
+	edited := geojson.Geometry{Type: g.Type, Coordinates: append([][][2]float64(nil), g.Coordinates...)}
+
+	i := rng.Intn(n)
+	var out, region [][2]float64
+	if kind == editVertex {
+		moved := offsetPosition(ring[i], distanceKm, rng.Float64()*2*math.Pi)
+		out = append([][2]float64(nil), ring...)
+		out[i] = moved
+		if i == 0 {
+			out[n] = moved
+		}
+		region = [][2]float64{ring[(i+n-1)%n], ring[i], moved, ring[i+1]}
+	} else {
+		a, b := ring[i], ring[i+1]
+		mid := [2]float64{(a[0] + b[0]) / 2, (a[1] + b[1]) / 2}
+		// The outward normal is to the right of the edge on a
+		// counterclockwise ring and to the left on a clockwise one
+		dx := (b[0] - a[0]) * math.Cos(mid[1]*math.Pi/180)
+		dy := b[1] - a[1]
+		angle := math.Atan2(-dx, dy)
+		if planarSignedArea(ring) < 0 {
+			angle += math.Pi
+		}
+		bump := offsetPosition(mid, distanceKm, angle)
+		out = make([][2]float64, 0, len(ring)+1)
+		out = append(out, ring[:i+1]...)
+		out = append(out, bump)
+		out = append(out, ring[i+1:]...)
+		region = [][2]float64{a, bump, b}
+	}
+	edited.Coordinates[0] = out
+	return edited, region, nil
+}
+
+// offsetPosition moves a [lng, lat] position distanceKm towards angle
+// (radians counterclockwise from east), treating the area around it as flat
+func offsetPosition(p [2]float64, distanceKm, angle float64) [2]float64 {
+	lat := p[1] + distanceKm*math.Sin(angle)/kmPerDegree
+	lng := p[0] + distanceKm*math.Cos(angle)/(kmPerDegree*math.Cos(p[1]*math.Pi/180))
+	return [2]float64{lng, lat}
+}
+
+// planarSignedArea is the shoelace area of a ring in degrees², positive
+// when it runs counterclockwise
+func planarSignedArea(ring [][2]float64) float64 {
+	var sum float64
+	for i := 0; i+1 < len(ring); i++ {
+		sum += ring[i][0]*ring[i+1][1] - ring[i+1][0]*ring[i][1]
+	}
+	return sum / 2
+}
+
+// cellChurn counts the cells after has and before lacks, and the reverse
+func cellChurn(before, after []uint64) (added, removed int) {
+	old := make(map[uint64]bool, len(before))
+	for _, cell := range before {
+		old[cell] = true
+	}
+	for _, cell := range after {
+		if old[cell] {
+			delete(old, cell)
+		} else {
+			added++
+		}
+	}
+	return added, len(old)
+}
+
+// patchH3Covering updates an H3 covering in place for an edit confined to
+// region. Only cells whose centers fall in the bounding box of the region
+// can change, so those are filled and each one's center re-tested against
+// the edited polygon. It returns the number of cells re-tested.
+func patchH3Covering(covering map[uint64]bool, edited geojson.Geometry, region [][2]float64, resolution int) (int, error) {
+	minLng, minLat, maxLng, maxLat := region[0][0], region[0][1], region[0][0], region[0][1]
+	for _, p := range region[1:] {
+		minLng, maxLng = math.Min(minLng, p[0]), math.Max(maxLng, p[0])
+		minLat, maxLat = math.Min(minLat, p[1]), math.Max(maxLat, p[1])
+	}
+	box := h3.GeoPolygon{GeoLoop: dh3.LoopFromRing([][2]float64{
+		{minLng, minLat}, {maxLng, minLat}, {maxLng, maxLat}, {minLng, maxLat}, {minLng, minLat},
+	})}
+	candidates, err := dh3.Cover(box, resolution)
+	if err != nil {
+		return 0, err
+	}
+	for _, cell := range candidates {
+		center, err := h3.CellToLatLng(h3.Cell(cell))
+		if err != nil {
+			return 0, err
+		}
+		if edited.ContainsPlanar(center.Lng, center.Lat) {
+			covering[cell] = true
+		} else {
+			delete(covering, cell)
+		}
+	}
+	return len(candidates), nil
+}
+
+func saveEditResultsToCSV(filename string, results []EditResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "Resolution", "Edit", "DistanceKm", "Features", "Edits", "CellsBefore", "Added", "Removed",
+		"Churn", "RecoverNs", "IncrementalNs", "Candidates", "Mismatched"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			r.System,
+			strconv.Itoa(r.Resolution),
+			r.Edit,
+			strconv.FormatFloat(r.DistanceKm, 'f', -1, 64),
+			strconv.Itoa(r.Features),
+			strconv.Itoa(r.Edits),
+			strconv.Itoa(r.CellsBefore),
+			strconv.Itoa(r.Added),
+			strconv.Itoa(r.Removed),
+			strconv.FormatFloat(r.Churn(), 'f', -1, 64),
+			strconv.FormatFloat(r.RecoverNs(), 'f', -1, 64),
+			strconv.FormatFloat(r.IncrementalNs(), 'f', -1, 64),
+			strconv.Itoa(r.Candidates),
+			strconv.Itoa(r.Mismatched),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
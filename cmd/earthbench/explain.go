@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	dh3 "github.com/nkk36/earth-discretization-benchmark/discretize/h3"
+	ds2 "github.com/nkk36/earth-discretization-benchmark/discretize/s2"
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+	"github.com/nkk36/earth-discretization-benchmark/report"
+	"github.com/uber/h3-go/v4"
+)
+
+// explainRounds is how many times -explain times every stage, after one
+// untimed round warming the caches; the median of each is reported
+const explainRounds = 11
+
+// StageTiming is the median duration of one stage of covering a feature
+type StageTiming struct {
+	Stage    string
+	Duration time.Duration
+	Part     bool // part of the stage before it, not added to the total
+}
+
+// Explanation breaks the end-to-end cost of covering one feature at one
+// resolution into its stages, from the GeoJSON text to the serialized
+// cells
+type Explanation struct {
+	System     string
+	Resolution int
+	Cells      int
+	Bytes      int
+	Stages     []StageTiming
+}
+
+// Total is the sum of the stages, leaving out the ones that are part of
+// another
+func (e Explanation) Total() time.Duration {
+	var total time.Duration
+	for _, s := range e.Stages {
+		if !s.Part {
+			total += s.Duration
+		}
+	}
+	return total
+}
+
+// runExplain prints the stage breakdown of one feature at every sweep
+// point, in place of the conversion benchmark
+func runExplain(ds *bench.Dataset, featureID int, sweepPoints []bench.SweepPoint, maxCells int, formatName string) error {
+	i := slices.IndexFunc(ds.Features, func(f bench.Feature) bool { return f.FeatureID == featureID })
+	if i < 0 {
+		return fmt.Errorf("-explain: no feature %d in %s", featureID, ds.Path)
+	}
+	f := ds.Features[i]
+	format := slices.IndexFunc(cellFormats, func(c cellFormat) bool { return c.Name == formatName })
+	if format < 0 {
+		return fmt.Errorf("-explain-format: unknown format %q", formatName)
+	}
+
+	fmt.Printf("\nFeature %d: %d vertices, %d ring(s), %.4g km²; median of %d rounds per stage, serialized as %s\n",
+		f.FeatureID, f.NumVertices(), len(f.Geometry.Coordinates), f.AreaKm2(), explainRounds, formatName)
+	for _, sp := range sweepPoints {
+		e, err := explainFeature(f, sp, maxCells, cellFormats[format])
+		if err != nil {
+			return fmt.Errorf("%s resolution %d: %w", sp.System, sp.Resolution, err)
+		}
+		fmt.Printf("\n%s res %2d: %d cells, %s serialized, %v end to end\n", e.System, e.Resolution, e.Cells, formatBytes(float64(e.Bytes)), e.Total())
+		for _, s := range e.Stages {
+			name := s.Stage
+			if s.Part {
+				name = "  " + name
+			}
+			share := 0.0
+			if e.Total() > 0 {
+				share = 100 * float64(s.Duration) / float64(e.Total())
+			}
+			fmt.Printf("  %-20s %12v %6.1f%%\n", name, s.Duration, share)
+		}
+	}
+	return nil
+}
+
+// explainFeature times each stage of covering a feature at one sweep
+// point: parsing it back from GeoJSON text, converting it (with the loop
+// construction of that conversion on its own), the library's covering
+// call, turning its result into cell IDs and serializing them
+func explainFeature(f bench.Feature, sp bench.SweepPoint, maxCells int, format cellFormat) (Explanation, error) {
+	e := Explanation{System: sp.System, Resolution: sp.Resolution}
+	data, err := json.Marshal(geojson.FeatureCollection{
+		Type:     "FeatureCollection",
+		Features: []geojson.Feature{{Type: "Feature", Geometry: f.Geometry, Properties: f.Properties}},
+	})
+	if err != nil {
+		return e, err
+	}
+
+	names := []string{"parse", "convert", "loop construction", "covering call", "post-processing", "serialization"}
+	samples := make([][]float64, len(names))
+	warm := true
+	record := func(stage int, start time.Time) {
+		if !warm {
+			samples[stage] = append(samples[stage], float64(time.Since(start)))
+		}
+	}
+	for round := range explainRounds + 1 {
+		warm = round == 0
+		start := time.Now()
+		fc, err := geojson.Parse(data)
+		record(0, start)
+		if err != nil {
+			return e, err
+		}
+		geometry := fc.Features[0].Geometry
+
+		var cells []uint64
+		if sp.System == bench.SystemH3 {
+			start = time.Now()
+			polygon, err := dh3.FromGeometry(geometry, geojson.IgnoreSkippedRing)
+			record(1, start)
+			if err != nil {
+				return e, err
+			}
+			start = time.Now()
+			for _, ring := range geometry.Coordinates {
+				dh3.LoopFromRing(ring)
+			}
+			record(2, start)
+
+			start = time.Now()
+			raw, err := h3.PolygonToCells(polygon, sp.Resolution)
+			record(3, start)
+			if err != nil {
+				return e, err
+			}
+			start = time.Now()
+			cells = make([]uint64, len(raw))
+			for i, c := range raw {
+				cells[i] = uint64(c)
+			}
+			record(4, start)
+		} else {
+			start = time.Now()
+			polygon, err := ds2.FromGeometry(geometry, geojson.IgnoreSkippedRing)
+			if err == nil {
+				// Any index query forces the pending shape index update
+				polygon.IntersectsCell(s2.CellFromCellID(s2.CellIDFromFace(0)))
+			}
+			record(1, start)
+			if err != nil {
+				return e, err
+			}
+			start = time.Now()
+			for _, ring := range geometry.Coordinates {
+				ds2.LoopFromRing(ring)
+			}
+			record(2, start)
+
+			start = time.Now()
+			rc := &s2.RegionCoverer{MinLevel: sp.Resolution, MaxLevel: sp.Resolution, MaxCells: maxCells, LevelMod: 1}
+			raw := rc.Covering(polygon)
+			record(3, start)
+			start = time.Now()
+			cells = make([]uint64, len(raw))
+			for i, c := range raw {
+				cells[i] = uint64(c)
+			}
+			record(4, start)
+		}
+
+		start = time.Now()
+		encoded, err := format.Encode(sp.System, cells)
+		record(5, start)
+		if err != nil {
+			return e, err
+		}
+		e.Cells, e.Bytes = len(cells), len(encoded)
+	}
+
+	for stage, name := range names {
+		slices.Sort(samples[stage])
+		e.Stages = append(e.Stages, StageTiming{
+			Stage:    name,
+			Duration: time.Duration(report.Percentile(samples[stage], 50)),
+			Part:     name == "loop construction",
+		})
+	}
+	return e, nil
+}
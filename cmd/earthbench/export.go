@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	earthbenchpb "github.com/nkk36/earth-discretization-benchmark/proto"
+)
+
+func runExportCommand(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "5", "9")
+	toPostGIS := fs.Bool("postgis", false, "write covering cell polygons into a PostGIS table")
+	dsn := fs.String("dsn", "", "PostgreSQL connection string for -postgis")
+	table := fs.String("table", "earthbench_coverings", "PostGIS table for -postgis")
+	appendRows := fs.Bool("append", false, "append to the -postgis table instead of recreating it")
+	bigQuery := fs.String("bigquery", "", "write S2 coverings as BigQuery newline-delimited JSON to this path")
+	bqMinLevel := fs.Int("bigquery-min-level", bigQueryMinLevel, "coarsest S2 level of the -bigquery coverings (S2_COVERINGCELLIDS min_level)")
+	bqMaxLevel := fs.Int("bigquery-max-level", bigQueryMaxLevel, "finest S2 level of the -bigquery coverings (S2_COVERINGCELLIDS max_level)")
+	bqMaxCells := fs.Int("bigquery-max-cells", bigQueryMaxCells, "cells per -bigquery covering (S2_COVERINGCELLIDS max_cells)")
+	html := fs.String("html", "", "write a self-contained deck.gl HTML page to this path")
+	protoOutput := fs.String("protobuf", "", "write the coverings as a CoveringSet message of proto/results.proto to this path")
+	wkt := fs.String("wkt", "", "write the outline of every covering as a WKT MULTIPOLYGON in a CSV at this path")
+	wkb := fs.String("wkb", "", "write the outline of every covering as hex-encoded WKB in a CSV at this path")
+	geoPackage := fs.String("geopackage", "", "write the source polygons, covering cells and per-feature metrics as GeoPackage layers to this path")
+	sqlite := fs.String("sqlite3", "sqlite3", "path to the sqlite3 CLI for -geopackage")
+	qaDir := fs.String("qa", "", "write one GeoJSON file per feature with its source polygon, coverings and their metrics to this directory")
+	features := fs.String("features", "", "feature IDs to export, e.g. 1-10 or 3,7 (default all)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if !*toPostGIS && *bigQuery == "" && *html == "" && *protoOutput == "" && *wkt == "" && *wkb == "" && *geoPackage == "" && *qaDir == "" {
+		return fmt.Errorf("choose an export format: -postgis, -bigquery <path>, -html <path>, -protobuf <path>, -wkt <path>, -wkb <path>, -geopackage <path> or -qa <dir>")
+	}
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d features from %s\n", len(ds.Features), ds.Path)
+	if *features != "" {
+		ids, err := parseIntRange(*features)
+		if err != nil {
+			return fmt.Errorf("-features: %w", err)
+		}
+		ds = ds.Subset(ids)
+	}
+
+	if *toPostGIS {
+		if *dsn == "" {
+			return fmt.Errorf("-postgis requires -dsn")
+		}
+		n, err := exportCoveringsToPostGIS(context.Background(), *dsn, *table, !*appendRows, ds, sweepPoints, *sweep.MaxCells)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %d cell polygons to %s\n", n, *table)
+	}
+	if *bigQuery != "" {
+		n, err := exportS2CoveringsForBigQuery(*bigQuery, ds, *bqMinLevel, *bqMaxLevel, *bqMaxCells)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %d S2 cell rows to %s\n", n, *bigQuery)
+	}
+	if *html != "" {
+		n, err := exportDeckGLHTML(*html, ds, sweepPoints, *sweep.MaxCells)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %d cells to %s\n", n, *html)
+	}
+	if *protoOutput != "" {
+		n, err := exportCoveringsProto(*protoOutput, ds, sweepPoints, *sweep.MaxCells)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %d cells to %s\n", n, *protoOutput)
+	}
+	for _, out := range []struct {
+		path string
+		wkb  bool
+	}{{*wkt, false}, {*wkb, true}} {
+		if out.path == "" {
+			continue
+		}
+		n, err := exportDissolvedCoverings(out.path, out.wkb, ds, sweepPoints, *sweep.MaxCells)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %d covering outlines to %s\n", n, out.path)
+	}
+	if *geoPackage != "" {
+		n, err := exportGeoPackage(*sqlite, *geoPackage, ds, sweepPoints, *sweep.MaxCells)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %d cells to %s\n", n, *geoPackage)
+	}
+	if *qaDir != "" {
+		n, err := exportQABundles(*qaDir, ds, sweepPoints, *sweep.MaxCells)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %d QA bundles to %s\n", n, *qaDir)
+	}
+	return nil
+}
+
+// exportCoveringsProto writes the coverings of every feature at every sweep
+// point as one CoveringSet message
+func exportCoveringsProto(path string, ds *bench.Dataset, sweepPoints []bench.SweepPoint, maxCells int) (int, error) {
+	set := &earthbenchpb.CoveringSet{Dataset: ds.Path}
+	written := 0
+	for _, sp := range sweepPoints {
+		coverings, err := bench.ComputeCoverings(ds, sp.System, sp.Resolution, maxCells)
+		if err != nil {
+			return 0, err
+		}
+		for i, f := range ds.Features {
+			set.Coverings = append(set.Coverings, &earthbenchpb.Covering{
+				FeatureId:  int64(f.FeatureID),
+				System:     systemToProto(sp.System),
+				Resolution: int32(sp.Resolution),
+				Cells:      coverings[i],
+			})
+			written += len(coverings[i])
+		}
+	}
+	return written, writeResultsFile(path, writeProto(set))
+}
+
+// exportCoveringsToPostGIS writes one row per covering cell with its
+// polygon so coverage quality can be inspected in QGIS against the source
+// features
+func exportCoveringsToPostGIS(ctx context.Context, dsn string, table string, recreate bool,
+	ds *bench.Dataset, sweepPoints []bench.SweepPoint, maxCells int) (int, error) {
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return 0, fmt.Errorf("connecting to postgres: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	ident := pgx.Identifier{table}.Sanitize()
+	setup := []string{"CREATE EXTENSION IF NOT EXISTS postgis"}
+	if recreate {
+		setup = append(setup, "DROP TABLE IF EXISTS "+ident)
+	}
+	setup = append(setup,
+		"CREATE TABLE IF NOT EXISTS "+ident+" (feature_id bigint NOT NULL, system text NOT NULL,"+
+			" resolution integer NOT NULL, cell text NOT NULL, geom geometry(Polygon, 4326) NOT NULL)")
+	for _, sql := range setup {
+		if _, err := conn.Exec(ctx, sql); err != nil {
+			return 0, err
+		}
+	}
+
+	insertSQL := "INSERT INTO " + ident + " (feature_id, system, resolution, cell, geom)" +
+		" VALUES ($1, $2, $3, $4, ST_GeomFromText($5, 4326))"
+	written := 0
+	for _, sp := range sweepPoints {
+		coverings, err := bench.ComputeCoverings(ds, sp.System, sp.Resolution, maxCells)
+		if err != nil {
+			return written, err
+		}
+		for i, f := range ds.Features {
+			batch := &pgx.Batch{}
+			for _, cell := range coverings[i] {
+				ring, err := cellBoundary(sp.System, cell)
+				if err != nil {
+					return written, err
+				}
+				batch.Queue(insertSQL, f.FeatureID, sp.System, sp.Resolution, cellToken(sp.System, cell), ringToWKT(ring))
+			}
+			if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+				return written, fmt.Errorf("feature %d: %w", f.FeatureID, err)
+			}
+			written += len(coverings[i])
+		}
+		fmt.Printf("%s res %d exported\n", sp.System, sp.Resolution)
+	}
+
+	if recreate {
+		if _, err := conn.Exec(ctx, "CREATE INDEX ON "+ident+" USING GIST (geom)"); err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// ringToWKT formats a closed [lon, lat] ring as a WKT POLYGON
+func ringToWKT(ring [][2]float64) string {
+	return polygonToWKT([][][2]float64{ring})
+}
+
+// polygonToWKT formats GeoJSON polygon rings (exterior first, then holes)
+// as a WKT POLYGON
+func polygonToWKT(rings [][][2]float64) string {
+	var b strings.Builder
+	b.WriteString("POLYGON(")
+	for r, ring := range rings {
+		if r > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteByte('(')
+		for i, p := range ring {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(strconv.FormatFloat(p[0], 'f', -1, 64))
+			b.WriteByte(' ')
+			b.WriteString(strconv.FormatFloat(p[1], 'f', -1, 64))
+		}
+		b.WriteByte(')')
+	}
+	b.WriteByte(')')
+	return b.String()
+}
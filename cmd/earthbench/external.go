@@ -0,0 +1,405 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+)
+
+// externalAdapter covers polygons with another implementation in a
+// subprocess. The program is run as
+//
+//	<command...> <polygons.geojson> <resolutions> <s2-max-cells> <repetitions>
+//
+// where the GeoJSON holds one Polygon feature per dataset feature with the
+// feature ID as its id, and resolutions is a comma-separated list. For every
+// repetition, resolution and feature, in that order, it prints
+//
+//	resolution,repetition,feature_id,cells,duration_ns
+//
+// to stdout, timing only the covering call with its own clock so process
+// start-up and parsing are left out. Other stdout lines starting with # are
+// ignored; anything else goes to stderr.
+type externalAdapter struct {
+	Name    string
+	System  string   // bench.SystemH3 or bench.SystemS2
+	Command []string // program and its leading arguments
+	Script  string   // when set, written to a file appended to Command
+}
+
+// externalAdapters are the built-in adapters, by name. Each needs python3
+// with the named package installed.
+var externalAdapters = []externalAdapter{
+	{Name: "python-h3", System: bench.SystemH3, Command: []string{"python3"}, Script: pythonH3Adapter},
+	{Name: "python-s2", System: bench.SystemS2, Command: []string{"python3"}, Script: pythonS2Adapter},
+	{Name: "duckdb-h3", System: bench.SystemH3, Command: []string{"python3"}, Script: duckDBH3Adapter},
+}
+
+// pythonH3Adapter covers with h3-py 4 (pip install h3), the Python binding
+// of the same C library as h3-go
+const pythonH3Adapter = `import json, sys, time
+import h3
+
+path, resolutions, repetitions = sys.argv[1], [int(r) for r in sys.argv[2].split(",")], int(sys.argv[4])
+with open(path) as f:
+    features = json.load(f)["features"]
+polygons = []
+for feature in features:
+    rings = [[(lat, lng) for lng, lat in ring] for ring in feature["geometry"]["coordinates"]]
+    polygons.append((feature["id"], h3.LatLngPoly(*rings)))
+for rep in range(repetitions):
+    for res in resolutions:
+        for fid, polygon in polygons:
+            start = time.perf_counter_ns()
+            cells = h3.polygon_to_cells(polygon, res)
+            elapsed = time.perf_counter_ns() - start
+            print(f"{res},{rep},{fid},{len(cells)},{elapsed}")
+`
+
+// pythonS2Adapter covers with pywraps2, the SWIG binding of the C++
+// s2geometry library, at a fixed level like discretize/s2.Cover
+const pythonS2Adapter = `import json, sys, time
+import pywraps2 as s2
+
+path, levels, max_cells, repetitions = sys.argv[1], [int(r) for r in sys.argv[2].split(",")], int(sys.argv[3]), int(sys.argv[4])
+with open(path) as f:
+    features = json.load(f)["features"]
+polygons = []
+for feature in features:
+    loops = []
+    for ring in feature["geometry"]["coordinates"]:
+        loop = s2.S2Loop([s2.S2LatLng.FromDegrees(lat, lng).ToPoint() for lng, lat in ring[:-1]])
+        loop.Normalize()
+        loops.append(loop)
+    polygon = s2.S2Polygon()
+    polygon.InitNested(loops)
+    polygons.append((feature["id"], polygon))
+for rep in range(repetitions):
+    for level in levels:
+        coverer = s2.S2RegionCoverer()
+        coverer.set_min_level(level)
+        coverer.set_max_level(level)
+        coverer.set_max_cells(max_cells)
+        for fid, polygon in polygons:
+            start = time.perf_counter_ns()
+            cells = coverer.GetCovering(polygon)
+            elapsed = time.perf_counter_ns() - start
+            print(f"{level},{rep},{fid},{len(cells)},{elapsed}")
+`
+
+// duckDBH3Adapter covers with the h3 community extension of DuckDB through
+// its Python client; the time includes the query round trip
+const duckDBH3Adapter = `import json, sys, time
+import duckdb
+
+path, resolutions, repetitions = sys.argv[1], [int(r) for r in sys.argv[2].split(",")], int(sys.argv[4])
+with open(path) as f:
+    features = json.load(f)["features"]
+con = duckdb.connect()
+con.execute("INSTALL h3 FROM community")
+con.execute("LOAD h3")
+polygons = []
+for feature in features:
+    rings = ", ".join("(" + ", ".join(f"{lng!r} {lat!r}" for lng, lat in ring) + ")" for ring in feature["geometry"]["coordinates"])
+    polygons.append((feature["id"], f"POLYGON ({rings})"))
+query = "SELECT len(h3_polygon_wkt_to_cells(?, ?))"
+for rep in range(repetitions):
+    for res in resolutions:
+        for fid, wkt in polygons:
+            start = time.perf_counter_ns()
+            cells = con.execute(query, [wkt, res]).fetchone()[0]
+            elapsed = time.perf_counter_ns() - start
+            print(f"{res},{rep},{fid},{cells},{elapsed}")
+`
+
+func runExternalCommand(args []string) error {
+	fs := flag.NewFlagSet("external", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "0-6", "0-11")
+	names := fs.String("adapters", "python-h3,python-s2,duckdb-h3", "comma-separated built-in adapters to run (empty for none)")
+	var custom []externalAdapter
+	fs.Func("adapter", "an external program as name=SYSTEM:command args..., e.g. rust-h3=H3:./h3cover (repeatable)", func(s string) error {
+		a, err := parseExternalAdapter(s)
+		if err != nil {
+			return err
+		}
+		custom = append(custom, a)
+		return nil
+	})
+	repetitions := fs.Int("repetitions", 3, "times each adapter covers every feature at every resolution")
+	baseline := fs.Bool("go", true, "also time the in-process Go coverings for comparison")
+	outputDir := fs.String("output-dir", "output/external", "directory for one results JSON per implementation, readable by diff")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	var adapters []externalAdapter
+	for _, name := range strings.Split(*names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		i := slices.IndexFunc(externalAdapters, func(a externalAdapter) bool { return a.Name == name })
+		if i < 0 {
+			return fmt.Errorf("unknown adapter %q", name)
+		}
+		adapters = append(adapters, externalAdapters[i])
+	}
+	adapters = append(adapters, custom...)
+	if len(adapters) == 0 {
+		return fmt.Errorf("no adapters selected")
+	}
+	if *repetitions < 1 {
+		return fmt.Errorf("-repetitions must be at least 1")
+	}
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d features from %s\n", len(ds.Features), ds.Path)
+	if err := os.MkdirAll(*outputDir, 0o755); err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "earthbench-external-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	polygonsPath := filepath.Join(dir, "polygons.geojson")
+	if err := writeExternalPolygons(polygonsPath, ds); err != nil {
+		return err
+	}
+
+	options := bench.CoveringOptions{MaxCells: *sweep.MaxCells}
+	runs := make(map[string]*bench.Results)
+	var order []string
+	if *baseline {
+		results := &bench.Results{Dataset: ds.Path, Options: options}
+		for rep := range *repetitions {
+			measurements, err := bench.BenchmarkCoverings(context.Background(), ds, sweepPoints, options)
+			if err != nil {
+				return err
+			}
+			for _, m := range measurements {
+				m.Repetition = rep
+				results.Measurements = append(results.Measurements, m)
+			}
+		}
+		runs["go"], order = results, append(order, "go")
+	}
+	for _, a := range adapters {
+		var points []bench.SweepPoint
+		for _, sp := range sweepPoints {
+			if sp.System == a.System {
+				points = append(points, sp)
+			}
+		}
+		if len(points) == 0 {
+			continue
+		}
+		fmt.Printf("Running %s...\n", a.Name)
+		start := time.Now()
+		results, err := runExternalAdapter(a, dir, polygonsPath, ds, points, options, *repetitions)
+		if err != nil {
+			log.Printf("Warning: skipping %s: %v", a.Name, err)
+			continue
+		}
+		fmt.Printf("  done in %s\n", time.Since(start).Round(time.Millisecond))
+		runs[a.Name], order = results, append(order, a.Name)
+	}
+	if len(runs) == 0 || (*baseline && len(runs) == 1) {
+		return fmt.Errorf("no adapter ran")
+	}
+
+	printExternalComparison(order, runs)
+	info := bench.CurrentRunInfo()
+	for _, name := range order {
+		runs[name].Info = &info
+		path := filepath.Join(*outputDir, name+".json")
+		if err := writeResultsFile(path, runs[name].WriteJSON); err != nil {
+			return err
+		}
+		fmt.Printf("Results saved to %s\n", path)
+	}
+	return nil
+}
+
+// parseExternalAdapter parses an -adapter flag
+func parseExternalAdapter(s string) (externalAdapter, error) {
+	name, rest, ok := strings.Cut(s, "=")
+	system, command, ok2 := strings.Cut(rest, ":")
+	if !ok || !ok2 || strings.TrimSpace(name) == "" || len(strings.Fields(command)) == 0 {
+		return externalAdapter{}, fmt.Errorf("want name=SYSTEM:command, got %q", s)
+	}
+	system, err := parseSystem(system)
+	if err != nil {
+		return externalAdapter{}, err
+	}
+	return externalAdapter{Name: strings.TrimSpace(name), System: system, Command: strings.Fields(command)}, nil
+}
+
+// writeExternalPolygons writes the dataset as the GeoJSON the adapters read
+func writeExternalPolygons(path string, ds *bench.Dataset) error {
+	fc := geojson.FeatureCollection{Type: "FeatureCollection"}
+	for _, f := range ds.Features {
+		fc.Features = append(fc.Features, geojson.Feature{
+			Type:       "Feature",
+			ID:         f.FeatureID,
+			Geometry:   geojson.Geometry{Type: "Polygon", Coordinates: f.Geometry.Coordinates},
+			Properties: map[string]interface{}{},
+		})
+	}
+	data, err := json.Marshal(fc)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// runExternalAdapter runs one adapter over the sweep points of its system
+// and collects its lines into results, one measurement per repetition and
+// resolution with the features in dataset order
+func runExternalAdapter(a externalAdapter, dir, polygonsPath string, ds *bench.Dataset, points []bench.SweepPoint, options bench.CoveringOptions, repetitions int) (*bench.Results, error) {
+	command := slices.Clone(a.Command)
+	if _, err := exec.LookPath(command[0]); err != nil {
+		return nil, err
+	}
+	if a.Script != "" {
+		script := filepath.Join(dir, a.Name+".py")
+		if err := os.WriteFile(script, []byte(a.Script), 0o644); err != nil {
+			return nil, err
+		}
+		command = append(command, script)
+	}
+	resolutions := make([]string, len(points))
+	for i, sp := range points {
+		resolutions[i] = strconv.Itoa(sp.Resolution)
+	}
+	command = append(command, polygonsPath, strings.Join(resolutions, ","), strconv.Itoa(options.MaxCells), strconv.Itoa(repetitions))
+
+	cmd := exec.Command(command[0], command[1:]...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, lastLine(stderr.String()))
+	}
+
+	areas := make(map[int]float64, len(ds.Features))
+	for _, f := range ds.Features {
+		areas[f.FeatureID] = f.AreaKm2()
+	}
+	type key struct{ res, rep int }
+	measurements := make(map[key]*bench.CoveringMeasurement)
+	var keys []key
+	if err := parseExternalLines(bytes.NewReader(out), func(res, rep, featureID, cells int, d time.Duration) error {
+		area, ok := areas[featureID]
+		if !ok {
+			return fmt.Errorf("unknown feature %d", featureID)
+		}
+		k := key{res, rep}
+		m := measurements[k]
+		if m == nil {
+			m = &bench.CoveringMeasurement{System: a.System, Resolution: res, Repetition: rep}
+			measurements[k] = m
+			keys = append(keys, k)
+		}
+		m.Cells += cells
+		m.AreaKm2 += area
+		m.Durations = append(m.Durations, d)
+		m.FeatureIDs = append(m.FeatureIDs, featureID)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if len(keys) != len(points)*repetitions {
+		return nil, fmt.Errorf("printed %d resolution-repetitions, want %d", len(keys), len(points)*repetitions)
+	}
+
+	results := &bench.Results{Dataset: ds.Path, Options: options}
+	for _, k := range keys {
+		if m := measurements[k]; len(m.Durations) != len(ds.Features) {
+			return nil, fmt.Errorf("resolution %d repetition %d: %d coverings, want %d", k.res, k.rep, len(m.Durations), len(ds.Features))
+		}
+		results.Measurements = append(results.Measurements, *measurements[k])
+	}
+	return results, nil
+}
+
+// parseExternalLines reads the resolution,repetition,feature_id,cells,
+// duration_ns lines of an adapter
+func parseExternalLines(r io.Reader, record func(res, rep, featureID, cells int, d time.Duration) error) error {
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, ",")
+		if len(fields) != 5 {
+			return fmt.Errorf("line %d: want 5 fields, got %q", line, text)
+		}
+		var values [5]int64
+		for i, field := range fields {
+			v, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+			values[i] = v
+		}
+		if err := record(int(values[0]), int(values[1]), int(values[2]), int(values[3]), time.Duration(values[4])); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// lastLine is the last non-empty line of s, where tracebacks put the error
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// printExternalComparison lists the mean covering time and cell count of
+// every implementation per sweep point, with the time relative to the first
+// implementation of that system and a note where cell counts differ
+func printExternalComparison(order []string, runs map[string]*bench.Results) {
+	fmt.Printf("%-12s %-3s %3s %14s %10s %12s\n", "IMPL", "SYS", "RES", "MEAN NS", "VS FIRST", "CELLS")
+	first := make(map[bench.SweepPoint]bench.Summary)
+	for _, name := range order {
+		for _, s := range runs[name].Summary() {
+			sp := bench.SweepPoint{System: s.System, Resolution: s.Resolution}
+			ratio, note := "", ""
+			if f, ok := first[sp]; ok {
+				ratio = fmt.Sprintf("%.2fx", s.MeanNs/f.MeanNs)
+				if s.Cells != f.Cells {
+					note = fmt.Sprintf(" (differs by %+d)", s.Cells-f.Cells)
+				}
+			} else {
+				first[sp] = s
+			}
+			fmt.Printf("%-12s %-3s %3d %14.0f %10s %12d%s\n", name, s.System, s.Resolution, s.MeanNs, ratio, s.Cells, note)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+)
+
+// FaceCovering is the S2 covering of one feature at one level and the cube
+// faces its cells lie on
+type FaceCovering struct {
+	FeatureID int
+	Level     int
+	Faces     []int
+	Cells     int
+	Duration  time.Duration // fastest round
+	AreaKm2   float64
+}
+
+// FaceGroup compares, at one level, the features whose coverings span a
+// cube-face boundary with those that stay on one face. Cell counts and
+// times are also given per polygon area since the two groups can differ
+// in size.
+type FaceGroup struct {
+	Level    int
+	Spanning bool
+	Features int
+	Cells    int
+	Duration time.Duration
+	AreaKm2  float64
+}
+
+// AverageDurationNs is the mean covering time per feature
+func (g FaceGroup) AverageDurationNs() float64 {
+	if g.Features == 0 {
+		return 0
+	}
+	return float64(g.Duration.Nanoseconds()) / float64(g.Features)
+}
+
+// CellsPerFeature is the mean covering size
+func (g FaceGroup) CellsPerFeature() float64 {
+	if g.Features == 0 {
+		return 0
+	}
+	return float64(g.Cells) / float64(g.Features)
+}
+
+// CellsPer1000Km2 is the covering size per polygon area
+func (g FaceGroup) CellsPer1000Km2() float64 {
+	if g.AreaKm2 == 0 {
+		return 0
+	}
+	return float64(g.Cells) / g.AreaKm2 * 1000
+}
+
+// NsPerKm2 is the covering time per polygon area
+func (g FaceGroup) NsPerKm2() float64 {
+	if g.AreaKm2 == 0 {
+		return 0
+	}
+	return float64(g.Duration.Nanoseconds()) / g.AreaKm2
+}
+
+func (g FaceGroup) label() string {
+	if g.Spanning {
+		return "spanning faces"
+	}
+	return "one face"
+}
+
+func runFacesCommand(args []string) error {
+	fs := flag.NewFlagSet("faces", flag.ExitOnError)
+	dataset := addDatasetFlags(fs)
+	s2Levels := fs.String("s2-levels", "4-11", "S2 levels to cover at")
+	maxCells := fs.Int("s2-max-cells", 8, "S2 RegionCoverer MaxCells")
+	rounds := fs.Int("rounds", 3, "times every covering is timed; the fastest is kept")
+	output := fs.String("output", "output/faces.csv", "CSV file comparing spanning and single-face features per level")
+	detail := fs.String("detail", "output/faces_features.csv", "CSV file of every feature's faces per level (empty to skip)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	levels, err := parseIntRange(*s2Levels)
+	if err != nil {
+		return fmt.Errorf("-s2-levels: %w", err)
+	}
+	if *rounds < 1 {
+		return fmt.Errorf("-rounds must be at least 1")
+	}
+	ds, err := dataset.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d features from %s\n", len(ds.Features), ds.Path)
+
+	var coverings []FaceCovering
+	var groups []FaceGroup
+	for _, level := range levels {
+		byGroup := [2]FaceGroup{{Level: level}, {Level: level, Spanning: true}}
+		for _, f := range ds.Features {
+			c := FaceCovering{FeatureID: f.FeatureID, Level: level, Duration: time.Duration(math.MaxInt64), AreaKm2: f.AreaKm2()}
+			var cells []uint64
+			for range *rounds {
+				start := time.Now()
+				if cells, err = bench.CoverFeature(f, bench.SystemS2, level, *maxCells); err != nil {
+					return fmt.Errorf("level %d, feature %d: %w", level, f.FeatureID, err)
+				}
+				c.Duration = min(c.Duration, time.Since(start))
+			}
+			c.Cells = len(cells)
+			for _, cell := range cells {
+				if face := s2.CellID(cell).Face(); !slices.Contains(c.Faces, face) {
+					c.Faces = append(c.Faces, face)
+				}
+			}
+			slices.Sort(c.Faces)
+			coverings = append(coverings, c)
+
+			g := &byGroup[0]
+			if len(c.Faces) > 1 {
+				g = &byGroup[1]
+			}
+			g.Features++
+			g.Cells += c.Cells
+			g.Duration += c.Duration
+			g.AreaKm2 += c.AreaKm2
+		}
+		for _, g := range byGroup {
+			if g.Features == 0 {
+				continue
+			}
+			fmt.Printf("S2 level %2d %-14s: %4d features, %10.0f ns/covering, %9.1f cells/feature, %9.3f cells/1000 km², %8.3f ns/km²\n",
+				g.Level, g.label(), g.Features, g.AverageDurationNs(), g.CellsPerFeature(), g.CellsPer1000Km2(), g.NsPerKm2())
+			groups = append(groups, g)
+		}
+	}
+
+	if err := saveFaceGroupsToCSV(*output, groups); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	if *detail != "" {
+		if err := saveFaceCoveringsToCSV(*detail, coverings); err != nil {
+			return err
+		}
+		fmt.Printf("Per-feature faces saved to %s\n", *detail)
+	}
+	return nil
+}
+
+// saveFaceGroupsToCSV writes one row per level and group
+func saveFaceGroupsToCSV(filename string, groups []FaceGroup) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"Level", "SpansFaces", "Features", "Cells", "AreaKm2", "AverageDurationNs", "CellsPerFeature", "CellsPer1000Km2", "NsPerKm2"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, g := range groups {
+		row := []string{
+			strconv.Itoa(g.Level),
+			strconv.FormatBool(g.Spanning),
+			strconv.Itoa(g.Features),
+			strconv.Itoa(g.Cells),
+			strconv.FormatFloat(g.AreaKm2, 'f', -1, 64),
+			strconv.FormatFloat(g.AverageDurationNs(), 'f', 0, 64),
+			strconv.FormatFloat(g.CellsPerFeature(), 'f', -1, 64),
+			strconv.FormatFloat(g.CellsPer1000Km2(), 'f', -1, 64),
+			strconv.FormatFloat(g.NsPerKm2(), 'f', -1, 64),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
+
+// saveFaceCoveringsToCSV writes one row per feature and level
+func saveFaceCoveringsToCSV(filename string, coverings []FaceCovering) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"FeatureID", "Level", "Faces", "Cells", "DurationNs", "AreaKm2"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, c := range coverings {
+		faces := make([]string, len(c.Faces))
+		for i, face := range c.Faces {
+			faces[i] = strconv.Itoa(face)
+		}
+		row := []string{
+			strconv.Itoa(c.FeatureID),
+			strconv.Itoa(c.Level),
+			strings.Join(faces, ";"),
+			strconv.Itoa(c.Cells),
+			strconv.FormatInt(c.Duration.Nanoseconds(), 10),
+			strconv.FormatFloat(c.AreaKm2, 'f', -1, 64),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
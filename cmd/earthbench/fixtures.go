@@ -0,0 +1,224 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"slices"
+	"strings"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/nkk36/earth-discretization-benchmark/data"
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+)
+
+// fixtureModules are the libraries whose behavior the fixtures pin down
+var fixtureModules = []string{"github.com/uber/h3-go/v4", "github.com/golang/geo"}
+
+// CoveringFixtures is a golden file of coverings: a checksum of the cells
+// of every feature at every resolution, with the library versions and
+// dataset they were recorded from
+type CoveringFixtures struct {
+	Dataset       string            `json:"dataset"`
+	DatasetSHA256 string            `json:"dataset_sha256"`
+	S2MaxCells    int               `json:"s2_max_cells"`
+	Modules       map[string]string `json:"modules"`
+	Coverings     []CoveringFixture `json:"coverings,omitempty"`
+}
+
+// CoveringFixture is the expected covering of one feature at one resolution
+type CoveringFixture struct {
+	System     string `json:"system"`
+	Resolution int    `json:"resolution"`
+	FeatureID  int    `json:"feature_id"`
+	Cells      int    `json:"cells"`
+	Checksum   string `json:"checksum"`
+}
+
+func (c CoveringFixture) key() string {
+	return fmt.Sprintf("%s/%d/%d", c.System, c.Resolution, c.FeatureID)
+}
+
+func runVerifyCommand(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "0-5", "0-10")
+	fixtures := fs.String("fixtures", "data/coverings.golden.json", "golden file of expected coverings")
+	update := fs.Bool("update", false, "record the current coverings as the new golden file instead of comparing")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	source := data.SamplePolygons
+	if ds.Path != embeddedInput {
+		if source, err = geojson.ReadData(ds.Path); err != nil {
+			return err
+		}
+	}
+	sum := sha256.Sum256(source)
+	current := CoveringFixtures{
+		Dataset:       ds.Path,
+		DatasetSHA256: hex.EncodeToString(sum[:]),
+		S2MaxCells:    *sweep.MaxCells,
+		Modules:       moduleVersions(),
+	}
+	for _, sp := range sweepPoints {
+		coverings, err := bench.ComputeCoverings(ds, sp.System, sp.Resolution, *sweep.MaxCells)
+		if err != nil {
+			return fmt.Errorf("%s resolution %d: %w", sp.System, sp.Resolution, err)
+		}
+		for i, cells := range coverings {
+			current.Coverings = append(current.Coverings, CoveringFixture{
+				System:     sp.System,
+				Resolution: sp.Resolution,
+				FeatureID:  ds.Features[i].FeatureID,
+				Cells:      len(cells),
+				Checksum:   coveringChecksum(cells),
+			})
+		}
+	}
+
+	if *update {
+		if err := writeFixtures(*fixtures, current); err != nil {
+			return err
+		}
+		fmt.Printf("Recorded %d coverings to %s\n", len(current.Coverings), *fixtures)
+		return nil
+	}
+
+	raw, err := os.ReadFile(*fixtures)
+	if err != nil {
+		return fmt.Errorf("reading fixtures (run with -update to record them): %w", err)
+	}
+	var golden CoveringFixtures
+	if err := json.Unmarshal(raw, &golden); err != nil {
+		return fmt.Errorf("parsing %s: %w", *fixtures, err)
+	}
+	return compareFixtures(golden, current)
+}
+
+// writeFixtures writes the golden file with one covering per line, so a
+// re-record shows up in a diff as the changed lines only
+func writeFixtures(filename string, f CoveringFixtures) error {
+	coverings := f.Coverings
+	f.Coverings = nil
+	header, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	// Reopen the object to append the coverings array
+	b.Write(header[:len(header)-2])
+	b.WriteString(",\n  \"coverings\": [\n")
+	for i, c := range coverings {
+		line, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		b.WriteString("    ")
+		b.Write(line)
+		if i < len(coverings)-1 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('\n')
+	}
+	b.WriteString("  ]\n}\n")
+	return os.WriteFile(filename, []byte(b.String()), 0o644)
+}
+
+// moduleVersions reports the versions of fixtureModules linked into the
+// binary
+func moduleVersions() map[string]string {
+	versions := make(map[string]string)
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return versions
+	}
+	for _, dep := range info.Deps {
+		if slices.Contains(fixtureModules, dep.Path) {
+			versions[dep.Path] = dep.Version
+		}
+	}
+	return versions
+}
+
+// coveringChecksum hashes the sorted cell IDs so cell order does not matter
+func coveringChecksum(cells []uint64) string {
+	sorted := slices.Clone(cells)
+	slices.Sort(sorted)
+	h := sha256.New()
+	var buf [8]byte
+	for _, c := range sorted {
+		binary.BigEndian.PutUint64(buf[:], c)
+		h.Write(buf[:])
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// compareFixtures reports every covering that differs from the golden file.
+// Coverings only in the current run (resolutions the golden file does not
+// have) are listed but are not drift.
+func compareFixtures(golden, current CoveringFixtures) error {
+	if golden.DatasetSHA256 != current.DatasetSHA256 {
+		fmt.Printf("Warning: the dataset differs from the one the fixtures were recorded from (%s); differences below may be data changes\n", golden.Dataset)
+	}
+	if golden.S2MaxCells != current.S2MaxCells {
+		fmt.Printf("Warning: fixtures were recorded with -s2-max-cells %d, comparing with %d\n", golden.S2MaxCells, current.S2MaxCells)
+	}
+	for _, module := range fixtureModules {
+		if golden.Modules[module] != current.Modules[module] {
+			fmt.Printf("%s: %s -> %s\n", module, golden.Modules[module], current.Modules[module])
+		}
+	}
+
+	expected := make(map[string]CoveringFixture, len(golden.Coverings))
+	for _, c := range golden.Coverings {
+		expected[c.key()] = c
+	}
+	changed, unrecorded := 0, 0
+	perResolution := make(map[string]int)
+	var order []string
+	for _, c := range current.Coverings {
+		want, ok := expected[c.key()]
+		if !ok {
+			unrecorded++
+			continue
+		}
+		if want.Checksum == c.Checksum {
+			continue
+		}
+		changed++
+		res := fmt.Sprintf("%s res %d", c.System, c.Resolution)
+		if perResolution[res] == 0 {
+			order = append(order, res)
+		}
+		perResolution[res]++
+		fmt.Printf("  %s feature %d: %d -> %d cells\n", res, c.FeatureID, want.Cells, c.Cells)
+	}
+
+	fmt.Printf("Compared %d coverings: %d changed", len(current.Coverings)-unrecorded, changed)
+	if unrecorded > 0 {
+		fmt.Printf(", %d not in the fixtures", unrecorded)
+	}
+	fmt.Println()
+	if changed == 0 {
+		return nil
+	}
+	var summary []string
+	for _, res := range order {
+		summary = append(summary, fmt.Sprintf("%s: %d", res, perResolution[res]))
+	}
+	return fmt.Errorf("covering output drifted (%s)", strings.Join(summary, ", "))
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+)
+
+// GenDataParams are the parameters a generated dataset is drawn from. They
+// are written into the file as its "generator" member, so a dataset states
+// how it was produced and the same file can be drawn again.
+type GenDataParams struct {
+	Count       int     `json:"count"`
+	Seed        int64   `json:"seed"`
+	MinSizeKm   float64 `json:"min_size_km"`
+	MaxSizeKm   float64 `json:"max_size_km"`
+	SizeDist    string  `json:"size_dist"`
+	MinVertices int     `json:"min_vertices"`
+	MaxVertices int     `json:"max_vertices"`
+	Circles     bool    `json:"circles"`
+	Ovals       bool    `json:"ovals"`
+	HoleProb    float64 `json:"hole_prob"`
+	BBox        string  `json:"bbox"`
+}
+
+// generatedCollection is a FeatureCollection with the generator parameters
+// as a foreign member, which readers of the file ignore
+type generatedCollection struct {
+	geojson.FeatureCollection
+	Generator GenDataParams `json:"generator"`
+}
+
+// genBounds is the region the polygons are drawn in, in degrees
+type genBounds struct {
+	minLng, minLat, maxLng, maxLat float64
+}
+
+func runGenDataCommand(args []string) error {
+	fs := flag.NewFlagSet("gen-data", flag.ExitOnError)
+	output := fs.String("output", "output/generated_polygons.geojson", "GeoJSON file to write ('-' for stdout)")
+	p := GenDataParams{}
+	fs.IntVar(&p.Count, "count", 250, "polygons to generate")
+	fs.Int64Var(&p.Seed, "seed", 123, "generator seed")
+	fs.Float64Var(&p.MinSizeKm, "min-size-km", 1, "smallest polygon radius")
+	fs.Float64Var(&p.MaxSizeKm, "max-size-km", 315, "largest polygon radius")
+	fs.StringVar(&p.SizeDist, "size-dist", "uniform", "distribution of radii between the bounds: uniform, or log for as many small polygons as large")
+	fs.IntVar(&p.MinVertices, "min-vertices", 4, "fewest sides of the regular n-gons")
+	fs.IntVar(&p.MaxVertices, "max-vertices", 4, "most sides of the regular n-gons")
+	fs.BoolVar(&p.Circles, "circles", true, "include 64-vertex circles among the shapes")
+	fs.BoolVar(&p.Ovals, "ovals", true, "include 64-vertex ovals among the shapes")
+	fs.Float64Var(&p.HoleProb, "hole-prob", 0, "probability that a polygon gets a hole")
+	fs.StringVar(&p.BBox, "bbox", "-180,-85,180,85", "region the polygons lie in, as minLng,minLat,maxLng,maxLat")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	fc, err := generatePolygons(p)
+	if err != nil {
+		return err
+	}
+	w, err := createOutput(*output)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(generatedCollection{FeatureCollection: fc, Generator: p}); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if *output != "-" {
+		fmt.Printf("Generated %d polygons to %s\n", len(fc.Features), *output)
+		fmt.Printf("Regenerate with: earthbench gen-data %s\n", p.flags())
+	}
+	return nil
+}
+
+// flags returns the command-line flags that draw the dataset again
+func (p GenDataParams) flags() string {
+	return strings.Join([]string{
+		fmt.Sprintf("-count %d -seed %d", p.Count, p.Seed),
+		fmt.Sprintf("-min-size-km %g -max-size-km %g -size-dist %s", p.MinSizeKm, p.MaxSizeKm, p.SizeDist),
+		fmt.Sprintf("-min-vertices %d -max-vertices %d -circles=%t -ovals=%t", p.MinVertices, p.MaxVertices, p.Circles, p.Ovals),
+		fmt.Sprintf("-hole-prob %g -bbox %s", p.HoleProb, p.BBox),
+	}, " ")
+}
+
+// generatePolygons draws the dataset the way data/generate_mock_polygons.py
+// does: n-gons, circles and ovals around uniformly drawn centers, with
+// radii in km turned into degrees at the center latitude, redrawn until
+// they fit the region without wrapping the antimeridian or reaching a
+// pole. The same parameters always give the same file.
+func generatePolygons(p GenDataParams) (geojson.FeatureCollection, error) {
+	if p.Count < 1 {
+		return geojson.FeatureCollection{}, fmt.Errorf("-count must be at least 1")
+	}
+	if p.MinSizeKm <= 0 || p.MaxSizeKm < p.MinSizeKm {
+		return geojson.FeatureCollection{}, fmt.Errorf("sizes must satisfy 0 < -min-size-km <= -max-size-km")
+	}
+	if p.SizeDist != "uniform" && p.SizeDist != "log" {
+		return geojson.FeatureCollection{}, fmt.Errorf("unknown -size-dist %q (want uniform or log)", p.SizeDist)
+	}
+	if p.MinVertices < 3 || p.MaxVertices < p.MinVertices {
+		return geojson.FeatureCollection{}, fmt.Errorf("vertices must satisfy 3 <= -min-vertices <= -max-vertices")
+	}
+	if p.HoleProb < 0 || p.HoleProb > 1 {
+		return geojson.FeatureCollection{}, fmt.Errorf("-hole-prob must be between 0 and 1")
+	}
+	rect, err := bench.ParseBBox(p.BBox)
+	if err != nil {
+		return geojson.FeatureCollection{}, fmt.Errorf("-bbox: %w", err)
+	}
+	if rect.Lng.IsInverted() {
+		return geojson.FeatureCollection{}, fmt.Errorf("-bbox %q crosses the antimeridian, which generated polygons may not", p.BBox)
+	}
+	bounds := genBounds{
+		minLng: rect.Lo().Lng.Degrees(), minLat: rect.Lo().Lat.Degrees(),
+		maxLng: rect.Hi().Lng.Degrees(), maxLat: rect.Hi().Lat.Degrees(),
+	}
+
+	shapes := []string{}
+	for n := p.MinVertices; n <= p.MaxVertices; n++ {
+		shapes = append(shapes, fmt.Sprintf("%d-gon", n))
+	}
+	if p.Circles {
+		shapes = append(shapes, "circle")
+	}
+	if p.Ovals {
+		shapes = append(shapes, "oval")
+	}
+
+	rng := rand.New(rand.NewSource(p.Seed))
+	fc := geojson.FeatureCollection{Type: "FeatureCollection"}
+	for i := 0; i < p.Count; i++ {
+		feature, ok := generatePolygon(rng, p, bounds, shapes)
+		if !ok {
+			return geojson.FeatureCollection{}, fmt.Errorf("polygon %d: no center in -bbox %s fits a polygon of %g-%g km after %d attempts",
+				i+1, p.BBox, p.MinSizeKm, p.MaxSizeKm, maxGenAttempts)
+		}
+		feature.Properties["id"] = i + 1
+		fc.Features = append(fc.Features, feature)
+	}
+	return fc, nil
+}
+
+// maxGenAttempts bounds the redraws of one polygon that does not fit
+const maxGenAttempts = 100
+
+func generatePolygon(rng *rand.Rand, p GenDataParams, b genBounds, shapes []string) (geojson.Feature, bool) {
+	for attempt := 0; attempt < maxGenAttempts; attempt++ {
+		centerLat := b.minLat + rng.Float64()*(b.maxLat-b.minLat)
+		centerLng := b.minLng + rng.Float64()*(b.maxLng-b.minLng)
+		sizeKm := p.MinSizeKm + rng.Float64()*(p.MaxSizeKm-p.MinSizeKm)
+		if p.SizeDist == "log" {
+			sizeKm = p.MinSizeKm * math.Pow(p.MaxSizeKm/p.MinSizeKm, rng.Float64())
+		}
+		latRadius := sizeKm / 111.0
+		lngRadius := sizeKm / (111.0 * math.Cos(centerLat*math.Pi/180))
+
+		shape := shapes[rng.Intn(len(shapes))]
+		// rx and ry are the semi-axes of the ellipse the vertices lie on
+		rx, ry, vertices := lngRadius, latRadius, 64
+		switch shape {
+		case "circle":
+			rx = (latRadius + lngRadius) / 2
+			ry = rx
+		case "oval":
+			if rng.Intn(2) == 0 {
+				rx, ry = lngRadius*1.5, latRadius*0.7
+			} else {
+				rx, ry = lngRadius*0.7, latRadius*1.5
+			}
+		default:
+			fmt.Sscanf(shape, "%d-gon", &vertices)
+		}
+		if centerLng-rx < b.minLng || centerLng+rx > b.maxLng || centerLat-ry < b.minLat || centerLat+ry > b.maxLat {
+			continue
+		}
+
+		rings := [][][2]float64{ellipseRing(centerLng, centerLat, rx, ry, vertices)}
+		holes := 0
+		if p.HoleProb > 0 && rng.Float64() < p.HoleProb {
+			// A hole of a third to two thirds the size, wound clockwise
+			scale := 1.0/3 + rng.Float64()/3
+			hole := ellipseRing(centerLng, centerLat, rx*scale, ry*scale, vertices)
+			for i, j := 0, len(hole)-1; i < j; i, j = i+1, j-1 {
+				hole[i], hole[j] = hole[j], hole[i]
+			}
+			rings = append(rings, hole)
+			holes = 1
+		}
+		return geojson.Feature{
+			Type:     "Feature",
+			Geometry: geojson.Geometry{Type: "Polygon", Coordinates: rings},
+			Properties: map[string]interface{}{
+				"shape":   shape,
+				"size_km": sizeKm,
+				"holes":   holes,
+			},
+		}, true
+	}
+	return geojson.Feature{}, false
+}
+
+// ellipseRing is a closed counter-clockwise ring of n vertices on the
+// ellipse with semi-axes rx and ry degrees around a center
+func ellipseRing(centerLng, centerLat, rx, ry float64, n int) [][2]float64 {
+	ring := make([][2]float64, 0, n+1)
+	for i := 0; i < n; i++ {
+		angle := 2 * math.Pi * float64(i) / float64(n)
+		ring = append(ring, [2]float64{centerLng + rx*math.Cos(angle), centerLat + ry*math.Sin(angle)})
+	}
+	return append(ring, ring[0])
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+)
+
+// The point distributions of gen-points
+const (
+	pointsSphere    = "sphere"
+	pointsUniform   = "uniform"
+	pointsClustered = "clustered"
+	pointsDensity   = "density"
+)
+
+func runGenPointsCommand(args []string) error {
+	fs := flag.NewFlagSet("gen-points", flag.ExitOnError)
+	dataset := addDatasetFlags(fs)
+	output := fs.String("output", "output/points.csv", "file to write: .csv (lng,lat), .geojson or '-' for CSV on stdout; .gz compresses")
+	n := fs.Int("n", 1000000, "points to generate")
+	seed := fs.Int64("seed", 1, "generator seed")
+	distribution := fs.String("distribution", pointsUniform, "sphere (uniform over the globe), uniform (in degrees over the dataset bounds), clustered (around random centers in the bounds) or density (inside the polygons, weighted by area)")
+	clusters := fs.Int("clusters", 50, "cluster centers for -distribution clustered")
+	clusterKm := fs.Float64("cluster-km", 25, "standard deviation of the distance from a cluster center")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *n < 1 {
+		return fmt.Errorf("-n must be at least 1")
+	}
+
+	var points []s2.LatLng
+	if *distribution == pointsSphere {
+		points = bench.RandomPointsOnSphere(*n, *seed)
+	} else {
+		ds, err := dataset.LoadDataset()
+		if err != nil {
+			return err
+		}
+		switch *distribution {
+		case pointsUniform:
+			points = bench.RandomPointsInRect(ds.Bounds(), *n, *seed)
+		case pointsClustered:
+			if *clusters < 1 || *clusterKm <= 0 {
+				return fmt.Errorf("-clusters and -cluster-km must be positive")
+			}
+			points = bench.ClusteredPoints(ds.Bounds(), *n, *clusters, *clusterKm, *seed)
+		case pointsDensity:
+			if points, err = bench.PointsInFeatures(ds, *n, *seed); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown -distribution %q (want %s, %s, %s or %s)", *distribution, pointsSphere, pointsUniform, pointsClustered, pointsDensity)
+		}
+	}
+
+	if err := writePoints(*output, points); err != nil {
+		return err
+	}
+	if *output != "-" {
+		fmt.Printf("Wrote %d %s points to %s\n", len(points), *distribution, *output)
+	}
+	return nil
+}
+
+// isGeoJSONPath reports whether a points file is GeoJSON rather than CSV
+func isGeoJSONPath(path string) bool {
+	path = strings.TrimSuffix(path, ".gz")
+	return strings.HasSuffix(path, ".geojson") || strings.HasSuffix(path, ".json")
+}
+
+// writePoints writes points as a CSV of lng,lat or, by extension, as a
+// GeoJSON FeatureCollection of Points
+func writePoints(filename string, points []s2.LatLng) error {
+	w, err := createOutput(filename)
+	if err != nil {
+		return err
+	}
+	if isGeoJSONPath(filename) {
+		err = writePointsGeoJSON(w, points)
+	} else {
+		err = writePointsCSV(w, points)
+	}
+	if err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func writePointsCSV(w io.Writer, points []s2.LatLng) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+	if err := writer.Write([]string{"lng", "lat"}); err != nil {
+		return err
+	}
+	for _, ll := range points {
+		if err := writer.Write([]string{
+			strconv.FormatFloat(ll.Lng.Degrees(), 'f', 7, 64),
+			strconv.FormatFloat(ll.Lat.Degrees(), 'f', 7, 64),
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// pointFeature is the part of a GeoJSON Point feature points files use;
+// geojson.Geometry decodes only Polygon coordinates
+type pointFeature struct {
+	Type     string `json:"type"`
+	Geometry struct {
+		Type        string     `json:"type"`
+		Coordinates [2]float64 `json:"coordinates"`
+	} `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+func writePointsGeoJSON(w io.Writer, points []s2.LatLng) error {
+	features := make([]pointFeature, len(points))
+	for i, ll := range points {
+		features[i].Type = "Feature"
+		features[i].Geometry.Type = "Point"
+		features[i].Geometry.Coordinates = [2]float64{ll.Lng.Degrees(), ll.Lat.Degrees()}
+		features[i].Properties = map[string]interface{}{}
+	}
+	return json.NewEncoder(w).Encode(struct {
+		Type     string         `json:"type"`
+		Features []pointFeature `json:"features"`
+	}{"FeatureCollection", features})
+}
+
+// readPoints reads a points file as written by gen-points: a CSV whose
+// first two columns are longitude and latitude, with or without a header,
+// or a GeoJSON FeatureCollection whose Point features are kept
+func readPoints(filename string) ([]s2.LatLng, error) {
+	data, err := geojson.ReadData(filename)
+	if err != nil {
+		return nil, err
+	}
+	var points []s2.LatLng
+	if isGeoJSONPath(filename) {
+		var fc struct {
+			Features []pointFeature `json:"features"`
+		}
+		if err := json.Unmarshal(data, &fc); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		for _, f := range fc.Features {
+			if f.Geometry.Type == "Point" {
+				points = append(points, s2.LatLngFromDegrees(f.Geometry.Coordinates[1], f.Geometry.Coordinates[0]))
+			}
+		}
+	} else {
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.FieldsPerRecord = -1
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		for i, record := range records {
+			if len(record) < 2 {
+				return nil, fmt.Errorf("%s line %d: want lng,lat", filename, i+1)
+			}
+			lng, errLng := strconv.ParseFloat(strings.TrimSpace(record[0]), 64)
+			lat, errLat := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+			if errLng != nil || errLat != nil {
+				if i == 0 {
+					continue // header
+				}
+				return nil, fmt.Errorf("%s line %d: %q is not lng,lat", filename, i+1, strings.Join(record, ","))
+			}
+			points = append(points, s2.LatLngFromDegrees(lat, lng))
+		}
+	}
+	if len(points) == 0 {
+		return nil, fmt.Errorf("%s has no points", filename)
+	}
+	return points, nil
+}
+
+// benchmarkPoints returns the points of a -points-file when one is given,
+// and otherwise n points drawn uniformly (in degrees) from rect
+func benchmarkPoints(file string, rect s2.Rect, n int, seed int64) ([]s2.LatLng, error) {
+	if file == "" {
+		return bench.RandomPointsInRect(rect, n, seed), nil
+	}
+	points, err := readPoints(file)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Read %d points from %s\n", len(points), file)
+	return points, nil
+}
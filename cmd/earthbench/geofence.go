@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/nkk36/earth-discretization-benchmark/report"
+)
+
+// GeofenceResult summarises one streaming run. Latencies are measured from
+// the moment a ping is emitted to the moment it has been matched, so they
+// include time spent queued behind earlier pings.
+type GeofenceResult struct {
+	System     string
+	Resolution int
+	Pings      int
+	Matches    int
+	Elapsed    time.Duration
+	P50        time.Duration
+	P95        time.Duration
+	P99        time.Duration
+	P999       time.Duration
+	Max        time.Duration
+}
+
+// PingsPerSecond is the sustained ping throughput
+func (r GeofenceResult) PingsPerSecond() float64 {
+	return float64(r.Pings) / r.Elapsed.Seconds()
+}
+
+// MatchesPerSecond is the sustained rate of pings matched to a geofence
+func (r GeofenceResult) MatchesPerSecond() float64 {
+	return float64(r.Matches) / r.Elapsed.Seconds()
+}
+
+type ping struct {
+	ll      s2.LatLng
+	emitted time.Time
+}
+
+func runGeofenceCommand(args []string) error {
+	fs := flag.NewFlagSet("geofence", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "7", "12")
+	output := fs.String("output", "output/geofence.csv", "CSV file for the results")
+	duration := fs.Duration("duration", 10*time.Second, "how long to stream pings per system/resolution")
+	rate := fs.Int("rate", 0, "target pings per second (0 streams as fast as the matchers keep up)")
+	devices := fs.Int("devices", 10000, "number of simulated devices")
+	step := fs.Float64("step-m", 50, "mean distance a device moves between pings, in meters")
+	workers := fs.Int("workers", 1, "number of matcher goroutines")
+	seed := fs.Int64("seed", 1, "seed for device start positions and movement")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d geofences from %s\n", len(ds.Features), ds.Path)
+
+	var results []GeofenceResult
+	for _, sp := range sweepPoints {
+		cells := make(map[uint64][]int)
+		for i, f := range ds.Features {
+			covering, err := bench.CoverFeature(f, sp.System, sp.Resolution, *sweep.MaxCells)
+			if err != nil {
+				return fmt.Errorf("%s resolution %d, feature %d: %w", sp.System, sp.Resolution, f.FeatureID, err)
+			}
+			for _, cell := range covering {
+				cells[cell] = append(cells[cell], i)
+			}
+		}
+
+		r := simulateGeofencing(ds, sp, cells, *duration, *rate, *devices, *step, *workers, *seed)
+		fmt.Printf("%s res %2d: %9d pings (%.0f/s), %8d matches (%.0f/s), p50 %v p95 %v p99 %v p99.9 %v max %v\n",
+			r.System, r.Resolution, r.Pings, r.PingsPerSecond(), r.Matches, r.MatchesPerSecond(),
+			r.P50, r.P95, r.P99, r.P999, r.Max)
+		results = append(results, r)
+	}
+
+	if err := saveGeofenceResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// simulateGeofencing streams pings from randomly walking devices through a
+// buffered channel to the matchers for the given duration. A ping matches
+// when its cell is in the index and the exact test against one of the
+// candidate geofences succeeds.
+func simulateGeofencing(ds *bench.Dataset, sp bench.SweepPoint, cells map[uint64][]int, duration time.Duration, rate, devices int, stepMeters float64, workers int, seed int64) GeofenceResult {
+	pings := make(chan ping, 4096)
+	go producePings(pings, ds.Bounds(), duration, rate, devices, stepMeters, seed)
+
+	var mu sync.Mutex
+	var latencies []float64
+	matches := 0
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var local []float64
+			localMatches := 0
+			for p := range pings {
+				cell, err := bench.PointCell(sp.System, p.ll, sp.Resolution)
+				if err == nil {
+					if candidates := cells[cell]; len(candidates) > 0 {
+						pt := s2.PointFromLatLng(p.ll)
+						for _, i := range candidates {
+							if ds.Features[i].S2Polygon.ContainsPoint(pt) {
+								localMatches++
+								break
+							}
+						}
+					}
+				}
+				local = append(local, float64(time.Since(p.emitted)))
+			}
+			mu.Lock()
+			latencies = append(latencies, local...)
+			matches += localMatches
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	result := GeofenceResult{
+		System:     sp.System,
+		Resolution: sp.Resolution,
+		Pings:      len(latencies),
+		Matches:    matches,
+		Elapsed:    time.Since(start),
+	}
+	if len(latencies) > 0 {
+		sort.Float64s(latencies)
+		result.P50 = time.Duration(report.Percentile(latencies, 50))
+		result.P95 = time.Duration(report.Percentile(latencies, 95))
+		result.P99 = time.Duration(report.Percentile(latencies, 99))
+		result.P999 = time.Duration(report.Percentile(latencies, 99.9))
+		result.Max = time.Duration(latencies[len(latencies)-1])
+	}
+	return result
+}
+
+// producePings moves every device by a random step in turn and emits its
+// new position, closing out once duration has passed. With a target rate,
+// pings are emitted in 1ms batches.
+func producePings(out chan<- ping, bounds s2.Rect, duration time.Duration, rate, devices int, stepMeters float64, seed int64) {
+	defer close(out)
+	rng := rand.New(rand.NewSource(seed))
+	positions := bench.RandomPointsInRect(bounds, devices, seed)
+	stepRad := stepMeters / 1000 / bench.EarthRadiusKm
+
+	next := 0
+	emit := func() {
+		ll := positions[next]
+		heading := rng.Float64() * 2 * math.Pi
+		dist := rng.ExpFloat64() * stepRad
+		lat := ll.Lat.Radians() + dist*math.Cos(heading)
+		lng := ll.Lng.Radians() + dist*math.Sin(heading)/math.Max(math.Cos(ll.Lat.Radians()), 1e-6)
+		// Keep devices inside the dataset bounds
+		lat = math.Max(bounds.Lat.Lo, math.Min(bounds.Lat.Hi, lat))
+		lng = math.Max(bounds.Lng.Lo, math.Min(bounds.Lng.Hi, lng))
+		ll = s2.LatLng{Lat: s1.Angle(lat), Lng: s1.Angle(lng)}
+		positions[next] = ll
+		next = (next + 1) % devices
+		out <- ping{ll: ll, emitted: time.Now()}
+	}
+
+	deadline := time.Now().Add(duration)
+	if rate <= 0 {
+		for time.Now().Before(deadline) {
+			for i := 0; i < 256; i++ {
+				emit()
+			}
+		}
+		return
+	}
+
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	start := time.Now()
+	sent := 0
+	for now := range ticker.C {
+		if now.After(deadline) {
+			return
+		}
+		due := int(float64(rate) * now.Sub(start).Seconds())
+		for ; sent < due; sent++ {
+			emit()
+		}
+	}
+}
+
+func saveGeofenceResultsToCSV(filename string, results []GeofenceResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "Resolution", "Pings", "Matches", "ElapsedNs", "PingsPerSecond", "MatchesPerSecond",
+		"P50Ns", "P95Ns", "P99Ns", "P999Ns", "MaxNs"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			r.System,
+			strconv.Itoa(r.Resolution),
+			strconv.Itoa(r.Pings),
+			strconv.Itoa(r.Matches),
+			strconv.FormatInt(r.Elapsed.Nanoseconds(), 10),
+			strconv.FormatFloat(r.PingsPerSecond(), 'f', -1, 64),
+			strconv.FormatFloat(r.MatchesPerSecond(), 'f', -1, 64),
+			strconv.FormatInt(r.P50.Nanoseconds(), 10),
+			strconv.FormatInt(r.P95.Nanoseconds(), 10),
+			strconv.FormatInt(r.P99.Nanoseconds(), 10),
+			strconv.FormatInt(r.P999.Nanoseconds(), 10),
+			strconv.FormatInt(r.Max.Nanoseconds(), 10),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
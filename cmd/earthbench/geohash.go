@@ -0,0 +1,35 @@
+package main
+
+// geohashMaxPrecision is the longest geohash that fits in a uint64 (5 bits
+// per character)
+const geohashMaxPrecision = 12
+
+// geohashBits returns the geohash of a point as its 5*precision interleaved
+// bits (longitude first), which is cheaper to use as a map key than the
+// base32 string
+func geohashBits(lat, lng float64, precision int) uint64 {
+	minLat, maxLat := -90.0, 90.0
+	minLng, maxLng := -180.0, 180.0
+	var bits uint64
+	for i := 0; i < 5*precision; i++ {
+		bits <<= 1
+		if i%2 == 0 {
+			mid := (minLng + maxLng) / 2
+			if lng >= mid {
+				bits |= 1
+				minLng = mid
+			} else {
+				maxLng = mid
+			}
+		} else {
+			mid := (minLat + maxLat) / 2
+			if lat >= mid {
+				bits |= 1
+				minLat = mid
+			} else {
+				maxLat = mid
+			}
+		}
+	}
+	return bits
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+)
+
+// gpkgApplicationID and gpkgVersion mark the SQLite file as a GeoPackage
+// 1.3 ("GPKG" and 10300)
+const (
+	gpkgApplicationID = 0x47504B47
+	gpkgVersion       = 10300
+)
+
+// gpkgCore creates the GeoPackage metadata tables with the three spatial
+// reference systems the specification requires
+const gpkgCore = `CREATE TABLE gpkg_spatial_ref_sys (srs_name TEXT NOT NULL, srs_id INTEGER NOT NULL PRIMARY KEY,
+  organization TEXT NOT NULL, organization_coordsys_id INTEGER NOT NULL, definition TEXT NOT NULL, description TEXT);
+CREATE TABLE gpkg_contents (table_name TEXT NOT NULL PRIMARY KEY, data_type TEXT NOT NULL, identifier TEXT UNIQUE,
+  description TEXT DEFAULT '', last_change DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+  min_x DOUBLE, min_y DOUBLE, max_x DOUBLE, max_y DOUBLE, srs_id INTEGER,
+  CONSTRAINT fk_gc_r_srs_id FOREIGN KEY (srs_id) REFERENCES gpkg_spatial_ref_sys(srs_id));
+CREATE TABLE gpkg_geometry_columns (table_name TEXT NOT NULL, column_name TEXT NOT NULL, geometry_type_name TEXT NOT NULL,
+  srs_id INTEGER NOT NULL, z TINYINT NOT NULL, m TINYINT NOT NULL,
+  CONSTRAINT pk_geom_cols PRIMARY KEY (table_name, column_name), CONSTRAINT uk_gc_table_name UNIQUE (table_name),
+  CONSTRAINT fk_gc_tn FOREIGN KEY (table_name) REFERENCES gpkg_contents(table_name),
+  CONSTRAINT fk_gc_srs FOREIGN KEY (srs_id) REFERENCES gpkg_spatial_ref_sys (srs_id));
+INSERT INTO gpkg_spatial_ref_sys VALUES
+  ('Undefined cartesian SRS', -1, 'NONE', -1, 'undefined', 'undefined cartesian coordinate reference system'),
+  ('Undefined geographic SRS', 0, 'NONE', 0, 'undefined', 'undefined geographic coordinate reference system'),
+  ('WGS 84 geodetic', 4326, 'EPSG', 4326, 'GEOGCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563,AUTHORITY["EPSG","7030"]],AUTHORITY["EPSG","6326"]],PRIMEM["Greenwich",0,AUTHORITY["EPSG","8901"]],UNIT["degree",0.0174532925199433,AUTHORITY["EPSG","9122"]],AUTHORITY["EPSG","4326"]]', 'longitude/latitude coordinates in decimal degrees on the WGS 84 spheroid');
+`
+
+// gpkgLayer is a feature table being written, with the envelope of its
+// geometries for gpkg_contents
+type gpkgLayer struct {
+	Table  string
+	Bounds [4]float64 // min x, min y, max x, max y
+	Rows   int
+}
+
+// exportGeoPackage writes the dataset and its coverings to a GeoPackage
+// through the sqlite3 CLI: a features layer with the source polygons and,
+// per sweep point, a layer of the source polygons with the metrics
+// measured covering them and a layer of the covering cells. The file is
+// recreated.
+func exportGeoPackage(binary, path string, ds *bench.Dataset, sweepPoints []bench.SweepPoint, maxCells int) (int, error) {
+	if _, err := exec.LookPath(binary); err != nil {
+		return 0, fmt.Errorf("sqlite3 CLI not found (set -sqlite3): %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, err
+	}
+
+	cmd := exec.Command(binary, path)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return 0, err
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	w := bufio.NewWriter(stdin)
+	written, writeErr := writeGeoPackageSQL(w, ds, sweepPoints, maxCells)
+	if writeErr == nil {
+		writeErr = w.Flush()
+	}
+	stdin.Close()
+	if err := cmd.Wait(); err != nil {
+		return 0, fmt.Errorf("running sqlite3: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return written, writeErr
+}
+
+// writeGeoPackageSQL writes the SQL script building the GeoPackage and
+// returns the number of cells in it
+func writeGeoPackageSQL(w io.Writer, ds *bench.Dataset, sweepPoints []bench.SweepPoint, maxCells int) (int, error) {
+	fmt.Fprintf(w, ".bail on\nPRAGMA application_id = %d;\nPRAGMA user_version = %d;\nBEGIN;\n%s", gpkgApplicationID, gpkgVersion, gpkgCore)
+
+	source := startGeoPackageLayer(w, "features", "feature_id INTEGER NOT NULL, vertices INTEGER NOT NULL, area_km2 REAL NOT NULL",
+		"source polygons of "+bench.DatasetName(ds.Path))
+	for _, f := range ds.Features {
+		source.insert(w, f.Geometry.Coordinates, "feature_id, vertices, area_km2", f.FeatureID, f.NumVertices(), f.AreaKm2())
+	}
+	source.finish(w)
+
+	written := 0
+	for _, sp := range sweepPoints {
+		name := fmt.Sprintf("%s_%d", strings.ToLower(sp.System), sp.Resolution)
+		metrics := startGeoPackageLayer(w, name+"_features",
+			"feature_id INTEGER NOT NULL, cells INTEGER NOT NULL, duration_ns INTEGER NOT NULL, covering_area_km2 REAL NOT NULL, area_ratio REAL",
+			fmt.Sprintf("source polygons with their %s resolution %d covering metrics", sp.System, sp.Resolution))
+		cells := startGeoPackageLayer(w, name+"_cells", "feature_id INTEGER NOT NULL, cell TEXT NOT NULL, area_km2 REAL NOT NULL",
+			fmt.Sprintf("%s resolution %d covering cells", sp.System, sp.Resolution))
+		for _, f := range ds.Features {
+			covering, duration, err := timeCovering(f, sp, maxCells)
+			if err != nil {
+				return written, fmt.Errorf("feature %d: %w", f.FeatureID, err)
+			}
+			var coveredKm2 float64
+			for _, cell := range covering {
+				ring, err := cellBoundary(sp.System, cell)
+				if err != nil {
+					return written, err
+				}
+				area, err := cellAreaKm2(sp.System, cell)
+				if err != nil {
+					return written, err
+				}
+				coveredKm2 += area
+				cells.insert(w, [][][2]float64{ring}, "feature_id, cell, area_km2", f.FeatureID, cellToken(sp.System, cell), area)
+			}
+			var ratio interface{} // NULL for a polygon without area
+			if areaKm2 := f.AreaKm2(); areaKm2 > 0 {
+				ratio = coveredKm2 / areaKm2
+			}
+			metrics.insert(w, f.Geometry.Coordinates, "feature_id, cells, duration_ns, covering_area_km2, area_ratio",
+				f.FeatureID, len(covering), duration.Nanoseconds(), coveredKm2, ratio)
+			written += len(covering)
+		}
+		metrics.finish(w)
+		cells.finish(w)
+	}
+	_, err := io.WriteString(w, "COMMIT;\n")
+	return written, err
+}
+
+// startGeoPackageLayer creates a feature table of polygons in WGS 84 with
+// the given attribute columns and registers it in the metadata tables
+func startGeoPackageLayer(w io.Writer, table, columns, description string) *gpkgLayer {
+	fmt.Fprintf(w, "CREATE TABLE %s (fid INTEGER PRIMARY KEY AUTOINCREMENT, geom POLYGON, %s);\n", table, columns)
+	fmt.Fprintf(w, "INSERT INTO gpkg_contents (table_name, data_type, identifier, description, srs_id) VALUES ('%s', 'features', '%s', %s, 4326);\n",
+		table, table, sqlString(description))
+	fmt.Fprintf(w, "INSERT INTO gpkg_geometry_columns VALUES ('%s', 'geom', 'POLYGON', 4326, 0, 0);\n", table)
+	return &gpkgLayer{Table: table, Bounds: [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}}
+}
+
+// insert writes a row of the layer, its polygon followed by the values of
+// the named columns
+func (l *gpkgLayer) insert(w io.Writer, rings [][][2]float64, columns string, values ...interface{}) {
+	blob, envelope := gpkgGeometry(rings)
+	l.Bounds = [4]float64{
+		min(l.Bounds[0], envelope[0]), min(l.Bounds[1], envelope[2]),
+		max(l.Bounds[2], envelope[1]), max(l.Bounds[3], envelope[3]),
+	}
+	l.Rows++
+	fmt.Fprintf(w, "INSERT INTO %s (geom, %s) VALUES (X'%s'", l.Table, columns, hex.EncodeToString(blob))
+	for _, v := range values {
+		switch v := v.(type) {
+		case nil:
+			io.WriteString(w, ", NULL")
+		case string:
+			fmt.Fprintf(w, ", %s", sqlString(v))
+		case float64:
+			fmt.Fprintf(w, ", %s", strconv.FormatFloat(v, 'g', -1, 64))
+		default:
+			fmt.Fprintf(w, ", %v", v)
+		}
+	}
+	io.WriteString(w, ");\n")
+}
+
+// finish records the extent of the layer in gpkg_contents
+func (l *gpkgLayer) finish(w io.Writer) {
+	if l.Rows == 0 {
+		return
+	}
+	fmt.Fprintf(w, "UPDATE gpkg_contents SET min_x = %s, min_y = %s, max_x = %s, max_y = %s WHERE table_name = '%s';\n",
+		strconv.FormatFloat(l.Bounds[0], 'g', -1, 64), strconv.FormatFloat(l.Bounds[1], 'g', -1, 64),
+		strconv.FormatFloat(l.Bounds[2], 'g', -1, 64), strconv.FormatFloat(l.Bounds[3], 'g', -1, 64), l.Table)
+}
+
+// gpkgGeometry encodes polygon rings as a GeoPackage geometry blob: the
+// "GP" header with the SRS and the [min x, max x, min y, max y] envelope,
+// which it returns too, followed by the little-endian WKB polygon
+func gpkgGeometry(rings [][][2]float64) ([]byte, [4]float64) {
+	envelope := [4]float64{math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)}
+	for _, ring := range rings {
+		for _, p := range ring {
+			envelope = [4]float64{min(envelope[0], p[0]), max(envelope[1], p[0]), min(envelope[2], p[1]), max(envelope[3], p[1])}
+		}
+	}
+	const flags = wkbLittleEndian | 1<<1 // little endian, xy envelope
+	b := []byte{'G', 'P', 0, flags}
+	b = binary.LittleEndian.AppendUint32(b, 4326)
+	for _, v := range envelope {
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+	}
+	return appendPolygonWKB(b, rings), envelope
+}
+
+// sqlString quotes a string as an SQL literal
+func sqlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
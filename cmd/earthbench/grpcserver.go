@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+	earthbenchpb "github.com/nkk36/earth-discretization-benchmark/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// discretizerServer implements the Discretizer gRPC service on top of the
+// same covering helpers the CLI uses
+type discretizerServer struct {
+	earthbenchpb.UnimplementedDiscretizerServer
+}
+
+func runGRPCCommand(args []string) error {
+	fs := flag.NewFlagSet("grpc", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:50051", "address to listen on")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	lis, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	server := grpc.NewServer()
+	earthbenchpb.RegisterDiscretizerServer(server, &discretizerServer{})
+	reflection.Register(server)
+
+	fmt.Printf("Discretizer gRPC service listening on %s\n", lis.Addr())
+	return server.Serve(lis)
+}
+
+// Cover converts and covers one polygon. Stage timings are returned in the
+// response and as earthbench-convert-ns/earthbench-cover-ns trailers.
+func (s *discretizerServer) Cover(ctx context.Context, req *earthbenchpb.CoverRequest) (*earthbenchpb.CoverResponse, error) {
+	system, err := systemFromProto(req.GetSystem())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	var geometry geojson.Geometry
+	if err := json.Unmarshal([]byte(req.GetGeometryGeojson()), &geometry); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "geometry_geojson: %v", err)
+	}
+
+	cells, convert, cover, err := bench.CoverGeometry(geometry, system, int(req.GetResolution()), maxCellsOrDefault(req.GetS2MaxCells()))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	grpc.SetTrailer(ctx, metadata.Pairs(
+		"earthbench-convert-ns", strconv.FormatInt(convert.Nanoseconds(), 10),
+		"earthbench-cover-ns", strconv.FormatInt(cover.Nanoseconds(), 10),
+	))
+	resp := &earthbenchpb.CoverResponse{
+		Cells:  cells,
+		Timing: &earthbenchpb.CoverTiming{ConvertNs: convert.Nanoseconds(), CoverNs: cover.Nanoseconds()},
+	}
+	for _, cell := range cells {
+		resp.Tokens = append(resp.Tokens, cellToken(system, cell))
+	}
+	return resp, nil
+}
+
+// Benchmark runs BenchmarkCoverings over an inline dataset
+func (s *discretizerServer) Benchmark(ctx context.Context, req *earthbenchpb.BenchmarkRequest) (*earthbenchpb.BenchmarkResponse, error) {
+	start := time.Now()
+	ds, err := bench.ParseDataset("request", []byte(req.GetDatasetGeojson()))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "dataset_geojson: %v", err)
+	}
+
+	var sweepPoints []bench.SweepPoint
+	for _, r := range req.GetH3Resolutions() {
+		sweepPoints = append(sweepPoints, bench.SweepPoint{System: bench.SystemH3, Resolution: int(r)})
+	}
+	for _, l := range req.GetS2Levels() {
+		sweepPoints = append(sweepPoints, bench.SweepPoint{System: bench.SystemS2, Resolution: int(l)})
+	}
+	if len(sweepPoints) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no h3_resolutions or s2_levels requested")
+	}
+
+	measurements, err := bench.BenchmarkCoverings(ctx, ds, sweepPoints, bench.CoveringOptions{
+		MaxCells: maxCellsOrDefault(req.GetS2MaxCells()),
+		Timeout:  time.Duration(req.GetCoverTimeoutMs()) * time.Millisecond,
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, status.FromContextError(err).Err()
+		}
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	resp := &earthbenchpb.BenchmarkResponse{}
+	for _, m := range measurements {
+		pm := &earthbenchpb.Measurement{
+			System:            systemToProto(m.System),
+			Resolution:        int32(m.Resolution),
+			Features:          int32(len(m.Durations)),
+			Cells:             int64(m.Cells),
+			AverageDurationNs: m.AverageDurationNs(),
+			TotalDurationNs:   m.TotalDuration().Nanoseconds(),
+		}
+		for _, id := range m.TimedOut {
+			pm.TimedOutFeatureIds = append(pm.TimedOutFeatureIds, int64(id))
+		}
+		resp.Measurements = append(resp.Measurements, pm)
+	}
+	resp.ElapsedNs = time.Since(start).Nanoseconds()
+	return resp, nil
+}
+
+func systemFromProto(s earthbenchpb.System) (string, error) {
+	switch s {
+	case earthbenchpb.System_SYSTEM_H3:
+		return bench.SystemH3, nil
+	case earthbenchpb.System_SYSTEM_S2:
+		return bench.SystemS2, nil
+	}
+	return "", fmt.Errorf("system must be SYSTEM_H3 or SYSTEM_S2")
+}
+
+func systemToProto(system string) earthbenchpb.System {
+	if system == bench.SystemH3 {
+		return earthbenchpb.System_SYSTEM_H3
+	}
+	return earthbenchpb.System_SYSTEM_S2
+}
+
+// maxCellsOrDefault applies the coverer default of 8 used throughout the repo
+func maxCellsOrDefault(n int32) int {
+	if n <= 0 {
+		return 8
+	}
+	return int(n)
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	dh3 "github.com/nkk36/earth-discretization-benchmark/discretize/h3"
+)
+
+// H3ExperimentalResult compares PolygonToCellsExperimental in one
+// containment mode with the stable PolygonToCells at one resolution, over
+// the features both filled. Added and Removed count the cells the
+// experimental fill has that the stable one lacks and the other way round;
+// Identical counts the features with exactly the same cells. Durations are
+// the fastest round of each feature, summed. Failed counts the features
+// the experimental call returned an error for.
+type H3ExperimentalResult struct {
+	Resolution   int
+	Mode         string
+	Features     int
+	Failed       int
+	StableCells  int
+	Cells        int
+	Added        int
+	Removed      int
+	Identical    int
+	Stable       time.Duration
+	Experimental time.Duration
+}
+
+// Speedup is the stable fill time over the experimental one, above 1 when
+// the experimental algorithm is faster
+func (r H3ExperimentalResult) Speedup() float64 {
+	if r.Experimental == 0 {
+		return 0
+	}
+	return float64(r.Stable) / float64(r.Experimental)
+}
+
+func runH3ExperimentalCommand(args []string) error {
+	fs := flag.NewFlagSet("h3-experimental", flag.ExitOnError)
+	dataset := addDatasetFlags(fs)
+	h3Res := fs.String("h3-res", "3-6", "H3 resolutions, e.g. 3-6 or 4,7")
+	modes := fs.String("modes", "center,full,overlapping,overlapping-bbox", "containment modes of PolygonToCellsExperimental to compare")
+	rounds := fs.Int("rounds", 3, "times every fill is timed; the fastest is kept")
+	output := fs.String("output", "output/h3_experimental.csv", "CSV file for the results")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	resolutions, err := parseIntRange(*h3Res)
+	if err != nil {
+		return fmt.Errorf("-h3-res: %w", err)
+	}
+	var modeNames []string
+	for _, m := range strings.Split(*modes, ",") {
+		m = strings.TrimSpace(m)
+		if _, ok := dh3.Containment[m]; !ok {
+			return fmt.Errorf("-modes: unknown containment mode %q", m)
+		}
+		modeNames = append(modeNames, m)
+	}
+	if *rounds < 1 {
+		return fmt.Errorf("-rounds must be positive")
+	}
+	ds, err := dataset.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d features from %s\n", len(ds.Features), ds.Path)
+
+	var results []H3ExperimentalResult
+	for _, res := range resolutions {
+		r, err := benchmarkH3Experimental(ds, res, modeNames, *rounds)
+		if err != nil {
+			return fmt.Errorf("H3 resolution %d: %w", res, err)
+		}
+		for _, m := range r {
+			fmt.Printf("H3 res %2d %-16s: %9d cells vs %9d stable (+%d -%d), %4d of %4d identical, %4d failed, x%.2f the stable speed\n",
+				m.Resolution, m.Mode, m.Cells, m.StableCells, m.Added, m.Removed, m.Identical, m.Features, m.Failed, m.Speedup())
+		}
+		results = append(results, r...)
+	}
+
+	if err := saveH3ExperimentalResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// benchmarkH3Experimental fills every feature with the stable call and
+// with the experimental one in each mode, alternating between them round
+// by round so both see the same cache and frequency conditions
+func benchmarkH3Experimental(ds *bench.Dataset, res int, modes []string, rounds int) ([]H3ExperimentalResult, error) {
+	results := make([]H3ExperimentalResult, len(modes))
+	for i, mode := range modes {
+		results[i] = H3ExperimentalResult{Resolution: res, Mode: mode}
+	}
+
+	for _, f := range ds.Features {
+		stableTime := time.Duration(math.MaxInt64)
+		times := make([]time.Duration, len(modes))
+		for i := range times {
+			times[i] = math.MaxInt64
+		}
+		var stable []uint64
+		fills := make([][]uint64, len(modes))
+		errs := make([]error, len(modes))
+		for range rounds {
+			start := time.Now()
+			cells, err := dh3.Cover(f.H3Polygon, res)
+			stableTime = min(stableTime, time.Since(start))
+			if err != nil {
+				return nil, fmt.Errorf("feature %d: %w", f.FeatureID, err)
+			}
+			stable = cells
+			for i, mode := range modes {
+				if errs[i] != nil {
+					continue
+				}
+				start := time.Now()
+				fills[i], errs[i] = dh3.CoverContainment(f.H3Polygon, res, dh3.Containment[mode])
+				times[i] = min(times[i], time.Since(start))
+			}
+		}
+
+		inStable := make(map[uint64]bool, len(stable))
+		for _, c := range stable {
+			inStable[c] = true
+		}
+		for i := range modes {
+			r := &results[i]
+			if errs[i] != nil {
+				if r.Failed == 0 {
+					log.Printf("Warning: H3 res %d %s, feature %d: %v", res, r.Mode, f.FeatureID, errs[i])
+				}
+				r.Failed++
+				continue
+			}
+			added := 0
+			inFill := make(map[uint64]bool, len(fills[i]))
+			for _, c := range fills[i] {
+				inFill[c] = true
+				if !inStable[c] {
+					added++
+				}
+			}
+			removed := 0
+			for _, c := range stable {
+				if !inFill[c] {
+					removed++
+				}
+			}
+			if added == 0 && removed == 0 {
+				r.Identical++
+			}
+			r.Features++
+			r.StableCells += len(stable)
+			r.Cells += len(fills[i])
+			r.Added += added
+			r.Removed += removed
+			r.Stable += stableTime
+			r.Experimental += times[i]
+		}
+	}
+	return results, nil
+}
+
+func saveH3ExperimentalResultsToCSV(filename string, results []H3ExperimentalResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"Resolution", "Mode", "Features", "Failed", "StableCells", "Cells", "AddedCells", "RemovedCells",
+		"IdenticalFeatures", "StableDurationNs", "ExperimentalDurationNs", "Speedup"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			strconv.Itoa(r.Resolution),
+			r.Mode,
+			strconv.Itoa(r.Features),
+			strconv.Itoa(r.Failed),
+			strconv.Itoa(r.StableCells),
+			strconv.Itoa(r.Cells),
+			strconv.Itoa(r.Added),
+			strconv.Itoa(r.Removed),
+			strconv.Itoa(r.Identical),
+			strconv.FormatInt(r.Stable.Nanoseconds(), 10),
+			strconv.FormatInt(r.Experimental.Nanoseconds(), 10),
+			strconv.FormatFloat(r.Speedup(), 'f', -1, 64),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	dh3 "github.com/nkk36/earth-discretization-benchmark/discretize/h3"
+	ds2 "github.com/nkk36/earth-discretization-benchmark/discretize/s2"
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+)
+
+// HolesResult is the conversion and covering of the generated polygons with
+// one number of holes, at one system and resolution. Convert is the time
+// of the system's GeoJSON converter, which handles every ring, and Cover
+// that of the covering call; both are the fastest round of each feature.
+type HolesResult struct {
+	System      string
+	Resolution  int
+	Holes       int
+	Features    int
+	Vertices    int // per feature, of the shell and all holes
+	Cells       int
+	Convert     time.Duration
+	Cover       time.Duration
+	PolygonKm2  float64
+	CoveringKm2 float64
+}
+
+// ConvertNs is the mean conversion time per feature
+func (r HolesResult) ConvertNs() float64 {
+	if r.Features == 0 {
+		return 0
+	}
+	return float64(r.Convert.Nanoseconds()) / float64(r.Features)
+}
+
+// CoverNs is the mean covering time per feature
+func (r HolesResult) CoverNs() float64 {
+	if r.Features == 0 {
+		return 0
+	}
+	return float64(r.Cover.Nanoseconds()) / float64(r.Features)
+}
+
+// CellsPerFeature is the mean covering size
+func (r HolesResult) CellsPerFeature() float64 {
+	if r.Features == 0 {
+		return 0
+	}
+	return float64(r.Cells) / float64(r.Features)
+}
+
+// AreaRatio is the covering area over the polygon area net of its holes; a
+// converter that dropped or misread holes shows as a ratio well above 1
+func (r HolesResult) AreaRatio() float64 {
+	if r.PolygonKm2 == 0 {
+		return 0
+	}
+	return r.CoveringKm2 / r.PolygonKm2
+}
+
+func runHolesCommand(args []string) error {
+	fs := flag.NewFlagSet("holes", flag.ExitOnError)
+	// No dataset flags: the polygons are generated
+	sweep := &sweepFlags{
+		H3Res:    fs.String("h3-res", "4-6", "H3 resolutions, e.g. 0-6 or 3,5,7 (empty to skip H3)"),
+		S2Levels: fs.String("s2-levels", "8-11", "S2 levels, e.g. 0-11 or 4,8 (empty to skip S2)"),
+		MaxCells: fs.Int("s2-max-cells", 8, "S2 RegionCoverer MaxCells"),
+	}
+	holeCounts := fs.String("holes", "0,1,4,16,64,256,1024", "numbers of holes per polygon")
+	polygons := fs.Int("polygons", 10, "polygons generated per number of holes")
+	sizeKm := fs.Float64("size-km", 200, "radius of the polygons' outer rings")
+	holeVertices := fs.Int("hole-vertices", 16, "vertices of each hole")
+	seed := fs.Int64("seed", 1, "seed for the polygon centers")
+	rounds := fs.Int("rounds", 3, "times every conversion and covering is timed; the fastest is kept")
+	write := fs.String("write", "", "also write the generated polygons to this GeoJSON file")
+	output := fs.String("output", "output/holes.csv", "CSV file for the results")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	counts, err := parseIntRange(*holeCounts)
+	if err != nil {
+		return fmt.Errorf("-holes: %w", err)
+	}
+	if *polygons < 1 || *sizeKm <= 0 || *holeVertices < 3 || *rounds < 1 {
+		return fmt.Errorf("-polygons, -size-km and -rounds must be positive and -hole-vertices at least 3")
+	}
+
+	fc := generateHoleyPolygons(counts, *polygons, *sizeKm, *holeVertices, *seed)
+	if *write != "" {
+		if err := writeGeoJSON(*write, fc); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %d polygons to %s\n", len(fc.Features), *write)
+	}
+	ds, err := bench.NewDataset("holes", fc)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Generated %d polygons of %g km with %v holes of %d vertices\n", len(ds.Features), *sizeKm, counts, *holeVertices)
+
+	var results []HolesResult
+	for _, sp := range sweepPoints {
+		byCount := make(map[int]*HolesResult)
+		for _, f := range ds.Features {
+			holes := len(f.Geometry.Coordinates) - 1
+			r := byCount[holes]
+			if r == nil {
+				r = &HolesResult{System: sp.System, Resolution: sp.Resolution, Holes: holes, Vertices: f.NumVertices()}
+				byCount[holes] = r
+			}
+			convert, cover := time.Duration(math.MaxInt64), time.Duration(math.MaxInt64)
+			var covering []uint64
+			for range *rounds {
+				start := time.Now()
+				if sp.System == bench.SystemH3 {
+					_, err = dh3.FromGeometry(f.Geometry, geojson.IgnoreSkippedRing)
+				} else {
+					_, err = ds2.FromGeometry(f.Geometry, geojson.IgnoreSkippedRing)
+				}
+				convert = min(convert, time.Since(start))
+				if err != nil {
+					return fmt.Errorf("%s, feature %d: %w", sp.System, f.FeatureID, err)
+				}
+				start = time.Now()
+				covering, err = bench.CoverFeature(f, sp.System, sp.Resolution, *sweep.MaxCells)
+				cover = min(cover, time.Since(start))
+				if err != nil {
+					return fmt.Errorf("%s resolution %d, feature %d: %w", sp.System, sp.Resolution, f.FeatureID, err)
+				}
+			}
+			area, err := coveringAreaKm2(sp.System, covering)
+			if err != nil {
+				return err
+			}
+			r.Features++
+			r.Cells += len(covering)
+			r.Convert += convert
+			r.Cover += cover
+			r.PolygonKm2 += f.AreaKm2()
+			r.CoveringKm2 += area
+		}
+
+		for _, holes := range counts {
+			r := byCount[holes]
+			if r == nil {
+				continue
+			}
+			fmt.Printf("%s res %2d, %5d holes (%6d vertices): convert %10.0f ns, cover %11.0f ns, %9.1f cells/feature, area ratio %.3f\n",
+				r.System, r.Resolution, r.Holes, r.Vertices, r.ConvertNs(), r.CoverNs(), r.CellsPerFeature(), r.AreaRatio())
+			results = append(results, *r)
+		}
+	}
+
+	if err := saveHolesResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// generateHoleyPolygons draws polygons of a 64-vertex circular shell with
+// a field of holes: for each count, n polygons at random centers away from
+// the poles and the antimeridian, whose holes are laid out on a square
+// grid inside the shell and wound clockwise
+func generateHoleyPolygons(counts []int, n int, sizeKm float64, holeVertices int, seed int64) geojson.FeatureCollection {
+	rng := rand.New(rand.NewSource(seed))
+	fc := geojson.FeatureCollection{Type: "FeatureCollection"}
+	for _, count := range counts {
+		count = max(count, 0)
+		for range n {
+			centerLat := rng.Float64()*120 - 60
+			centerLng := rng.Float64()*340 - 170
+			ry := sizeKm / 111.0
+			rx := ry / math.Cos(centerLat*math.Pi/180)
+			rings := [][][2]float64{ellipseRing(centerLng, centerLat, rx, ry, 64)}
+
+			// The grid fills the square inscribed in the shell, with room to spare
+			side := int(math.Ceil(math.Sqrt(float64(count))))
+			half := 0.65
+			pitch := 2 * half / float64(max(side, 1))
+			for i := range count {
+				x := -half + pitch*(float64(i%side)+0.5)
+				y := -half + pitch*(float64(i/side)+0.5)
+				hole := ellipseRing(centerLng+x*rx, centerLat+y*ry, 0.35*pitch*rx, 0.35*pitch*ry, holeVertices)
+				for a, b := 0, len(hole)-1; a < b; a, b = a+1, b-1 {
+					hole[a], hole[b] = hole[b], hole[a]
+				}
+				rings = append(rings, hole)
+			}
+			fc.Features = append(fc.Features, geojson.Feature{
+				Type:       "Feature",
+				Geometry:   geojson.Geometry{Type: "Polygon", Coordinates: rings},
+				Properties: map[string]interface{}{"id": len(fc.Features) + 1, "holes": count},
+			})
+		}
+	}
+	return fc
+}
+
+// saveHolesResultsToCSV writes one row per system, resolution and number
+// of holes
+func saveHolesResultsToCSV(filename string, results []HolesResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "Resolution", "Holes", "Features", "VerticesPerFeature", "ConvertNs", "CoverNs",
+		"CellsPerFeature", "PolygonKm2", "CoveringKm2", "AreaRatio"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			r.System,
+			strconv.Itoa(r.Resolution),
+			strconv.Itoa(r.Holes),
+			strconv.Itoa(r.Features),
+			strconv.Itoa(r.Vertices),
+			strconv.FormatFloat(r.ConvertNs(), 'f', 0, 64),
+			strconv.FormatFloat(r.CoverNs(), 'f', 0, 64),
+			strconv.FormatFloat(r.CellsPerFeature(), 'f', -1, 64),
+			strconv.FormatFloat(r.PolygonKm2, 'f', -1, 64),
+			strconv.FormatFloat(r.CoveringKm2, 'f', -1, 64),
+			strconv.FormatFloat(r.AreaRatio(), 'f', -1, 64),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
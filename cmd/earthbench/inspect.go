@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	ds2 "github.com/nkk36/earth-discretization-benchmark/discretize/s2"
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+	"github.com/nkk36/earth-discretization-benchmark/report"
+)
+
+// InvalidFeature records why a feature cannot be benchmarked as-is
+type InvalidFeature struct {
+	Index     int    `json:"index"`
+	FeatureID int    `json:"feature_id"`
+	Code      string `json:"code,omitempty"` // degenerate ring reason code, see geojson.ClassifyRing
+	Reason    string `json:"reason"`
+}
+
+// DatasetStats is the report printed by the inspect command
+type DatasetStats struct {
+	Path            string              `json:"path"`
+	Features        int                 `json:"features"`
+	GeometryTypes   map[string]int      `json:"geometry_types"`
+	Vertices        report.Distribution `json:"vertices"`
+	Holes           int                 `json:"holes"`
+	AreaKm2         report.Distribution `json:"area_km2"`
+	MinLon          float64             `json:"min_lon"`
+	MinLat          float64             `json:"min_lat"`
+	MaxLon          float64             `json:"max_lon"`
+	MaxLat          float64             `json:"max_lat"`
+	InvalidFeatures []InvalidFeature    `json:"invalid_features"`
+}
+
+func runInspectCommand(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	input := fs.String("input", defaultInput, "GeoJSON FeatureCollection to inspect")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	idsOutput := fs.String("ids", "", "also write a CSV mapping every feature's position and ID in the file to the FeatureID in results")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	name := resolveInput(*input)
+	fc, err := readInput(name)
+	if err != nil {
+		return err
+	}
+	if *idsOutput != "" {
+		if err := saveFeatureIDs(*idsOutput, fc); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Feature IDs saved to %s\n", *idsOutput)
+	}
+	stats := inspectFeatureCollection(name, fc)
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(stats)
+	}
+	printDatasetStats(stats)
+	return nil
+}
+
+// inspectFeatureCollection gathers dataset statistics without skipping
+// anything, so the features the benchmark would drop are listed with a reason
+func inspectFeatureCollection(path string, fc geojson.FeatureCollection) DatasetStats {
+	stats := DatasetStats{
+		Path:          path,
+		Features:      len(fc.Features),
+		GeometryTypes: make(map[string]int),
+		MinLon:        math.Inf(1),
+		MinLat:        math.Inf(1),
+		MaxLon:        math.Inf(-1),
+		MaxLat:        math.Inf(-1),
+	}
+	var vertices, areas []float64
+
+	featureIDs := geojson.FeatureIDs(fc)
+	for i, feature := range fc.Features {
+		featureID := featureIDs[i]
+		invalid := func(reason string) {
+			stats.InvalidFeatures = append(stats.InvalidFeatures, InvalidFeature{Index: i, FeatureID: featureID, Reason: reason})
+		}
+
+		stats.GeometryTypes[feature.Geometry.Type]++
+		if feature.Geometry.Type != "Polygon" {
+			invalid(fmt.Sprintf("geometry type %q is not supported", feature.Geometry.Type))
+			continue
+		}
+		if err := feature.Geometry.Err(); err != nil {
+			invalid(err.Error())
+			continue
+		}
+		if len(feature.Geometry.Coordinates) == 0 {
+			invalid("polygon has no coordinates")
+			continue
+		}
+
+		n := 0
+		for r, ring := range feature.Geometry.Coordinates {
+			if r > 0 {
+				stats.Holes++
+			}
+			if len(ring) < 4 {
+				invalid(fmt.Sprintf("ring %d has %d positions, fewer than 4", r, len(ring)))
+			} else if ring[0] != ring[len(ring)-1] {
+				invalid(fmt.Sprintf("ring %d is not closed", r))
+			}
+			for _, p := range ring {
+				if p[0] < -180 || p[0] > 180 || p[1] < -90 || p[1] > 90 {
+					invalid(fmt.Sprintf("ring %d has out-of-range position [%v, %v]", r, p[0], p[1]))
+					break
+				}
+			}
+			for _, p := range ring {
+				stats.MinLon, stats.MaxLon = math.Min(stats.MinLon, p[0]), math.Max(stats.MaxLon, p[0])
+				stats.MinLat, stats.MaxLat = math.Min(stats.MinLat, p[1]), math.Max(stats.MaxLat, p[1])
+			}
+			if len(ring) > 0 {
+				n += len(ring) - 1 // the closing position repeats the first
+			}
+		}
+		vertices = append(vertices, float64(n))
+
+		// Degenerate rings are left out before conversion, as the benchmark
+		// does; kept maps the rings that remain back to their index
+		geometry, defects := geojson.SanitizeGeometry(feature.Geometry, false)
+		degenerateExterior := false
+		for _, d := range defects {
+			if len(feature.Geometry.Coordinates[d.Ring]) < 4 { // short rings are listed above
+				continue
+			}
+			stats.InvalidFeatures = append(stats.InvalidFeatures, InvalidFeature{
+				Index: i, FeatureID: featureID, Code: d.Code, Reason: fmt.Sprintf("ring %d %s", d.Ring, d.Detail),
+			})
+			degenerateExterior = degenerateExterior || d.Ring == 0
+		}
+		if degenerateExterior {
+			continue
+		}
+		var kept []int
+		for r := range feature.Geometry.Coordinates {
+			if !slices.ContainsFunc(defects, func(d geojson.RingDefect) bool { return d.Ring == r }) {
+				kept = append(kept, r)
+			}
+		}
+
+		polygon, err := ds2.FromGeometry(geometry, func(ring int, reason string) error {
+			if ring := kept[ring]; len(feature.Geometry.Coordinates[ring]) >= 4 {
+				invalid(fmt.Sprintf("hole %d %s", ring-1, reason))
+			}
+			return nil
+		})
+		if err != nil {
+			invalid(err.Error())
+			continue
+		}
+		areas = append(areas, polygon.Area()*bench.EarthRadiusKm*bench.EarthRadiusKm)
+	}
+
+	stats.Vertices = report.NewDistribution(vertices)
+	stats.AreaKm2 = report.NewDistribution(areas)
+	return stats
+}
+
+func printDatasetStats(s DatasetStats) {
+	fmt.Printf("Dataset: %s\n", s.Path)
+	fmt.Printf("Features: %d\n", s.Features)
+
+	types := make([]string, 0, len(s.GeometryTypes))
+	for t := range s.GeometryTypes {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	fmt.Println("Geometry types:")
+	for _, t := range types {
+		fmt.Printf("  %-16s %d\n", t, s.GeometryTypes[t])
+	}
+
+	fmt.Printf("Holes: %d\n", s.Holes)
+	fmt.Printf("Bounding box: [%.6f, %.6f, %.6f, %.6f]\n", s.MinLon, s.MinLat, s.MaxLon, s.MaxLat)
+	printDistribution("Vertices per polygon", s.Vertices)
+	printDistribution("Area (km^2)", s.AreaKm2)
+
+	fmt.Printf("Invalid features: %d\n", len(s.InvalidFeatures))
+	for _, inv := range s.InvalidFeatures {
+		if inv.Code != "" {
+			fmt.Printf("  feature %d (index %d): [%s] %s\n", inv.FeatureID, inv.Index, inv.Code, inv.Reason)
+		} else {
+			fmt.Printf("  feature %d (index %d): %s\n", inv.FeatureID, inv.Index, inv.Reason)
+		}
+	}
+}
+
+func printDistribution(name string, d report.Distribution) {
+	fmt.Printf("%s: n=%d min=%.4g p25=%.4g median=%.4g p75=%.4g p90=%.4g max=%.4g mean=%.4g\n",
+		name, d.Count, d.Min, d.P25, d.Median, d.P75, d.P90, d.Max, d.Mean)
+}
+
+// saveFeatureIDs writes the FeatureID every feature is known by in results
+// next to its position and the ID written in the file, for joining results
+// back to the source data when IDs are strings, missing or duplicated
+func saveFeatureIDs(filename string, fc geojson.FeatureCollection) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	if err := writer.Write([]string{"Index", "FeatureID", "SourceID"}); err != nil {
+		return err
+	}
+	for i, id := range geojson.FeatureIDs(fc) {
+		if err := writer.Write([]string{strconv.Itoa(i), strconv.Itoa(id), geojson.SourceID(fc.Features[i])}); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
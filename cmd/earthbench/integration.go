@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+)
+
+// OperationResult is the timing of one operation type against one system,
+// shared by the external-store integrations so their rows line up with the
+// in-process cell index baseline. Resolution is -1 for systems without one.
+type OperationResult struct {
+	System     string
+	Resolution int
+	Operation  string
+	Operations int
+	Matches    int
+	Duration   time.Duration
+}
+
+// AverageNs is the mean latency of a single operation
+func (r OperationResult) AverageNs() float64 {
+	if r.Operations == 0 {
+		return 0
+	}
+	return float64(r.Duration.Nanoseconds()) / float64(r.Operations)
+}
+
+// benchmarkInProcessIndex builds an in-memory cell -> feature IDs map from
+// the coverings of each sweep point and times random point lookups against
+// it, giving the cell-based counterpart of an external store's index and
+// point-query rows
+func benchmarkInProcessIndex(ds *bench.Dataset, sweepPoints []bench.SweepPoint, maxCells int, points []s2.LatLng) ([]OperationResult, error) {
+	var results []OperationResult
+	for _, sp := range sweepPoints {
+		index := OperationResult{System: sp.System, Resolution: sp.Resolution, Operation: "index"}
+		cells := make(map[uint64][]int)
+		start := time.Now()
+		for _, f := range ds.Features {
+			covering, err := bench.CoverFeature(f, sp.System, sp.Resolution, maxCells)
+			if err != nil {
+				return nil, fmt.Errorf("%s resolution %d, feature %d: %w", sp.System, sp.Resolution, f.FeatureID, err)
+			}
+			for _, cell := range covering {
+				cells[cell] = append(cells[cell], f.FeatureID)
+			}
+			index.Operations++
+		}
+		index.Duration = time.Since(start)
+
+		query := OperationResult{System: sp.System, Resolution: sp.Resolution, Operation: "point_query"}
+		start = time.Now()
+		for _, ll := range points {
+			cell, err := bench.PointCell(sp.System, ll, sp.Resolution)
+			if err != nil {
+				continue
+			}
+			if len(cells[cell]) > 0 {
+				query.Matches++
+			}
+			query.Operations++
+		}
+		query.Duration = time.Since(start)
+
+		results = append(results, index, query)
+	}
+	return results, nil
+}
+
+func printOperationResults(results []OperationResult) {
+	for _, r := range results {
+		fmt.Printf("%-13s res %2d %-18s %8d ops, %12.0f ns/op, %d matches\n",
+			r.System, r.Resolution, r.Operation, r.Operations, r.AverageNs(), r.Matches)
+	}
+}
+
+func saveOperationResultsToCSV(filename string, results []OperationResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "Resolution", "Operation", "Operations", "Matches", "DurationNs", "AverageNs"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			r.System,
+			strconv.Itoa(r.Resolution),
+			r.Operation,
+			strconv.Itoa(r.Operations),
+			strconv.Itoa(r.Matches),
+			strconv.FormatInt(r.Duration.Nanoseconds(), 10),
+			strconv.FormatFloat(r.AverageNs(), 'f', -1, 64),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
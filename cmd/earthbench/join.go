@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+)
+
+// JoinResult is one points × polygons join. Candidates counts the
+// (point, polygon) pairs produced by the cell lookup before the exact
+// refine; Pairs counts those that survive it.
+type JoinResult struct {
+	System        string
+	Resolution    int
+	Points        int
+	Candidates    int
+	Pairs         int
+	IndexDuration time.Duration
+	JoinDuration  time.Duration
+}
+
+// PointsPerSecond is the join throughput, excluding index construction
+func (r JoinResult) PointsPerSecond() float64 {
+	if r.JoinDuration == 0 {
+		return 0
+	}
+	return float64(r.Points) / r.JoinDuration.Seconds()
+}
+
+func runJoinCommand(args []string) error {
+	fs := flag.NewFlagSet("join", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "3-7", "6-12")
+	output := fs.String("output", "output/join.csv", "CSV file for the results")
+	numPoints := fs.Int("points", 1000000, "number of synthetic points to join")
+	seed := fs.Int64("seed", 1, "seed for the synthetic points")
+	pointsFile := fs.String("points-file", "", "points from gen-points (CSV or GeoJSON) instead of -points synthetic ones")
+	baseline := fs.Bool("baseline", true, "run the brute-force exact join")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d features from %s\n", len(ds.Features), ds.Path)
+
+	points, err := benchmarkPoints(*pointsFile, ds.Bounds(), *numPoints, *seed)
+	if err != nil {
+		return err
+	}
+
+	var results []JoinResult
+	if *baseline {
+		results = append(results, bruteForceJoin(ds, points))
+	}
+	for _, sp := range sweepPoints {
+		r, err := cellIndexJoin(ds, sp, *sweep.MaxCells, points)
+		if err != nil {
+			return fmt.Errorf("%s resolution %d: %w", sp.System, sp.Resolution, err)
+		}
+		results = append(results, r)
+	}
+
+	for _, r := range results {
+		fmt.Printf("%-11s res %2d: %8d points, %9d candidates, %8d pairs, index %v, join %v (%.0f points/s)\n",
+			r.System, r.Resolution, r.Points, r.Candidates, r.Pairs, r.IndexDuration, r.JoinDuration, r.PointsPerSecond())
+	}
+
+	if err := saveJoinResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// bruteForceJoin tests every point against every polygon whose bounding
+// rectangle contains it
+func bruteForceJoin(ds *bench.Dataset, points []s2.LatLng) JoinResult {
+	result := JoinResult{System: "brute_force", Resolution: -1, Points: len(points)}
+	start := time.Now()
+	bounds := make([]s2.Rect, len(ds.Features))
+	for i, f := range ds.Features {
+		bounds[i] = f.S2Polygon.RectBound()
+	}
+	result.IndexDuration = time.Since(start)
+
+	start = time.Now()
+	for _, ll := range points {
+		p := s2.PointFromLatLng(ll)
+		for i, f := range ds.Features {
+			if bounds[i].ContainsLatLng(ll) {
+				result.Candidates++
+				if f.S2Polygon.ContainsPoint(p) {
+					result.Pairs++
+				}
+			}
+		}
+	}
+	result.JoinDuration = time.Since(start)
+	return result
+}
+
+// cellIndexJoin joins every point to all polygons whose covering holds the
+// point's cell, then refines the candidates exactly. Points that fall in a
+// polygon but outside its H3 covering are lost, so Pairs can be lower than
+// the brute-force count.
+func cellIndexJoin(ds *bench.Dataset, sp bench.SweepPoint, maxCells int, points []s2.LatLng) (JoinResult, error) {
+	result := JoinResult{System: sp.System, Resolution: sp.Resolution, Points: len(points)}
+
+	start := time.Now()
+	cells := make(map[uint64][]int)
+	for i, f := range ds.Features {
+		covering, err := bench.CoverFeature(f, sp.System, sp.Resolution, maxCells)
+		if err != nil {
+			return result, fmt.Errorf("feature %d: %w", f.FeatureID, err)
+		}
+		for _, cell := range covering {
+			cells[cell] = append(cells[cell], i)
+		}
+	}
+	result.IndexDuration = time.Since(start)
+
+	start = time.Now()
+	for _, ll := range points {
+		cell, err := bench.PointCell(sp.System, ll, sp.Resolution)
+		if err != nil {
+			continue
+		}
+		candidates := cells[cell]
+		if len(candidates) == 0 {
+			continue
+		}
+		result.Candidates += len(candidates)
+		p := s2.PointFromLatLng(ll)
+		for _, i := range candidates {
+			if ds.Features[i].S2Polygon.ContainsPoint(p) {
+				result.Pairs++
+			}
+		}
+	}
+	result.JoinDuration = time.Since(start)
+	return result, nil
+}
+
+func saveJoinResultsToCSV(filename string, results []JoinResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "Resolution", "Points", "Candidates", "Pairs", "IndexDurationNs", "JoinDurationNs", "PointsPerSecond"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			r.System,
+			strconv.Itoa(r.Resolution),
+			strconv.Itoa(r.Points),
+			strconv.Itoa(r.Candidates),
+			strconv.Itoa(r.Pairs),
+			strconv.FormatInt(r.IndexDuration.Nanoseconds(), 10),
+			strconv.FormatInt(r.JoinDuration.Nanoseconds(), 10),
+			strconv.FormatFloat(r.PointsPerSecond(), 'f', -1, 64),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
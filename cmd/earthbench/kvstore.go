@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	bolt "go.etcd.io/bbolt"
+)
+
+var kvCellsBucket = []byte("cells")
+
+// KVStoreResult is one (system, resolution) row of the key-value store benchmark
+type KVStoreResult struct {
+	System        string
+	Resolution    int
+	Features      int
+	CellsWritten  int
+	DistinctKeys  int
+	WriteDuration time.Duration
+	StoreBytes    int64
+	Queries       int
+	Hits          int
+	Matches       int // feature IDs in the posting lists of the hits
+	QueryDuration time.Duration
+}
+
+// WritesPerSecond is the number of covering cells written per second
+func (r KVStoreResult) WritesPerSecond() float64 {
+	if r.WriteDuration <= 0 {
+		return 0
+	}
+	return float64(r.CellsWritten) / r.WriteDuration.Seconds()
+}
+
+// AverageQueryNs is the mean latency of a point lookup, including the
+// point-to-cell conversion
+func (r KVStoreResult) AverageQueryNs() float64 {
+	if r.Queries == 0 {
+		return 0
+	}
+	return float64(r.QueryDuration.Nanoseconds()) / float64(r.Queries)
+}
+
+func runKVStoreCommand(args []string) error {
+	fs := flag.NewFlagSet("kvstore", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "0-6", "0-11")
+	output := fs.String("output", "output/kvstore.csv", "CSV file for the results")
+	dir := fs.String("dir", "", "directory for the Bolt files (defaults to a temporary directory that is removed afterwards)")
+	queries := fs.Int("queries", 100000, "number of random point lookups per resolution")
+	seed := fs.Int64("seed", 1, "seed for the random query points")
+	noSync := fs.Bool("nosync", false, "skip fsync on every write transaction")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d features from %s\n", len(ds.Features), ds.Path)
+
+	storeDir := *dir
+	if storeDir == "" {
+		storeDir, err = os.MkdirTemp("", "earthbench-kv-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(storeDir)
+	} else if err := os.MkdirAll(storeDir, 0o755); err != nil {
+		return err
+	}
+
+	points := bench.RandomPointsInRect(ds.Bounds(), *queries, *seed)
+
+	var results []KVStoreResult
+	for _, sp := range sweepPoints {
+		result, err := benchmarkKVStore(ds, sp.System, sp.Resolution, *sweep.MaxCells, points, storeDir, *noSync)
+		if err != nil {
+			return fmt.Errorf("%s resolution %d: %w", sp.System, sp.Resolution, err)
+		}
+		printKVStoreResult(result)
+		results = append(results, result)
+	}
+
+	return saveKVStoreResultsToCSV(*output, results)
+}
+
+// benchmarkKVStore writes every covering of the dataset into a fresh Bolt
+// file keyed by cell ID (one transaction per feature, the way a geofence
+// service would ingest them) and then times random point lookups against it
+func benchmarkKVStore(ds *bench.Dataset, system string, resolution int, maxCells int,
+	points []s2.LatLng, dir string, noSync bool) (KVStoreResult, error) {
+	result := KVStoreResult{System: system, Resolution: resolution, Features: len(ds.Features)}
+
+	// Coverings are computed up front so only the store is timed
+	coverings, err := bench.ComputeCoverings(ds, system, resolution, maxCells)
+	if err != nil {
+		return result, err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s-res%d.db", system, resolution))
+	os.Remove(path)
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second, NoSync: noSync})
+	if err != nil {
+		return result, err
+	}
+
+	start := time.Now()
+	for i, f := range ds.Features {
+		err := db.Update(func(tx *bolt.Tx) error {
+			b, err := tx.CreateBucketIfNotExists(kvCellsBucket)
+			if err != nil {
+				return err
+			}
+			return putCovering(b, coverings[i], int64(f.FeatureID))
+		})
+		if err != nil {
+			db.Close()
+			return result, err
+		}
+		result.CellsWritten += len(coverings[i])
+	}
+	result.WriteDuration = time.Since(start)
+
+	err = db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket(kvCellsBucket); b != nil {
+			result.DistinctKeys = b.Stats().KeyN
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return result, err
+	}
+
+	key := make([]byte, 8)
+	start = time.Now()
+	for _, ll := range points {
+		cell, err := bench.PointCell(system, ll, resolution)
+		if err != nil {
+			continue
+		}
+		binary.BigEndian.PutUint64(key, cell)
+		db.View(func(tx *bolt.Tx) error {
+			if b := tx.Bucket(kvCellsBucket); b != nil {
+				if ids := postingList(b.Get(key)); len(ids) > 0 {
+					result.Hits++
+					result.Matches += len(ids)
+				}
+			}
+			return nil
+		})
+		result.Queries++
+	}
+	result.QueryDuration = time.Since(start)
+
+	if err := db.Close(); err != nil {
+		return result, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return result, err
+	}
+	result.StoreBytes = info.Size()
+
+	return result, nil
+}
+
+// putCovering appends featureID to the posting list of every cell in the
+// covering. Keys are big-endian cell IDs so neighbouring cells sort together;
+// the list holds 8 big-endian bytes per feature, as hashed IDs need 64 bits.
+func putCovering(b *bolt.Bucket, cells []uint64, featureID int64) error {
+	key := make([]byte, 8)
+	for _, cell := range cells {
+		binary.BigEndian.PutUint64(key, cell)
+		existing := b.Get(key)
+		value := make([]byte, len(existing)+8)
+		copy(value, existing)
+		binary.BigEndian.PutUint64(value[len(existing):], uint64(featureID))
+		if err := b.Put(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// postingList decodes the feature IDs putCovering stored under a cell
+func postingList(value []byte) []int64 {
+	ids := make([]int64, 0, len(value)/8)
+	for i := 0; i+8 <= len(value); i += 8 {
+		ids = append(ids, int64(binary.BigEndian.Uint64(value[i:])))
+	}
+	return ids
+}
+
+func printKVStoreResult(r KVStoreResult) {
+	fmt.Printf("%s res %2d: %9d cells, %9.0f writes/s, %10d bytes, %8.0f ns/query, %d/%d hits, %d matches\n",
+		r.System, r.Resolution, r.CellsWritten, r.WritesPerSecond(), r.StoreBytes,
+		r.AverageQueryNs(), r.Hits, r.Queries, r.Matches)
+}
+
+func saveKVStoreResultsToCSV(filename string, results []KVStoreResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "Resolution", "Features", "CellsWritten", "DistinctKeys",
+		"WriteDurationNs", "WritesPerSecond", "StoreBytes", "Queries", "Hits", "Matches", "AverageQueryNs"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			r.System,
+			strconv.Itoa(r.Resolution),
+			strconv.Itoa(r.Features),
+			strconv.Itoa(r.CellsWritten),
+			strconv.Itoa(r.DistinctKeys),
+			strconv.FormatInt(r.WriteDuration.Nanoseconds(), 10),
+			strconv.FormatFloat(r.WritesPerSecond(), 'f', -1, 64),
+			strconv.FormatInt(r.StoreBytes, 10),
+			strconv.Itoa(r.Queries),
+			strconv.Itoa(r.Hits),
+			strconv.Itoa(r.Matches),
+			strconv.FormatFloat(r.AverageQueryNs(), 'f', -1, 64),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+)
+
+// LatitudeResult is the coverings of the features whose centroids fall in
+// one band of absolute latitude, at one system and resolution. Bands are
+// folded over the equator because both grids are distorted alike in each
+// hemisphere. AreaError is the mean over features of how far the covering
+// area is from the polygon area, as a share of the polygon area.
+type LatitudeResult struct {
+	System      string
+	Resolution  int
+	BandLo      float64 // degrees from the equator
+	BandHi      float64
+	Features    int
+	Cells       int
+	Duration    time.Duration // of all coverings, the fastest round of each
+	PolygonKm2  float64
+	CoveringKm2 float64
+	AreaError   float64
+}
+
+// AverageDurationNs is the mean covering time per feature
+func (r LatitudeResult) AverageDurationNs() float64 {
+	if r.Features == 0 {
+		return 0
+	}
+	return float64(r.Duration.Nanoseconds()) / float64(r.Features)
+}
+
+// CellsPerFeature is the mean covering size
+func (r LatitudeResult) CellsPerFeature() float64 {
+	if r.Features == 0 {
+		return 0
+	}
+	return float64(r.Cells) / float64(r.Features)
+}
+
+// MeanCellKm2 is the mean area of the covering cells, which shows how the
+// grid's cells shrink or grow with latitude
+func (r LatitudeResult) MeanCellKm2() float64 {
+	if r.Cells == 0 {
+		return 0
+	}
+	return r.CoveringKm2 / float64(r.Cells)
+}
+
+// AreaRatio is the total covering area over the total polygon area
+func (r LatitudeResult) AreaRatio() float64 {
+	if r.PolygonKm2 == 0 {
+		return 0
+	}
+	return r.CoveringKm2 / r.PolygonKm2
+}
+
+func runLatitudeCommand(args []string) error {
+	fs := flag.NewFlagSet("latitude", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "3-6", "6-11")
+	output := fs.String("output", "output/latitude.csv", "CSV file for the results")
+	bandWidth := fs.Float64("band", 15, "width of the latitude bands in degrees")
+	rounds := fs.Int("rounds", 3, "times every covering is timed; the fastest is kept")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *bandWidth <= 0 || *bandWidth > 90 {
+		return fmt.Errorf("-band must be between 0 and 90 degrees")
+	}
+	if *rounds < 1 {
+		return fmt.Errorf("-rounds must be at least 1")
+	}
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d features from %s\n", len(ds.Features), ds.Path)
+
+	// Every feature goes in the band of its centroid, the poles in the last
+	numBands := int(math.Ceil(90 / *bandWidth))
+	bands := make([]int, len(ds.Features))
+	counts := make([]int, numBands)
+	for i, f := range ds.Features {
+		lat := math.Abs(s2.LatLngFromPoint(f.S2Polygon.Centroid()).Lat.Degrees())
+		bands[i] = min(int(lat / *bandWidth), numBands-1)
+		counts[bands[i]]++
+	}
+	for b := range numBands {
+		if counts[b] > 0 {
+			fmt.Printf("  %4.0f-%2.0f°: %d features\n", float64(b)**bandWidth, math.Min(float64(b+1)**bandWidth, 90), counts[b])
+		}
+	}
+
+	var results []LatitudeResult
+	for _, sp := range sweepPoints {
+		byBand := make([]LatitudeResult, numBands)
+		errorSums := make([]float64, numBands)
+		for b := range byBand {
+			byBand[b] = LatitudeResult{System: sp.System, Resolution: sp.Resolution,
+				BandLo: float64(b) * *bandWidth, BandHi: math.Min(float64(b+1)**bandWidth, 90)}
+		}
+		for i, f := range ds.Features {
+			var covering []uint64
+			fastest := time.Duration(math.MaxInt64)
+			for range *rounds {
+				start := time.Now()
+				covering, err = bench.CoverFeature(f, sp.System, sp.Resolution, *sweep.MaxCells)
+				if err != nil {
+					return fmt.Errorf("%s resolution %d, feature %d: %w", sp.System, sp.Resolution, f.FeatureID, err)
+				}
+				fastest = min(fastest, time.Since(start))
+			}
+			area, err := coveringAreaKm2(sp.System, covering)
+			if err != nil {
+				return err
+			}
+			polygonArea := f.AreaKm2()
+
+			r := &byBand[bands[i]]
+			r.Features++
+			r.Cells += len(covering)
+			r.Duration += fastest
+			r.PolygonKm2 += polygonArea
+			r.CoveringKm2 += area
+			if polygonArea > 0 {
+				errorSums[bands[i]] += math.Abs(area-polygonArea) / polygonArea
+			}
+		}
+
+		for b, r := range byBand {
+			if r.Features == 0 {
+				continue
+			}
+			r.AreaError = errorSums[b] / float64(r.Features)
+			fmt.Printf("%s res %2d %4.0f-%2.0f°: %4d features, %10.0f ns/covering, %9.1f cells/feature, mean cell %10.4g km², area ratio %.3f, area error %.3f\n",
+				r.System, r.Resolution, r.BandLo, r.BandHi, r.Features, r.AverageDurationNs(), r.CellsPerFeature(), r.MeanCellKm2(), r.AreaRatio(), r.AreaError)
+			results = append(results, r)
+		}
+	}
+
+	if err := saveLatitudeResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// saveLatitudeResultsToCSV writes one row per system, resolution and band
+func saveLatitudeResultsToCSV(filename string, results []LatitudeResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "Resolution", "LatitudeFrom", "LatitudeTo", "Features", "Cells", "CellsPerFeature",
+		"AverageDurationNs", "PolygonKm2", "CoveringKm2", "MeanCellKm2", "AreaRatio", "AreaError"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			r.System,
+			strconv.Itoa(r.Resolution),
+			strconv.FormatFloat(r.BandLo, 'f', -1, 64),
+			strconv.FormatFloat(r.BandHi, 'f', -1, 64),
+			strconv.Itoa(r.Features),
+			strconv.Itoa(r.Cells),
+			strconv.FormatFloat(r.CellsPerFeature(), 'f', -1, 64),
+			strconv.FormatFloat(r.AverageDurationNs(), 'f', 0, 64),
+			strconv.FormatFloat(r.PolygonKm2, 'f', -1, 64),
+			strconv.FormatFloat(r.CoveringKm2, 'f', -1, 64),
+			strconv.FormatFloat(r.MeanCellKm2(), 'f', -1, 64),
+			strconv.FormatFloat(r.AreaRatio(), 'f', -1, 64),
+			strconv.FormatFloat(r.AreaError, 'f', -1, 64),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+)
+
+func runMatrixCommand(args []string) (err error) {
+	fs := flag.NewFlagSet("matrix", flag.ExitOnError)
+	config := fs.String("config", "", "JSON file with the parameter axes: systems, resolutions, max_cells, containment, workers")
+	data := addDatasetFlags(fs)
+	output := fs.String("output", "output/matrix.csv", "CSV file for the measurements, one row per experiment and repetition ('-' for stdout)")
+	jsonOutput := fs.String("json", "", "also write the tagged measurements as JSON to this file")
+	timeout := fs.Duration("cover-timeout", 0, "give up on a single covering after this long, e.g. 30s (0 for no limit)")
+	h3Cap := fs.Int("h3-cell-cap", 0, "skip H3 fills estimated to return more cells than this (0 for no cap)")
+	verify := fs.Int("verify", 0, "check every covering against its system's contract with this many interior sample points (0 to skip; center containment only)")
+	repeat := fs.Int("repeat", 1, "run every experiment this many times")
+	sinkSpecs := fs.String("sinks", "table", "comma-separated sinks receiving each measurement as it completes, as for sweep")
+	sqliteBinary := fs.String("sqlite3", "sqlite3", "path to the sqlite3 CLI used by sqlite sinks")
+	precision := fs.Float64("precision", 0.05, "time each covering until the 95% confidence interval of its mean is within this fraction of it (0 for a single timing)")
+	maxSamples := fs.Int("max-samples", 20, "stop timing a covering after this many samples even if -precision is not reached")
+	budget := fs.Duration("budget", 0, "instead of -repeat, spend this long (e.g. 10m) repeating the noisiest sweep points (0 for fixed repetitions)")
+	dryRun := fs.Bool("dry-run", false, "print the plan with a runtime estimated from -calibrate features instead of running it")
+	calibrate := fs.Int("calibrate", 5, "features covered once per sweep point to estimate the runtime of -dry-run")
+	compress := fs.Bool("gzip", false, "gzip-compress the output files, adding .gz to their names, as for sweep")
+	notify := addNotifyFlags(fs)
+	sched := addSchedFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *compress {
+		*output, *jsonOutput = gzipName(*output), gzipName(*jsonOutput)
+	}
+	out := progressOutput(*output, *jsonOutput)
+	data.Out = out
+
+	start := time.Now()
+	interrupted := false
+	defer func() {
+		notify.send("matrix", start, err, interrupted, *output, nil)
+	}()
+
+	if *config == "" {
+		return fmt.Errorf("-config is required")
+	}
+	matrix, err := bench.LoadMatrix(*config)
+	if err != nil {
+		return err
+	}
+	experiments, err := matrix.Experiments()
+	if err != nil {
+		return err
+	}
+	ds, err := data.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Loaded %d features from %s; %d experiments\n", len(ds.Features), ds.Path, len(experiments))
+
+	if err := sched.apply(out); err != nil {
+		return err
+	}
+
+	// Ctrl-C stops the matrix but still saves the experiments already done
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	options := []bench.Option{
+		bench.WithCoveringOptions(bench.CoveringOptions{
+			Timeout:    *timeout,
+			H3CellCap:  *h3Cap,
+			Verify:     *verify,
+			Precision:  *precision,
+			MaxSamples: *maxSamples,
+		}),
+		bench.WithRepetitions(*repeat),
+		bench.WithBudget(*budget),
+	}
+	if *dryRun {
+		plans, err := bench.PlanMatrix(ctx, ds, matrix, *calibrate, options...)
+		if err != nil {
+			return err
+		}
+		printPlans(out, plans)
+		return nil
+	}
+
+	sinks, err := openSinks(*sinkSpecs, *sqliteBinary, out)
+	if err != nil {
+		return err
+	}
+	results, err := bench.RunMatrix(ctx, ds, matrix, append(options, bench.WithSinks(sinks.sinks...))...)
+	if closeErr := sinks.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil && ctx.Err() == nil {
+		return err
+	}
+	interrupted = ctx.Err() != nil
+	switch {
+	case ctx.Err() != nil && *budget > 0:
+		fmt.Fprintf(out, "Interrupted after %d measurements\n", len(results.Rows))
+	case ctx.Err() != nil:
+		fmt.Fprintf(out, "Interrupted after %d of %d measurements\n", len(results.Rows), len(experiments)**repeat)
+	case *budget > 0:
+		fmt.Fprintf(out, "Made %d measurements of %d experiments in the %v budget\n", len(results.Rows), len(experiments), *budget)
+	}
+
+	if err := writeResultsFile(*output, results.WriteCSV); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Results saved to %s\n", *output)
+	if *jsonOutput != "" {
+		if err := writeResultsFile(*jsonOutput, results.WriteJSON); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "JSON results saved to %s\n", *jsonOutput)
+	}
+	return nil
+}
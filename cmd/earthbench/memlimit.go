@@ -0,0 +1,317 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/nkk36/earth-discretization-benchmark/report"
+)
+
+// The runtime/metrics samples read around every covering
+const (
+	metricTotalBytes    = "/memory/classes/total:bytes"
+	metricReleasedBytes = "/memory/classes/heap/released:bytes"
+	metricGCCycles      = "/gc/cycles/total:gc-cycles"
+	metricGCCPU         = "/cpu/classes/gc/total:cpu-seconds"
+)
+
+// MemLimitResult measures covering every feature at one resolution with
+// the Go soft memory limit (GOMEMLIMIT) at LimitMB, 0 for none. Footprint
+// is the memory the Go runtime held from the OS, without what it had
+// released; coverings after which it was above the limit are Failed, since
+// a container with a hard limit of that size would have been killed.
+// Memory the H3 C library allocates is outside the runtime and the limit.
+//
+// Over several rounds, Duration, GCCycles and GCCPU are the medians of the
+// rounds, while Failed and PeakFootprint are the worst round's.
+type MemLimitResult struct {
+	System        string
+	Resolution    int
+	LimitMB       int
+	Features      int
+	Cells         int
+	Failed        int
+	Duration      time.Duration
+	Baseline      time.Duration // the same coverings without a limit
+	GCCycles      uint64
+	GCCPU         time.Duration
+	PeakFootprint uint64
+	Rounds        int
+}
+
+// Reached reports whether the footprint ever got to the limit. Below it
+// the limit never started a collection, so the run measured nothing but
+// noise against the baseline.
+func (r MemLimitResult) Reached() bool {
+	return r.LimitMB > 0 && r.PeakFootprint >= uint64(r.LimitMB)<<20
+}
+
+// Throughput is the coverings per second
+func (r MemLimitResult) Throughput() float64 {
+	if r.Duration == 0 {
+		return 0
+	}
+	return float64(r.Features) / r.Duration.Seconds()
+}
+
+// Degradation is the share of the unlimited throughput lost to the limit,
+// negative when the run was faster
+func (r MemLimitResult) Degradation() float64 {
+	if r.Duration == 0 {
+		return 0
+	}
+	return 1 - float64(r.Baseline)/float64(r.Duration)
+}
+
+func runMemLimitCommand(args []string) error {
+	fs := flag.NewFlagSet("memlimit", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "4-7", "9-13")
+	limits := fs.String("limits-mb", "0,256,64,16", "soft memory limits to run under in MB, 0 for none (the baseline, run either way)")
+	rounds := fs.Int("rounds", 5, "times every limit is run at each sweep point, in a new random order each round; the median is reported")
+	seed := fs.Int64("seed", 1, "seed for the order of the limits in each round")
+	gcOff := fs.Bool("gc-off", false, "turn off the GOGC heap-growth trigger so only the limit starts collections, as GOGC=off with GOMEMLIMIT in containers")
+	output := fs.String("output", "output/memlimit.csv", "CSV file for the results")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *rounds < 1 {
+		return fmt.Errorf("-rounds must be at least 1")
+	}
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	limitsMB, err := parseIntRange(*limits)
+	if err != nil {
+		return fmt.Errorf("-limits-mb: %w", err)
+	}
+	for _, mb := range limitsMB {
+		if mb < 0 {
+			return fmt.Errorf("-limits-mb: %d is negative", mb)
+		}
+	}
+	limitsMB = append([]int{0}, slices.DeleteFunc(limitsMB, func(mb int) bool { return mb == 0 })...)
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d features from %s\n", len(ds.Features), ds.Path)
+
+	defer debug.SetMemoryLimit(debug.SetMemoryLimit(-1))
+	if *gcOff {
+		defer debug.SetGCPercent(debug.SetGCPercent(-1))
+	}
+
+	rng := rand.New(rand.NewSource(*seed))
+	var results []MemLimitResult
+	for _, sp := range sweepPoints {
+		// The first pass pays for cold caches and a heap still growing to
+		// its working size, whichever limit it runs under
+		if _, err := benchmarkMemLimit(ds, sp, 0, *sweep.MaxCells); err != nil {
+			return fmt.Errorf("%s resolution %d, warm-up: %w", sp.System, sp.Resolution, err)
+		}
+		runs := make(map[int][]MemLimitResult, len(limitsMB))
+		order := slices.Clone(limitsMB)
+		for range *rounds {
+			// A fixed order would give every limit the same position
+			// relative to the heap the previous one left behind
+			rng.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+			for _, mb := range order {
+				r, err := benchmarkMemLimit(ds, sp, mb, *sweep.MaxCells)
+				if err != nil {
+					return fmt.Errorf("%s resolution %d, limit %d MB: %w", sp.System, sp.Resolution, mb, err)
+				}
+				runs[mb] = append(runs[mb], r)
+			}
+		}
+		baseline := medianMemLimitResult(runs[0]).Duration
+		for _, mb := range limitsMB {
+			r := medianMemLimitResult(runs[mb])
+			r.Baseline = baseline
+			change := fmt.Sprintf("%+6.1f%% vs none", -100*r.Degradation())
+			if mb > 0 && !r.Reached() {
+				change = "not reached"
+			}
+			fmt.Printf("%s res %2d, limit %5s: %9d cells in %12v, %8.1f coverings/s (%15s), %5d GCs (%v CPU), peak %s, %d failed\n",
+				r.System, r.Resolution, memLimitName(r.LimitMB), r.Cells, r.Duration, r.Throughput(), change,
+				r.GCCycles, r.GCCPU.Round(time.Millisecond), formatBytes(float64(r.PeakFootprint)), r.Failed)
+			results = append(results, r)
+		}
+	}
+
+	printMemLimitSummary(results, limitsMB)
+
+	if err := saveMemLimitResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// memLimitName prints a limit of 0 as none
+func memLimitName(mb int) string {
+	if mb == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%dMB", mb)
+}
+
+// medianMemLimitResult combines the rounds of one limit at one sweep
+// point: the median duration and collections, the worst footprint and
+// failures
+func medianMemLimitResult(runs []MemLimitResult) MemLimitResult {
+	r := runs[0]
+	r.Rounds = len(runs)
+	durations := make([]float64, 0, len(runs))
+	cycles := make([]float64, 0, len(runs))
+	gcCPU := make([]float64, 0, len(runs))
+	for _, run := range runs {
+		durations = append(durations, float64(run.Duration))
+		cycles = append(cycles, float64(run.GCCycles))
+		gcCPU = append(gcCPU, float64(run.GCCPU))
+		r.Failed = max(r.Failed, run.Failed)
+		r.PeakFootprint = max(r.PeakFootprint, run.PeakFootprint)
+	}
+	slices.Sort(durations)
+	slices.Sort(cycles)
+	slices.Sort(gcCPU)
+	r.Duration = time.Duration(report.Percentile(durations, 50))
+	r.GCCycles = uint64(math.Round(report.Percentile(cycles, 50)))
+	r.GCCPU = time.Duration(report.Percentile(gcCPU, 50))
+	return r
+}
+
+// printMemLimitSummary totals every limit per system over the sweep
+// points: the throughput lost against no limit, over the sweep points
+// where the footprint reached the limit, and the failed coverings
+func printMemLimitSummary(results []MemLimitResult, limitsMB []int) {
+	fmt.Printf("\n%-6s %7s %12s %8s %12s %10s\n", "SYSTEM", "LIMIT", "DEGRADATION", "REACHED", "FAILED", "GCS")
+	for _, system := range []string{bench.SystemH3, bench.SystemS2} {
+		for _, mb := range limitsMB {
+			var duration, baseline time.Duration
+			var points, reached, features, failed int
+			var cycles uint64
+			for _, r := range results {
+				if r.System != system || r.LimitMB != mb {
+					continue
+				}
+				points++
+				if mb == 0 || r.Reached() {
+					reached++
+					duration += r.Duration
+					baseline += r.Baseline
+				}
+				features += r.Features
+				failed += r.Failed
+				cycles += r.GCCycles
+			}
+			if features == 0 {
+				continue
+			}
+			degradation := "not reached"
+			if reached > 0 {
+				total := MemLimitResult{Duration: duration, Baseline: baseline}
+				degradation = fmt.Sprintf("%.1f%%", 100*total.Degradation())
+			}
+			fmt.Printf("%-6s %7s %12s %4d/%-3d %5d/%6d %10d\n", system, memLimitName(mb), degradation, reached, points, failed, features, cycles)
+		}
+	}
+}
+
+// benchmarkMemLimit covers every feature at one sweep point under a soft
+// memory limit, reading the runtime's footprint after each covering while
+// its cells are still live
+func benchmarkMemLimit(ds *bench.Dataset, sp bench.SweepPoint, limitMB, maxCells int) (MemLimitResult, error) {
+	r := MemLimitResult{System: sp.System, Resolution: sp.Resolution, LimitMB: limitMB}
+	limit := int64(math.MaxInt64)
+	if limitMB > 0 {
+		limit = int64(limitMB) << 20
+	}
+	debug.SetMemoryLimit(limit)
+	runtime.GC()
+
+	samples := []metrics.Sample{{Name: metricTotalBytes}, {Name: metricReleasedBytes}, {Name: metricGCCycles}, {Name: metricGCCPU}}
+	metrics.Read(samples)
+	startCycles, startCPU := samples[2].Value.Uint64(), samples[3].Value.Float64()
+	for _, f := range ds.Features {
+		start := time.Now()
+		covering, err := bench.CoverFeature(f, sp.System, sp.Resolution, maxCells)
+		r.Duration += time.Since(start)
+		if err != nil {
+			return r, fmt.Errorf("feature %d: %w", f.FeatureID, err)
+		}
+		metrics.Read(samples)
+		footprint := samples[0].Value.Uint64() - samples[1].Value.Uint64()
+		r.PeakFootprint = max(r.PeakFootprint, footprint)
+		if limitMB > 0 && footprint > uint64(limit) {
+			r.Failed++
+		}
+		r.Features++
+		r.Cells += len(covering)
+	}
+	// The GC CPU estimate is only brought up to date by a collection
+	runtime.GC()
+	metrics.Read(samples)
+	r.GCCycles = samples[2].Value.Uint64() - startCycles - 1
+	r.GCCPU = time.Duration((samples[3].Value.Float64() - startCPU) * float64(time.Second))
+	return r, nil
+}
+
+func saveMemLimitResultsToCSV(filename string, results []MemLimitResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "Resolution", "LimitMB", "Features", "Cells", "Failed", "DurationNs", "BaselineDurationNs",
+		"CoveringsPerSecond", "Degradation", "GCCycles", "GCCPUNs", "PeakFootprintBytes", "LimitReached", "Rounds"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		// A limit the footprint never reached has no degradation to report
+		degradation := strconv.FormatFloat(r.Degradation(), 'f', -1, 64)
+		if r.LimitMB > 0 && !r.Reached() {
+			degradation = ""
+		}
+		row := []string{
+			r.System,
+			strconv.Itoa(r.Resolution),
+			strconv.Itoa(r.LimitMB),
+			strconv.Itoa(r.Features),
+			strconv.Itoa(r.Cells),
+			strconv.Itoa(r.Failed),
+			strconv.FormatInt(r.Duration.Nanoseconds(), 10),
+			strconv.FormatInt(r.Baseline.Nanoseconds(), 10),
+			strconv.FormatFloat(r.Throughput(), 'f', -1, 64),
+			degradation,
+			strconv.FormatUint(r.GCCycles, 10),
+			strconv.FormatInt(r.GCCPU.Nanoseconds(), 10),
+			strconv.FormatUint(r.PeakFootprint, 10),
+			strconv.FormatBool(r.Reached()),
+			strconv.Itoa(r.Rounds),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+)
+
+// machineRuns are the results of the runs made on one kind of machine (see
+// bench.RunInfo.Machine), merged into one set of repetitions
+type machineRuns struct {
+	Machine string
+	Info    bench.RunInfo
+	Results *bench.Results
+	Means   map[bench.SweepPoint]bench.Summary
+}
+
+func runMergeCommand(args []string) error {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	output := fs.String("output", "output/merge.csv", "CSV file with the summary of every sweep point on every machine")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: earthbench merge [flags] run...\n\nEach run is a sweep -json file, a -protobuf .pb file (either optionally .gz) or a directory holding sweep.json or sweep.pb. Runs from the same kind of machine are merged into one; the machine of the first run is the reference the others are compared with.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return fmt.Errorf("want at least two runs to merge, got %d", fs.NArg())
+	}
+
+	var machines []*machineRuns
+	for _, path := range fs.Args() {
+		results, err := loadRun(path)
+		if err != nil {
+			return err
+		}
+		var info bench.RunInfo
+		if results.Info != nil {
+			info = *results.Info
+		} else {
+			fmt.Printf("Warning: %s is not tagged with its machine (written before runs were); it is counted as unknown\n", path)
+		}
+		if results.Normalized() {
+			fmt.Printf("Warning: %s has normalized durations (sweep -normalize), which hide the differences between machines\n", path)
+		}
+		machine := info.Machine()
+		i := slices.IndexFunc(machines, func(m *machineRuns) bool { return m.Machine == machine })
+		if i < 0 {
+			machines = append(machines, &machineRuns{Machine: machine, Info: info, Results: results})
+			continue
+		}
+		if machines[i].Results, err = machines[i].Results.Merge(results); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if len(machines) < 2 {
+		return fmt.Errorf("all runs are from %s; merge compares different machines, diff compares runs on one", machines[0].Machine)
+	}
+
+	reference := machines[0]
+	var points []bench.SweepPoint
+	for _, m := range machines {
+		if bench.DatasetName(m.Results.Dataset) != bench.DatasetName(reference.Results.Dataset) {
+			fmt.Printf("Warning: comparing different datasets (%s and %s)\n", reference.Results.Dataset, m.Results.Dataset)
+		}
+		if m.Results.Options != reference.Results.Options {
+			fmt.Printf("Warning: the runs on %s used different covering options (%+v)\n", m.Machine, m.Results.Options)
+		}
+		m.Means = make(map[bench.SweepPoint]bench.Summary)
+		for _, s := range m.Results.Summary() {
+			sp := bench.SweepPoint{System: s.System, Resolution: s.Resolution}
+			m.Means[sp] = s
+			if !slices.Contains(points, sp) {
+				points = append(points, sp)
+			}
+		}
+		fmt.Printf("Machine %d: %s, %d measurement(s), commit %s, %s\n",
+			slices.Index(machines, m)+1, m.Machine, len(m.Results.Measurements), m.Info.Commit, m.Info.GoVersion)
+	}
+
+	printMergeTable(machines, points)
+	printArchScaling(machines, points)
+
+	if err := writeResultsFile(*output, func(w io.Writer) error { return writeMergeCSV(w, machines, points) }); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// vsReference is the mean duration of a sweep point on a machine over
+// that on the reference machine, 0 when either did not measure it
+func vsReference(m, reference *machineRuns, sp bench.SweepPoint) float64 {
+	s, ok := m.Means[sp]
+	ref, refOK := reference.Means[sp]
+	if !ok || !refOK || ref.MeanNs == 0 {
+		return 0
+	}
+	return s.MeanNs / ref.MeanNs
+}
+
+// printMergeTable prints the mean duration per feature of every sweep
+// point, one column per machine, with its ratio to the reference machine
+func printMergeTable(machines []*machineRuns, points []bench.SweepPoint) {
+	fmt.Printf("\nMean ns per feature by machine (x the time on machine 1)\n%-6s %4s", "SYSTEM", "RES")
+	for i := range machines {
+		fmt.Printf(" %22s", fmt.Sprintf("MACHINE %d", i+1))
+	}
+	fmt.Println()
+	for _, sp := range points {
+		fmt.Printf("%-6s %4d", sp.System, sp.Resolution)
+		for i, m := range machines {
+			s, ok := m.Means[sp]
+			switch {
+			case !ok:
+				fmt.Printf(" %22s", "-")
+			case i == 0:
+				fmt.Printf(" %22.0f", s.MeanNs)
+			default:
+				fmt.Printf(" %14.0f (x%5.2f)", s.MeanNs, vsReference(m, machines[0], sp))
+			}
+		}
+		fmt.Println()
+	}
+}
+
+// printArchScaling prints, for every machine and system, the geometric
+// mean of its time over the reference machine's across the sweep points
+// both measured. H3 runs C code through cgo and S2 pure Go, so the two
+// need not speed up or slow down alike from one architecture to another.
+func printArchScaling(machines []*machineRuns, points []bench.SweepPoint) {
+	systems := []string{bench.SystemH3, bench.SystemS2}
+	fmt.Printf("\nTime against machine 1, geometric mean over the shared sweep points\n%-50s", "MACHINE")
+	for _, system := range systems {
+		fmt.Printf(" %10s", system)
+	}
+	fmt.Printf(" %10s\n", "H3/S2")
+	for i, m := range machines[1:] {
+		fmt.Printf("%-50s", fmt.Sprintf("%d: %s", i+2, m.Machine))
+		ratios := make(map[string]float64)
+		for _, system := range systems {
+			var logSum float64
+			n := 0
+			for _, sp := range points {
+				if r := vsReference(m, machines[0], sp); sp.System == system && r > 0 {
+					logSum += math.Log(r)
+					n++
+				}
+			}
+			if n == 0 {
+				fmt.Printf(" %10s", "-")
+				continue
+			}
+			ratios[system] = math.Exp(logSum / float64(n))
+			fmt.Printf(" %9.2fx", ratios[system])
+		}
+		if ratios[bench.SystemH3] > 0 && ratios[bench.SystemS2] > 0 {
+			fmt.Printf(" %10.2f", ratios[bench.SystemH3]/ratios[bench.SystemS2])
+		}
+		fmt.Println()
+	}
+}
+
+// writeMergeCSV writes one row per machine and sweep point
+func writeMergeCSV(w io.Writer, machines []*machineRuns, points []bench.SweepPoint) error {
+	writer := csv.NewWriter(w)
+
+	headers := []string{"Machine", "GOOS", "GOARCH", "CPU", "Microarch", "Host", "System", "Resolution", "Repetitions",
+		"Cells", "MeanDurationNs", "MedianDurationNs", "P90DurationNs", "CellsPerSecond", "VsReference"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, sp := range points {
+		for _, m := range machines {
+			s, ok := m.Means[sp]
+			if !ok {
+				continue
+			}
+			row := []string{
+				m.Machine,
+				m.Info.OS,
+				m.Info.Arch,
+				m.Info.CPU,
+				m.Info.Microarch,
+				m.Info.Host,
+				sp.System,
+				strconv.Itoa(sp.Resolution),
+				strconv.Itoa(s.Repetitions),
+				strconv.Itoa(s.Cells),
+				strconv.FormatFloat(s.MeanNs, 'f', -1, 64),
+				strconv.FormatFloat(s.MedianNs, 'f', -1, 64),
+				strconv.FormatFloat(s.P90Ns, 'f', -1, 64),
+				strconv.FormatFloat(s.CellsPerSec, 'f', -1, 64),
+				strconv.FormatFloat(vsReference(m, machines[0], sp), 'f', -1, 64),
+			}
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
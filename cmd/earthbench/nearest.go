@@ -0,0 +1,335 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/uber/h3-go/v4"
+)
+
+// NearestResult is the outcome of one nearest-feature query method. Found
+// counts queries that returned a feature within the ring limit; Correct
+// counts those whose distance matches the s2.ClosestEdgeQuery answer, to
+// within nearestTolerance.
+type NearestResult struct {
+	System     string
+	Resolution int
+	Queries    int
+	Found      int
+	Correct    int
+	Rings      int
+	Duration   time.Duration
+}
+
+// AverageNs is the mean query latency
+func (r NearestResult) AverageNs() float64 {
+	if r.Queries == 0 {
+		return 0
+	}
+	return float64(r.Duration.Nanoseconds()) / float64(r.Queries)
+}
+
+// Accuracy is the share of the queries found that were answered with the
+// true nearest feature; queries given up on count in Found instead
+func (r NearestResult) Accuracy() float64 {
+	if r.Found == 0 {
+		return 0
+	}
+	return float64(r.Correct) / float64(r.Found)
+}
+
+// nearestTolerance is how much farther than the ClosestEdgeQuery answer a
+// ring search answer may be and still count as correct, about 6 mm on the
+// Earth: the two reach the same distance through different edge sets, so
+// they can disagree in the last bits
+const nearestTolerance = 1e-9 * s1.Radian
+
+// nearestIndex holds an exact distance query per feature, for refining the
+// candidates the cell search turns up, and one over all features for the
+// baseline
+type nearestIndex struct {
+	all      *s2.EdgeQuery
+	features []*s2.EdgeQuery
+}
+
+func newNearestIndex(ds *bench.Dataset) *nearestIndex {
+	opts := func() *s2.EdgeQueryOptions {
+		return s2.NewClosestEdgeQueryOptions().IncludeInteriors(true).MaxResults(1)
+	}
+	all := s2.NewShapeIndex()
+	idx := &nearestIndex{features: make([]*s2.EdgeQuery, len(ds.Features))}
+	for i, f := range ds.Features {
+		all.Add(f.S2Polygon)
+		one := s2.NewShapeIndex()
+		one.Add(f.S2Polygon)
+		idx.features[i] = s2.NewClosestEdgeQuery(one, opts())
+	}
+	idx.all = s2.NewClosestEdgeQuery(all, opts())
+	return idx
+}
+
+func runNearestCommand(args []string) error {
+	fs := flag.NewFlagSet("nearest", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "2-5", "4-10")
+	output := fs.String("output", "output/nearest.csv", "CSV file for the results")
+	queries := fs.Int("queries", 1000, "number of random query points")
+	seed := fs.Int64("seed", 1, "seed for the random query points")
+	maxRings := fs.Int("max-rings", 0, "give up after expanding this many rings around the query cell (0 for no limit)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d features from %s\n", len(ds.Features), ds.Path)
+
+	points := bench.RandomPointsInRect(ds.Bounds(), *queries, *seed)
+	idx := newNearestIndex(ds)
+
+	truth, baseline := benchmarkClosestEdgeQuery(idx, points)
+	results := []NearestResult{baseline}
+	for _, sp := range sweepPoints {
+		r, err := benchmarkRingSearch(ds, idx, sp, *sweep.MaxCells, points, truth, *maxRings)
+		if err != nil {
+			return fmt.Errorf("%s resolution %d: %w", sp.System, sp.Resolution, err)
+		}
+		results = append(results, r)
+	}
+
+	for _, r := range results {
+		avgRings := 0.0
+		if r.Found > 0 {
+			avgRings = float64(r.Rings) / float64(r.Found)
+		}
+		fmt.Printf("%-17s res %2d: %7d queries, %10.0f ns/query, %6d found, %6d correct (accuracy %.4f), %.1f rings/query\n",
+			r.System, r.Resolution, r.Queries, r.AverageNs(), r.Found, r.Correct, r.Accuracy(), avgRings)
+	}
+
+	if err := saveNearestResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// benchmarkClosestEdgeQuery answers every query with a single
+// s2.ClosestEdgeQuery over all polygons and returns the distances as ground
+// truth. Points inside a polygon are at distance zero.
+func benchmarkClosestEdgeQuery(idx *nearestIndex, points []s2.LatLng) ([]s1.ChordAngle, NearestResult) {
+	truth := make([]s1.ChordAngle, len(points))
+	result := NearestResult{System: "ClosestEdgeQuery", Resolution: -1}
+	start := time.Now()
+	for i, ll := range points {
+		truth[i] = idx.all.Distance(s2.NewMinDistanceToPointTarget(s2.PointFromLatLng(ll)))
+	}
+	result.Duration = time.Since(start)
+	result.Queries = len(points)
+	result.Found = len(points)
+	result.Correct = len(points)
+	return truth, result
+}
+
+// benchmarkRingSearch indexes the coverings and, per query, expands rings of
+// neighbouring cells around the query cell, measuring the exact distance to
+// every feature they turn up. Each ring encloses the ones before it, so the
+// search stops at the first ring farther from the query point than the
+// nearest feature found so far: nothing beyond it can be closer.
+func benchmarkRingSearch(ds *bench.Dataset, idx *nearestIndex, sp bench.SweepPoint, maxCells int, points []s2.LatLng, truth []s1.ChordAngle, maxRings int) (NearestResult, error) {
+	cells := make(map[uint64][]int)
+	for i, f := range ds.Features {
+		covering, err := bench.CoverFeature(f, sp.System, sp.Resolution, maxCells)
+		if err != nil {
+			return NearestResult{}, fmt.Errorf("feature %d: %w", f.FeatureID, err)
+		}
+		for _, cell := range covering {
+			cells[cell] = append(cells[cell], i)
+		}
+	}
+
+	result := NearestResult{System: sp.System, Resolution: sp.Resolution, Queries: len(points)}
+	start := time.Now()
+	for q, ll := range points {
+		origin, err := bench.PointCell(sp.System, ll, sp.Resolution)
+		if err != nil {
+			continue
+		}
+		p := s2.PointFromLatLng(ll)
+		target := s2.NewMinDistanceToPointTarget(p)
+		rings := newRingIterator(sp.System, origin, sp.Resolution)
+		measured := make(map[int]bool)
+		best := s1.InfChordAngle()
+		k := 0
+		for ; maxRings <= 0 || k <= maxRings; k++ {
+			ring, err := rings.next()
+			if err != nil {
+				return result, fmt.Errorf("ring %d around cell %x: %w", k, origin, err)
+			}
+			if len(ring) == 0 {
+				break // the whole grid has been searched
+			}
+			if nearestRingDistance(sp.System, ring, p) > best {
+				break
+			}
+			for _, cell := range ring {
+				for _, i := range cells[cell] {
+					if measured[i] {
+						continue
+					}
+					measured[i] = true
+					if d := idx.features[i].Distance(target); d < best {
+						best = d
+					}
+				}
+			}
+		}
+		if best == s1.InfChordAngle() {
+			continue
+		}
+		result.Found++
+		result.Rings += k
+		if best.Angle() <= truth[q].Angle()+nearestTolerance {
+			result.Correct++
+		}
+	}
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// ringIterator yields the cells at grid distance 0, 1, 2, ... from an
+// origin cell, each cell once
+type ringIterator struct {
+	system   string
+	origin   uint64
+	level    int
+	k        int
+	frontier []uint64
+	seen     map[uint64]bool
+}
+
+func newRingIterator(system string, origin uint64, level int) *ringIterator {
+	return &ringIterator{system: system, origin: origin, level: level, seen: make(map[uint64]bool)}
+}
+
+func (it *ringIterator) next() ([]uint64, error) {
+	defer func() { it.k++ }()
+	if it.k == 0 {
+		it.frontier = []uint64{it.origin}
+		it.seen[it.origin] = true
+		return it.frontier, nil
+	}
+
+	var ring []uint64
+	add := func(cell uint64) {
+		if !it.seen[cell] {
+			it.seen[cell] = true
+			ring = append(ring, cell)
+		}
+	}
+	if it.system == bench.SystemH3 {
+		// GridRing is built on the full disk, so take the fast ring and,
+		// where it fails near a pentagon, expand the previous ring by the
+		// neighbours of its cells instead
+		if cells, err := h3.GridRingUnsafe(h3.Cell(it.origin), it.k); err == nil {
+			for _, c := range cells {
+				add(uint64(c))
+			}
+		} else {
+			for _, cell := range it.frontier {
+				neighbours, err := h3.GridDisk(h3.Cell(cell), 1)
+				if err != nil {
+					return nil, err
+				}
+				for _, n := range neighbours {
+					add(uint64(n))
+				}
+			}
+		}
+	} else {
+		// S2 has no ring primitive: expand the previous ring by its
+		// edge and vertex neighbours
+		for _, cell := range it.frontier {
+			for _, n := range s2.CellID(cell).AllNeighbors(it.level) {
+				add(uint64(n))
+			}
+		}
+	}
+	it.frontier = ring
+	return ring, nil
+}
+
+// nearestRingDistance is a lower bound on the distance from p to the cells
+// of a ring: exact for S2 cells, and for H3 the distance to the cell center
+// less the farthest vertex, the cap around the cell
+func nearestRingDistance(system string, ring []uint64, p s2.Point) s1.ChordAngle {
+	nearest := s1.InfChordAngle()
+	for _, cell := range ring {
+		var d s1.ChordAngle
+		if system == bench.SystemH3 {
+			center, err := h3.CellToLatLng(h3.Cell(cell))
+			if err != nil {
+				return 0 // no bound, so the search goes on
+			}
+			boundary, err := h3.CellToBoundary(h3.Cell(cell))
+			if err != nil {
+				return 0
+			}
+			c := s2.PointFromLatLng(s2.LatLngFromDegrees(center.Lat, center.Lng))
+			var radius s1.Angle
+			for _, v := range boundary {
+				radius = max(radius, c.Distance(s2.PointFromLatLng(s2.LatLngFromDegrees(v.Lat, v.Lng))))
+			}
+			d = s1.ChordAngleFromAngle(max(0, p.Distance(c)-radius))
+		} else {
+			d = s2.CellFromCellID(s2.CellID(cell)).Distance(p)
+		}
+		nearest = min(nearest, d)
+	}
+	return nearest
+}
+
+func saveNearestResultsToCSV(filename string, results []NearestResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "Resolution", "Queries", "Found", "Correct", "Accuracy", "DurationNs", "AverageNs"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			r.System,
+			strconv.Itoa(r.Resolution),
+			strconv.Itoa(r.Queries),
+			strconv.Itoa(r.Found),
+			strconv.Itoa(r.Correct),
+			strconv.FormatFloat(r.Accuracy(), 'f', -1, 64),
+			strconv.FormatInt(r.Duration.Nanoseconds(), 10),
+			strconv.FormatFloat(r.AverageNs(), 'f', -1, 64),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+)
+
+// notifyFlags are the completion notification flags of long-running
+// commands
+type notifyFlags struct {
+	URL  *string
+	Link *string
+}
+
+func addNotifyFlags(fs *flag.FlagSet) *notifyFlags {
+	return &notifyFlags{
+		URL:  fs.String("notify", "", "POST a run summary as JSON to this webhook (e.g. a Slack incoming webhook) when the run finishes or fails"),
+		Link: fs.String("notify-link", "", "link to the results in the summary, e.g. the URL of `earthbench serve` (default: the output directory as a file:// URL)"),
+	}
+}
+
+// runNotification is the body posted to a webhook. Text is what Slack,
+// Mattermost and Teams-style webhooks display; the other fields are for
+// receivers that parse the summary.
+type runNotification struct {
+	Text            string    `json:"text"`
+	Command         string    `json:"command"`
+	Status          string    `json:"status"` // finished, interrupted or failed
+	Error           string    `json:"error,omitempty"`
+	Started         time.Time `json:"started"`
+	DurationSeconds float64   `json:"duration_s"`
+	Results         string    `json:"results"`
+	Headlines       []string  `json:"headlines,omitempty"`
+}
+
+// send posts the summary of a run that started at start and failed with
+// err, was interrupted or finished, with its results written next to
+// output. A webhook that cannot be reached only logs a warning: the results
+// are already saved by then.
+func (f *notifyFlags) send(command string, start time.Time, err error, interrupted bool, output string, headlines []string) {
+	if *f.URL == "" {
+		return
+	}
+	n := runNotification{
+		Command:         command,
+		Status:          "finished",
+		Started:         start.UTC(),
+		DurationSeconds: time.Since(start).Seconds(),
+		Results:         *f.Link,
+		Headlines:       headlines,
+	}
+	switch {
+	case interrupted:
+		n.Status = "interrupted"
+	case err != nil:
+		n.Status, n.Error = "failed", err.Error()
+	}
+	if n.Results == "" {
+		if dir, err := filepath.Abs(filepath.Dir(output)); err == nil && output != "-" {
+			n.Results = "file://" + filepath.ToSlash(dir)
+		}
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "earthbench %s %s after %v", command, n.Status, time.Since(start).Round(time.Second))
+	if n.Error != "" {
+		fmt.Fprintf(&text, ": %s", n.Error)
+	}
+	for _, headline := range headlines {
+		fmt.Fprintf(&text, "\n• %s", headline)
+	}
+	if n.Results != "" {
+		fmt.Fprintf(&text, "\nResults: %s", n.Results)
+	}
+	n.Text = text.String()
+
+	if err := postNotification(*f.URL, n); err != nil {
+		log.Printf("Warning: could not send the run summary to %s: %v", *f.URL, err)
+	}
+}
+
+// postNotification posts n as JSON, failing on any status but 2xx
+func postNotification(url string, n runNotification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		reply, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(reply)))
+	}
+	return nil
+}
+
+// sweepHeadlines sums up each dataset's results by the finest resolution
+// measured of each system, where the systems differ most
+func sweepHeadlines(all []*bench.Results) []string {
+	var headlines []string
+	for _, results := range all {
+		finest := make(map[string]bench.Summary)
+		var systems []string
+		for _, s := range results.Summary() {
+			if _, ok := finest[s.System]; !ok {
+				systems = append(systems, s.System)
+			}
+			if s.Resolution >= finest[s.System].Resolution {
+				finest[s.System] = s
+			}
+		}
+		var parts []string
+		for _, system := range systems {
+			s := finest[system]
+			parts = append(parts, fmt.Sprintf("%s res %d: %v/feature, %d cells, %.0f cells/s",
+				system, s.Resolution, time.Duration(s.MeanNs).Round(time.Microsecond), s.Cells, s.CellsPerSec))
+		}
+		if len(parts) > 0 {
+			headlines = append(headlines, bench.DatasetName(results.Dataset)+": "+strings.Join(parts, "; "))
+		}
+	}
+	return headlines
+}
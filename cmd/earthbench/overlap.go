@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+)
+
+// OverlapResult is the outcome of testing every feature pair for
+// intersection with one method. FalsePositives and FalseNegatives are
+// relative to the exact s2.Polygon.Intersects row (Resolution -1).
+type OverlapResult struct {
+	System         string
+	Resolution     int
+	Method         string
+	Pairs          int
+	Positives      int
+	FalsePositives int
+	FalseNegatives int
+	Duration       time.Duration
+}
+
+// FalsePositiveRate is the share of reported overlaps that are not real
+func (r OverlapResult) FalsePositiveRate() float64 {
+	if r.Positives == 0 {
+		return 0
+	}
+	return float64(r.FalsePositives) / float64(r.Positives)
+}
+
+// AverageNs is the mean cost of testing a single pair
+func (r OverlapResult) AverageNs() float64 {
+	if r.Pairs == 0 {
+		return 0
+	}
+	return float64(r.Duration.Nanoseconds()) / float64(r.Pairs)
+}
+
+func runOverlapCommand(args []string) error {
+	fs := flag.NewFlagSet("overlap", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "3-6", "6-11")
+	output := fs.String("output", "output/overlap.csv", "CSV file for the results")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	n := len(ds.Features)
+	fmt.Printf("Loaded %d features from %s (%d pairs)\n", n, ds.Path, n*(n-1)/2)
+
+	truth, exact := benchmarkExactOverlap(ds)
+	results := []OverlapResult{exact}
+	for _, sp := range sweepPoints {
+		coverings, err := bench.ComputeCoverings(ds, sp.System, sp.Resolution, *sweep.MaxCells)
+		if err != nil {
+			return fmt.Errorf("%s resolution %d: %w", sp.System, sp.Resolution, err)
+		}
+		if sp.System == bench.SystemH3 {
+			results = append(results, benchmarkH3SetOverlap(coverings, truth, sp.Resolution))
+		} else {
+			results = append(results, benchmarkS2UnionOverlap(coverings, truth, sp.Resolution))
+		}
+	}
+
+	for _, r := range results {
+		fmt.Printf("%-10s res %2d %-22s %8d pairs, %10.0f ns/pair, %6d overlaps, %5d false positives (%.2f%%), %d false negatives\n",
+			r.System, r.Resolution, r.Method, r.Pairs, r.AverageNs(), r.Positives,
+			r.FalsePositives, 100*r.FalsePositiveRate(), r.FalseNegatives)
+	}
+
+	if err := saveOverlapResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// forEachPair calls fn for every unordered pair of feature indexes, in the
+// same order for every method so truth can be a flat slice
+func forEachPair(n int, fn func(k, i, j int)) {
+	k := 0
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			fn(k, i, j)
+			k++
+		}
+	}
+}
+
+// benchmarkExactOverlap runs s2.Polygon.Intersects on every pair and returns
+// the answers as the ground truth for the covering-based methods
+func benchmarkExactOverlap(ds *bench.Dataset) ([]bool, OverlapResult) {
+	n := len(ds.Features)
+	truth := make([]bool, n*(n-1)/2)
+	result := OverlapResult{System: "s2.Polygon", Resolution: -1, Method: "exact_intersects"}
+	start := time.Now()
+	forEachPair(n, func(k, i, j int) {
+		truth[k] = ds.Features[i].S2Polygon.Intersects(ds.Features[j].S2Polygon)
+	})
+	result.Duration = time.Since(start)
+	for _, t := range truth {
+		result.Pairs++
+		if t {
+			result.Positives++
+		}
+	}
+	return truth, result
+}
+
+func (r *OverlapResult) record(got, want bool) {
+	r.Pairs++
+	switch {
+	case got && want:
+		r.Positives++
+	case got:
+		r.Positives++
+		r.FalsePositives++
+	case want:
+		r.FalseNegatives++
+	}
+}
+
+// benchmarkH3SetOverlap tests pairs by probing the smaller covering's cells
+// in a hash set of the larger one. H3 coverings of neighbouring polygons
+// can miss each other because polygonToCells uses center containment.
+func benchmarkH3SetOverlap(coverings [][]uint64, truth []bool, res int) OverlapResult {
+	sets := make([]map[uint64]struct{}, len(coverings))
+	for i, covering := range coverings {
+		sets[i] = make(map[uint64]struct{}, len(covering))
+		for _, cell := range covering {
+			sets[i][cell] = struct{}{}
+		}
+	}
+
+	result := OverlapResult{System: bench.SystemH3, Resolution: res, Method: "set_intersection"}
+	got := make([]bool, len(truth))
+	start := time.Now()
+	forEachPair(len(coverings), func(k, i, j int) {
+		small, large := coverings[i], sets[j]
+		if len(small) > len(coverings[j]) {
+			small, large = coverings[j], sets[i]
+		}
+		for _, cell := range small {
+			if _, ok := large[cell]; ok {
+				got[k] = true
+				break
+			}
+		}
+	})
+	result.Duration = time.Since(start)
+	for k := range truth {
+		result.record(got[k], truth[k])
+	}
+	return result
+}
+
+// benchmarkS2UnionOverlap tests pairs with s2.CellUnion.Intersects on
+// normalized coverings
+func benchmarkS2UnionOverlap(coverings [][]uint64, truth []bool, level int) OverlapResult {
+	unions := make([]s2.CellUnion, len(coverings))
+	for i, covering := range coverings {
+		unions[i] = make(s2.CellUnion, len(covering))
+		for k, cell := range covering {
+			unions[i][k] = s2.CellID(cell)
+		}
+		unions[i].Normalize()
+	}
+
+	result := OverlapResult{System: bench.SystemS2, Resolution: level, Method: "cellunion_intersects"}
+	got := make([]bool, len(truth))
+	start := time.Now()
+	forEachPair(len(unions), func(k, i, j int) {
+		got[k] = unions[i].Intersects(unions[j])
+	})
+	result.Duration = time.Since(start)
+	for k := range truth {
+		result.record(got[k], truth[k])
+	}
+	return result
+}
+
+func saveOverlapResultsToCSV(filename string, results []OverlapResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "Resolution", "Method", "Pairs", "Positives", "FalsePositives", "FalseNegatives", "FalsePositiveRate", "DurationNs", "AverageNs"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			r.System,
+			strconv.Itoa(r.Resolution),
+			r.Method,
+			strconv.Itoa(r.Pairs),
+			strconv.Itoa(r.Positives),
+			strconv.Itoa(r.FalsePositives),
+			strconv.Itoa(r.FalseNegatives),
+			strconv.FormatFloat(r.FalsePositiveRate(), 'f', -1, 64),
+			strconv.FormatInt(r.Duration.Nanoseconds(), 10),
+			strconv.FormatFloat(r.AverageNs(), 'f', -1, 64),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	dh3 "github.com/nkk36/earth-discretization-benchmark/discretize/h3"
+	ds2 "github.com/nkk36/earth-discretization-benchmark/discretize/s2"
+	"github.com/uber/h3-go/v4"
+)
+
+// TradeoffPoint is one covering representation of one polygon (or, in the
+// dataset summary, of all polygons with FeatureID -1): its size and how far
+// its area is from the polygon's. OverError is covering area outside the
+// polygon and UnderError polygon area the covering misses, both as a share
+// of the polygon area, estimated from area-uniform sample points.
+type TradeoffPoint struct {
+	FeatureID      int
+	System         string
+	Representation string
+	Parameter      int
+	Cells          int
+	OverError      float64
+	UnderError     float64
+	Pareto         bool
+}
+
+// Error is the symmetric-difference area as a share of the polygon area
+func (p TradeoffPoint) Error() float64 {
+	return p.OverError + p.UnderError
+}
+
+func runParetoCommand(args []string) error {
+	fs := flag.NewFlagSet("pareto", flag.ExitOnError)
+	dataset := addDatasetFlags(fs)
+	features := fs.String("features", "", "feature IDs to include, e.g. 1-50 (default all)")
+	h3Res := fs.String("h3-res", "0-6", "H3 resolutions (empty to skip H3)")
+	s2Levels := fs.String("s2-levels", "0-12", "S2 fixed levels (empty to skip S2)")
+	s2Budgets := fs.String("s2-max-cells", "4,8,16,32,64,128,256,512,1024", "MaxCells budgets for mixed-level S2 coverings")
+	samples := fs.Int("samples", 2000, "sample points per polygon for the error estimate")
+	seed := fs.Int64("seed", 1, "seed for the sample points")
+	output := fs.String("output", "output/pareto.csv", "CSV file for the dataset-level tradeoff")
+	detail := fs.String("detail", "output/pareto_features.csv", "CSV file for the per-polygon tradeoff (empty to skip)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	ds, err := dataset.LoadDataset()
+	if err != nil {
+		return err
+	}
+	if *features != "" {
+		ids, err := parseIntRange(*features)
+		if err != nil {
+			return fmt.Errorf("-features: %w", err)
+		}
+		ds = ds.Subset(ids)
+	}
+	var resolutions, levels, budgets []int
+	if *h3Res != "" {
+		if resolutions, err = parseIntRange(*h3Res); err != nil {
+			return fmt.Errorf("-h3-res: %w", err)
+		}
+	}
+	if *s2Levels != "" {
+		if levels, err = parseIntRange(*s2Levels); err != nil {
+			return fmt.Errorf("-s2-levels: %w", err)
+		}
+		if budgets, err = parseIntRange(*s2Budgets); err != nil {
+			return fmt.Errorf("-s2-max-cells: %w", err)
+		}
+	}
+	fmt.Printf("Measuring %d polygons with %d samples each\n", len(ds.Features), *samples)
+
+	var perFeature []TradeoffPoint
+	for i, f := range ds.Features {
+		points, err := featureTradeoffs(f, resolutions, levels, budgets, *samples, *seed+int64(i))
+		if err != nil {
+			return fmt.Errorf("feature %d: %w", f.FeatureID, err)
+		}
+		markPareto(points)
+		perFeature = append(perFeature, points...)
+	}
+
+	summary := summarizeTradeoffs(perFeature)
+	markPareto(summary)
+	for _, p := range summary {
+		marker := " "
+		if p.Pareto {
+			marker = "*"
+		}
+		fmt.Printf("%s %s %-19s %4d: %9d cells, error %.4f (over %.4f, under %.4f)\n",
+			marker, p.System, p.Representation, p.Parameter, p.Cells, p.Error(), p.OverError, p.UnderError)
+	}
+
+	if err := saveTradeoffsToCSV(*output, summary); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s (* marks the Pareto frontier)\n", *output)
+	if *detail != "" {
+		if err := saveTradeoffsToCSV(*detail, perFeature); err != nil {
+			return err
+		}
+		fmt.Printf("Per-polygon results saved to %s\n", *detail)
+	}
+	return nil
+}
+
+// areaSample is a sample point with whether it lies in the polygon
+type areaSample struct {
+	ll     s2.LatLng
+	leaf   s2.CellID
+	inside bool
+}
+
+// samplePolygonBounds draws points uniformly by area from a polygon's
+// bounding rectangle
+func samplePolygonBounds(polygon *s2.Polygon, n int, seed int64) ([]areaSample, int) {
+	rng := rand.New(rand.NewSource(seed))
+	rect := polygon.RectBound()
+	sinLo, sinHi := math.Sin(rect.Lat.Lo), math.Sin(rect.Lat.Hi)
+	samples := make([]areaSample, n)
+	inside := 0
+	for i := range samples {
+		lat := math.Asin(sinLo + rng.Float64()*(sinHi-sinLo))
+		lng := rect.Lng.Lo + rng.Float64()*rect.Lng.Length()
+		ll := s2.LatLng{Lat: s1.Angle(lat), Lng: s1.Angle(math.Remainder(lng, 2*math.Pi))}
+		p := s2.PointFromLatLng(ll)
+		samples[i] = areaSample{ll: ll, leaf: s2.CellIDFromLatLng(ll), inside: polygon.ContainsPoint(p)}
+		if samples[i].inside {
+			inside++
+		}
+	}
+	return samples, inside
+}
+
+// featureTradeoffs measures every representation of one polygon:
+//
+//   - H3 center-containment and overlapping fills at each resolution, each
+//     also compacted (same area, fewer cells)
+//   - S2 fixed-level coverings at each level, also normalized
+//   - S2 mixed-level coverings for each MaxCells budget
+func featureTradeoffs(f bench.Feature, resolutions, levels, budgets []int, numSamples int, seed int64) ([]TradeoffPoint, error) {
+	samples, inside := samplePolygonBounds(f.S2Polygon, numSamples, seed)
+	if inside == 0 {
+		return nil, fmt.Errorf("no sample points fell inside the polygon; raise -samples")
+	}
+	errors := func(contains func(i int, s areaSample) bool) (over, under float64) {
+		var o, u int
+		for i, s := range samples {
+			c := contains(i, s)
+			if c && !s.inside {
+				o++
+			} else if !c && s.inside {
+				u++
+			}
+		}
+		return float64(o) / float64(inside), float64(u) / float64(inside)
+	}
+	point := func(system, rep string, param, cells int, over, under float64) TradeoffPoint {
+		return TradeoffPoint{FeatureID: f.FeatureID, System: system, Representation: rep, Parameter: param, Cells: cells, OverError: over, UnderError: under}
+	}
+
+	var points []TradeoffPoint
+	for _, res := range resolutions {
+		sampleCells := make([]uint64, len(samples))
+		for i, s := range samples {
+			c, err := dh3.PointCell(s.ll.Lat.Degrees(), s.ll.Lng.Degrees(), res)
+			if err != nil {
+				return nil, err
+			}
+			sampleCells[i] = c
+		}
+		for _, mode := range []struct {
+			name string
+			mode h3.ContainmentMode
+		}{{"center", h3.ContainmentCenter}, {"overlapping", h3.ContainmentOverlapping}} {
+			cells, err := h3.PolygonToCellsExperimental(f.H3Polygon, res, mode.mode)
+			if err != nil {
+				return nil, err
+			}
+			set := make(map[uint64]bool, len(cells))
+			for _, c := range cells {
+				set[uint64(c)] = true
+			}
+			over, under := errors(func(i int, _ areaSample) bool { return set[sampleCells[i]] })
+			// CompactCells panics on an empty slice
+			compacted := cells
+			if len(cells) > 0 {
+				if compacted, err = h3.CompactCells(cells); err != nil {
+					return nil, err
+				}
+			}
+			points = append(points,
+				point(bench.SystemH3, mode.name, res, len(cells), over, under),
+				point(bench.SystemH3, mode.name+"_compact", res, len(compacted), over, under))
+		}
+	}
+
+	for _, level := range levels {
+		covering := ds2.Cover(f.S2Polygon, level, 8)
+		union := make(s2.CellUnion, len(covering))
+		for i, c := range covering {
+			union[i] = s2.CellID(c)
+		}
+		union.Normalize()
+		over, under := errors(func(_ int, s areaSample) bool { return union.ContainsCellID(s.leaf) })
+		points = append(points,
+			point(bench.SystemS2, "fixed", level, len(covering), over, under),
+			point(bench.SystemS2, "fixed_normalized", level, len(union), over, under))
+	}
+	for _, budget := range budgets {
+		rc := &s2.RegionCoverer{MinLevel: 0, MaxLevel: s2.MaxLevel, MaxCells: budget, LevelMod: 1}
+		union := rc.Covering(f.S2Polygon)
+		over, under := errors(func(_ int, s areaSample) bool { return union.ContainsCellID(s.leaf) })
+		points = append(points, point(bench.SystemS2, "mixed", budget, len(union), over, under))
+	}
+	return points, nil
+}
+
+// summarizeTradeoffs totals cells and averages errors over polygons for
+// each system/representation/parameter
+func summarizeTradeoffs(points []TradeoffPoint) []TradeoffPoint {
+	type key struct {
+		system, rep string
+		param       int
+	}
+	sums := make(map[key]*TradeoffPoint)
+	counts := make(map[key]int)
+	var order []key
+	for _, p := range points {
+		k := key{p.System, p.Representation, p.Parameter}
+		s, ok := sums[k]
+		if !ok {
+			s = &TradeoffPoint{FeatureID: -1, System: p.System, Representation: p.Representation, Parameter: p.Parameter}
+			sums[k] = s
+			order = append(order, k)
+		}
+		s.Cells += p.Cells
+		s.OverError += p.OverError
+		s.UnderError += p.UnderError
+		counts[k]++
+	}
+	summary := make([]TradeoffPoint, len(order))
+	for i, k := range order {
+		s := *sums[k]
+		s.OverError /= float64(counts[k])
+		s.UnderError /= float64(counts[k])
+		summary[i] = s
+	}
+	return summary
+}
+
+// markPareto flags, per FeatureID and system, the points no other point
+// beats on both cell count and error
+func markPareto(points []TradeoffPoint) {
+	groups := make(map[string][]int)
+	for i, p := range points {
+		k := strconv.Itoa(p.FeatureID) + "/" + p.System
+		groups[k] = append(groups[k], i)
+	}
+	for _, idx := range groups {
+		sort.Slice(idx, func(a, b int) bool {
+			pa, pb := points[idx[a]], points[idx[b]]
+			if pa.Cells != pb.Cells {
+				return pa.Cells < pb.Cells
+			}
+			return pa.Error() < pb.Error()
+		})
+		best := math.Inf(1)
+		for _, i := range idx {
+			if e := points[i].Error(); e < best {
+				points[i].Pareto = true
+				best = e
+			}
+		}
+	}
+}
+
+func saveTradeoffsToCSV(filename string, points []TradeoffPoint) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"FeatureID", "System", "Representation", "Parameter", "Cells", "OverError", "UnderError", "Error", "Pareto"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, p := range points {
+		row := []string{
+			strconv.Itoa(p.FeatureID),
+			p.System,
+			p.Representation,
+			strconv.Itoa(p.Parameter),
+			strconv.Itoa(p.Cells),
+			strconv.FormatFloat(p.OverError, 'f', -1, 64),
+			strconv.FormatFloat(p.UnderError, 'f', -1, 64),
+			strconv.FormatFloat(p.Error(), 'f', -1, 64),
+			strconv.FormatBool(p.Pareto),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
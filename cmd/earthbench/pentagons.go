@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/uber/h3-go/v4"
+)
+
+// PentagonEncounter is a feature whose H3 covering at one resolution holds
+// one of the twelve pentagons, or cells within -ring steps of one, where
+// the grid is distorted: a pentagon has five neighbours instead of six,
+// and its neighbours' neighbourhoods are irregular
+type PentagonEncounter struct {
+	FeatureID    int
+	Resolution   int
+	Cells        int
+	Pentagons    []uint64
+	NearPentagon int // cells within the ring, pentagons excluded
+}
+
+func runPentagonsCommand(args []string) error {
+	fs := flag.NewFlagSet("pentagons", flag.ExitOnError)
+	dataset := addDatasetFlags(fs)
+	h3Res := fs.String("h3-res", "0-7", "H3 resolutions to check")
+	ring := fs.Int("ring", 1, "grid distance from a pentagon within which cells count as distorted (0 for pentagons only)")
+	output := fs.String("output", "output/pentagons.csv", "CSV file of the features whose coverings meet pentagons")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	resolutions, err := parseIntRange(*h3Res)
+	if err != nil {
+		return fmt.Errorf("-h3-res: %w", err)
+	}
+	if *ring < 0 {
+		return fmt.Errorf("-ring must not be negative")
+	}
+	ds, err := dataset.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d features from %s\n", len(ds.Features), ds.Path)
+
+	var encounters []PentagonEncounter
+	for _, res := range resolutions {
+		pentagons, near, err := pentagonNeighbourhood(res, *ring)
+		if err != nil {
+			return fmt.Errorf("resolution %d: %w", res, err)
+		}
+		withPentagons, onlyNear := 0, 0
+		for _, f := range ds.Features {
+			covering, err := bench.CoverFeature(f, bench.SystemH3, res, 0)
+			if err != nil {
+				return fmt.Errorf("resolution %d, feature %d: %w", res, f.FeatureID, err)
+			}
+			e := PentagonEncounter{FeatureID: f.FeatureID, Resolution: res, Cells: len(covering)}
+			for _, cell := range covering {
+				if pentagons[cell] {
+					e.Pentagons = append(e.Pentagons, cell)
+				} else if near[cell] {
+					e.NearPentagon++
+				}
+			}
+			if len(e.Pentagons) > 0 {
+				withPentagons++
+			} else if e.NearPentagon > 0 {
+				onlyNear++
+			} else {
+				continue
+			}
+			encounters = append(encounters, e)
+		}
+		fmt.Printf("H3 res %2d: %d features include a pentagon, %d more include cells within %d of one\n", res, withPentagons, onlyNear, *ring)
+	}
+
+	for _, e := range encounters {
+		if len(e.Pentagons) > 0 {
+			fmt.Printf("  feature %d at res %d: pentagon %s, %d cells near it\n", e.FeatureID, e.Resolution, pentagonTokens(e.Pentagons), e.NearPentagon)
+		}
+	}
+
+	if err := savePentagonEncountersToCSV(*output, encounters); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// pentagonNeighbourhood returns the pentagons of a resolution and the other
+// cells within ring steps of them
+func pentagonNeighbourhood(res, ring int) (pentagons, near map[uint64]bool, err error) {
+	cells, err := h3.Pentagons(res)
+	if err != nil {
+		return nil, nil, err
+	}
+	pentagons, near = make(map[uint64]bool), make(map[uint64]bool)
+	for _, p := range cells {
+		pentagons[uint64(p)] = true
+	}
+	for _, p := range cells {
+		disk, err := p.GridDisk(ring)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, c := range disk {
+			if !pentagons[uint64(c)] {
+				near[uint64(c)] = true
+			}
+		}
+	}
+	return pentagons, near, nil
+}
+
+// pentagonTokens joins cells as H3 index strings
+func pentagonTokens(cells []uint64) string {
+	tokens := make([]string, len(cells))
+	for i, cell := range cells {
+		tokens[i] = cellToken(bench.SystemH3, cell)
+	}
+	return strings.Join(tokens, ";")
+}
+
+// savePentagonEncountersToCSV writes one row per feature and resolution
+// whose covering meets a pentagon or its neighbourhood
+func savePentagonEncountersToCSV(filename string, encounters []PentagonEncounter) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"FeatureID", "Resolution", "Cells", "Pentagons", "PentagonCells", "NearPentagonCells"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, e := range encounters {
+		row := []string{
+			strconv.Itoa(e.FeatureID),
+			strconv.Itoa(e.Resolution),
+			strconv.Itoa(e.Cells),
+			strconv.Itoa(len(e.Pentagons)),
+			pentagonTokens(e.Pentagons),
+			strconv.Itoa(e.NearPentagon),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
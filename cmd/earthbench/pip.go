@@ -0,0 +1,143 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+)
+
+func runPIPCommand(args []string) error {
+	fs := flag.NewFlagSet("pip", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "3-7", "6-12")
+	output := fs.String("output", "output/pip.csv", "CSV file for the results")
+	queries := fs.Int("queries", 1000000, "number of random query points")
+	seed := fs.Int64("seed", 1, "seed for the random query points")
+	pointsFile := fs.String("points-file", "", "query points from gen-points (CSV or GeoJSON) instead of -queries random ones")
+	refine := fs.Bool("refine", true, "also time lookups refined with an exact s2.Polygon.ContainsPoint on the candidates")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d features from %s\n", len(ds.Features), ds.Path)
+
+	points, err := benchmarkPoints(*pointsFile, ds.Bounds(), *queries, *seed)
+	if err != nil {
+		return err
+	}
+
+	baseline := benchmarkContainsPointBaseline(ds, points)
+	results := []OperationResult{baseline}
+	for _, sp := range sweepPoints {
+		rows, err := benchmarkPointInPolygon(ds, sp, *sweep.MaxCells, points, *refine)
+		if err != nil {
+			return fmt.Errorf("%s resolution %d: %w", sp.System, sp.Resolution, err)
+		}
+		results = append(results, rows...)
+	}
+
+	printOperationResults(results)
+	for _, r := range results {
+		if r.Operation == "lookup_refine" && baseline.Matches > 0 {
+			fmt.Printf("%s res %2d recall after refine: %.4f\n", r.System, r.Resolution, float64(r.Matches)/float64(baseline.Matches))
+		}
+	}
+
+	if err := saveOperationResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// benchmarkContainsPointBaseline answers every query with an exact
+// ContainsPoint against each polygon whose bounding rectangle holds the
+// point; its matches are the ground truth for the cell-based rows
+func benchmarkContainsPointBaseline(ds *bench.Dataset, points []s2.LatLng) OperationResult {
+	bounds := make([]s2.Rect, len(ds.Features))
+	for i, f := range ds.Features {
+		bounds[i] = f.S2Polygon.RectBound()
+	}
+
+	result := OperationResult{System: "s2.Polygon", Resolution: -1, Operation: "contains_point"}
+	start := time.Now()
+	for _, ll := range points {
+		p := s2.PointFromLatLng(ll)
+		for i, f := range ds.Features {
+			if bounds[i].ContainsLatLng(ll) && f.S2Polygon.ContainsPoint(p) {
+				result.Matches++
+				break
+			}
+		}
+		result.Operations++
+	}
+	result.Duration = time.Since(start)
+	return result
+}
+
+// benchmarkPointInPolygon indexes the coverings of one sweep point and times
+// the queries twice: a bare cell lookup, whose matches include points in
+// covering cells that fall outside the polygon, and a lookup refined with an
+// exact test on the candidate polygons
+func benchmarkPointInPolygon(ds *bench.Dataset, sp bench.SweepPoint, maxCells int, points []s2.LatLng, refine bool) ([]OperationResult, error) {
+	index := OperationResult{System: sp.System, Resolution: sp.Resolution, Operation: "index"}
+	cells := make(map[uint64][]int)
+	start := time.Now()
+	for i, f := range ds.Features {
+		covering, err := bench.CoverFeature(f, sp.System, sp.Resolution, maxCells)
+		if err != nil {
+			return nil, fmt.Errorf("feature %d: %w", f.FeatureID, err)
+		}
+		for _, cell := range covering {
+			cells[cell] = append(cells[cell], i)
+		}
+		index.Operations++
+	}
+	index.Duration = time.Since(start)
+
+	lookup := OperationResult{System: sp.System, Resolution: sp.Resolution, Operation: "lookup"}
+	start = time.Now()
+	for _, ll := range points {
+		cell, err := bench.PointCell(sp.System, ll, sp.Resolution)
+		if err == nil && len(cells[cell]) > 0 {
+			lookup.Matches++
+		}
+		lookup.Operations++
+	}
+	lookup.Duration = time.Since(start)
+
+	results := []OperationResult{index, lookup}
+	if !refine {
+		return results, nil
+	}
+
+	refined := OperationResult{System: sp.System, Resolution: sp.Resolution, Operation: "lookup_refine"}
+	start = time.Now()
+	for _, ll := range points {
+		cell, err := bench.PointCell(sp.System, ll, sp.Resolution)
+		if err == nil {
+			if candidates := cells[cell]; len(candidates) > 0 {
+				p := s2.PointFromLatLng(ll)
+				for _, i := range candidates {
+					if ds.Features[i].S2Polygon.ContainsPoint(p) {
+						refined.Matches++
+						break
+					}
+				}
+			}
+		}
+		refined.Operations++
+	}
+	refined.Duration = time.Since(start)
+	return append(results, refined), nil
+}
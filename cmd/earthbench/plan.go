@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+)
+
+// printPlans prints the plans of a dry run, one block per group of sweep
+// points run together, and the estimated total
+func printPlans(out io.Writer, plans []*bench.Plan) {
+	var total time.Duration
+	var coverings int
+	var calibration time.Duration
+	for _, plan := range plans {
+		fmt.Fprintf(out, "\nDataset %s: %d features, %d repetition(s), %d sweep point(s) at once", plan.Dataset, plan.Features, plan.Repetitions, plan.Concurrency)
+		if slices.ContainsFunc(plan.Points, func(pp bench.PlannedPoint) bool { return pp.System == bench.SystemS2 }) {
+			fmt.Fprintf(out, ", S2 MaxCells %d", plan.Options.MaxCells)
+		}
+		if plan.Options.H3Containment != "" {
+			fmt.Fprintf(out, ", H3 containment %s", plan.Options.H3Containment)
+		}
+		fmt.Fprintln(out)
+		fmt.Fprintf(out, "%-6s %4s %12s %14s %14s\n", "SYSTEM", "RES", "COVERINGS", "PER COVERING", "ESTIMATED")
+		for _, pp := range plan.Points {
+			note := ""
+			if pp.TimedOut > 0 || pp.OverCap > 0 {
+				note = fmt.Sprintf("  at least; %d of %d calibration coverings timed out, %d over the cell cap", pp.TimedOut, plan.Sample, pp.OverCap)
+			}
+			fmt.Fprintf(out, "%-6s %4d %12d %14v %14v%s\n", pp.System, pp.Resolution, pp.Coverings,
+				pp.PerCovering.Round(time.Microsecond), pp.Estimated().Round(time.Millisecond), note)
+		}
+		total += plan.Estimated()
+		coverings += plan.Coverings()
+		calibration += plan.Calibration
+	}
+	fmt.Fprintf(out, "\n%d coverings, estimated %v (calibrated on %d feature(s) in %v)\n",
+		coverings, total.Round(time.Millisecond), plans[0].Sample, calibration.Round(time.Millisecond))
+}
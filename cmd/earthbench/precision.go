@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+)
+
+// metersPerDegree is the length of a degree of latitude, for the position
+// shift rounding to some decimal places can cause
+const metersPerDegree = 111_320.0
+
+// PrecisionResult compares the coverings of a dataset with its coordinates
+// rounded to Decimals places against those at full precision, at one system
+// and resolution. Features counts the rounded features compared, matched by
+// FeatureID; Dropped those rounding collapsed. Identical counts coverings
+// with exactly the full-precision cells, and ChangedCells the cells gained
+// or lost across all of them. AreaChange is the mean relative change in
+// polygon area.
+type PrecisionResult struct {
+	System        string
+	Resolution    int
+	Decimals      int
+	Features      int
+	Dropped       int
+	Cells         int
+	BaselineCells int
+	Identical     int
+	ChangedCells  int
+	AreaChange    float64
+	MeanNs        float64
+	BaselineNs    float64
+}
+
+// CellChange is the share of the full-precision cells gained or lost
+func (r PrecisionResult) CellChange() float64 {
+	if r.BaselineCells == 0 {
+		return 0
+	}
+	return float64(r.ChangedCells) / float64(r.BaselineCells)
+}
+
+// TimeRatio is the mean covering time against full precision
+func (r PrecisionResult) TimeRatio() float64 {
+	if r.BaselineNs == 0 {
+		return 0
+	}
+	return r.MeanNs / r.BaselineNs
+}
+
+func runPrecisionCommand(args []string) error {
+	fs := flag.NewFlagSet("precision", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "5,7", "10,13")
+	decimalsFlag := fs.String("decimals", "3-7", "decimal places to round coordinates to, each compared with full precision")
+	output := fs.String("output", "output/precision.csv", "CSV file for the results")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *sweep.Round >= 0 {
+		return fmt.Errorf("-round does not apply to precision: the full-precision dataset is the baseline, set -decimals instead")
+	}
+
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	decimals, err := parseIntRange(*decimalsFlag)
+	if err != nil {
+		return fmt.Errorf("-decimals: %w", err)
+	}
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d features from %s\n", len(ds.Features), ds.Path)
+
+	baselineAreas := make(map[int]float64, len(ds.Features))
+	for _, f := range ds.Features {
+		baselineAreas[f.FeatureID] = f.AreaKm2()
+	}
+	rounded := make(map[int]*bench.Dataset, len(decimals))
+	for _, d := range decimals {
+		if d < 0 {
+			return fmt.Errorf("-decimals: %d is negative", d)
+		}
+		rounded[d] = ds.Round(d)
+		fmt.Printf("%d decimal places (positions move up to %.3g m): %d of %d features kept, %d ring(s) repaired or dropped\n",
+			d, 0.5*math.Pow(10, -float64(d))*metersPerDegree*math.Sqrt2, len(rounded[d].Features), len(ds.Features), len(rounded[d].Issues))
+	}
+
+	options := bench.CoveringOptions{MaxCells: *sweep.MaxCells}
+	var results []PrecisionResult
+	for _, sp := range sweepPoints {
+		baselineNs, baseline, err := precisionCoverings(ds, sp, options)
+		if err != nil {
+			return err
+		}
+		for _, d := range decimals {
+			r, err := comparePrecision(rounded[d], sp, options, baseline, baselineAreas)
+			if err != nil {
+				return err
+			}
+			r.Decimals, r.BaselineNs = d, baselineNs
+			r.Dropped = len(ds.Features) - r.Features
+			fmt.Printf("%s res %2d, %d decimals: %5d of %5d coverings identical, %6.3f%% cells changed, area %+.2e, time x%.3f\n",
+				r.System, r.Resolution, r.Decimals, r.Identical, r.Features, 100*r.CellChange(), r.AreaChange, r.TimeRatio())
+			results = append(results, r)
+		}
+	}
+
+	if err := savePrecisionResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// precisionCoverings times the dataset's coverings through
+// BenchmarkCoverings and returns the mean time with the coverings by
+// FeatureID
+func precisionCoverings(ds *bench.Dataset, sp bench.SweepPoint, options bench.CoveringOptions) (float64, map[int][]uint64, error) {
+	measurements, err := bench.BenchmarkCoverings(context.Background(), ds, []bench.SweepPoint{sp}, options)
+	if err != nil {
+		return 0, nil, err
+	}
+	coverings, err := bench.ComputeCoverings(ds, sp.System, sp.Resolution, options.MaxCells)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s resolution %d: %w", sp.System, sp.Resolution, err)
+	}
+	byID := make(map[int][]uint64, len(coverings))
+	for i, f := range ds.Features {
+		byID[f.FeatureID] = coverings[i]
+	}
+	return measurements[0].AverageDurationNs(), byID, nil
+}
+
+// comparePrecision covers a rounded dataset and compares every covering
+// with the full-precision one of the same feature
+func comparePrecision(ds *bench.Dataset, sp bench.SweepPoint, options bench.CoveringOptions, baseline map[int][]uint64, baselineAreas map[int]float64) (PrecisionResult, error) {
+	r := PrecisionResult{System: sp.System, Resolution: sp.Resolution, Features: len(ds.Features)}
+	if len(ds.Features) == 0 {
+		return r, nil
+	}
+	meanNs, coverings, err := precisionCoverings(ds, sp, options)
+	if err != nil {
+		return r, err
+	}
+	r.MeanNs = meanNs
+
+	for _, f := range ds.Features {
+		before, after := baseline[f.FeatureID], coverings[f.FeatureID]
+		r.BaselineCells += len(before)
+		r.Cells += len(after)
+		inBefore := make(map[uint64]bool, len(before))
+		for _, c := range before {
+			inBefore[c] = true
+		}
+		changed := 0
+		for _, c := range after {
+			if inBefore[c] {
+				delete(inBefore, c)
+			} else {
+				changed++ // gained
+			}
+		}
+		changed += len(inBefore) // lost
+		if changed == 0 {
+			r.Identical++
+		}
+		r.ChangedCells += changed
+		if area := baselineAreas[f.FeatureID]; area > 0 {
+			r.AreaChange += (f.AreaKm2() - area) / area
+		}
+	}
+	r.AreaChange /= float64(len(ds.Features))
+	return r, nil
+}
+
+func savePrecisionResultsToCSV(filename string, results []PrecisionResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "Resolution", "Decimals", "Features", "Dropped", "Cells", "BaselineCells", "IdenticalCoverings",
+		"ChangedCells", "CellChange", "AreaChange", "AverageDurationNs", "BaselineDurationNs", "TimeRatio"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			r.System,
+			strconv.Itoa(r.Resolution),
+			strconv.Itoa(r.Decimals),
+			strconv.Itoa(r.Features),
+			strconv.Itoa(r.Dropped),
+			strconv.Itoa(r.Cells),
+			strconv.Itoa(r.BaselineCells),
+			strconv.Itoa(r.Identical),
+			strconv.Itoa(r.ChangedCells),
+			strconv.FormatFloat(r.CellChange(), 'f', -1, 64),
+			strconv.FormatFloat(r.AreaChange, 'g', -1, 64),
+			strconv.FormatFloat(r.MeanNs, 'f', 0, 64),
+			strconv.FormatFloat(r.BaselineNs, 'f', 0, 64),
+			strconv.FormatFloat(r.TimeRatio(), 'f', -1, 64),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// presets are named sets of sweep flag values, from a first look at a
+// dataset to a run worth publishing. A preset only fills flags that are not
+// set on the command line, in the environment or in the config file.
+var presets = map[string]map[string]string{
+	// quick finishes in a couple of minutes on a few thousand features: the
+	// cheap resolutions, a stratified sample and a single timing each
+	"quick": {
+		"h3-res":            "0-6",
+		"s2-levels":         "0-10",
+		"sample-per-bucket": "10",
+		"precision":         "0",
+		"repeat":            "1",
+	},
+	// standard is the default sweep repeated, to see the run-to-run spread
+	"standard": {
+		"h3-res":      "0-8",
+		"s2-levels":   "0-13",
+		"precision":   "0.05",
+		"max-samples": "20",
+		"repeat":      "3",
+	},
+	// exhaustive covers every feature down to street-level cells, times each
+	// covering to 1% and checks it against its system's contract; expect
+	// hours on a large dataset
+	"exhaustive": {
+		"h3-res":      "0-10",
+		"s2-levels":   "0-16",
+		"precision":   "0.01",
+		"max-samples": "200",
+		"repeat":      "5",
+		"verify":      "20",
+		"h3-cell-cap": "50000000",
+	},
+}
+
+// presetNames lists the presets for flag help and errors
+func presetNames() string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return strings.Join(names, ", ")
+}
+
+// addPresetFlag adds -preset to a command; parseFlags applies the chosen
+// preset to the command's other flags
+func addPresetFlag(fs *flag.FlagSet) {
+	fs.String("preset", "", "fill resolutions, repetitions and sampling from a named preset: "+presetNames()+" (flags given explicitly win)")
+}
+
+// applyPreset sets the flags of the preset selected by fs's -preset that
+// are not in set, the flags already given a value
+func applyPreset(fs *flag.FlagSet, set map[string]bool) error {
+	f := fs.Lookup("preset")
+	if f == nil || f.Value.String() == "" {
+		return nil
+	}
+	preset, ok := presets[f.Value.String()]
+	if !ok {
+		return fmt.Errorf("unknown -preset %q (want %s)", f.Value.String(), presetNames())
+	}
+	for name, value := range preset {
+		if set[name] || fs.Lookup(name) == nil {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("-preset %s: -%s: %w", f.Value.String(), name, err)
+		}
+	}
+	return nil
+}
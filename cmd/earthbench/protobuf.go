@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	earthbenchpb "github.com/nkk36/earth-discretization-benchmark/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// runToProto converts the results of a run to the Run message of
+// proto/results.proto
+func runToProto(results *bench.Results) *earthbenchpb.Run {
+	run := &earthbenchpb.Run{
+		Dataset: results.Dataset,
+		Options: &earthbenchpb.CoveringOptions{
+			MaxCells:         int32(results.Options.MaxCells),
+			TimeoutNs:        results.Options.Timeout.Nanoseconds(),
+			H3CellCap:        int64(results.Options.H3CellCap),
+			Verify:           int32(results.Options.Verify),
+			H3Containment:    results.Options.H3Containment,
+			Precision:        results.Options.Precision,
+			MaxSamples:       int32(results.Options.MaxSamples),
+			CentroidFallback: results.Options.CentroidFallback,
+			S2LevelSpan:      int32(results.Options.S2LevelSpan),
+		},
+	}
+	if info := results.Info; info != nil {
+		run.Info = &earthbenchpb.RunInfo{
+			Commit:           info.Commit,
+			GoVersion:        info.GoVersion,
+			H3GoVersion:      info.H3Version,
+			GolangGeoVersion: info.GeoVersion,
+			Host:             info.Host,
+			Goos:             info.OS,
+			Goarch:           info.Arch,
+			Cpu:              info.CPU,
+			Microarch:        info.Microarch,
+		}
+	}
+	if c := results.Calibration; c != nil {
+		run.Calibration = &earthbenchpb.Calibration{Score: c.Score, WorkloadNs: c.WorkloadNs, Normalized: c.Normalized}
+	}
+	for _, m := range results.Measurements {
+		pm := &earthbenchpb.CoveringMeasurement{
+			System:                systemToProto(m.System),
+			Resolution:            int32(m.Resolution),
+			Repetition:            int32(m.Repetition),
+			Cells:                 int64(m.Cells),
+			AreaKm2:               m.AreaKm2,
+			FeatureIds:            int64s(m.FeatureIDs),
+			Samples:               int32s(m.Samples),
+			UnconvergedFeatureIds: int64s(m.Unconverged),
+			TimedOutFeatureIds:    int64s(m.TimedOut),
+			Quality:               m.Quality,
+			EmptyFeatureIds:       int64s(m.Empty),
+		}
+		for _, d := range m.Durations {
+			pm.DurationsNs = append(pm.DurationsNs, d.Nanoseconds())
+		}
+		if m.LevelCells != nil {
+			pm.LevelCells = make(map[int32]int64, len(m.LevelCells))
+			for level, n := range m.LevelCells {
+				pm.LevelCells[int32(level)] = int64(n)
+			}
+		}
+		for _, c := range m.OverCap {
+			pm.OverCap = append(pm.OverCap, &earthbenchpb.CappedFeature{FeatureId: int64(c.FeatureID), EstimatedCells: int64(c.EstimatedCells)})
+		}
+		for _, v := range m.Violations {
+			pm.Violations = append(pm.Violations, &earthbenchpb.CoveringViolation{FeatureId: int64(v.FeatureID), Count: int32(v.Count), Example: v.Example})
+		}
+		run.Measurements = append(run.Measurements, pm)
+	}
+	return run
+}
+
+// runFromProto converts a Run message back to results
+func runFromProto(run *earthbenchpb.Run) (*bench.Results, error) {
+	o := run.GetOptions()
+	results := &bench.Results{
+		Dataset: run.GetDataset(),
+		Options: bench.CoveringOptions{
+			MaxCells:         int(o.GetMaxCells()),
+			Timeout:          time.Duration(o.GetTimeoutNs()),
+			H3CellCap:        int(o.GetH3CellCap()),
+			Verify:           int(o.GetVerify()),
+			H3Containment:    o.GetH3Containment(),
+			Precision:        o.GetPrecision(),
+			MaxSamples:       int(o.GetMaxSamples()),
+			CentroidFallback: o.GetCentroidFallback(),
+			S2LevelSpan:      int(o.GetS2LevelSpan()),
+		},
+	}
+	if i := run.GetInfo(); i != nil {
+		results.Info = &bench.RunInfo{
+			Commit:     i.GetCommit(),
+			GoVersion:  i.GetGoVersion(),
+			H3Version:  i.GetH3GoVersion(),
+			GeoVersion: i.GetGolangGeoVersion(),
+			Host:       i.GetHost(),
+			OS:         i.GetGoos(),
+			Arch:       i.GetGoarch(),
+			CPU:        i.GetCpu(),
+			Microarch:  i.GetMicroarch(),
+		}
+	}
+	if c := run.GetCalibration(); c != nil {
+		results.Calibration = &bench.Calibration{Score: c.GetScore(), WorkloadNs: c.GetWorkloadNs(), Normalized: c.GetNormalized()}
+	}
+	for _, pm := range run.GetMeasurements() {
+		system, err := systemFromProto(pm.GetSystem())
+		if err != nil {
+			return nil, err
+		}
+		m := bench.CoveringMeasurement{
+			System:      system,
+			Resolution:  int(pm.GetResolution()),
+			Repetition:  int(pm.GetRepetition()),
+			Cells:       int(pm.GetCells()),
+			AreaKm2:     pm.GetAreaKm2(),
+			FeatureIDs:  ints(pm.GetFeatureIds()),
+			Samples:     ints(pm.GetSamples()),
+			Unconverged: ints(pm.GetUnconvergedFeatureIds()),
+			TimedOut:    ints(pm.GetTimedOutFeatureIds()),
+			Quality:     pm.GetQuality(),
+			Empty:       ints(pm.GetEmptyFeatureIds()),
+		}
+		for _, d := range pm.GetDurationsNs() {
+			m.Durations = append(m.Durations, time.Duration(d))
+		}
+		if len(pm.GetLevelCells()) > 0 {
+			m.LevelCells = make(map[int]int, len(pm.GetLevelCells()))
+			for level, n := range pm.GetLevelCells() {
+				m.LevelCells[int(level)] = int(n)
+			}
+		}
+		for _, c := range pm.GetOverCap() {
+			m.OverCap = append(m.OverCap, bench.CappedFeature{FeatureID: int(c.GetFeatureId()), EstimatedCells: int(c.GetEstimatedCells())})
+		}
+		for _, v := range pm.GetViolations() {
+			m.Violations = append(m.Violations, bench.CoveringViolation{FeatureID: int(v.GetFeatureId()), Count: int(v.GetCount()), Example: v.GetExample()})
+		}
+		results.Measurements = append(results.Measurements, m)
+	}
+	return results, nil
+}
+
+// readRunProto reads the Run message written by sweep -protobuf
+func readRunProto(r io.Reader) (*bench.Results, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var run earthbenchpb.Run
+	if err := proto.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("reading protobuf: %w", err)
+	}
+	return runFromProto(&run)
+}
+
+// writeProto returns a writer of msg for writeResultsFile
+func writeProto(msg proto.Message) func(io.Writer) error {
+	return func(w io.Writer) error {
+		data, err := proto.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+}
+
+func int32s(values []int) []int32 {
+	if values == nil {
+		return nil
+	}
+	out := make([]int32, len(values))
+	for i, v := range values {
+		out[i] = int32(v)
+	}
+	return out
+}
+
+func int64s(values []int) []int64 {
+	if values == nil {
+		return nil
+	}
+	out := make([]int64, len(values))
+	for i, v := range values {
+		out[i] = int64(v)
+	}
+	return out
+}
+
+func ints[T int32 | int64](values []T) []int {
+	if values == nil {
+		return nil
+	}
+	out := make([]int, len(values))
+	for i, v := range values {
+		out[i] = int(v)
+	}
+	return out
+}
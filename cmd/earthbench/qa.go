@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+	"github.com/nkk36/earth-discretization-benchmark/report"
+)
+
+// qaRounds is how many times a QA bundle or GeoPackage times each
+// covering; the median is reported
+const qaRounds = 5
+
+// exportQABundles writes one GeoJSON file per feature to dir holding the
+// source polygon and its covering at every sweep point, each tagged with
+// what was measured covering it: the median duration, the cell count and
+// the covered area against the polygon's. The source polygon carries the
+// metrics of all its coverings so one file is enough to check a suspicious
+// result in geojson.io or QGIS.
+func exportQABundles(dir string, ds *bench.Dataset, sweepPoints []bench.SweepPoint, maxCells int) (int, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, err
+	}
+	for _, f := range ds.Features {
+		fc, err := qaBundle(f, sweepPoints, maxCells)
+		if err != nil {
+			return 0, fmt.Errorf("feature %d: %w", f.FeatureID, err)
+		}
+		if err := writeGeoJSON(filepath.Join(dir, fmt.Sprintf("feature-%d.geojson", f.FeatureID)), fc); err != nil {
+			return 0, err
+		}
+	}
+	return len(ds.Features), nil
+}
+
+// timeCovering covers a feature qaRounds times and returns its covering
+// with the median duration
+func timeCovering(f bench.Feature, sp bench.SweepPoint, maxCells int) ([]uint64, time.Duration, error) {
+	var covering []uint64
+	samples := make([]float64, 0, qaRounds)
+	for range qaRounds {
+		start := time.Now()
+		cells, err := bench.CoverFeature(f, sp.System, sp.Resolution, maxCells)
+		samples = append(samples, float64(time.Since(start)))
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s resolution %d: %w", sp.System, sp.Resolution, err)
+		}
+		covering = cells
+	}
+	slices.Sort(samples)
+	return covering, time.Duration(report.Percentile(samples, 50)), nil
+}
+
+// qaBundle covers one feature at every sweep point and returns the source
+// polygon followed by the cells of each covering
+func qaBundle(f bench.Feature, sweepPoints []bench.SweepPoint, maxCells int) (geojson.FeatureCollection, error) {
+	fc := geojson.FeatureCollection{Type: "FeatureCollection"}
+	areaKm2 := f.AreaKm2()
+	var summaries []map[string]interface{}
+	var cellFeatures []geojson.Feature
+	for _, sp := range sweepPoints {
+		covering, duration, err := timeCovering(f, sp, maxCells)
+		if err != nil {
+			return fc, err
+		}
+		coveredKm2, err := coveringAreaKm2(sp.System, covering)
+		if err != nil {
+			return fc, err
+		}
+		metrics := map[string]interface{}{
+			"system":            sp.System,
+			"resolution":        sp.Resolution,
+			"cells":             len(covering),
+			"duration_ns":       duration.Nanoseconds(),
+			"covering_area_km2": coveredKm2,
+		}
+		if areaKm2 > 0 {
+			metrics["area_ratio"] = coveredKm2 / areaKm2
+		}
+		summaries = append(summaries, metrics)
+
+		for _, cell := range covering {
+			ring, err := cellBoundary(sp.System, cell)
+			if err != nil {
+				return fc, err
+			}
+			properties := maps.Clone(metrics)
+			properties["kind"] = "cell"
+			properties["feature_id"] = f.FeatureID
+			properties["cell"] = cellToken(sp.System, cell)
+			cellFeatures = append(cellFeatures, geojson.Feature{
+				Type:       "Feature",
+				Geometry:   geojson.Geometry{Type: "Polygon", Coordinates: [][][2]float64{ring}},
+				Properties: properties,
+			})
+		}
+	}
+
+	properties := maps.Clone(f.Properties)
+	if properties == nil {
+		properties = make(map[string]interface{})
+	}
+	properties["kind"] = "source"
+	properties["feature_id"] = f.FeatureID
+	properties["vertices"] = f.NumVertices()
+	properties["rings"] = len(f.Geometry.Coordinates)
+	properties["area_km2"] = areaKm2
+	properties["coverings"] = summaries
+	fc.Features = append([]geojson.Feature{{Type: "Feature", Geometry: f.Geometry, Properties: properties}}, cellFeatures...)
+	return fc, nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/nkk36/earth-discretization-benchmark/report"
+)
+
+// resolutionProfile is what the dataset measurements say about one
+// resolution: cells per feature and how much larger the covering is than
+// the polygons it covers
+type resolutionProfile struct {
+	System          string
+	Resolution      int
+	CellsPerFeature report.Distribution
+	AreaRatio       float64 // covering area / polygon area
+}
+
+func runRecommendCommand(args []string) error {
+	fs := flag.NewFlagSet("recommend", flag.ExitOnError)
+	edgeKm := fs.Float64("edge-km", 0, "target average cell edge length in km")
+	areaKm2 := fs.Float64("area-km2", 0, "target average cell area in km²")
+	maxCells := fs.Int("max-cells", 0, "maximum cells per geofence")
+	pct := fs.Float64("percentile", 90, "with -max-cells, the share of geofences (percentile) that must fit the limit")
+	dataset := addDatasetFlags(fs)
+	s2MaxCells := fs.Int("s2-max-cells", 8, "S2 RegionCoverer MaxCells used for the fixed-level measurements")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	targets := 0
+	for _, set := range []bool{*edgeKm > 0, *areaKm2 > 0, *maxCells > 0} {
+		if set {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return fmt.Errorf("give exactly one of -edge-km, -area-km2 or -max-cells")
+	}
+
+	ds, err := dataset.LoadDataset()
+	if err != nil {
+		return err
+	}
+
+	for _, system := range []string{bench.SystemH3, bench.SystemS2} {
+		var profile resolutionProfile
+		switch {
+		case *maxCells > 0:
+			profile, err = finestResolutionWithin(ds, system, *maxCells, *pct, *s2MaxCells)
+		case *edgeKm > 0:
+			profile, err = closestResolution(ds, system, *edgeKm, averageEdgeLengthKm, *s2MaxCells)
+		default:
+			profile, err = closestResolution(ds, system, *areaKm2, measuredCellAreaKm2, *s2MaxCells)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", system, err)
+		}
+		printRecommendation(profile, *maxCells)
+	}
+	return nil
+}
+
+// measuredCellAreaKm2 prefers the average cell areas recorded alongside the
+// original experiments and falls back to the library averages beyond them
+func measuredCellAreaKm2(system string, res int) (float64, error) {
+	table := H3ResolutionAveragesKm2
+	if system == bench.SystemS2 {
+		table = S2ResolutionAveragesKm2
+	}
+	if area, ok := table[res]; ok {
+		return area, nil
+	}
+	return averageCellAreaKm2(system, res)
+}
+
+// closestResolution picks the resolution whose metric is closest to target
+// on a log scale and measures it on the dataset
+func closestResolution(ds *bench.Dataset, system string, target float64, metric func(string, int) (float64, error), s2MaxCells int) (resolutionProfile, error) {
+	best, bestDiff := 0, math.Inf(1)
+	for res := 0; res <= maxResolution(system); res++ {
+		value, err := metric(system, res)
+		if err != nil {
+			return resolutionProfile{}, err
+		}
+		if diff := math.Abs(math.Log(value / target)); diff < bestDiff {
+			best, bestDiff = res, diff
+		}
+	}
+	return profileResolution(ds, system, best, s2MaxCells, 0)
+}
+
+// finestResolutionWithin walks resolutions from coarse to fine and returns
+// the last one at which the given percentile of cells per feature stays
+// within maxCells
+func finestResolutionWithin(ds *bench.Dataset, system string, maxCells int, pct float64, s2MaxCells int) (resolutionProfile, error) {
+	var best *resolutionProfile
+	for res := 0; res <= maxResolution(system); res++ {
+		profile, err := profileResolution(ds, system, res, s2MaxCells, maxCells)
+		if err != nil {
+			return resolutionProfile{}, err
+		}
+		if profile.CellsPerFeature.Count == 0 {
+			// Bailed out early: at least one feature is over the limit by far
+			break
+		}
+		values := profile.CellsPerFeature
+		if cellsAtPercentile(values, pct) > float64(maxCells) {
+			break
+		}
+		best = &profile
+	}
+	if best == nil {
+		return resolutionProfile{}, fmt.Errorf("no resolution keeps p%g of geofences within %d cells", pct, maxCells)
+	}
+	return *best, nil
+}
+
+// cellsAtPercentile reads a percentile off a Distribution, interpolating
+// between the quantiles it stores
+func cellsAtPercentile(d report.Distribution, pct float64) float64 {
+	points := []struct{ p, v float64 }{{0, d.Min}, {25, d.P25}, {50, d.Median}, {75, d.P75}, {90, d.P90}, {100, d.Max}}
+	for i := 1; i < len(points); i++ {
+		if pct <= points[i].p {
+			lo, hi := points[i-1], points[i]
+			return lo.v + (pct-lo.p)/(hi.p-lo.p)*(hi.v-lo.v)
+		}
+	}
+	return d.Max
+}
+
+// profileResolution covers the dataset at one resolution. With a positive
+// limit it stops as soon as the total cell count shows the limit cannot be
+// met, returning an empty profile, so fine resolutions are never fully
+// computed just to be rejected.
+func profileResolution(ds *bench.Dataset, system string, res, s2MaxCells, limit int) (resolutionProfile, error) {
+	profile := resolutionProfile{System: system, Resolution: res}
+	sizes := make([]float64, 0, len(ds.Features))
+	var coverArea, polygonArea float64
+	total := 0
+	for _, f := range ds.Features {
+		covering, err := bench.CoverFeature(f, system, res, s2MaxCells)
+		if err != nil {
+			return profile, fmt.Errorf("resolution %d, feature %d: %w", res, f.FeatureID, err)
+		}
+		total += len(covering)
+		if limit > 0 && total > 2*limit*len(ds.Features) {
+			return resolutionProfile{System: system, Resolution: res}, nil
+		}
+		area, err := coveringAreaKm2(system, covering)
+		if err != nil {
+			return profile, err
+		}
+		coverArea += area
+		polygonArea += f.S2Polygon.Area() * bench.EarthRadiusKm * bench.EarthRadiusKm
+		sizes = append(sizes, float64(len(covering)))
+	}
+	profile.CellsPerFeature = report.NewDistribution(sizes)
+	if polygonArea > 0 {
+		profile.AreaRatio = coverArea / polygonArea
+	}
+	return profile, nil
+}
+
+func printRecommendation(p resolutionProfile, maxCells int) {
+	area, _ := measuredCellAreaKm2(p.System, p.Resolution)
+	edge, _ := averageEdgeLengthKm(p.System, p.Resolution)
+	name := "resolution"
+	if p.System == bench.SystemS2 {
+		name = "level"
+	}
+	fmt.Printf("%s %s %d: average cell %.4g km², edge %.4g km\n", p.System, name, p.Resolution, area, edge)
+	d := p.CellsPerFeature
+	fmt.Printf("  measured cells per geofence: median %.0f, p90 %.0f, max %.0f; covering/polygon area %.3f\n",
+		d.Median, d.P90, d.Max, p.AreaRatio)
+	if p.System == bench.SystemS2 {
+		fmt.Printf("  RegionCoverer{MinLevel: %d, MaxLevel: %d, LevelMod: 1} for a fixed-level covering\n", p.Resolution, p.Resolution)
+		if maxCells > 0 {
+			fmt.Printf("  RegionCoverer{MaxLevel: %d, MaxCells: %d} for a mixed-level covering capped at the limit\n", p.Resolution, maxCells)
+		}
+	}
+}
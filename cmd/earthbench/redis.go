@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisResult is one (system, resolution) row of the Redis set benchmark
+type RedisResult struct {
+	System        string
+	Resolution    int
+	Features      int
+	CellsWritten  int
+	WriteDuration time.Duration
+	MemoryBytes   int64
+	Queries       int
+	Hits          int
+	QueryDuration time.Duration
+}
+
+// WritesPerSecond is the number of covering cells added per second
+func (r RedisResult) WritesPerSecond() float64 {
+	if r.WriteDuration <= 0 {
+		return 0
+	}
+	return float64(r.CellsWritten) / r.WriteDuration.Seconds()
+}
+
+// AverageQueryNs is the mean round trip of a point lookup
+func (r RedisResult) AverageQueryNs() float64 {
+	if r.Queries == 0 {
+		return 0
+	}
+	return float64(r.QueryDuration.Nanoseconds()) / float64(r.Queries)
+}
+
+func runRedisCommand(args []string) error {
+	fs := flag.NewFlagSet("redis", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "0-6", "0-11")
+	url := fs.String("url", "", "Redis connection string, e.g. redis://localhost:6379/0 (required)")
+	prefix := fs.String("prefix", "earthbench", "key prefix; keys under it are deleted before each resolution")
+	output := fs.String("output", "output/redis.csv", "CSV file for the results")
+	queries := fs.Int("queries", 10000, "number of random point lookups per resolution")
+	seed := fs.Int64("seed", 1, "seed for the random query points")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *url == "" {
+		return fmt.Errorf("-url is required")
+	}
+	opts, err := redis.ParseURL(*url)
+	if err != nil {
+		return fmt.Errorf("-url: %w", err)
+	}
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	client := redis.NewClient(opts)
+	defer client.Close()
+	if err := client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("connecting to redis: %w", err)
+	}
+
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d features from %s\n", len(ds.Features), ds.Path)
+
+	points := bench.RandomPointsInRect(ds.Bounds(), *queries, *seed)
+
+	var results []RedisResult
+	for _, sp := range sweepPoints {
+		result, err := benchmarkRedis(ctx, client, *prefix, ds, sp.System, sp.Resolution, *sweep.MaxCells, points)
+		if err != nil {
+			return fmt.Errorf("%s resolution %d: %w", sp.System, sp.Resolution, err)
+		}
+		printRedisResult(result)
+		results = append(results, result)
+	}
+
+	return saveRedisResultsToCSV(*output, results)
+}
+
+// redisCellKey is the set holding the feature IDs whose covering contains a cell
+func redisCellKey(prefix, system string, resolution int, cell uint64) string {
+	return fmt.Sprintf("%s:%s:%d:%x", prefix, system, resolution, cell)
+}
+
+// benchmarkRedis SADDs each feature ID into the set of every cell of its
+// covering (one pipeline per feature) and then times SMEMBERS round trips
+// for random points, the lookup a Redis-backed geofencing service performs
+func benchmarkRedis(ctx context.Context, client *redis.Client, prefix string, ds *bench.Dataset,
+	system string, resolution int, maxCells int, points []s2.LatLng) (RedisResult, error) {
+	result := RedisResult{System: system, Resolution: resolution, Features: len(ds.Features)}
+
+	coverings, err := bench.ComputeCoverings(ds, system, resolution, maxCells)
+	if err != nil {
+		return result, err
+	}
+
+	pattern := fmt.Sprintf("%s:%s:%d:*", prefix, system, resolution)
+	if err := deleteRedisKeys(ctx, client, pattern); err != nil {
+		return result, err
+	}
+	memoryBefore := redisUsedMemory(ctx, client)
+
+	start := time.Now()
+	for i, f := range ds.Features {
+		pipe := client.Pipeline()
+		for _, cell := range coverings[i] {
+			pipe.SAdd(ctx, redisCellKey(prefix, system, resolution, cell), f.FeatureID)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return result, err
+		}
+		result.CellsWritten += len(coverings[i])
+	}
+	result.WriteDuration = time.Since(start)
+	result.MemoryBytes = redisUsedMemory(ctx, client) - memoryBefore
+
+	start = time.Now()
+	for _, ll := range points {
+		cell, err := bench.PointCell(system, ll, resolution)
+		if err != nil {
+			continue
+		}
+		members, err := client.SMembers(ctx, redisCellKey(prefix, system, resolution, cell)).Result()
+		if err != nil {
+			return result, err
+		}
+		if len(members) > 0 {
+			result.Hits++
+		}
+		result.Queries++
+	}
+	result.QueryDuration = time.Since(start)
+
+	return result, deleteRedisKeys(ctx, client, pattern)
+}
+
+// deleteRedisKeys removes every key matching pattern using SCAN so large
+// indexes do not block the server
+func deleteRedisKeys(ctx context.Context, client *redis.Client, pattern string) error {
+	iter := client.Scan(ctx, 0, pattern, 1000).Iterator()
+	var batch []string
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == 1000 {
+			if err := client.Unlink(ctx, batch...).Err(); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		return client.Unlink(ctx, batch...).Err()
+	}
+	return nil
+}
+
+// redisUsedMemory returns the server's used_memory, or 0 if INFO is unavailable
+func redisUsedMemory(ctx context.Context, client *redis.Client) int64 {
+	info, err := client.InfoMap(ctx, "memory").Result()
+	if err != nil {
+		return 0
+	}
+	used, _ := strconv.ParseInt(info["Memory"]["used_memory"], 10, 64)
+	return used
+}
+
+func printRedisResult(r RedisResult) {
+	fmt.Printf("%s res %2d: %9d cells, %9.0f writes/s, %10d bytes, %8.0f ns/round trip, %d/%d hits\n",
+		r.System, r.Resolution, r.CellsWritten, r.WritesPerSecond(), r.MemoryBytes,
+		r.AverageQueryNs(), r.Hits, r.Queries)
+}
+
+func saveRedisResultsToCSV(filename string, results []RedisResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "Resolution", "Features", "CellsWritten", "WriteDurationNs",
+		"WritesPerSecond", "MemoryBytes", "Queries", "Hits", "AverageQueryNs"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			r.System,
+			strconv.Itoa(r.Resolution),
+			strconv.Itoa(r.Features),
+			strconv.Itoa(r.CellsWritten),
+			strconv.FormatInt(r.WriteDuration.Nanoseconds(), 10),
+			strconv.FormatFloat(r.WritesPerSecond(), 'f', -1, 64),
+			strconv.FormatInt(r.MemoryBytes, 10),
+			strconv.Itoa(r.Queries),
+			strconv.Itoa(r.Hits),
+			strconv.FormatFloat(r.AverageQueryNs(), 'f', -1, 64),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
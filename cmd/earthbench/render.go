@@ -0,0 +1,201 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+)
+
+var (
+	renderBackground = color.RGBA{255, 255, 255, 255}
+	renderCellFill   = color.NRGBA{66, 133, 244, 90}
+	renderCellEdge   = color.RGBA{30, 80, 170, 255}
+	renderSource     = color.RGBA{220, 40, 40, 255}
+)
+
+func runRenderCommand(args []string) error {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "5", "9")
+	outputDir := fs.String("output-dir", "output/render", "directory for the PNG files")
+	features := fs.String("features", "1-5", "feature IDs to render, e.g. 1-10 or 3,7 (empty for all)")
+	size := fs.Int("size", 800, "width and height of each image in pixels")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	if *features != "" {
+		ids, err := parseIntRange(*features)
+		if err != nil {
+			return fmt.Errorf("-features: %w", err)
+		}
+		ds = ds.Subset(ids)
+	}
+	if err := os.MkdirAll(*outputDir, 0o755); err != nil {
+		return err
+	}
+
+	written := 0
+	for _, sp := range sweepPoints {
+		for _, f := range ds.Features {
+			covering, err := bench.CoverFeature(f, sp.System, sp.Resolution, *sweep.MaxCells)
+			if err != nil {
+				return fmt.Errorf("feature %d: %w", f.FeatureID, err)
+			}
+			img, err := renderCovering(f, sp.System, covering, *size)
+			if err != nil {
+				return fmt.Errorf("feature %d: %w", f.FeatureID, err)
+			}
+			name := filepath.Join(*outputDir, fmt.Sprintf("feature-%d-%s-res%d.png", f.FeatureID, sp.System, sp.Resolution))
+			if err := savePNG(name, img); err != nil {
+				return err
+			}
+			written++
+		}
+	}
+	fmt.Printf("Wrote %d images to %s\n", written, *outputDir)
+	return nil
+}
+
+// renderCovering draws the covering cells (filled) and the source polygon
+// (outlined) in an equirectangular projection centred on the feature, with
+// longitudes scaled by cos(latitude) so shapes are not stretched
+func renderCovering(f bench.Feature, system string, covering []uint64, size int) (*image.RGBA, error) {
+	var cells [][][2]float64
+	for _, cell := range covering {
+		ring, err := cellBoundary(system, cell)
+		if err != nil {
+			return nil, err
+		}
+		cells = append(cells, ring)
+	}
+
+	// Fit the view to everything that is drawn
+	minLon, minLat := math.Inf(1), math.Inf(1)
+	maxLon, maxLat := math.Inf(-1), math.Inf(-1)
+	extend := func(rings [][][2]float64) {
+		for _, ring := range rings {
+			for _, p := range ring {
+				minLon, maxLon = math.Min(minLon, p[0]), math.Max(maxLon, p[0])
+				minLat, maxLat = math.Min(minLat, p[1]), math.Max(maxLat, p[1])
+			}
+		}
+	}
+	extend(f.Geometry.Coordinates)
+	extend(cells)
+
+	xScale := math.Cos((minLat + maxLat) / 2 * math.Pi / 180)
+	width := math.Max((maxLon-minLon)*xScale, 1e-9)
+	height := math.Max(maxLat-minLat, 1e-9)
+	margin := float64(size) * 0.05
+	scale := (float64(size) - 2*margin) / math.Max(width, height)
+	offsetX := margin + (float64(size)-2*margin-width*scale)/2
+	offsetY := margin + (float64(size)-2*margin-height*scale)/2
+	project := func(p [2]float64) (float64, float64) {
+		return offsetX + (p[0]-minLon)*xScale*scale, offsetY + (maxLat-p[1])*scale
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), &image.Uniform{renderBackground}, image.Point{}, draw.Src)
+	for _, ring := range cells {
+		fillRing(img, ring, project, renderCellFill)
+	}
+	for _, ring := range cells {
+		strokeRing(img, ring, project, renderCellEdge)
+	}
+	for _, ring := range f.Geometry.Coordinates {
+		strokeRing(img, ring, project, renderSource)
+	}
+	return img, nil
+}
+
+// fillRing fills a ring with scanline even-odd filling, blending c over the image
+func fillRing(img *image.RGBA, ring [][2]float64, project func([2]float64) (float64, float64), c color.NRGBA) {
+	xs := make([]float64, len(ring))
+	ys := make([]float64, len(ring))
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for i, p := range ring {
+		xs[i], ys[i] = project(p)
+		minY, maxY = math.Min(minY, ys[i]), math.Max(maxY, ys[i])
+	}
+
+	src := &image.Uniform{c}
+	bounds := img.Bounds()
+	for y := int(math.Max(math.Floor(minY), 0)); y <= int(math.Min(math.Ceil(maxY), float64(bounds.Max.Y-1))); y++ {
+		scan := float64(y) + 0.5
+		var crossings []float64
+		for i := 0; i+1 < len(ring); i++ {
+			y0, y1 := ys[i], ys[i+1]
+			if (y0 <= scan) != (y1 <= scan) {
+				crossings = append(crossings, xs[i]+(scan-y0)/(y1-y0)*(xs[i+1]-xs[i]))
+			}
+		}
+		sort.Float64s(crossings)
+		for i := 0; i+1 < len(crossings); i += 2 {
+			x0, x1 := int(math.Round(crossings[i])), int(math.Round(crossings[i+1]))
+			draw.Draw(img, image.Rect(x0, y, x1, y+1).Intersect(bounds), src, image.Point{}, draw.Over)
+		}
+	}
+}
+
+// strokeRing draws the edges of a ring with Bresenham lines
+func strokeRing(img *image.RGBA, ring [][2]float64, project func([2]float64) (float64, float64), c color.RGBA) {
+	for i := 0; i+1 < len(ring); i++ {
+		x0, y0 := project(ring[i])
+		x1, y1 := project(ring[i+1])
+		drawLine(img, int(math.Round(x0)), int(math.Round(y0)), int(math.Round(x1)), int(math.Round(y1)), c)
+	}
+}
+
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA) {
+	dx := int(math.Abs(float64(x1 - x0)))
+	dy := -int(math.Abs(float64(y1 - y0)))
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		img.SetRGBA(x0, y0, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func savePNG(filename string, img image.Image) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return png.Encode(file, img)
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+)
+
+// runIDPattern restricts run IDs to the form generated by newRunID so they
+// are safe to use as file names
+var runIDPattern = regexp.MustCompile(`^[0-9]{8}T[0-9]{6}-[0-9]+$`)
+
+type apiCoverRequest struct {
+	Geometry   geojson.Geometry `json:"geometry"`
+	System     string           `json:"system"`
+	Resolution int              `json:"resolution"`
+	S2MaxCells int32            `json:"s2_max_cells"`
+}
+
+type apiTiming struct {
+	ConvertNs int64 `json:"convert_ns"`
+	CoverNs   int64 `json:"cover_ns"`
+}
+
+// apiCoverResponse returns cells as tokens only: 64-bit IDs do not survive
+// JSON numbers in most non-Go clients
+type apiCoverResponse struct {
+	System     string    `json:"system"`
+	Resolution int       `json:"resolution"`
+	Cells      []string  `json:"cells"`
+	Timing     apiTiming `json:"timing"`
+}
+
+type apiBenchmarkRequest struct {
+	Dataset       json.RawMessage `json:"dataset"`
+	H3Resolutions []int           `json:"h3_resolutions"`
+	S2Levels      []int           `json:"s2_levels"`
+	S2MaxCells    int32           `json:"s2_max_cells"`
+	CoverTimeout  int             `json:"cover_timeout_ms"`
+}
+
+type apiMeasurement struct {
+	System            string  `json:"system"`
+	Resolution        int     `json:"resolution"`
+	Features          int     `json:"features"`
+	Cells             int     `json:"cells"`
+	AverageDurationNs float64 `json:"average_duration_ns"`
+	TotalDurationNs   int64   `json:"total_duration_ns"`
+	TimedOut          []int   `json:"timed_out_feature_ids,omitempty"`
+}
+
+type apiRun struct {
+	Run          string           `json:"run"`
+	CreatedAt    time.Time        `json:"created_at"`
+	ElapsedNs    int64            `json:"elapsed_ns"`
+	Measurements []apiMeasurement `json:"measurements"`
+}
+
+// apiServer keeps benchmark runs in memory and, when resultsDir is set,
+// persists each as <run>.json so they can be fetched after a restart
+type apiServer struct {
+	resultsDir string
+
+	mu   sync.Mutex
+	seq  int
+	runs map[string]apiRun
+}
+
+func runAPICommand(args []string) error {
+	fs := flag.NewFlagSet("api", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8081", "address to listen on")
+	resultsDir := fs.String("results-dir", "output/api-runs", "directory to persist benchmark runs in (empty to keep them in memory only)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *resultsDir != "" {
+		if err := os.MkdirAll(*resultsDir, 0o755); err != nil {
+			return err
+		}
+	}
+	srv := &apiServer{resultsDir: *resultsDir, runs: make(map[string]apiRun)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /cover", srv.handleCover)
+	mux.HandleFunc("POST /benchmark", srv.handleBenchmark)
+	mux.HandleFunc("GET /results/{run}", srv.handleResults)
+
+	fmt.Printf("REST API listening on http://%s\n", *addr)
+	return http.ListenAndServe(*addr, mux)
+}
+
+func (s *apiServer) handleCover(w http.ResponseWriter, r *http.Request) {
+	var req apiCoverRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.System != bench.SystemH3 && req.System != bench.SystemS2 {
+		http.Error(w, `system must be "H3" or "S2"`, http.StatusBadRequest)
+		return
+	}
+
+	cells, convert, cover, err := bench.CoverGeometry(req.Geometry, req.System, req.Resolution, maxCellsOrDefault(req.S2MaxCells))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp := apiCoverResponse{
+		System:     req.System,
+		Resolution: req.Resolution,
+		Cells:      make([]string, len(cells)),
+		Timing:     apiTiming{ConvertNs: convert.Nanoseconds(), CoverNs: cover.Nanoseconds()},
+	}
+	for i, cell := range cells {
+		resp.Cells[i] = cellToken(req.System, cell)
+	}
+	writeJSON(w, resp)
+}
+
+func (s *apiServer) handleBenchmark(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	var req apiBenchmarkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	ds, err := bench.ParseDataset("request", req.Dataset)
+	if err != nil {
+		http.Error(w, "dataset: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var sweepPoints []bench.SweepPoint
+	for _, res := range req.H3Resolutions {
+		sweepPoints = append(sweepPoints, bench.SweepPoint{System: bench.SystemH3, Resolution: res})
+	}
+	for _, level := range req.S2Levels {
+		sweepPoints = append(sweepPoints, bench.SweepPoint{System: bench.SystemS2, Resolution: level})
+	}
+	if len(sweepPoints) == 0 {
+		http.Error(w, "no h3_resolutions or s2_levels requested", http.StatusBadRequest)
+		return
+	}
+
+	measurements, err := bench.BenchmarkCoverings(r.Context(), ds, sweepPoints, bench.CoveringOptions{
+		MaxCells: maxCellsOrDefault(req.S2MaxCells),
+		Timeout:  time.Duration(req.CoverTimeout) * time.Millisecond,
+	})
+	if err != nil {
+		if r.Context().Err() != nil {
+			// The client went away; there is nobody to answer
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	run := apiRun{Run: s.newRunID(start), CreatedAt: start.UTC()}
+	for _, m := range measurements {
+		run.Measurements = append(run.Measurements, apiMeasurement{
+			System:            m.System,
+			Resolution:        m.Resolution,
+			Features:          len(m.Durations),
+			Cells:             m.Cells,
+			AverageDurationNs: m.AverageDurationNs(),
+			TotalDurationNs:   m.TotalDuration().Nanoseconds(),
+			TimedOut:          m.TimedOut,
+		})
+	}
+	run.ElapsedNs = time.Since(start).Nanoseconds()
+
+	if err := s.saveRun(run); err != nil {
+		http.Error(w, "saving run: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Location", "/results/"+run.Run)
+	writeJSON(w, run)
+}
+
+func (s *apiServer) handleResults(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("run")
+	if !runIDPattern.MatchString(id) {
+		http.Error(w, "invalid run id", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	run, ok := s.runs[id]
+	s.mu.Unlock()
+	if !ok && s.resultsDir != "" {
+		data, err := os.ReadFile(filepath.Join(s.resultsDir, id+".json"))
+		if err == nil && json.Unmarshal(data, &run) == nil {
+			ok = true
+		}
+	}
+	if !ok {
+		http.Error(w, "run not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, run)
+}
+
+func (s *apiServer) newRunID(t time.Time) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	return fmt.Sprintf("%s-%d", t.UTC().Format("20060102T150405"), s.seq)
+}
+
+func (s *apiServer) saveRun(run apiRun) error {
+	s.mu.Lock()
+	s.runs[run.Run] = run
+	s.mu.Unlock()
+
+	if s.resultsDir == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.resultsDir, run.Run+".json"), data, 0o644)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/uber/h3-go/v4"
+)
+
+// RollupResult is the cost of aggregating per-cell counts from a fine
+// resolution to a coarser one
+type RollupResult struct {
+	System         string
+	FromResolution int
+	ToResolution   int
+	InputCells     int
+	OutputCells    int
+	Duration       time.Duration
+}
+
+// CellsPerSecond is the roll-up throughput in input cells
+func (r RollupResult) CellsPerSecond() float64 {
+	if r.Duration == 0 {
+		return 0
+	}
+	return float64(r.InputCells) / r.Duration.Seconds()
+}
+
+func runRollupCommand(args []string) error {
+	fs := flag.NewFlagSet("rollup", flag.ExitOnError)
+	dataset := addDatasetFlags(fs)
+	h3Fine := fs.Int("h3-res", 9, "H3 resolution the points are binned at")
+	h3Coarse := fs.String("h3-to", "0-8", "H3 resolutions to roll up to (empty to skip H3)")
+	s2Fine := fs.Int("s2-level", 16, "S2 level the points are binned at")
+	s2Coarse := fs.String("s2-to", "0-15", "S2 levels to roll up to (empty to skip S2)")
+	numPoints := fs.Int("points", 5000000, "number of synthetic points binned at the fine resolution")
+	seed := fs.Int64("seed", 1, "seed for the synthetic points")
+	output := fs.String("output", "output/rollup.csv", "CSV file for the results")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	ds, err := dataset.LoadDataset()
+	if err != nil {
+		return err
+	}
+	points := bench.RandomPointsInRect(ds.Bounds(), *numPoints, *seed)
+
+	var results []RollupResult
+	for _, sys := range []struct {
+		system string
+		fine   int
+		coarse string
+	}{{bench.SystemH3, *h3Fine, *h3Coarse}, {bench.SystemS2, *s2Fine, *s2Coarse}} {
+		if sys.coarse == "" {
+			continue
+		}
+		targets, err := parseIntRange(sys.coarse)
+		if err != nil {
+			return fmt.Errorf("%s roll-up resolutions: %w", sys.system, err)
+		}
+
+		counts := make(map[uint64]int)
+		for _, ll := range points {
+			cell, err := bench.PointCell(sys.system, ll, sys.fine)
+			if err != nil {
+				return err
+			}
+			counts[cell]++
+		}
+		fmt.Printf("%s: binned %d points into %d cells at resolution %d\n", sys.system, len(points), len(counts), sys.fine)
+
+		for _, to := range targets {
+			if to >= sys.fine {
+				return fmt.Errorf("%s: cannot roll up from resolution %d to %d", sys.system, sys.fine, to)
+			}
+			r, rolled, err := benchmarkRollup(counts, sys.system, sys.fine, to)
+			if err != nil {
+				return err
+			}
+			if total := sumCounts(rolled); total != len(points) {
+				return fmt.Errorf("%s resolution %d: roll-up lost points (%d of %d)", sys.system, to, total, len(points))
+			}
+			fmt.Printf("%s res %2d -> %2d: %8d -> %8d cells in %v (%.0f cells/s)\n",
+				r.System, r.FromResolution, r.ToResolution, r.InputCells, r.OutputCells, r.Duration, r.CellsPerSecond())
+			results = append(results, r)
+		}
+	}
+
+	if err := saveRollupResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// benchmarkRollup aggregates counts to their ancestors at resolution to:
+// H3 CellToParent, S2 CellID.Parent bit truncation
+func benchmarkRollup(counts map[uint64]int, system string, from, to int) (RollupResult, map[uint64]int, error) {
+	rolled := make(map[uint64]int)
+	start := time.Now()
+	for cell, n := range counts {
+		var parent uint64
+		if system == bench.SystemH3 {
+			p, err := h3.Cell(cell).Parent(to)
+			if err != nil {
+				return RollupResult{}, nil, err
+			}
+			parent = uint64(p)
+		} else {
+			parent = uint64(s2.CellID(cell).Parent(to))
+		}
+		rolled[parent] += n
+	}
+	duration := time.Since(start)
+
+	return RollupResult{
+		System:         system,
+		FromResolution: from,
+		ToResolution:   to,
+		InputCells:     len(counts),
+		OutputCells:    len(rolled),
+		Duration:       duration,
+	}, rolled, nil
+}
+
+func sumCounts(counts map[uint64]int) int {
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	return total
+}
+
+func saveRollupResultsToCSV(filename string, results []RollupResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "FromResolution", "ToResolution", "InputCells", "OutputCells", "DurationNs", "CellsPerSecond"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			r.System,
+			strconv.Itoa(r.FromResolution),
+			strconv.Itoa(r.ToResolution),
+			strconv.Itoa(r.InputCells),
+			strconv.Itoa(r.OutputCells),
+			strconv.FormatInt(r.Duration.Nanoseconds(), 10),
+			strconv.FormatFloat(r.CellsPerSecond(), 'f', -1, 64),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"slices"
+)
+
+// schedFlags are the CPU pinning and scheduling priority flags of the
+// commands that measure, for steadier timings on shared machines
+type schedFlags struct {
+	CPUs *string
+	Nice *int
+}
+
+func addSchedFlags(fs *flag.FlagSet) *schedFlags {
+	return &schedFlags{
+		CPUs: fs.String("cpus", "", "pin the benchmark to these CPUs, e.g. 2-5 or 0,2; GOMAXPROCS follows unless set in the environment (Linux only)"),
+		Nice: fs.Int("nice", 0, "scheduling priority from -20 (highest) to 19; going below the current one needs root or CAP_SYS_NICE (Linux only)"),
+	}
+}
+
+// apply pins every thread of the process to the CPUs and sets their
+// priority. Pinning to CPUs that do not exist is an error; a priority the
+// OS refuses only logs a warning, since it is often out of reach on a
+// shared machine and the run is still worth doing. What it set is printed
+// to out.
+func (f *schedFlags) apply(out io.Writer) error {
+	if *f.CPUs != "" {
+		cpus, err := parseIntRange(*f.CPUs)
+		if err != nil {
+			return fmt.Errorf("-cpus: %w", err)
+		}
+		slices.Sort(cpus)
+		cpus = slices.Compact(cpus)
+		if err := pinCPUs(cpus); err != nil {
+			return fmt.Errorf("-cpus %s: %w", *f.CPUs, err)
+		}
+		if os.Getenv("GOMAXPROCS") == "" {
+			runtime.GOMAXPROCS(len(cpus))
+		}
+		fmt.Fprintf(out, "Pinned to CPUs %s (GOMAXPROCS %d)\n", *f.CPUs, runtime.GOMAXPROCS(0))
+	}
+	if *f.Nice != 0 {
+		if err := setNice(*f.Nice); err != nil {
+			log.Printf("Warning: could not set priority %d: %v", *f.Nice, err)
+		} else {
+			fmt.Fprintf(out, "Running at priority %d\n", *f.Nice)
+		}
+	}
+	return nil
+}
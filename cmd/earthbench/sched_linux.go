@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// pinCPUs sets the affinity of every thread of the process. Linux applies
+// affinity per thread and new threads inherit their creator's, so the
+// threads are listed until a pass finds none that is not pinned yet.
+func pinCPUs(cpus []int) error {
+	var set unix.CPUSet
+	for _, cpu := range cpus {
+		set.Set(cpu)
+	}
+	err := forEachThread(func(tid int) error { return unix.SchedSetaffinity(tid, &set) })
+	if errors.Is(err, unix.EINVAL) {
+		return fmt.Errorf("none of these CPUs is available to the process (it started with %d)", runtime.NumCPU())
+	}
+	return err
+}
+
+// setNice sets the nice value of every thread of the process, which Linux
+// also keeps per thread
+func setNice(nice int) error {
+	return forEachThread(func(tid int) error { return unix.Setpriority(unix.PRIO_PROCESS, tid, nice) })
+}
+
+// forEachThread calls fn once with the ID of every thread of the process,
+// including threads started meanwhile
+func forEachThread(fn func(tid int) error) error {
+	done := make(map[int]bool)
+	for {
+		entries, err := os.ReadDir("/proc/self/task")
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, entry := range entries {
+			tid, err := strconv.Atoi(entry.Name())
+			if err != nil || done[tid] {
+				continue
+			}
+			if err := fn(tid); err != nil {
+				return err
+			}
+			done[tid] = true
+			found = true
+		}
+		if !found {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func pinCPUs(cpus []int) error {
+	return fmt.Errorf("CPU pinning is not supported on %s", runtime.GOOS)
+}
+
+func setNice(nice int) error {
+	return fmt.Errorf("setting the priority is not supported on %s", runtime.GOOS)
+}
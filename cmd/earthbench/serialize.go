@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	earthbenchpb "github.com/nkk36/earth-discretization-benchmark/proto"
+	"github.com/uber/h3-go/v4"
+	"google.golang.org/protobuf/proto"
+)
+
+// SerializeResult is the cost of one wire format for the coverings of one
+// (system, resolution): the encoded size and the time to encode and decode
+// every covering once, each as its own message as a service would send it
+type SerializeResult struct {
+	System     string
+	Resolution int
+	Format     string
+	Coverings  int
+	Cells      int
+	Bytes      int
+	EncodeTime time.Duration
+	DecodeTime time.Duration
+}
+
+// BytesPerCell is the encoded size per cell ID
+func (r SerializeResult) BytesPerCell() float64 {
+	if r.Cells == 0 {
+		return 0
+	}
+	return float64(r.Bytes) / float64(r.Cells)
+}
+
+// cellsPerSecond converts a time to handle all cells into a rate
+func (r SerializeResult) cellsPerSecond(d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(r.Cells) / d.Seconds()
+}
+
+// megabytesPerSecond converts a time to handle all encoded bytes into a rate
+func (r SerializeResult) megabytesPerSecond(d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(r.Bytes) / 1e6 / d.Seconds()
+}
+
+// cellFormat encodes a covering to bytes and back. Formats that sort the
+// cells decode them sorted.
+type cellFormat struct {
+	Name   string
+	Sorted bool
+	Encode func(system string, cells []uint64) ([]byte, error)
+	Decode func(system string, data []byte) ([]uint64, error)
+}
+
+// cellFormats are the wire formats compared, by name
+var cellFormats = []cellFormat{
+	{
+		// A JSON array of numbers. IDs above 2^53 lose precision in
+		// JavaScript, which is why many APIs use tokens instead.
+		Name:   "json",
+		Encode: func(_ string, cells []uint64) ([]byte, error) { return json.Marshal(cells) },
+		Decode: func(_ string, data []byte) ([]uint64, error) {
+			var cells []uint64
+			err := json.Unmarshal(data, &cells)
+			return cells, err
+		},
+	},
+	{
+		// Comma-separated H3 index strings or S2 tokens
+		Name: "tokens",
+		Encode: func(system string, cells []uint64) ([]byte, error) {
+			var b strings.Builder
+			for i, cell := range cells {
+				if i > 0 {
+					b.WriteByte(',')
+				}
+				b.WriteString(cellToken(system, cell))
+			}
+			return []byte(b.String()), nil
+		},
+		Decode: func(system string, data []byte) ([]uint64, error) {
+			if len(data) == 0 {
+				return nil, nil
+			}
+			tokens := strings.Split(string(data), ",")
+			cells := make([]uint64, len(tokens))
+			for i, token := range tokens {
+				if system == bench.SystemH3 {
+					cells[i] = uint64(h3.IndexFromString(token))
+				} else {
+					cells[i] = uint64(s2.CellIDFromToken(token))
+				}
+			}
+			return cells, nil
+		},
+	},
+	{
+		// Fixed 8-byte little-endian IDs
+		Name: "binary",
+		Encode: func(_ string, cells []uint64) ([]byte, error) {
+			data := make([]byte, 0, 8*len(cells))
+			for _, cell := range cells {
+				data = binary.LittleEndian.AppendUint64(data, cell)
+			}
+			return data, nil
+		},
+		Decode: func(_ string, data []byte) ([]uint64, error) {
+			if len(data)%8 != 0 {
+				return nil, fmt.Errorf("%d bytes is not a whole number of IDs", len(data))
+			}
+			cells := make([]uint64, len(data)/8)
+			for i := range cells {
+				cells[i] = binary.LittleEndian.Uint64(data[8*i:])
+			}
+			return cells, nil
+		},
+	},
+	{
+		// Sorted IDs as unsigned varints of the difference to the previous
+		// one. Cells of a covering share their high bits, so most deltas
+		// take a few bytes.
+		Name:   "varint-delta",
+		Sorted: true,
+		Encode: func(_ string, cells []uint64) ([]byte, error) {
+			sorted := slices.Clone(cells)
+			slices.Sort(sorted)
+			data := make([]byte, 0, 4*len(sorted))
+			previous := uint64(0)
+			for _, cell := range sorted {
+				data = binary.AppendUvarint(data, cell-previous)
+				previous = cell
+			}
+			return data, nil
+		},
+		Decode: func(_ string, data []byte) ([]uint64, error) {
+			var cells []uint64
+			previous := uint64(0)
+			for len(data) > 0 {
+				delta, n := binary.Uvarint(data)
+				if n <= 0 {
+					return nil, fmt.Errorf("malformed varint")
+				}
+				previous += delta
+				cells = append(cells, previous)
+				data = data[n:]
+			}
+			return cells, nil
+		},
+	},
+	{
+		// The cells field of the gRPC CoverResponse, a packed repeated
+		// uint64 (varints of the full IDs)
+		Name: "protobuf",
+		Encode: func(_ string, cells []uint64) ([]byte, error) {
+			return proto.Marshal(&earthbenchpb.CoverResponse{Cells: cells})
+		},
+		Decode: func(_ string, data []byte) ([]uint64, error) {
+			var response earthbenchpb.CoverResponse
+			err := proto.Unmarshal(data, &response)
+			return response.Cells, err
+		},
+	},
+}
+
+func runSerializeCommand(args []string) error {
+	fs := flag.NewFlagSet("serialize", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "3-7", "6-14")
+	output := fs.String("output", "output/serialize.csv", "CSV file for the results")
+	formatNames := fs.String("formats", "json,tokens,binary,varint-delta,protobuf", "comma-separated wire formats to compare")
+	rounds := fs.Int("rounds", 5, "times every covering is encoded and decoded; the fastest round is kept")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	var formats []cellFormat
+	for _, name := range strings.Split(*formatNames, ",") {
+		i := slices.IndexFunc(cellFormats, func(f cellFormat) bool { return f.Name == strings.TrimSpace(name) })
+		if i < 0 {
+			return fmt.Errorf("unknown format %q", name)
+		}
+		formats = append(formats, cellFormats[i])
+	}
+	if *rounds < 1 {
+		return fmt.Errorf("-rounds must be at least 1")
+	}
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d features from %s\n", len(ds.Features), ds.Path)
+
+	var results []SerializeResult
+	for _, sp := range sweepPoints {
+		coverings, err := bench.ComputeCoverings(ds, sp.System, sp.Resolution, *sweep.MaxCells)
+		if err != nil {
+			return fmt.Errorf("%s resolution %d: %w", sp.System, sp.Resolution, err)
+		}
+		for _, format := range formats {
+			r, err := measureSerialization(coverings, sp, format, *rounds)
+			if err != nil {
+				return fmt.Errorf("%s resolution %d, %s: %w", sp.System, sp.Resolution, format.Name, err)
+			}
+			fmt.Printf("%s res %2d %-12s: %10d cells, %12s (%5.2f B/cell), encode %10.0f cells/s %8.1f MB/s, decode %10.0f cells/s %8.1f MB/s\n",
+				r.System, r.Resolution, r.Format, r.Cells, formatBytes(float64(r.Bytes)), r.BytesPerCell(),
+				r.cellsPerSecond(r.EncodeTime), r.megabytesPerSecond(r.EncodeTime), r.cellsPerSecond(r.DecodeTime), r.megabytesPerSecond(r.DecodeTime))
+			results = append(results, r)
+		}
+	}
+
+	if err := saveSerializeResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// measureSerialization encodes and decodes every covering rounds times,
+// keeping the fastest round of each, after checking that every covering
+// survives the round trip
+func measureSerialization(coverings [][]uint64, sp bench.SweepPoint, format cellFormat, rounds int) (SerializeResult, error) {
+	result := SerializeResult{System: sp.System, Resolution: sp.Resolution, Format: format.Name, Coverings: len(coverings)}
+	encoded := make([][]byte, len(coverings))
+	for i, covering := range coverings {
+		data, err := format.Encode(sp.System, covering)
+		if err != nil {
+			return result, err
+		}
+		decoded, err := format.Decode(sp.System, data)
+		if err != nil {
+			return result, err
+		}
+		want := covering
+		if format.Sorted {
+			want = slices.Sorted(slices.Values(covering))
+		}
+		if !slices.Equal(decoded, want) && (len(decoded) != 0 || len(want) != 0) {
+			return result, fmt.Errorf("covering %d does not survive the round trip", i)
+		}
+		encoded[i] = data
+		result.Cells += len(covering)
+		result.Bytes += len(data)
+	}
+
+	result.EncodeTime, result.DecodeTime = time.Duration(math.MaxInt64), time.Duration(math.MaxInt64)
+	for range rounds {
+		start := time.Now()
+		for _, covering := range coverings {
+			format.Encode(sp.System, covering)
+		}
+		result.EncodeTime = min(result.EncodeTime, time.Since(start))
+
+		start = time.Now()
+		for _, data := range encoded {
+			format.Decode(sp.System, data)
+		}
+		result.DecodeTime = min(result.DecodeTime, time.Since(start))
+	}
+	return result, nil
+}
+
+// saveSerializeResultsToCSV writes one row per system, resolution and format
+func saveSerializeResultsToCSV(filename string, results []SerializeResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "Resolution", "Format", "Coverings", "Cells", "Bytes", "BytesPerCell", "EncodeNs", "DecodeNs",
+		"EncodeCellsPerSecond", "DecodeCellsPerSecond", "EncodeMBPerSecond", "DecodeMBPerSecond"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			r.System,
+			strconv.Itoa(r.Resolution),
+			r.Format,
+			strconv.Itoa(r.Coverings),
+			strconv.Itoa(r.Cells),
+			strconv.Itoa(r.Bytes),
+			strconv.FormatFloat(r.BytesPerCell(), 'f', -1, 64),
+			strconv.FormatInt(r.EncodeTime.Nanoseconds(), 10),
+			strconv.FormatInt(r.DecodeTime.Nanoseconds(), 10),
+			strconv.FormatFloat(r.cellsPerSecond(r.EncodeTime), 'f', 0, 64),
+			strconv.FormatFloat(r.cellsPerSecond(r.DecodeTime), 'f', 0, 64),
+			strconv.FormatFloat(r.megabytesPerSecond(r.EncodeTime), 'f', -1, 64),
+			strconv.FormatFloat(r.megabytesPerSecond(r.DecodeTime), 'f', -1, 64),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+)
+
+// durationsFilePattern matches the per-feature duration dumps written by
+// h3Experiments and s2VaryLevels
+var durationsFilePattern = regexp.MustCompile(`^durations-(h3|s2)-res(\d+)\.csv$`)
+
+// resultsServer serves a results directory and computes coverings of the
+// dataset on demand for the map view
+type resultsServer struct {
+	dir      string
+	ds       *bench.Dataset
+	maxCells int
+}
+
+func runServeCommand(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	dir := fs.String("dir", "output", "results directory to serve")
+	dataset := addDatasetFlags(fs)
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	maxCells := fs.Int("s2-max-cells", 8, "S2 RegionCoverer MaxCells for map coverings")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	ds, err := dataset.LoadDataset()
+	if err != nil {
+		return err
+	}
+	srv := &resultsServer{dir: *dir, ds: ds, maxCells: *maxCells}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", srv.handleIndex)
+	mux.HandleFunc("/api/files", srv.handleFiles)
+	mux.HandleFunc("/api/csv", srv.handleCSV)
+	mux.HandleFunc("/api/durations", srv.handleDurations)
+	mux.HandleFunc("/api/covering", srv.handleCovering)
+
+	fmt.Printf("Serving %s on http://%s\n", *dir, *addr)
+	return http.ListenAndServe(*addr, mux)
+}
+
+func (s *resultsServer) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	dashboardTemplate.Execute(w, nil)
+}
+
+// handleFiles lists the CSV files of the results directory and the
+// system/resolution pairs that have per-feature durations
+func (s *resultsServer) handleFiles(w http.ResponseWriter, r *http.Request) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	type durationsRun struct {
+		System     string `json:"system"`
+		Resolution int    `json:"resolution"`
+	}
+	resp := struct {
+		Files     []string       `json:"files"`
+		Durations []durationsRun `json:"durations"`
+	}{Files: []string{}, Durations: []durationsRun{}}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".csv") {
+			continue
+		}
+		if m := durationsFilePattern.FindStringSubmatch(e.Name()); m != nil {
+			res, _ := strconv.Atoi(m[2])
+			resp.Durations = append(resp.Durations, durationsRun{System: strings.ToUpper(m[1]), Resolution: res})
+			continue
+		}
+		resp.Files = append(resp.Files, e.Name())
+	}
+	sort.Slice(resp.Durations, func(i, j int) bool {
+		if resp.Durations[i].System != resp.Durations[j].System {
+			return resp.Durations[i].System < resp.Durations[j].System
+		}
+		return resp.Durations[i].Resolution < resp.Durations[j].Resolution
+	})
+	writeJSON(w, resp)
+}
+
+// handleCSV returns a results CSV as {"headers": [...], "rows": [[...]]}
+func (s *resultsServer) handleCSV(w http.ResponseWriter, r *http.Request) {
+	records, err := s.readCSV(r.URL.Query().Get("name"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(records) == 0 {
+		http.Error(w, "empty file", http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, map[string]interface{}{"headers": records[0], "rows": records[1:]})
+}
+
+// handleDurations pairs each row of a durations file with the feature it
+// was measured on; rows are in dataset order
+func (s *resultsServer) handleDurations(w http.ResponseWriter, r *http.Request) {
+	system := strings.ToLower(r.URL.Query().Get("system"))
+	res, err := strconv.Atoi(r.URL.Query().Get("resolution"))
+	if err != nil || (system != "h3" && system != "s2") {
+		http.Error(w, "system must be H3 or S2 and resolution an integer", http.StatusBadRequest)
+		return
+	}
+	records, err := s.readCSV(fmt.Sprintf("durations-%s-res%d.csv", system, res))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	type row struct {
+		FeatureID  int   `json:"feature_id"`
+		DurationNs int64 `json:"duration_ns"`
+	}
+	rows := []row{}
+	for i, rec := range records[1:] {
+		if i >= len(s.ds.Features) || len(rec) == 0 {
+			break
+		}
+		ns, _ := strconv.ParseInt(rec[0], 10, 64)
+		rows = append(rows, row{FeatureID: s.ds.Features[i].FeatureID, DurationNs: ns})
+	}
+	writeJSON(w, rows)
+}
+
+// handleCovering returns one feature's covering as GeoJSON for the map
+func (s *resultsServer) handleCovering(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	featureID, err1 := strconv.Atoi(q.Get("feature"))
+	res, err2 := strconv.Atoi(q.Get("resolution"))
+	system := strings.ToUpper(q.Get("system"))
+	if err1 != nil || err2 != nil || (system != bench.SystemH3 && system != bench.SystemS2) {
+		http.Error(w, "feature, system (H3 or S2) and resolution are required", http.StatusBadRequest)
+		return
+	}
+	subset := s.ds.Subset([]int{featureID})
+	if len(subset.Features) == 0 {
+		http.Error(w, "unknown feature", http.StatusNotFound)
+		return
+	}
+	fc, err := coveringFeatureCollection(subset, []bench.SweepPoint{{System: system, Resolution: res}}, s.maxCells, true)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, fc)
+}
+
+// readCSV reads a CSV from the results directory, refusing paths outside it
+func (s *resultsServer) readCSV(name string) ([][]string, error) {
+	if name == "" || name != filepath.Base(name) || !strings.HasSuffix(name, ".csv") {
+		return nil, fmt.Errorf("invalid file name %q", name)
+	}
+	file, err := os.Open(filepath.Join(s.dir, name))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	return reader.ReadAll()
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Warning: writing response: %v", err)
+	}
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>earth-discretization-benchmark results</title>
+<link rel="stylesheet" href="https://unpkg.com/leaflet@1.9/dist/leaflet.css">
+<script src="https://unpkg.com/leaflet@1.9/dist/leaflet.js"></script>
+<script src="https://cdn.jsdelivr.net/npm/chart.js@4"></script>
+<style>
+  body { font-family: sans-serif; margin: 0; display: grid; grid-template-columns: 1fr 1fr; grid-template-rows: auto 1fr; height: 100vh; }
+  header { grid-column: 1 / 3; padding: 8px 16px; background: #263238; color: white; }
+  section { padding: 12px 16px; overflow: auto; }
+  #map { height: 60vh; }
+  table { border-collapse: collapse; font-size: 13px; width: 100%; }
+  td, th { border-bottom: 1px solid #ddd; padding: 2px 6px; text-align: right; }
+  tr.feature:hover { background: #e3f2fd; cursor: pointer; }
+</style>
+</head>
+<body>
+<header><strong>earth-discretization-benchmark</strong> results</header>
+<section>
+  <h3>Metric vs resolution</h3>
+  <label>File <select id="file"></select></label>
+  <label>Metric <select id="metric"></select></label>
+  <label><input type="checkbox" id="logy" checked> log scale</label>
+  <canvas id="chart"></canvas>
+  <h3>Per-feature durations</h3>
+  <label>Run <select id="run"></select></label>
+  <table><thead><tr><th>Feature</th><th>Duration (ns)</th></tr></thead><tbody id="features"></tbody></table>
+</section>
+<section>
+  <h3 id="maptitle">Covering</h3>
+  <div id="map"></div>
+</section>
+<script>
+const map = L.map('map').setView([0, 0], 2);
+L.tileLayer('https://tile.openstreetmap.org/{z}/{x}/{y}.png', { attribution: '&copy; OpenStreetMap' }).addTo(map);
+let coverLayer = null, chart = null, table = null;
+
+async function getJSON(url) { const r = await fetch(url); if (!r.ok) throw new Error(await r.text()); return r.json(); }
+
+function plot() {
+  if (!table) return;
+  const metric = document.getElementById('metric').value;
+  const h = table.headers;
+  const sysCol = h.indexOf('System') >= 0 ? h.indexOf('System') : h.indexOf('Product');
+  const resCol = h.indexOf('Resolution');
+  const yCol = h.indexOf(metric);
+  const series = {};
+  table.rows.forEach(r => {
+    const key = sysCol >= 0 ? r[sysCol] : 'all';
+    (series[key] = series[key] || []).push({ x: +r[resCol], y: +r[yCol] });
+  });
+  const datasets = Object.entries(series).map(([k, pts]) => ({ label: k, data: pts.sort((a, b) => a.x - b.x), showLine: true }));
+  if (chart) chart.destroy();
+  chart = new Chart(document.getElementById('chart'), {
+    type: 'scatter', data: { datasets },
+    options: { scales: { x: { title: { display: true, text: 'Resolution' } },
+                         y: { type: document.getElementById('logy').checked ? 'logarithmic' : 'linear', title: { display: true, text: metric } } } }
+  });
+}
+
+async function loadFile() {
+  table = await getJSON('/api/csv?name=' + encodeURIComponent(document.getElementById('file').value));
+  const metric = document.getElementById('metric');
+  metric.innerHTML = '';
+  table.headers.forEach((h, i) => {
+    if (h === 'Resolution' || !table.rows.length || isNaN(+table.rows[0][i])) return;
+    metric.add(new Option(h, h));
+  });
+  plot();
+}
+
+async function loadRun() {
+  const [system, resolution] = document.getElementById('run').value.split(':');
+  const rows = await getJSON('/api/durations?system=' + system + '&resolution=' + resolution);
+  const body = document.getElementById('features');
+  body.innerHTML = '';
+  rows.forEach(r => {
+    const tr = body.insertRow();
+    tr.className = 'feature';
+    tr.insertCell().textContent = r.feature_id;
+    tr.insertCell().textContent = r.duration_ns.toLocaleString();
+    tr.onclick = () => showCovering(r.feature_id, system, resolution);
+  });
+}
+
+async function showCovering(feature, system, resolution) {
+  const fc = await getJSON('/api/covering?feature=' + feature + '&system=' + system + '&resolution=' + resolution);
+  if (coverLayer) map.removeLayer(coverLayer);
+  coverLayer = L.geoJSON(fc, {
+    style: f => f.properties.kind === 'source' ? { color: '#d32f2f', weight: 2, fill: false } : { color: '#1e50aa', weight: 1, fillOpacity: 0.3 },
+    onEachFeature: (f, layer) => f.properties.cell && layer.bindTooltip(f.properties.cell)
+  }).addTo(map);
+  map.fitBounds(coverLayer.getBounds());
+  document.getElementById('maptitle').textContent = 'Feature ' + feature + ' · ' + system + ' res ' + resolution + ' · ' + (fc.features.length - 1) + ' cells';
+}
+
+(async () => {
+  const files = await getJSON('/api/files');
+  const fileSel = document.getElementById('file');
+  files.files.forEach(f => fileSel.add(new Option(f, f)));
+  const runSel = document.getElementById('run');
+  files.durations.forEach(d => runSel.add(new Option(d.system + ' res ' + d.resolution, d.system + ':' + d.resolution)));
+  fileSel.onchange = loadFile;
+  document.getElementById('metric').onchange = plot;
+  document.getElementById('logy').onchange = plot;
+  runSel.onchange = loadRun;
+  if (files.files.length) loadFile();
+  if (files.durations.length) loadRun();
+})();
+</script>
+</body>
+</html>
+`))
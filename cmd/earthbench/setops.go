@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+)
+
+// SetOpResult is the cost of one covering set operation applied to every
+// feature pair (or, for union_all, once over all features)
+type SetOpResult struct {
+	System      string
+	Resolution  int
+	Operation   string
+	Operations  int
+	InputCells  int
+	OutputCells int
+	Duration    time.Duration
+}
+
+// AverageNs is the mean cost of a single operation
+func (r SetOpResult) AverageNs() float64 {
+	if r.Operations == 0 {
+		return 0
+	}
+	return float64(r.Duration.Nanoseconds()) / float64(r.Operations)
+}
+
+func runSetOpsCommand(args []string) error {
+	fs := flag.NewFlagSet("setops", flag.ExitOnError)
+	dataset := addDatasetFlags(fs)
+	h3Res := fs.String("h3-res", "3-6", "H3 resolutions, e.g. 3-6 or 4,6")
+	s2Levels := fs.String("s2-levels", "", "S2 level paired with each H3 resolution (default: the level with the closest average cell area)")
+	maxCells := fs.Int("s2-max-cells", 8, "S2 RegionCoverer MaxCells")
+	output := fs.String("output", "output/setops.csv", "CSV file for the results")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	resolutions, levels, err := pairResolutions(*h3Res, *s2Levels)
+	if err != nil {
+		return err
+	}
+	ds, err := dataset.LoadDataset()
+	if err != nil {
+		return err
+	}
+	pairs := candidatePairs(ds)
+	fmt.Printf("Loaded %d features from %s (%d pairs with intersecting bounds)\n", len(ds.Features), ds.Path, len(pairs))
+
+	var results []SetOpResult
+	for i, res := range resolutions {
+		h3Coverings, err := bench.ComputeCoverings(ds, bench.SystemH3, res, *maxCells)
+		if err != nil {
+			return fmt.Errorf("H3 resolution %d: %w", res, err)
+		}
+		s2Coverings, err := bench.ComputeCoverings(ds, bench.SystemS2, levels[i], *maxCells)
+		if err != nil {
+			return fmt.Errorf("S2 level %d: %w", levels[i], err)
+		}
+		results = append(results, benchmarkH3SetOps(h3Coverings, pairs, res)...)
+		results = append(results, benchmarkS2SetOps(s2Coverings, pairs, levels[i])...)
+	}
+
+	for _, r := range results {
+		fmt.Printf("%s res %2d %-12s %6d ops, %10.0f ns/op, %9d -> %9d cells\n",
+			r.System, r.Resolution, r.Operation, r.Operations, r.AverageNs(), r.InputCells, r.OutputCells)
+	}
+
+	if err := saveSetOpResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// candidatePairs returns the feature index pairs whose bounding rectangles
+// intersect, so intersections and differences do real work
+func candidatePairs(ds *bench.Dataset) [][2]int {
+	bounds := make([]s2.Rect, len(ds.Features))
+	for i, f := range ds.Features {
+		bounds[i] = f.S2Polygon.RectBound()
+	}
+	var pairs [][2]int
+	forEachPair(len(ds.Features), func(_, i, j int) {
+		if bounds[i].Intersects(bounds[j]) {
+			pairs = append(pairs, [2]int{i, j})
+		}
+	})
+	return pairs
+}
+
+// benchmarkH3SetOps runs merge-based set operations on sorted H3 coverings.
+// All cells share one resolution, so no parent/child handling is needed.
+func benchmarkH3SetOps(coverings [][]uint64, pairs [][2]int, res int) []SetOpResult {
+	sorted := make([][]uint64, len(coverings))
+	for i, c := range coverings {
+		sorted[i] = slices.Clone(c)
+		slices.Sort(sorted[i])
+	}
+
+	ops := []struct {
+		name string
+		fn   func(a, b []uint64) []uint64
+	}{
+		{"union", sortedUnion},
+		{"intersection", sortedIntersection},
+		{"difference", sortedDifference},
+	}
+	var results []SetOpResult
+	for _, op := range ops {
+		r := SetOpResult{System: bench.SystemH3, Resolution: res, Operation: op.name}
+		for _, p := range pairs {
+			a, b := sorted[p[0]], sorted[p[1]]
+			start := time.Now()
+			out := op.fn(a, b)
+			r.Duration += time.Since(start)
+			r.Operations++
+			r.InputCells += len(a) + len(b)
+			r.OutputCells += len(out)
+		}
+		results = append(results, r)
+	}
+
+	all := SetOpResult{System: bench.SystemH3, Resolution: res, Operation: "union_all", Operations: 1}
+	start := time.Now()
+	var acc []uint64
+	for _, c := range sorted {
+		acc = append(acc, c...)
+		all.InputCells += len(c)
+	}
+	slices.Sort(acc)
+	acc = slices.Compact(acc)
+	all.Duration = time.Since(start)
+	all.OutputCells = len(acc)
+	return append(results, all)
+}
+
+// benchmarkS2SetOps runs the s2.CellUnion set operations on normalized
+// coverings; results are normalized too, so they can hold coarser cells
+func benchmarkS2SetOps(coverings [][]uint64, pairs [][2]int, level int) []SetOpResult {
+	unions := make([]s2.CellUnion, len(coverings))
+	for i, c := range coverings {
+		unions[i] = make(s2.CellUnion, len(c))
+		for k, cell := range c {
+			unions[i][k] = s2.CellID(cell)
+		}
+		unions[i].Normalize()
+	}
+
+	ops := []struct {
+		name string
+		fn   func(a, b s2.CellUnion) s2.CellUnion
+	}{
+		{"union", func(a, b s2.CellUnion) s2.CellUnion { return s2.CellUnionFromUnion(a, b) }},
+		{"intersection", s2.CellUnionFromIntersection},
+		{"difference", s2.CellUnionFromDifference},
+	}
+	var results []SetOpResult
+	for _, op := range ops {
+		r := SetOpResult{System: bench.SystemS2, Resolution: level, Operation: op.name}
+		for _, p := range pairs {
+			a, b := unions[p[0]], unions[p[1]]
+			start := time.Now()
+			out := op.fn(a, b)
+			r.Duration += time.Since(start)
+			r.Operations++
+			r.InputCells += len(a) + len(b)
+			r.OutputCells += len(out)
+		}
+		results = append(results, r)
+	}
+
+	all := SetOpResult{System: bench.SystemS2, Resolution: level, Operation: "union_all", Operations: 1}
+	for _, u := range unions {
+		all.InputCells += len(u)
+	}
+	start := time.Now()
+	out := s2.CellUnionFromUnion(unions...)
+	all.Duration = time.Since(start)
+	all.OutputCells = len(out)
+	return append(results, all)
+}
+
+func sortedUnion(a, b []uint64) []uint64 {
+	out := make([]uint64, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			out = append(out, a[i])
+			i++
+		case a[i] > b[j]:
+			out = append(out, b[j])
+			j++
+		default:
+			out = append(out, a[i])
+			i++
+			j++
+		}
+	}
+	out = append(out, a[i:]...)
+	return append(out, b[j:]...)
+}
+
+func sortedIntersection(a, b []uint64) []uint64 {
+	var out []uint64
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			out = append(out, a[i])
+			i++
+			j++
+		}
+	}
+	return out
+}
+
+func sortedDifference(a, b []uint64) []uint64 {
+	var out []uint64
+	j := 0
+	for _, cell := range a {
+		for j < len(b) && b[j] < cell {
+			j++
+		}
+		if j == len(b) || b[j] != cell {
+			out = append(out, cell)
+		}
+	}
+	return out
+}
+
+func saveSetOpResultsToCSV(filename string, results []SetOpResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "Resolution", "Operation", "Operations", "InputCells", "OutputCells", "DurationNs", "AverageNs"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			r.System,
+			strconv.Itoa(r.Resolution),
+			r.Operation,
+			strconv.Itoa(r.Operations),
+			strconv.Itoa(r.InputCells),
+			strconv.Itoa(r.OutputCells),
+			strconv.FormatInt(r.Duration.Nanoseconds(), 10),
+			strconv.FormatFloat(r.AverageNs(), 'f', -1, 64),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
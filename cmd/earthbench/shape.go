@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/nkk36/earth-discretization-benchmark/report"
+	"github.com/uber/h3-go/v4"
+)
+
+// ShapeResult is the geometric quality of the distinct cells used by the
+// coverings of a dataset at one system and resolution. Compactness is the
+// spherical isoperimetric quotient A(4π−A)/P² on the unit sphere: 1 for a
+// circle, about 0.91 for a regular hexagon and 0.79 for a square. Aspect is
+// the ratio of the long to the short principal axis of the cell's vertices:
+// 1 for a regular polygon, 2 for a 2:1 rectangle.
+type ShapeResult struct {
+	System      string
+	Resolution  int
+	Cells       int
+	Pentagons   int // H3 only; the twelve per resolution have five sides
+	Compactness report.Distribution
+	Aspect      report.Distribution
+}
+
+func runShapeCommand(args []string) error {
+	fs := flag.NewFlagSet("shape", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "3-6", "6-11")
+	output := fs.String("output", "output/shape.csv", "CSV file for the results")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d features from %s\n", len(ds.Features), ds.Path)
+
+	var results []ShapeResult
+	for _, sp := range sweepPoints {
+		coverings, err := bench.ComputeCoverings(ds, sp.System, sp.Resolution, *sweep.MaxCells)
+		if err != nil {
+			return fmt.Errorf("%s resolution %d: %w", sp.System, sp.Resolution, err)
+		}
+		r, err := measureCellShapes(sp, coverings)
+		if err != nil {
+			return fmt.Errorf("%s resolution %d: %w", sp.System, sp.Resolution, err)
+		}
+		fmt.Printf("%s res %2d: %9d cells, compactness mean %.4f (min %.4f, median %.4f), aspect median %.4f (p90 %.4f, max %.4f)\n",
+			r.System, r.Resolution, r.Cells, r.Compactness.Mean, r.Compactness.Min, r.Compactness.Median, r.Aspect.Median, r.Aspect.P90, r.Aspect.Max)
+		if r.Pentagons > 0 {
+			fmt.Printf("  pentagon cells: %d\n", r.Pentagons)
+		}
+		results = append(results, r)
+	}
+
+	if err := saveShapeResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// measureCellShapes measures every distinct cell of the coverings once
+func measureCellShapes(sp bench.SweepPoint, coverings [][]uint64) (ShapeResult, error) {
+	result := ShapeResult{System: sp.System, Resolution: sp.Resolution}
+	seen := make(map[uint64]bool)
+	var compactness, aspect []float64
+	for _, covering := range coverings {
+		for _, cell := range covering {
+			if seen[cell] {
+				continue
+			}
+			seen[cell] = true
+			c, a, err := cellShape(sp.System, cell)
+			if err != nil {
+				return result, err
+			}
+			compactness = append(compactness, c)
+			aspect = append(aspect, a)
+			if sp.System == bench.SystemH3 && h3.Cell(cell).IsPentagon() {
+				result.Pentagons++
+			}
+		}
+	}
+	result.Cells = len(seen)
+	result.Compactness = report.NewDistribution(compactness)
+	result.Aspect = report.NewDistribution(aspect)
+	return result, nil
+}
+
+// cellShape returns the compactness and aspect ratio of a cell (see
+// ShapeResult). Edges are measured as great-circle arcs between the
+// boundary vertices, which H3 gives in order including the extra vertices
+// where an edge crosses an icosahedron face.
+func cellShape(system string, cell uint64) (compactness, aspect float64, err error) {
+	var vertices []s2.Point
+	var center s2.Point
+	var area float64 // steradians
+	if system == bench.SystemH3 {
+		c := h3.Cell(cell)
+		boundary, err := h3.CellToBoundary(c)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, ll := range boundary {
+			vertices = append(vertices, s2.PointFromLatLng(s2.LatLngFromDegrees(ll.Lat, ll.Lng)))
+		}
+		ll, err := h3.CellToLatLng(c)
+		if err != nil {
+			return 0, 0, err
+		}
+		center = s2.PointFromLatLng(s2.LatLngFromDegrees(ll.Lat, ll.Lng))
+		if area, err = h3.CellAreaRads2(c); err != nil {
+			return 0, 0, err
+		}
+	} else {
+		c := s2.CellFromCellID(s2.CellID(cell))
+		for k := 0; k < 4; k++ {
+			vertices = append(vertices, c.Vertex(k))
+		}
+		center = c.Center()
+		area = c.ExactArea()
+	}
+
+	var perimeter float64
+	for i, v := range vertices {
+		perimeter += v.Distance(vertices[(i+1)%len(vertices)]).Radians()
+	}
+	if perimeter > 0 {
+		compactness = area * (4*math.Pi - area) / (perimeter * perimeter)
+	}
+
+	// Principal axes of the vertices projected onto the plane tangent at
+	// the center
+	e1 := s2.Point{Vector: center.Ortho()}
+	e2 := s2.Point{Vector: center.Cross(e1.Vector)}
+	xs, ys := make([]float64, len(vertices)), make([]float64, len(vertices))
+	var mx, my float64
+	for i, v := range vertices {
+		p := v.Mul(1 / v.Dot(center.Vector)) // gnomonic, so edges stay straight
+		xs[i], ys[i] = p.Dot(e1.Vector), p.Dot(e2.Vector)
+		mx += xs[i]
+		my += ys[i]
+	}
+	mx /= float64(len(vertices))
+	my /= float64(len(vertices))
+	var sxx, syy, sxy float64
+	for i := range vertices {
+		dx, dy := xs[i]-mx, ys[i]-my
+		sxx += dx * dx
+		syy += dy * dy
+		sxy += dx * dy
+	}
+	// Eigenvalues of the 2x2 covariance matrix
+	mean, diff := (sxx+syy)/2, math.Hypot((sxx-syy)/2, sxy)
+	if mean-diff > 0 {
+		aspect = math.Sqrt((mean + diff) / (mean - diff))
+	}
+	return compactness, aspect, nil
+}
+
+// saveShapeResultsToCSV writes one row per system and resolution
+func saveShapeResultsToCSV(filename string, results []ShapeResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "Resolution", "Cells", "Pentagons",
+		"CompactnessMean", "CompactnessMin", "CompactnessP25", "CompactnessMedian", "CompactnessP75", "CompactnessMax",
+		"AspectMean", "AspectMin", "AspectMedian", "AspectP75", "AspectP90", "AspectMax"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	for _, r := range results {
+		c, a := r.Compactness, r.Aspect
+		row := []string{
+			r.System,
+			strconv.Itoa(r.Resolution),
+			strconv.Itoa(r.Cells),
+			strconv.Itoa(r.Pentagons),
+			f(c.Mean), f(c.Min), f(c.P25), f(c.Median), f(c.P75), f(c.Max),
+			f(a.Mean), f(a.Min), f(a.Median), f(a.P75), f(a.P90), f(a.Max),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"runtime"
+	"runtime/metrics"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/nkk36/earth-discretization-benchmark/report"
+)
+
+// metricHeapObjects is the runtime/metrics sample of the live and not yet
+// swept Go heap
+const metricHeapObjects = "/memory/classes/heap/objects:bytes"
+
+// SoakLatency is the coverings of one system during one soak interval
+type SoakLatency struct {
+	Coverings int
+	MeanNs    float64
+	P50Ns     float64
+	P99Ns     float64
+}
+
+// SoakSample is the state of the process at the end of one soak interval.
+// RSS is the resident set of the whole process, Linux only; GoFootprint is
+// the part the Go runtime holds from the OS and GoHeap the Go heap within
+// it, so RSS - GoFootprint is mostly memory of the H3 C library.
+type SoakSample struct {
+	Elapsed     time.Duration
+	Throughput  float64 // coverings per second during the interval
+	RSS         uint64
+	GoFootprint uint64
+	GoHeap      uint64
+	Goroutines  int
+	Latency     map[string]SoakLatency // by system
+}
+
+// NonGo is the resident memory outside the Go runtime, 0 without an RSS
+func (s SoakSample) NonGo() uint64 {
+	if s.RSS < s.GoFootprint {
+		return 0
+	}
+	return s.RSS - s.GoFootprint
+}
+
+// SoakCycle is one pass of the workload, every feature at every sweep
+// point, ending End into the soak. Passes do the same work, so unlike the
+// intervals, whose mix of features and systems varies, their durations can
+// be compared with each other.
+type SoakCycle struct {
+	End      time.Duration
+	Duration time.Duration
+	Systems  map[string]time.Duration // time covering, by system
+}
+
+// SoakFinding is a trend flagged over the samples after the warm-up
+type SoakFinding struct {
+	Metric string
+	Detail string
+}
+
+func runSoakCommand(args []string) error {
+	fs := flag.NewFlagSet("soak", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "5", "11")
+	duration := fs.Duration("duration", time.Hour, "how long to loop the workload")
+	interval := fs.Duration("interval", time.Minute, "how often to sample memory, goroutines and latency")
+	warmup := fs.Duration("warmup", 5*time.Minute, "samples left out of the trend checks while caches and the heap settle (at most half of -duration)")
+	growth := fs.Float64("growth", 0.1, "flag memory that grew by more than this fraction over the run, without falling back, as a leak")
+	decay := fs.Float64("decay", 0.1, "flag a pass over the workload, overall or for one system, that got slower by more than this fraction over the run")
+	fail := fs.Bool("fail", false, "exit with an error when anything is flagged, e.g. in a nightly CI job")
+	output := fs.String("output", "output/soak.csv", "CSV file with one row per sample and system")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	if *interval <= 0 || *duration < 2**interval {
+		return fmt.Errorf("-duration must be at least two -interval")
+	}
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	if len(ds.Features) == 0 {
+		return fmt.Errorf("no features to cover")
+	}
+	fmt.Printf("Loaded %d features from %s\n", len(ds.Features), ds.Path)
+	if readRSS() == 0 {
+		fmt.Printf("Warning: the resident set size is only read on Linux; growth outside the Go runtime goes unseen on %s\n", runtime.GOOS)
+	}
+
+	// Ctrl-C ends the soak early but still checks and saves the samples
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fmt.Printf("Soaking %d sweep point(s) for %v, sampling every %v\n", len(sweepPoints), *duration, *interval)
+	samples, cycles, err := soak(ctx, ds, sweepPoints, *sweep.MaxCells, *duration, *interval)
+	if err != nil {
+		return err
+	}
+
+	settled := min(*warmup, *duration/2)
+	afterWarmup := slices.IndexFunc(samples, func(s SoakSample) bool { return s.Elapsed > settled })
+	if afterWarmup < 0 {
+		afterWarmup = len(samples)
+	}
+	cycles = slices.DeleteFunc(cycles, func(c SoakCycle) bool { return c.End-c.Duration < settled })
+	findings := checkSoak(samples[afterWarmup:], cycles, *growth, *decay)
+	fmt.Printf("\n%d sample(s) and %d pass(es) over the workload after the warm-up checked for trends\n", len(samples)-afterWarmup, len(cycles))
+	for _, f := range findings {
+		fmt.Printf("FLAGGED %-12s %s\n", f.Metric, f.Detail)
+	}
+	if len(findings) == 0 {
+		fmt.Println("No memory growth, goroutine growth or throughput decay beyond the thresholds")
+	}
+
+	if err := saveSoakSamplesToCSV(*output, samples); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	if *fail && len(findings) > 0 {
+		return fmt.Errorf("%d trend(s) flagged", len(findings))
+	}
+	return nil
+}
+
+// soak covers every feature at one sweep point after another, over and
+// over, until the duration is up or ctx is done, taking a sample at every
+// interval and recording every pass
+func soak(ctx context.Context, ds *bench.Dataset, points []bench.SweepPoint, maxCells int, duration, interval time.Duration) ([]SoakSample, []SoakCycle, error) {
+	var samples []SoakSample
+	var cycles []SoakCycle
+	start := time.Now()
+	windowStart, cycleStart := start, start
+	durations := make(map[string][]float64)
+	cycle := make(map[string]time.Duration)
+	coverings := 0
+	for i := 0; ctx.Err() == nil; i++ {
+		f := ds.Features[i%len(ds.Features)]
+		sp := points[(i/len(ds.Features))%len(points)]
+		t := time.Now()
+		if _, err := bench.CoverFeature(f, sp.System, sp.Resolution, maxCells); err != nil {
+			return samples, cycles, fmt.Errorf("%s resolution %d, feature %d: %w", sp.System, sp.Resolution, f.FeatureID, err)
+		}
+		elapsed := time.Since(t)
+		durations[sp.System] = append(durations[sp.System], float64(elapsed))
+		cycle[sp.System] += elapsed
+		coverings++
+
+		now := time.Now()
+		if (i+1)%(len(ds.Features)*len(points)) == 0 {
+			cycles = append(cycles, SoakCycle{End: now.Sub(start), Duration: now.Sub(cycleStart), Systems: cycle})
+			cycleStart, cycle = now, make(map[string]time.Duration)
+		}
+		if now.Sub(windowStart) < interval {
+			continue
+		}
+		s := sampleProcess()
+		s.Elapsed = now.Sub(start)
+		s.Throughput = float64(coverings) / now.Sub(windowStart).Seconds()
+		s.Latency = make(map[string]SoakLatency, len(durations))
+		for system, d := range durations {
+			slices.Sort(d)
+			var sum float64
+			for _, ns := range d {
+				sum += ns
+			}
+			s.Latency[system] = SoakLatency{Coverings: len(d), MeanNs: sum / float64(len(d)), P50Ns: report.Percentile(d, 50), P99Ns: report.Percentile(d, 99)}
+		}
+		samples = append(samples, s)
+		fmt.Printf("%10v: %8.1f coverings/s, RSS %9s (Go %9s, heap %9s), %4d goroutines",
+			s.Elapsed.Round(time.Second), s.Throughput, formatBytes(float64(s.RSS)), formatBytes(float64(s.GoFootprint)), formatBytes(float64(s.GoHeap)), s.Goroutines)
+		for _, system := range []string{bench.SystemH3, bench.SystemS2} {
+			if l, ok := s.Latency[system]; ok {
+				fmt.Printf(", %s p50 %v p99 %v", system, time.Duration(l.P50Ns).Round(time.Microsecond), time.Duration(l.P99Ns).Round(time.Microsecond))
+			}
+		}
+		fmt.Println()
+
+		if s.Elapsed >= duration {
+			break
+		}
+		windowStart, coverings = now, 0
+		clear(durations)
+	}
+	return samples, cycles, nil
+}
+
+// sampleProcess reads the memory and goroutines of the process
+func sampleProcess() SoakSample {
+	m := []metrics.Sample{{Name: metricTotalBytes}, {Name: metricReleasedBytes}, {Name: metricHeapObjects}}
+	metrics.Read(m)
+	return SoakSample{
+		RSS:         readRSS(),
+		GoFootprint: m[0].Value.Uint64() - m[1].Value.Uint64(),
+		GoHeap:      m[2].Value.Uint64(),
+		Goroutines:  runtime.NumGoroutine(),
+	}
+}
+
+// readRSS returns the resident set size of the process from
+// /proc/self/statm, or 0 where there is none
+func readRSS() uint64 {
+	data, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0
+	}
+	pages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return pages * uint64(os.Getpagesize())
+}
+
+// checkSoak flags the trends of the samples and passes. Memory has grown
+// when the lowest value of the last third of the samples is above the
+// highest of the first third, so the sawtooth of garbage collection is not
+// mistaken for growth, and the growth along the fitted line is more than
+// the growth fraction of its start. Throughput has decayed when the mean
+// pass of the last third takes longer than that of the first third by more
+// than the decay fraction, overall or for one system.
+func checkSoak(samples []SoakSample, cycles []SoakCycle, growth, decay float64) []SoakFinding {
+	var findings []SoakFinding
+	if len(cycles) >= 3 {
+		third := len(cycles) / 3
+		first, last := cycles[:third], cycles[len(cycles)-third:]
+		mean := func(cycles []SoakCycle, value func(SoakCycle) time.Duration) time.Duration {
+			var sum time.Duration
+			for _, c := range cycles {
+				sum += value(c)
+			}
+			return sum / time.Duration(len(cycles))
+		}
+		check := func(metric, what string, value func(SoakCycle) time.Duration) {
+			if before, after := mean(first, value), mean(last, value); before > 0 && float64(after) > (1+decay)*float64(before) {
+				findings = append(findings, SoakFinding{Metric: metric, Detail: fmt.Sprintf("%s went from %v to %v (+%.0f%%)",
+					what, before.Round(time.Millisecond), after.Round(time.Millisecond), 100*(float64(after)/float64(before)-1))})
+			}
+		}
+		check("throughput", "a pass over the workload", func(c SoakCycle) time.Duration { return c.Duration })
+		for _, system := range []string{bench.SystemH3, bench.SystemS2} {
+			check(system+"-latency", "the "+system+" coverings of a pass", func(c SoakCycle) time.Duration { return c.Systems[system] })
+		}
+	}
+
+	if len(samples) < 3 {
+		return findings
+	}
+	third := len(samples) / 3
+	first, last := samples[:third], samples[len(samples)-third:]
+	hours := (samples[len(samples)-1].Elapsed - samples[0].Elapsed).Hours()
+	memory := []struct {
+		name  string
+		value func(SoakSample) float64
+		cause string
+	}{
+		{"rss", func(s SoakSample) float64 { return float64(s.RSS) }, "the whole process"},
+		{"go-heap", func(s SoakSample) float64 { return float64(s.GoHeap) }, "Go objects are kept alive"},
+		{"non-go", func(s SoakSample) float64 { return float64(s.NonGo()) }, "outside the Go runtime, likely C allocations across the H3 cgo boundary"},
+	}
+	for _, m := range memory {
+		slope, intercept := fitLine(samples, m.value)
+		fitted := slope * hours
+		if risen(first, last, m.value) && intercept > 0 && fitted > growth*intercept {
+			findings = append(findings, SoakFinding{Metric: m.name, Detail: fmt.Sprintf("grew steadily by %s/hour (+%.0f%% over the run): %s",
+				formatBytes(slope), 100*fitted/intercept, m.cause)})
+		}
+	}
+
+	if g0, g1 := samples[0].Goroutines, samples[len(samples)-1].Goroutines; risen(first, last, func(s SoakSample) float64 { return float64(s.Goroutines) }) {
+		findings = append(findings, SoakFinding{Metric: "goroutines", Detail: fmt.Sprintf("rose from %d to %d", g0, g1)})
+	}
+
+	return findings
+}
+
+// risen reports whether the lowest value of the last samples is above the
+// highest of the first ones
+func risen(first, last []SoakSample, value func(SoakSample) float64) bool {
+	highFirst, lowLast := math.Inf(-1), math.Inf(1)
+	for _, s := range first {
+		highFirst = max(highFirst, value(s))
+	}
+	for _, s := range last {
+		lowLast = min(lowLast, value(s))
+	}
+	return lowLast > highFirst
+}
+
+// fitLine fits value = slope·hours + intercept by least squares, hours
+// counted from the first sample
+func fitLine(samples []SoakSample, value func(SoakSample) float64) (slope, intercept float64) {
+	var n, sx, sy, sxx, sxy float64
+	for _, s := range samples {
+		x, y := (s.Elapsed - samples[0].Elapsed).Hours(), value(s)
+		n++
+		sx += x
+		sy += y
+		sxx += x * x
+		sxy += x * y
+	}
+	if n*sxx == sx*sx {
+		return 0, sy / n
+	}
+	slope = (n*sxy - sx*sy) / (n*sxx - sx*sx)
+	return slope, (sy - slope*sx) / n
+}
+
+func saveSoakSamplesToCSV(filename string, samples []SoakSample) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"ElapsedSeconds", "CoveringsPerSecond", "RSSBytes", "GoFootprintBytes", "GoHeapBytes", "NonGoBytes", "Goroutines",
+		"System", "Coverings", "MeanDurationNs", "P50DurationNs", "P99DurationNs"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, s := range samples {
+		for _, system := range []string{bench.SystemH3, bench.SystemS2} {
+			l, ok := s.Latency[system]
+			if !ok {
+				continue
+			}
+			row := []string{
+				strconv.FormatFloat(s.Elapsed.Seconds(), 'f', 3, 64),
+				strconv.FormatFloat(s.Throughput, 'f', -1, 64),
+				strconv.FormatUint(s.RSS, 10),
+				strconv.FormatUint(s.GoFootprint, 10),
+				strconv.FormatUint(s.GoHeap, 10),
+				strconv.FormatUint(s.NonGo(), 10),
+				strconv.Itoa(s.Goroutines),
+				system,
+				strconv.Itoa(l.Coverings),
+				strconv.FormatFloat(l.MeanNs, 'f', 0, 64),
+				strconv.FormatFloat(l.P50Ns, 'f', 0, 64),
+				strconv.FormatFloat(l.P99Ns, 'f', 0, 64),
+			}
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	return writer.Error()
+}
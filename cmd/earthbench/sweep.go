@@ -0,0 +1,525 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	ds2 "github.com/nkk36/earth-discretization-benchmark/discretize/s2"
+)
+
+func runSweepCommand(args []string) (err error) {
+	fs := flag.NewFlagSet("sweep", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "0-8", "0-13")
+	output := fs.String("output", "output/sweep.csv", "CSV file for the per-resolution measurements ('-' for stdout, moving progress messages to stderr)")
+	skipped := fs.String("skipped", "output/sweep_skipped.csv", "CSV file listing the coverings left out of the measurements and why")
+	combined := fs.String("combined", "output/sweep_combined.csv", "with several -input files, CSV file with the measurements of all of them and a Dataset column")
+	bucketOutput := fs.String("buckets", "output/sweep_buckets.csv", "CSV file with the durations per area and vertex-count bucket")
+	timeout := fs.Duration("cover-timeout", 0, "give up on a single covering after this long, e.g. 30s (0 for no limit)")
+	h3Cap := fs.Int("h3-cell-cap", 0, "skip H3 fills estimated to return more cells than this (0 for no cap)")
+	verify := fs.Int("verify", 0, "check every covering against its system's contract with this many interior sample points (0 to skip)")
+	centroidFallback := fs.Bool("centroid-fallback", false, "replace empty coverings, of polygons smaller than a cell, with the cell of the polygon's centroid; they are counted in Empty either way")
+	levelSpan := fs.Int("s2-level-span", 0, "let S2 coverings mix levels from this many below each sweep level up to it (RegionCoverer MinLevel < MaxLevel) and count their cells per level; 0 fixes both at the sweep level")
+	jsonOutput := fs.String("json", "", "also write the measurements and a per-resolution summary as JSON to this file")
+	protoOutput := fs.String("protobuf", "", "also write the measurements as a Run message of proto/results.proto to this file")
+	repeat := fs.Int("repeat", 1, "run the whole sweep this many times")
+	workers := fs.Int("workers", 1, "resolutions measured at once; durations are only comparable at the same setting")
+	sinkSpecs := fs.String("sinks", "table", "comma-separated sinks receiving each measurement as it completes: table, csv:FILE, json:FILE (JSON Lines), sqlite:FILE, prometheus:FILE or prometheus:PUSHGATEWAY_URL")
+	sqliteBinary := fs.String("sqlite3", "sqlite3", "path to the sqlite3 CLI used by sqlite sinks")
+	precision := fs.Float64("precision", 0.05, "time each covering until the 95% confidence interval of its mean is within this fraction of it (0 for a single timing)")
+	maxSamples := fs.Int("max-samples", 20, "stop timing a covering after this many samples even if -precision is not reached")
+	budget := fs.Duration("budget", 0, "instead of -repeat, spend this long (e.g. 10m) repeating the noisiest sweep points (0 for fixed repetitions)")
+	dryRun := fs.Bool("dry-run", false, "print the plan with a runtime estimated from -calibrate features instead of running it")
+	calibrate := fs.Int("calibrate", 5, "features covered once per sweep point to estimate the runtime of -dry-run")
+	normalize := fs.Bool("normalize", false, "scale every duration by the machine score of the startup calibration, estimating the reference machine's durations so runs on different machines can be roughly compared")
+	noiseCPUs := fs.Float64("noise-cpus", 1, "flag measurements during which other processes kept more than this many CPUs busy on average (0 to disable)")
+	noiseFreqDrop := fs.Float64("noise-freq-drop", 0.1, "flag measurements during which the CPU clock was this fraction below its peak (0 to disable)")
+	tui := fs.Bool("tui", false, "redraw live per-resolution progress and a sortable results table on the terminal instead of the table sink")
+	addPresetFlag(fs)
+	notify := addNotifyFlags(fs)
+	sched := addSchedFlags(fs)
+	compress := fs.Bool("gzip", false, "gzip-compress the output files, adding .gz to their names (outputs and csv/json sinks named *.gz are always compressed)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *compress {
+		for _, name := range []*string{output, skipped, combined, bucketOutput, jsonOutput, protoOutput} {
+			*name = gzipName(*name)
+		}
+	}
+
+	sinkTargets := []string{*output, *skipped, *combined, *bucketOutput, *jsonOutput, *protoOutput}
+	for _, s := range strings.Split(*sinkSpecs, ",") {
+		_, target, _ := strings.Cut(strings.TrimSpace(s), ":")
+		sinkTargets = append(sinkTargets, target)
+	}
+	out := progressOutput(sinkTargets...)
+	sweep.Out = out
+	if *tui && !isTerminal(out) {
+		return fmt.Errorf("-tui needs a terminal on %s", out.Name())
+	}
+	if *levelSpan < 0 {
+		return fmt.Errorf("-s2-level-span must not be negative")
+	}
+
+	start := time.Now()
+	var all []*bench.Results
+	interrupted := false
+	defer func() {
+		notify.send("sweep", start, err, interrupted, *output, sweepHeadlines(all))
+	}()
+
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	datasets, err := sweep.LoadDatasets()
+	if err != nil {
+		return err
+	}
+	if len(datasets) > 1 && (*output == "-" || *skipped == "-" || *bucketOutput == "-" || *jsonOutput == "-" || *protoOutput == "-") {
+		return fmt.Errorf("with several -input files only -combined can be written to stdout ('-')")
+	}
+	for _, ds := range datasets {
+		fmt.Fprintf(out, "Loaded %d features from %s\n", len(ds.Features), ds.Path)
+	}
+
+	if err := sched.apply(out); err != nil {
+		return err
+	}
+
+	// Ctrl-C stops the sweep but still saves the resolutions already done
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	options := []bench.Option{
+		bench.WithSweepPoints(sweepPoints...),
+		bench.WithCoveringOptions(bench.CoveringOptions{
+			MaxCells:   *sweep.MaxCells,
+			Timeout:    *timeout,
+			H3CellCap:  *h3Cap,
+			Verify:     *verify,
+			Precision:  *precision,
+			MaxSamples: *maxSamples,
+
+			CentroidFallback: *centroidFallback,
+			S2LevelSpan:      *levelSpan,
+		}),
+		bench.WithRepetitions(*repeat),
+		bench.WithBudget(*budget / time.Duration(len(datasets))),
+		bench.WithConcurrency(*workers),
+	}
+	if *dryRun {
+		var plans []*bench.Plan
+		for _, ds := range datasets {
+			plan, err := bench.NewRunner(options...).Plan(ctx, ds, *calibrate)
+			if err != nil {
+				return err
+			}
+			plans = append(plans, plan)
+		}
+		printPlans(out, plans)
+		return nil
+	}
+
+	calibration := bench.Calibrate()
+	calibration.Normalized = *normalize
+	fmt.Fprintf(out, "Machine score %.2f (calibration workload %v)", calibration.Score, time.Duration(calibration.WorkloadNs).Round(time.Microsecond))
+	if *normalize {
+		fmt.Fprint(out, "; durations are normalized to the reference machine")
+	}
+	fmt.Fprintln(out)
+	noise := bench.StartNoiseMonitor(bench.NoiseThresholds{OtherCPUs: *noiseCPUs, FrequencyDrop: *noiseFreqDrop})
+	defer noise.Stop()
+	options = append(options, bench.WithCalibration(calibration), bench.WithNoiseMonitor(noise))
+
+	if *tui {
+		// The live view replaces the table sink's rows
+		var specs []string
+		for _, spec := range strings.Split(*sinkSpecs, ",") {
+			if strings.TrimSpace(spec) != "table" {
+				specs = append(specs, spec)
+			}
+		}
+		*sinkSpecs = strings.Join(specs, ",")
+	}
+	sinks, err := openSinks(*sinkSpecs, *sqliteBinary, out)
+	if err != nil {
+		return err
+	}
+	for _, ds := range datasets {
+		if len(datasets) > 1 {
+			fmt.Fprintf(out, "\nDataset %s\n", bench.DatasetName(ds.Path))
+		}
+		runnerOptions := append(slices.Clone(options), bench.WithSinks(sinks.sinks...))
+		var view *liveView
+		if *tui {
+			jobs := len(sweepPoints) * *repeat
+			if *budget > 0 {
+				jobs = 0
+			}
+			view = newLiveView(out, ds.Path, jobs)
+			runnerOptions = append(runnerOptions, bench.WithSinks(view), bench.WithProgress(view.Progress))
+		}
+		var results *bench.Results
+		results, err = bench.NewRunner(runnerOptions...).Run(ctx, ds)
+		if view != nil {
+			view.Close()
+		}
+		if results != nil {
+			all = append(all, results)
+		}
+		if err != nil {
+			break
+		}
+	}
+	if closeErr := sinks.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil && ctx.Err() == nil {
+		return err
+	}
+	interrupted = ctx.Err() != nil
+	measurements := 0
+	flagged := make(map[string]int)
+	for _, results := range all {
+		measurements += len(results.Measurements)
+		for _, m := range results.Measurements {
+			for _, flag := range m.Quality {
+				flagged[flag]++
+			}
+		}
+	}
+	if len(flagged) > 0 {
+		var counts []string
+		for _, flag := range []string{bench.QualityLoad, bench.QualityFrequency, bench.QualityThrottled} {
+			if flagged[flag] > 0 {
+				counts = append(counts, fmt.Sprintf("%s %d", flag, flagged[flag]))
+			}
+		}
+		log.Printf("Warning: measurements taken on a disturbed machine (%s); summaries leave them out where a sweep point has clean repetitions", strings.Join(counts, ", "))
+	}
+	switch {
+	case ctx.Err() != nil && *budget > 0:
+		fmt.Fprintf(out, "Interrupted after %d measurements\n", measurements)
+	case ctx.Err() != nil:
+		fmt.Fprintf(out, "Interrupted after %d of %d resolutions\n", measurements, len(sweepPoints)**repeat*len(datasets))
+	case *budget > 0:
+		fmt.Fprintf(out, "Made %d measurements of %d sweep points in the %v budget\n", measurements, len(sweepPoints)*len(datasets), *budget)
+	}
+
+	for i, results := range all {
+		// With several datasets every file gets the dataset's name
+		name := ""
+		if len(datasets) > 1 {
+			name = bench.DatasetName(results.Dataset)
+			fmt.Fprintf(out, "\nDataset %s\n", name)
+		}
+		if err := writeResultsFile(datasetOutput(*output, name), results.WriteCSV); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Results saved to %s\n", datasetOutput(*output, name))
+		if err := writeResultsFile(datasetOutput(*skipped, name), results.WriteSkippedCSV); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Skipped coverings saved to %s\n", datasetOutput(*skipped, name))
+
+		buckets := datasets[i].BucketsByID()
+		printBucketTable(out, results.ByBucket(buckets))
+		printLevelTable(out, results.Summary())
+		if err := writeResultsFile(datasetOutput(*bucketOutput, name), func(w io.Writer) error { return results.WriteBucketsCSV(w, buckets) }); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Per-bucket durations saved to %s\n", datasetOutput(*bucketOutput, name))
+		if *jsonOutput != "" {
+			if err := writeResultsFile(datasetOutput(*jsonOutput, name), results.WriteJSON); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "JSON results saved to %s\n", datasetOutput(*jsonOutput, name))
+		}
+		if *protoOutput != "" {
+			if err := writeResultsFile(datasetOutput(*protoOutput, name), writeProto(runToProto(results))); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Protobuf results saved to %s\n", datasetOutput(*protoOutput, name))
+		}
+	}
+
+	if len(datasets) > 1 {
+		printDatasetTable(out, all)
+		if err := writeResultsFile(*combined, func(w io.Writer) error { return bench.WriteCombinedCSV(w, all) }); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Combined results saved to %s\n", *combined)
+	}
+	return nil
+}
+
+// datasetOutput inserts a dataset name before the extension of an output
+// file, output/sweep.csv becoming output/sweep-urban.csv (and
+// output/sweep.csv.gz output/sweep-urban.csv.gz); an empty name keeps the
+// file name
+func datasetOutput(filename, name string) string {
+	if name == "" {
+		return filename
+	}
+	ext := filepath.Ext(strings.TrimSuffix(filename, ".gz"))
+	if strings.HasSuffix(filename, ".gz") {
+		ext += ".gz"
+	}
+	return strings.TrimSuffix(filename, ext) + "-" + name + ext
+}
+
+// printDatasetTable prints the mean duration per feature of every sweep
+// point, one column per dataset
+func printDatasetTable(out io.Writer, all []*bench.Results) {
+	var points []bench.SweepPoint
+	means := make([]map[bench.SweepPoint]float64, len(all))
+	fmt.Fprintf(out, "\nMean ns per feature by dataset\n%-6s %4s", "SYSTEM", "RES")
+	for i, results := range all {
+		fmt.Fprintf(out, " %14s", bench.DatasetName(results.Dataset))
+		means[i] = make(map[bench.SweepPoint]float64)
+		for _, s := range results.Summary() {
+			sp := bench.SweepPoint{System: s.System, Resolution: s.Resolution}
+			means[i][sp] = s.MeanNs
+			if !slices.Contains(points, sp) {
+				points = append(points, sp)
+			}
+		}
+	}
+	fmt.Fprintln(out)
+	for _, sp := range points {
+		fmt.Fprintf(out, "%-6s %4d", sp.System, sp.Resolution)
+		for i := range all {
+			if mean, ok := means[i][sp]; ok {
+				fmt.Fprintf(out, " %14.0f", mean)
+			} else {
+				fmt.Fprintf(out, " %14s", "-")
+			}
+		}
+		fmt.Fprintln(out)
+	}
+	fmt.Fprintln(out)
+}
+
+// printBucketTable prints the mean duration per feature of every bucket,
+// one row per sweep point, so the sizes at which the systems cross over
+// can be read off
+func printBucketTable(out io.Writer, summaries []bench.BucketSummary) {
+	if len(summaries) == 0 {
+		return
+	}
+	buckets := bench.AllBuckets()
+	fmt.Fprintf(out, "\nMean ns per feature by area / vertex-count bucket\n%-6s %4s", "SYSTEM", "RES")
+	for _, b := range buckets {
+		fmt.Fprintf(out, " %12s", b)
+	}
+	fmt.Fprintln(out)
+	for i := 0; i < len(summaries); {
+		sp := bench.SweepPoint{System: summaries[i].System, Resolution: summaries[i].Resolution}
+		means := make(map[bench.Bucket]float64)
+		for ; i < len(summaries) && summaries[i].System == sp.System && summaries[i].Resolution == sp.Resolution; i++ {
+			means[summaries[i].Bucket] = summaries[i].MeanNs
+		}
+		fmt.Fprintf(out, "%-6s %4d", sp.System, sp.Resolution)
+		for _, b := range buckets {
+			if mean, ok := means[b]; ok {
+				fmt.Fprintf(out, " %12.0f", mean)
+			} else {
+				fmt.Fprintf(out, " %12s", "-")
+			}
+		}
+		fmt.Fprintln(out)
+	}
+	fmt.Fprintln(out)
+}
+
+// printLevelTable prints how the S2 coverings of every sweep point spread
+// their cells over the levels, as a share of the cells, when -s2-level-span
+// let them mix levels
+func printLevelTable(out io.Writer, summaries []bench.Summary) {
+	minLevel, maxLevel := ds2.MaxLevel, -1
+	for _, s := range summaries {
+		for level := range s.LevelCells {
+			minLevel, maxLevel = min(minLevel, level), max(maxLevel, level)
+		}
+	}
+	if maxLevel < 0 {
+		return
+	}
+	fmt.Fprintf(out, "\nShare of S2 covering cells by level\n%-6s %4s", "SYSTEM", "RES")
+	for level := minLevel; level <= maxLevel; level++ {
+		fmt.Fprintf(out, " %6d", level)
+	}
+	fmt.Fprintln(out)
+	for _, s := range summaries {
+		if len(s.LevelCells) == 0 {
+			continue
+		}
+		fmt.Fprintf(out, "%-6s %4d", s.System, s.Resolution)
+		for level := minLevel; level <= maxLevel; level++ {
+			if n := s.LevelCells[level]; n > 0 {
+				fmt.Fprintf(out, " %5.1f%%", 100*float64(n)/float64(s.Cells))
+			} else {
+				fmt.Fprintf(out, " %6s", "-")
+			}
+		}
+		fmt.Fprintln(out)
+	}
+	fmt.Fprintln(out)
+}
+
+// writeResultsFile creates filename and writes results to it with write
+func writeResultsFile(filename string, write func(io.Writer) error) error {
+	file, err := createOutput(filename)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// progressOutput returns where a command prints its progress messages:
+// stderr when one of filenames is "-", so stdout carries only the results
+// written there and can be piped into csvkit, jq or the like, and stdout
+// otherwise
+func progressOutput(filenames ...string) *os.File {
+	if slices.Contains(filenames, "-") {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// nopCloser lets stdout stand in for an output file without being closed
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// createOutput creates an output file and any missing directories above
+// it, gzip-compressed when its name ends in .gz; "-" writes to stdout,
+// uncompressed
+func createOutput(filename string) (io.WriteCloser, error) {
+	if filename == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return nil, err
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(filename, ".gz") {
+		return file, nil
+	}
+	return &gzipFile{Writer: gzip.NewWriter(file), file: file}, nil
+}
+
+// gzipFile is a file written through a gzip.Writer; closing it flushes the
+// compressed stream before closing the file
+type gzipFile struct {
+	*gzip.Writer
+	file *os.File
+}
+
+func (g *gzipFile) Close() error {
+	err := g.Writer.Close()
+	if closeErr := g.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// gzipName appends .gz to filename unless it is empty, stdout ("-") or
+// already ends in it
+func gzipName(filename string) string {
+	if filename == "" || filename == "-" || strings.HasSuffix(filename, ".gz") {
+		return filename
+	}
+	return filename + ".gz"
+}
+
+// openedSinks are the sinks of a -sinks flag with the files they write to
+type openedSinks struct {
+	sinks []bench.ResultSink
+	files []io.Closer
+}
+
+// openSinks opens the comma-separated sinks of spec: table prints to
+// out, csv, json and sqlite write to a file, and prometheus to a
+// textfile or a Pushgateway URL
+func openSinks(spec, sqliteBinary string, out io.Writer) (*openedSinks, error) {
+	opened := &openedSinks{}
+	for _, s := range strings.Split(spec, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		kind, target, _ := strings.Cut(s, ":")
+		if kind != "table" && target == "" {
+			opened.Close()
+			return nil, fmt.Errorf("sink %q needs a target, e.g. %s:output/sweep.%s", s, kind, kind)
+		}
+		var sink bench.ResultSink
+		switch kind {
+		case "table":
+			sink = bench.NewTableSink(out)
+		case "csv", "json":
+			file, err := createOutput(target)
+			if err != nil {
+				opened.Close()
+				return nil, err
+			}
+			opened.files = append(opened.files, file)
+			if kind == "csv" {
+				sink = bench.NewCSVSink(file)
+			} else {
+				sink = bench.NewJSONSink(file)
+			}
+		case "sqlite":
+			sqlite, err := bench.NewSQLiteSink(sqliteBinary, target)
+			if err != nil {
+				opened.Close()
+				return nil, err
+			}
+			sink = sqlite
+		case "prometheus":
+			sink = bench.NewPrometheusSink(target)
+		default:
+			opened.Close()
+			return nil, fmt.Errorf("unknown sink %q (want table, csv, json, sqlite or prometheus)", kind)
+		}
+		opened.sinks = append(opened.sinks, sink)
+	}
+	return opened, nil
+}
+
+// Close closes every sink and then the files they write to, returning the
+// first error
+func (o *openedSinks) Close() error {
+	var firstErr error
+	for _, sink := range o.sinks {
+		if err := sink.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	for _, file := range o.files {
+		if err := file.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
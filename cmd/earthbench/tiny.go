@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+)
+
+// TinyResult is what one system returns at one resolution for polygons
+// smaller than a cell, with the polygon area a fixed share (Scale) of the
+// average cell area. Every covering falls in exactly one of Empty, Centroid
+// (the single cell containing the polygon's centroid), OtherCell (a single
+// other cell) and Several, counted before any centroid fallback. Duration is
+// the mean covering time, including the fallback when it is on.
+type TinyResult struct {
+	System     string
+	Resolution int
+	Scale      float64
+	Polygons   int
+	CellKm2    float64
+	Empty      int
+	Centroid   int
+	OtherCell  int
+	Several    int
+	MeanNs     float64
+	Fallback   bool
+}
+
+func runTinyCommand(args []string) error {
+	fs := flag.NewFlagSet("tiny", flag.ExitOnError)
+	// No dataset flags: the polygons are generated
+	sweep := &sweepFlags{
+		H3Res:    fs.String("h3-res", "5-9", "H3 resolutions, e.g. 0-6 or 3,5,7 (empty to skip H3)"),
+		S2Levels: fs.String("s2-levels", "10-16", "S2 levels, e.g. 0-11 or 4,8 (empty to skip S2)"),
+		MaxCells: fs.Int("s2-max-cells", 8, "S2 RegionCoverer MaxCells"),
+	}
+	scales := fs.String("scales", "0.01,0.1,0.5", "polygon areas as fractions of the average cell area at each resolution")
+	polygons := fs.Int("polygons", 200, "polygons generated per resolution and scale")
+	vertices := fs.Int("vertices", 16, "vertices of each polygon, a regular polygon around a random center")
+	seed := fs.Int64("seed", 1, "seed for the polygon centers")
+	fallback := fs.Bool("centroid-fallback", false, "replace empty coverings with the cell of the polygon's centroid, as sweep -centroid-fallback does")
+	output := fs.String("output", "output/tiny.csv", "CSV file for the results")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	var fractions []float64
+	for _, s := range strings.Split(*scales, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil || v <= 0 {
+			return fmt.Errorf("-scales: %q is not a positive fraction", s)
+		}
+		fractions = append(fractions, v)
+	}
+	if *polygons < 1 || *vertices < 3 {
+		return fmt.Errorf("-polygons must be positive and -vertices at least 3")
+	}
+
+	options := bench.CoveringOptions{MaxCells: *sweep.MaxCells, CentroidFallback: *fallback}
+	var results []TinyResult
+	for _, sp := range sweepPoints {
+		cellKm2, err := averageCellAreaKm2(sp.System, sp.Resolution)
+		if err != nil {
+			return err
+		}
+		for _, scale := range fractions {
+			radiusKm := math.Sqrt(scale * cellKm2 / math.Pi)
+			ds, err := bench.NewDataset("tiny", tinyPolygons(*polygons, radiusKm, *vertices, *seed+int64(sp.Resolution)))
+			if err != nil {
+				return err
+			}
+			r, err := benchmarkTiny(ds, sp, options)
+			if err != nil {
+				return err
+			}
+			r.Scale, r.CellKm2 = scale, cellKm2
+			fmt.Printf("%s res %2d, %5.1f%% of a %10.4g km² cell: %4d empty, %4d centroid cell, %4d other cell, %4d several, %8.0f ns/covering\n",
+				r.System, r.Resolution, 100*scale, cellKm2, r.Empty, r.Centroid, r.OtherCell, r.Several, r.MeanNs)
+			results = append(results, r)
+		}
+	}
+
+	if err := saveTinyResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// benchmarkTiny times the coverings through BenchmarkCoverings, so the
+// centroid fallback is the sweep's, then classifies the coverings as they
+// come from the covering call
+func benchmarkTiny(ds *bench.Dataset, sp bench.SweepPoint, options bench.CoveringOptions) (TinyResult, error) {
+	r := TinyResult{System: sp.System, Resolution: sp.Resolution, Polygons: len(ds.Features), Fallback: options.CentroidFallback}
+	measurements, err := bench.BenchmarkCoverings(context.Background(), ds, []bench.SweepPoint{sp}, options)
+	if err != nil {
+		return r, err
+	}
+	r.MeanNs = measurements[0].AverageDurationNs()
+
+	for _, f := range ds.Features {
+		covering, err := bench.CoverFeature(f, sp.System, sp.Resolution, options.MaxCells)
+		if err != nil {
+			return r, fmt.Errorf("%s resolution %d, feature %d: %w", sp.System, sp.Resolution, f.FeatureID, err)
+		}
+		switch len(covering) {
+		case 0:
+			r.Empty++
+		case 1:
+			centroid, err := bench.CentroidCell(f, sp.System, sp.Resolution)
+			if err != nil {
+				return r, err
+			}
+			if covering[0] == centroid {
+				r.Centroid++
+			} else {
+				r.OtherCell++
+			}
+		default:
+			r.Several++
+		}
+	}
+	if r.Empty != len(measurements[0].Empty) {
+		return r, fmt.Errorf("%s resolution %d: %d empty coverings, but the benchmark counted %d", sp.System, sp.Resolution, r.Empty, len(measurements[0].Empty))
+	}
+	return r, nil
+}
+
+// tinyPolygons draws n regular polygons of the given radius around centers
+// uniform over the sphere, away from the poles where the degree offsets of
+// ellipseRing would stretch
+func tinyPolygons(n int, radiusKm float64, vertices int, seed int64) geojson.FeatureCollection {
+	fc := geojson.FeatureCollection{Type: "FeatureCollection"}
+	for _, ll := range bench.RandomPointsOnSphere(n*2, seed) {
+		lat, lng := ll.Lat.Degrees(), ll.Lng.Degrees()
+		if math.Abs(lat) > 80 || math.Abs(lng) > 179 {
+			continue
+		}
+		ry := radiusKm / 111.0
+		rx := ry / math.Cos(lat*math.Pi/180)
+		fc.Features = append(fc.Features, geojson.Feature{
+			Type:       "Feature",
+			Geometry:   geojson.Geometry{Type: "Polygon", Coordinates: [][][2]float64{ellipseRing(lng, lat, rx, ry, vertices)}},
+			Properties: map[string]interface{}{"id": len(fc.Features) + 1},
+		})
+		if len(fc.Features) == n {
+			break
+		}
+	}
+	return fc
+}
+
+func saveTinyResultsToCSV(filename string, results []TinyResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "Resolution", "Scale", "CellKm2", "Polygons", "Empty", "CentroidCell", "OtherCell", "Several", "CentroidFallback", "AverageDurationNs"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			r.System,
+			strconv.Itoa(r.Resolution),
+			strconv.FormatFloat(r.Scale, 'f', -1, 64),
+			strconv.FormatFloat(r.CellKm2, 'f', -1, 64),
+			strconv.Itoa(r.Polygons),
+			strconv.Itoa(r.Empty),
+			strconv.Itoa(r.Centroid),
+			strconv.Itoa(r.OtherCell),
+			strconv.Itoa(r.Several),
+			strconv.FormatBool(r.Fallback),
+			strconv.FormatFloat(r.MeanNs, 'f', 0, 64),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
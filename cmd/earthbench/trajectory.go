@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+)
+
+// TrajectoryResult is the cost and compression of encoding every
+// trajectory as a cell sequence at one resolution. Cells counts the
+// sequence length after consecutive duplicates are dropped.
+type TrajectoryResult struct {
+	System       string
+	Resolution   int
+	Trajectories int
+	Points       int
+	Cells        int
+	Duration     time.Duration
+}
+
+// PointsPerSecond is the encoding throughput
+func (r TrajectoryResult) PointsPerSecond() float64 {
+	if r.Duration == 0 {
+		return 0
+	}
+	return float64(r.Points) / r.Duration.Seconds()
+}
+
+// CompressionRatio is the number of GPS points per emitted cell
+func (r TrajectoryResult) CompressionRatio() float64 {
+	if r.Cells == 0 {
+		return 0
+	}
+	return float64(r.Points) / float64(r.Cells)
+}
+
+func runTrajectoryCommand(args []string) error {
+	fs := flag.NewFlagSet("trajectory", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "6-10", "10-16")
+	output := fs.String("output", "output/trajectory.csv", "CSV file for the results")
+	count := fs.Int("trajectories", 1000, "number of synthetic trajectories")
+	length := fs.Int("length", 1000, "points per trajectory")
+	interval := fs.Duration("interval", time.Second, "time between GPS fixes")
+	speed := fs.Float64("speed-kmh", 50, "mean vehicle speed")
+	seed := fs.Int64("seed", 1, "seed for the synthetic trajectories")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+
+	stepKm := *speed * interval.Hours()
+	trajectories := syntheticTrajectories(ds.Bounds(), *count, *length, stepKm, *seed)
+	fmt.Printf("Generated %d trajectories of %d points (%.3f km between fixes)\n", *count, *length, stepKm)
+
+	var results []TrajectoryResult
+	for _, sp := range sweepPoints {
+		r, err := benchmarkTrajectoryEncoding(trajectories, sp)
+		if err != nil {
+			return fmt.Errorf("%s resolution %d: %w", sp.System, sp.Resolution, err)
+		}
+		fmt.Printf("%s res %2d: %9d points -> %9d cells (%.2f points/cell) in %v (%.0f points/s)\n",
+			r.System, r.Resolution, r.Points, r.Cells, r.CompressionRatio(), r.Duration, r.PointsPerSecond())
+		results = append(results, r)
+	}
+
+	if err := saveTrajectoryResultsToCSV(*output, results); err != nil {
+		return err
+	}
+	fmt.Printf("Results saved to %s\n", *output)
+	return nil
+}
+
+// syntheticTrajectories simulates vehicles that start at random points in
+// bounds and drive with a slowly drifting heading and jittered speed
+func syntheticTrajectories(bounds s2.Rect, count, length int, stepKm float64, seed int64) [][]s2.LatLng {
+	rng := rand.New(rand.NewSource(seed))
+	starts := bench.RandomPointsInRect(bounds, count, seed)
+	stepRad := stepKm / bench.EarthRadiusKm
+
+	trajectories := make([][]s2.LatLng, count)
+	for t := range trajectories {
+		track := make([]s2.LatLng, length)
+		ll := starts[t]
+		heading := rng.Float64() * 2 * math.Pi
+		for i := range track {
+			track[i] = ll
+			heading += rng.NormFloat64() * 0.1
+			dist := stepRad * math.Max(0, 1+rng.NormFloat64()*0.2)
+			lat := ll.Lat.Radians() + dist*math.Cos(heading)
+			lng := ll.Lng.Radians() + dist*math.Sin(heading)/math.Max(math.Cos(ll.Lat.Radians()), 1e-6)
+			if lat > math.Pi/2 || lat < -math.Pi/2 {
+				// Turn around instead of driving over a pole
+				heading += math.Pi
+				lat = ll.Lat.Radians()
+			}
+			ll = s2.LatLng{Lat: s1.Angle(lat), Lng: s1.Angle(math.Remainder(lng, 2*math.Pi))}
+		}
+		trajectories[t] = track
+	}
+	return trajectories
+}
+
+// encodeTrajectory maps a trajectory to its cell sequence, dropping
+// consecutive repeats of the same cell
+func encodeTrajectory(track []s2.LatLng, system string, resolution int) ([]uint64, error) {
+	var seq []uint64
+	for _, ll := range track {
+		cell, err := bench.PointCell(system, ll, resolution)
+		if err != nil {
+			return nil, err
+		}
+		if len(seq) == 0 || seq[len(seq)-1] != cell {
+			seq = append(seq, cell)
+		}
+	}
+	return seq, nil
+}
+
+func benchmarkTrajectoryEncoding(trajectories [][]s2.LatLng, sp bench.SweepPoint) (TrajectoryResult, error) {
+	result := TrajectoryResult{System: sp.System, Resolution: sp.Resolution, Trajectories: len(trajectories)}
+	start := time.Now()
+	for _, track := range trajectories {
+		seq, err := encodeTrajectory(track, sp.System, sp.Resolution)
+		if err != nil {
+			return result, err
+		}
+		result.Points += len(track)
+		result.Cells += len(seq)
+	}
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+func saveTrajectoryResultsToCSV(filename string, results []TrajectoryResult) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	headers := []string{"System", "Resolution", "Trajectories", "Points", "Cells", "CompressionRatio", "DurationNs", "PointsPerSecond"}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{
+			r.System,
+			strconv.Itoa(r.Resolution),
+			strconv.Itoa(r.Trajectories),
+			strconv.Itoa(r.Points),
+			strconv.Itoa(r.Cells),
+			strconv.FormatFloat(r.CompressionRatio(), 'f', -1, 64),
+			strconv.FormatInt(r.Duration.Nanoseconds(), 10),
+			strconv.FormatFloat(r.PointsPerSecond(), 'f', -1, 64),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.Error()
+}
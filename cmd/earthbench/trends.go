@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+)
+
+// trendSeries is a metric of one sweep point over the x axis of a trends
+// chart, NaN where a run did not measure the point
+type trendSeries struct {
+	Label  string    `json:"label"`
+	Values []float64 `json:"values"`
+}
+
+// trendChart is the data of the trends page
+type trendChart struct {
+	Metric string        `json:"metric"`
+	Labels []string      `json:"labels"`
+	Notes  []string      `json:"notes"` // commit and library versions of each x
+	Series []trendSeries `json:"series"`
+}
+
+func runTrendsCommand(args []string) error {
+	fs := flag.NewFlagSet("trends", flag.ExitOnError)
+	db := fs.String("db", "output/sweeps.db", "SQLite database written by sweep -sinks sqlite:FILE")
+	sqliteBinary := fs.String("sqlite3", "sqlite3", "path to the sqlite3 CLI")
+	metric := fs.String("metric", "average_duration_ns", "measurement to follow: "+strings.Join(bench.TrendMetrics, ", "))
+	system := fs.String("system", "", "only this system (h3 or s2)")
+	resolutions := fs.String("res", "", "only these resolutions, e.g. 5-8 or 7,13")
+	by := fs.String("by", "run", "x axis: run (every stored run) or commit (runs of a commit averaged)")
+	output := fs.String("output", "output/trends.html", "HTML page charting the metric (empty to skip)")
+	csvOutput := fs.String("csv", "", "also write the chart's table to this CSV file")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *by != "run" && *by != "commit" {
+		return fmt.Errorf("-by must be run or commit")
+	}
+
+	points, err := bench.ReadTrends(*sqliteBinary, *db, *metric)
+	if err != nil {
+		return err
+	}
+	if *system != "" {
+		sys, err := parseSystem(*system)
+		if err != nil {
+			return err
+		}
+		points = slices.DeleteFunc(points, func(p bench.TrendPoint) bool { return p.System != sys })
+	}
+	if *resolutions != "" {
+		keep, err := parseIntRange(*resolutions)
+		if err != nil {
+			return fmt.Errorf("-res: %w", err)
+		}
+		points = slices.DeleteFunc(points, func(p bench.TrendPoint) bool { return !slices.Contains(keep, p.Resolution) })
+	}
+	if len(points) == 0 {
+		return fmt.Errorf("no stored measurements in %s match", *db)
+	}
+
+	chart := buildTrendChart(points, *metric, *by)
+	fmt.Printf("%s of %d sweep point(s) over %d %s(s) in %s\n\n", *metric, len(chart.Series), len(chart.Labels), *by, *db)
+	printTrends(chart)
+
+	if *output != "" {
+		if err := writeResultsFile(*output, func(w io.Writer) error { return trendsTemplate.Execute(w, chart) }); err != nil {
+			return err
+		}
+		fmt.Printf("\nChart saved to %s\n", *output)
+	}
+	if *csvOutput != "" {
+		if err := writeResultsFile(*csvOutput, func(w io.Writer) error { return writeTrendsCSV(w, chart) }); err != nil {
+			return err
+		}
+		fmt.Printf("Table saved to %s\n", *csvOutput)
+	}
+	return nil
+}
+
+// buildTrendChart lays the points out by run, or by commit in order of its
+// first run with the values of its runs averaged
+func buildTrendChart(points []bench.TrendPoint, metric, by string) trendChart {
+	chart := trendChart{Metric: metric}
+	xIndex := make(map[string]int)
+	type cell struct{ sum, n float64 }
+	cells := make(map[bench.SweepPoint]map[int]*cell)
+	var order []bench.SweepPoint
+
+	for _, p := range points {
+		key := p.Run
+		if by == "commit" && p.Commit != "" {
+			key = p.Commit
+		}
+		x, ok := xIndex[key]
+		if !ok {
+			x = len(chart.Labels)
+			xIndex[key] = x
+			label := key
+			if by == "run" {
+				if t, err := time.Parse(time.RFC3339, p.Run); err == nil {
+					label = t.Local().Format(time.DateTime)
+				}
+			}
+			chart.Labels = append(chart.Labels, label)
+			chart.Notes = append(chart.Notes, fmt.Sprintf("commit %s, h3-go %s, golang/geo %s",
+				orUnknown(p.Commit), orUnknown(p.H3Version), orUnknown(p.GeoVersion)))
+		}
+		series := bench.SweepPoint{System: p.System, Resolution: p.Resolution}
+		if cells[series] == nil {
+			cells[series] = make(map[int]*cell)
+			order = append(order, series)
+		}
+		if cells[series][x] == nil {
+			cells[series][x] = &cell{}
+		}
+		cells[series][x].sum += p.Value
+		cells[series][x].n++
+	}
+
+	// Series in sweep order: H3 before S2, coarse to fine
+	slices.SortFunc(order, func(a, b bench.SweepPoint) int {
+		if c := strings.Compare(a.System, b.System); c != 0 {
+			return c
+		}
+		return a.Resolution - b.Resolution
+	})
+	for _, sp := range order {
+		label := fmt.Sprintf("H3 res %d", sp.Resolution)
+		if sp.System == bench.SystemS2 {
+			label = fmt.Sprintf("S2 level %d", sp.Resolution)
+		}
+		s := trendSeries{Label: label, Values: make([]float64, len(chart.Labels))}
+		for x := range s.Values {
+			s.Values[x] = math.NaN()
+			if c := cells[sp][x]; c != nil {
+				s.Values[x] = c.sum / c.n
+			}
+		}
+		chart.Series = append(chart.Series, s)
+	}
+	return chart
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
+
+// sparkBlocks draw a series as a line of block characters, lowest to highest
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// printTrends prints every series with its first and last value, the
+// change between them and a sparkline
+func printTrends(chart trendChart) {
+	for _, s := range chart.Series {
+		var present []float64
+		for _, v := range s.Values {
+			if !math.IsNaN(v) {
+				present = append(present, v)
+			}
+		}
+		lo, hi := slices.Min(present), slices.Max(present)
+		spark := make([]rune, len(s.Values))
+		for i, v := range s.Values {
+			switch {
+			case math.IsNaN(v):
+				spark[i] = ' '
+			case hi == lo:
+				spark[i] = sparkBlocks[len(sparkBlocks)/2]
+			default:
+				spark[i] = sparkBlocks[int((v-lo)/(hi-lo)*float64(len(sparkBlocks)-1)+0.5)]
+			}
+		}
+		first, last := present[0], present[len(present)-1]
+		change := "-"
+		if first != 0 {
+			change = fmt.Sprintf("%+.1f%%", 100*(last/first-1))
+		}
+		fmt.Printf("%-11s %14.6g -> %-14.6g %8s  %s\n", s.Label, first, last, change, string(spark))
+	}
+}
+
+// writeTrendsCSV writes one row per x with a column per series
+func writeTrendsCSV(w io.Writer, chart trendChart) error {
+	writer := csv.NewWriter(w)
+
+	headers := []string{"X", "Versions"}
+	for _, s := range chart.Series {
+		headers = append(headers, s.Label)
+	}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for x, label := range chart.Labels {
+		row := []string{label, chart.Notes[x]}
+		for _, s := range chart.Series {
+			if math.IsNaN(s.Values[x]) {
+				row = append(row, "")
+			} else {
+				row = append(row, strconv.FormatFloat(s.Values[x], 'f', -1, 64))
+			}
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// trendsTemplate charts every series over the runs or commits, with the
+// versions of each in the tooltip. nullable turns the NaN of missing values,
+// which JSON cannot carry, into null gaps.
+var trendsTemplate = template.Must(template.New("trends").Funcs(template.FuncMap{
+	"nullable": func(values []float64) []*float64 {
+		out := make([]*float64, len(values))
+		for i := range values {
+			if !math.IsNaN(values[i]) {
+				out[i] = &values[i]
+			}
+		}
+		return out
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>earth-discretization-benchmark trends: {{.Metric}}</title>
+<script src="https://cdn.jsdelivr.net/npm/chart.js@4"></script>
+<style>
+  body { font-family: sans-serif; margin: 16px; }
+</style>
+</head>
+<body>
+<h3>{{.Metric}} over time</h3>
+<label><input type="checkbox" id="logy" checked> log scale</label>
+<canvas id="chart"></canvas>
+<script>
+const labels = {{.Labels}};
+const notes = {{.Notes}};
+const series = [{{range .Series}}{ label: {{.Label}}, data: {{nullable .Values}} },{{end}}];
+let chart = null;
+function plot() {
+  if (chart) chart.destroy();
+  chart = new Chart(document.getElementById('chart'), {
+    type: 'line',
+    data: { labels, datasets: series.map(s => ({ label: s.label, data: s.data, spanGaps: true })) },
+    options: {
+      scales: { y: { type: document.getElementById('logy').checked ? 'logarithmic' : 'linear', title: { display: true, text: {{.Metric}} } } },
+      plugins: { tooltip: { callbacks: { footer: items => notes[items[0].dataIndex] } } }
+    }
+  });
+}
+document.getElementById('logy').addEventListener('change', plot);
+plot();
+</script>
+</body>
+</html>
+`))
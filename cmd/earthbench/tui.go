@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+)
+
+// liveTableRows is the most result rows the live view draws; sorting by a
+// column brings the interesting ones into view
+const liveTableRows = 30
+
+// liveColumns are the sortable columns of the live view's results table,
+// selected by their key
+var liveColumns = []struct {
+	key   byte
+	title string
+	value func(r *liveRow) float64
+}{
+	{'r', "resolution", nil},
+	{'c', "cells", func(r *liveRow) float64 { return float64(r.cells) }},
+	{'n', "ns/feature", func(r *liveRow) float64 { return r.mean(r.nsPerFeature) }},
+	{'a', "ns/km²", func(r *liveRow) float64 { return r.mean(r.nsPerKm2) }},
+	{'t', "cells/s", func(r *liveRow) float64 { return r.mean(r.cellsPerSecond) }},
+	{'s', "skipped", func(r *liveRow) float64 { return float64(r.skipped) }},
+}
+
+// liveRow accumulates the repetitions of one sweep point
+type liveRow struct {
+	point          bench.SweepPoint
+	order          int
+	runs           int
+	cells          int
+	nsPerFeature   float64
+	nsPerKm2       float64
+	cellsPerSecond float64
+	skipped        int
+}
+
+// mean is the running average of a sum over the row's repetitions
+func (r *liveRow) mean(sum float64) float64 {
+	if r.runs == 0 {
+		return 0
+	}
+	return sum / float64(r.runs)
+}
+
+// liveKey identifies a sweep point being measured
+type liveKey struct {
+	point      bench.SweepPoint
+	repetition int
+}
+
+// liveView redraws a terminal screen with the progress of the sweep points
+// being measured and a sortable table of the ones done, in place of the
+// table sink's scrolling rows. It is a ResultSink and takes the runner's
+// progress callbacks; Close draws the final table and stops redrawing.
+type liveView struct {
+	mu         sync.Mutex
+	w          io.Writer
+	dataset    string
+	start      time.Time
+	jobs       int // sweep points x repetitions, 0 when a budget decides
+	completed  int
+	active     map[liveKey]bench.Progress
+	rows       map[bench.SweepPoint]*liveRow
+	sortColumn int
+	descending bool
+	lines      int // lines drawn by the last redraw
+	stop       chan struct{}
+	stopped    chan struct{}
+}
+
+// isTerminal reports whether f is a terminal rather than a file or pipe
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// newLiveView starts redrawing a live view of the dataset's run on w every
+// 200ms. Keys typed on a terminal stdin (followed by Enter) sort the table.
+func newLiveView(w io.Writer, dataset string, jobs int) *liveView {
+	v := &liveView{
+		w:       w,
+		dataset: dataset,
+		start:   time.Now(),
+		jobs:    jobs,
+		active:  make(map[liveKey]bench.Progress),
+		rows:    make(map[bench.SweepPoint]*liveRow),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if isTerminal(os.Stdin) {
+		go v.readKeys(os.Stdin)
+	}
+	go func() {
+		defer close(v.stopped)
+		ticker := time.NewTicker(200 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			v.mu.Lock()
+			v.draw(true)
+			v.mu.Unlock()
+			select {
+			case <-v.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return v
+}
+
+// readKeys sorts the table by the column of each key read; the terminal
+// echoes the line typed, which the next redraw covers up
+func (v *liveView) readKeys(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		v.mu.Lock()
+		v.lines++
+		for _, key := range []byte(strings.ToLower(scanner.Text())) {
+			for i, column := range liveColumns {
+				if column.key != key {
+					continue
+				}
+				if v.sortColumn == i {
+					v.descending = !v.descending
+				} else {
+					v.sortColumn, v.descending = i, i != 0
+				}
+			}
+		}
+		v.draw(true)
+		v.mu.Unlock()
+	}
+}
+
+// Progress records how far a sweep point has got
+func (v *liveView) Progress(p bench.Progress) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	key := liveKey{point: p.SweepPoint, repetition: p.Repetition}
+	if p.Done == p.Total {
+		delete(v.active, key)
+		return
+	}
+	v.active[key] = p
+}
+
+// WriteMeasurement adds a completed measurement to its sweep point's row
+func (v *liveView) WriteMeasurement(m bench.CoveringMeasurement) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	point := bench.SweepPoint{System: m.System, Resolution: m.Resolution}
+	row, ok := v.rows[point]
+	if !ok {
+		row = &liveRow{point: point, order: len(v.rows)}
+		v.rows[point] = row
+	}
+	row.runs++
+	row.cells = m.Cells
+	row.nsPerFeature += m.AverageDurationNs()
+	row.nsPerKm2 += m.NsPerKm2()
+	row.cellsPerSecond += m.CellsPerSecond()
+	row.skipped += len(m.TimedOut) + len(m.OverCap) + len(m.Violations)
+	v.completed++
+	return nil
+}
+
+// Close stops redrawing and leaves the final table on screen
+func (v *liveView) Close() error {
+	select {
+	case <-v.stop:
+		return nil
+	default:
+	}
+	close(v.stop)
+	<-v.stopped
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.draw(false)
+	return nil
+}
+
+// draw replaces the previous frame with the current state; the key help is
+// left out of the final frame
+func (v *liveView) draw(live bool) {
+	var b strings.Builder
+	if v.lines > 0 {
+		fmt.Fprintf(&b, "\x1b[%dF", v.lines)
+	}
+	lines := v.frame(live)
+	for _, line := range lines {
+		b.WriteString(line)
+		b.WriteString("\x1b[K\n")
+	}
+	b.WriteString("\x1b[J")
+	io.WriteString(v.w, b.String())
+	v.lines = len(lines)
+}
+
+// frame lays out the lines of one redraw
+func (v *liveView) frame(live bool) []string {
+	progress := fmt.Sprintf("%d sweep points measured", v.completed)
+	if v.jobs > 0 {
+		progress = fmt.Sprintf("%d/%d sweep points measured", v.completed, v.jobs)
+	}
+	lines := []string{
+		fmt.Sprintf("%s  elapsed %v  %s", v.dataset, time.Since(v.start).Round(100*time.Millisecond), progress),
+		"",
+	}
+
+	if live {
+		keys := slices.Collect(maps.Keys(v.active))
+		slices.SortFunc(keys, func(a, b liveKey) int {
+			return cmp.Or(cmp.Compare(a.repetition, b.repetition), cmp.Compare(a.point.System, b.point.System),
+				cmp.Compare(a.point.Resolution, b.point.Resolution))
+		})
+		for _, key := range keys {
+			p := v.active[key]
+			filled := 0
+			if p.Total > 0 {
+				filled = 20 * p.Done / p.Total
+			}
+			lines = append(lines, fmt.Sprintf("%-3s res %2d run %-3d [%s%s] %7d/%-7d %10d cells  %12s/feature",
+				p.System, p.Resolution, p.Repetition+1, strings.Repeat("#", filled), strings.Repeat(".", 20-filled),
+				p.Done, p.Total, p.Cells, time.Duration(p.MeanNs).Round(time.Microsecond)))
+		}
+		if len(keys) > 0 {
+			lines = append(lines, "")
+		}
+	}
+
+	rows := slices.Collect(maps.Values(v.rows))
+	column := liveColumns[v.sortColumn]
+	slices.SortFunc(rows, func(a, b *liveRow) int {
+		c := cmp.Compare(a.order, b.order)
+		if column.value != nil {
+			c = cmp.Or(cmp.Compare(column.value(a), column.value(b)), c)
+		}
+		if v.descending {
+			return -c
+		}
+		return c
+	})
+	order := "ascending"
+	if v.descending {
+		order = "descending"
+	}
+	lines = append(lines, fmt.Sprintf("%-6s %4s %4s %12s %14s %12s %14s %8s   sorted by %s, %s",
+		"SYSTEM", "RES", "RUNS", "CELLS", "NS/FEATURE", "NS/KM2", "CELLS/S", "SKIPPED", column.title, order))
+	for i, row := range rows {
+		if i == liveTableRows {
+			lines = append(lines, fmt.Sprintf("... %d more", len(rows)-liveTableRows))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("%-6s %4d %4d %12d %14.0f %12.1f %14.0f %8d",
+			row.point.System, row.point.Resolution, row.runs, row.cells,
+			row.mean(row.nsPerFeature), row.mean(row.nsPerKm2), row.mean(row.cellsPerSecond), row.skipped))
+	}
+	if live && isTerminal(os.Stdin) {
+		lines = append(lines, "", "sort: r resolution  c cells  n ns/feature  a ns/km²  t cells/s  s skipped (again to reverse), then Enter")
+	}
+	return lines
+}
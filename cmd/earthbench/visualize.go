@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/nkk36/earth-discretization-benchmark/bench"
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+)
+
+func runVisualizeCommand(args []string) error {
+	fs := flag.NewFlagSet("visualize", flag.ExitOnError)
+	sweep := addSweepFlags(fs, "5", "9")
+	output := fs.String("output", "output/coverings.geojson", "GeoJSON file to write")
+	features := fs.String("features", "", "feature IDs to include, e.g. 1-10 or 3,7 (default all)")
+	source := fs.Bool("source", true, "include the source polygons alongside the cells")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sweepPoints, err := sweep.Points()
+	if err != nil {
+		return err
+	}
+	ds, err := sweep.LoadDataset()
+	if err != nil {
+		return err
+	}
+	if *features != "" {
+		ids, err := parseIntRange(*features)
+		if err != nil {
+			return fmt.Errorf("-features: %w", err)
+		}
+		ds = ds.Subset(ids)
+	}
+
+	fc, err := coveringFeatureCollection(ds, sweepPoints, *sweep.MaxCells, *source)
+	if err != nil {
+		return err
+	}
+	if err := writeGeoJSON(*output, fc); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d features to %s\n", len(fc.Features), *output)
+	return nil
+}
+
+// coveringFeatureCollection turns every covering cell into a Polygon feature
+// tagged with system, resolution, feature_id and cell so coverings can be
+// styled and filtered in geojson.io or kepler.gl
+func coveringFeatureCollection(ds *bench.Dataset, sweepPoints []bench.SweepPoint, maxCells int, includeSource bool) (geojson.FeatureCollection, error) {
+	fc := geojson.FeatureCollection{Type: "FeatureCollection"}
+	if includeSource {
+		for _, f := range ds.Features {
+			fc.Features = append(fc.Features, geojson.Feature{
+				Type:       "Feature",
+				Geometry:   f.Geometry,
+				Properties: map[string]interface{}{"kind": "source", "feature_id": f.FeatureID},
+			})
+		}
+	}
+
+	for _, sp := range sweepPoints {
+		coverings, err := bench.ComputeCoverings(ds, sp.System, sp.Resolution, maxCells)
+		if err != nil {
+			return fc, err
+		}
+		for i, f := range ds.Features {
+			for _, cell := range coverings[i] {
+				ring, err := cellBoundary(sp.System, cell)
+				if err != nil {
+					return fc, err
+				}
+				fc.Features = append(fc.Features, geojson.Feature{
+					Type:     "Feature",
+					Geometry: geojson.Geometry{Type: "Polygon", Coordinates: [][][2]float64{ring}},
+					Properties: map[string]interface{}{
+						"kind":       "cell",
+						"system":     sp.System,
+						"resolution": sp.Resolution,
+						"feature_id": f.FeatureID,
+						"cell":       cellToken(sp.System, cell),
+					},
+				})
+			}
+		}
+	}
+	return fc, nil
+}
+
+// writeGeoJSON writes a FeatureCollection to disk
+func writeGeoJSON(filename string, fc geojson.FeatureCollection) error {
+	data, err := json.Marshal(fc)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0o644)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+)
+
+// parseWKTPolygon parses a WKT POLYGON (optionally with holes) into a GeoJSON
+// Polygon geometry. Coordinates are "lon lat" as in WKT/EPSG:4326 output of
+// most GIS tools; Z and M values are dropped.
+func parseWKTPolygon(wkt string) (geojson.Geometry, error) {
+	s := strings.TrimSpace(wkt)
+	upper := strings.ToUpper(s)
+	if !strings.HasPrefix(upper, "POLYGON") {
+		return geojson.Geometry{}, fmt.Errorf("only WKT POLYGON is supported, got %q", firstWord(s))
+	}
+	body := strings.TrimSpace(s[len("POLYGON"):])
+	// Skip a dimension tag such as "Z" or "ZM"
+	for len(body) > 0 && body[0] != '(' {
+		body = strings.TrimSpace(body[1:])
+	}
+	if !strings.HasPrefix(body, "(") || !strings.HasSuffix(body, ")") {
+		return geojson.Geometry{}, fmt.Errorf("malformed WKT polygon")
+	}
+	body = strings.TrimSpace(body[1 : len(body)-1])
+
+	geometry := geojson.Geometry{Type: "Polygon"}
+	for len(body) > 0 {
+		if body[0] != '(' {
+			return geojson.Geometry{}, fmt.Errorf("malformed WKT ring near %q", body)
+		}
+		end := strings.IndexByte(body, ')')
+		if end < 0 {
+			return geojson.Geometry{}, fmt.Errorf("unterminated WKT ring")
+		}
+		ring, err := parseWKTRing(body[1:end])
+		if err != nil {
+			return geojson.Geometry{}, err
+		}
+		geometry.Coordinates = append(geometry.Coordinates, ring)
+		body = strings.TrimSpace(body[end+1:])
+		body = strings.TrimSpace(strings.TrimPrefix(body, ","))
+	}
+	if len(geometry.Coordinates) == 0 {
+		return geojson.Geometry{}, fmt.Errorf("WKT polygon has no rings")
+	}
+	return geometry, nil
+}
+
+func parseWKTRing(s string) ([][2]float64, error) {
+	var ring [][2]float64
+	for _, point := range strings.Split(s, ",") {
+		fields := strings.Fields(point)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("malformed WKT point %q", strings.TrimSpace(point))
+		}
+		lon, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed WKT point %q: %w", strings.TrimSpace(point), err)
+		}
+		lat, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed WKT point %q: %w", strings.TrimSpace(point), err)
+		}
+		ring = append(ring, [2]float64{lon, lat})
+	}
+	return ring, nil
+}
+
+func firstWord(s string) string {
+	if i := strings.IndexAny(s, " ("); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
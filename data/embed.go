@@ -0,0 +1,12 @@
+// Package data embeds the sample dataset, so the benchmark runs with no
+// files beside the binary.
+package data
+
+import _ "embed"
+
+// SamplePolygons is sample_polygons.geojson: 40 features of
+// mock_polygons.geojson, ten from each area quartile, as drawn by
+// -sample-per-bucket 10 -sample-seed 1
+//
+//go:embed sample_polygons.geojson
+var SamplePolygons []byte
@@ -0,0 +1,126 @@
+// Package h3 converts GeoJSON polygons to H3 GeoPolygons and fills them
+// with H3 cells.
+package h3
+
+import (
+	"fmt"
+
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+	"github.com/uber/h3-go/v4"
+)
+
+// MaxResolution is the finest H3 resolution
+const MaxResolution = 15
+
+// FromGeometry converts a GeoJSON Polygon geometry to an H3 GeoPolygon,
+// passing holes it cannot use to skip
+func FromGeometry(geometry geojson.Geometry, skip geojson.SkipRingFunc) (h3.GeoPolygon, error) {
+	if geometry.Type != "Polygon" {
+		return h3.GeoPolygon{}, fmt.Errorf("expected Polygon geometry, got %s", geometry.Type)
+	}
+
+	if err := geometry.Err(); err != nil {
+		return h3.GeoPolygon{}, err
+	}
+	if len(geometry.Coordinates) == 0 {
+		return h3.GeoPolygon{}, fmt.Errorf("polygon has no coordinates")
+	}
+
+	// GeoJSON Polygon coordinates are organized as:
+	// [[exterior ring], [hole1], [hole2], ...]
+	// First ring is the exterior boundary, rest are holes
+
+	// Convert exterior ring (first ring)
+	if err := geojson.CheckRing(geometry.Coordinates[0]); err != nil {
+		return h3.GeoPolygon{}, fmt.Errorf("exterior ring %w", err)
+	}
+	exterior := LoopFromRing(geometry.Coordinates[0])
+
+	// Convert holes (if any)
+	var holes []h3.GeoLoop
+	if len(geometry.Coordinates) > 1 {
+		for holeIndex, holeRing := range geometry.Coordinates[1:] {
+			if len(holeRing) < 4 {
+				if err := skip(holeIndex+1, "has fewer than 4 points"); err != nil {
+					return h3.GeoPolygon{}, err
+				}
+				continue
+			}
+			if err := geojson.CheckRing(holeRing); err != nil {
+				if err := skip(holeIndex+1, err.Error()); err != nil {
+					return h3.GeoPolygon{}, err
+				}
+				continue
+			}
+			hole := LoopFromRing(holeRing)
+			holes = append(holes, hole)
+		}
+	}
+
+	// Create H3 GeoPolygon
+	h3Polygon := h3.GeoPolygon{
+		GeoLoop: exterior,
+		Holes:   holes,
+	}
+
+	return h3Polygon, nil
+}
+
+// LoopFromRing converts a GeoJSON ring to an H3 GeoLoop
+func LoopFromRing(ring [][2]float64) h3.GeoLoop {
+	var geoLoop h3.GeoLoop
+
+	// Convert each [lon, lat] pair to H3 LatLng
+	// GeoJSON uses [longitude, latitude] order
+	for _, coord := range ring {
+		geoLoc := h3.LatLng{
+			Lat: coord[1], // latitude
+			Lng: coord[0], // longitude
+		}
+		geoLoop = append(geoLoop, geoLoc)
+	}
+
+	return geoLoop
+}
+
+// Cover fills a polygon with H3 cells and returns their indexes
+func Cover(polygon h3.GeoPolygon, resolution int) ([]uint64, error) {
+	cells, err := h3.PolygonToCells(polygon, resolution)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint64, len(cells))
+	for i, c := range cells {
+		ids[i] = uint64(c)
+	}
+	return ids, nil
+}
+
+// Containment modes by name, for flags and config files. "center" is the
+// contract of PolygonToCells.
+var Containment = map[string]h3.ContainmentMode{
+	"center":           h3.ContainmentCenter,
+	"full":             h3.ContainmentFull,
+	"overlapping":      h3.ContainmentOverlapping,
+	"overlapping-bbox": h3.ContainmentOverlappingBbox,
+}
+
+// CoverContainment fills a polygon with the H3 cells selected by a
+// containment mode through PolygonToCellsExperimental
+func CoverContainment(polygon h3.GeoPolygon, resolution int, mode h3.ContainmentMode) ([]uint64, error) {
+	cells, err := h3.PolygonToCellsExperimental(polygon, resolution, mode)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint64, len(cells))
+	for i, c := range cells {
+		ids[i] = uint64(c)
+	}
+	return ids, nil
+}
+
+// PointCell returns the H3 cell containing a point at a resolution
+func PointCell(lat, lng float64, resolution int) (uint64, error) {
+	cell, err := h3.LatLngToCell(h3.NewLatLng(lat, lng), resolution)
+	return uint64(cell), err
+}
@@ -0,0 +1,44 @@
+package h3
+
+import (
+	"testing"
+
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+	"github.com/nkk36/earth-discretization-benchmark/geojson/geojsontest"
+	"pgregory.net/rapid"
+)
+
+func TestLoopFromRingKeepsPositions(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		position := rapid.Custom(func(t *rapid.T) [2]float64 {
+			return [2]float64{rapid.Float64Range(-180, 180).Draw(t, "lng"), rapid.Float64Range(-90, 90).Draw(t, "lat")}
+		})
+		ring := rapid.SliceOfN(position, 0, 64).Draw(t, "ring")
+		loop := LoopFromRing(ring)
+		if len(loop) != len(ring) {
+			t.Fatalf("%d positions became %d", len(ring), len(loop))
+		}
+		for i, p := range ring {
+			if loop[i].Lat != p[1] || loop[i].Lng != p[0] {
+				t.Fatalf("position %d %v became lat %g lng %g", i, p, loop[i].Lat, loop[i].Lng)
+			}
+		}
+	})
+}
+
+// FuzzFromGeometry converts the polygons geojsontest.Polygons makes of a
+// fuzzed document and covers what converts: either step may reject the
+// input but neither may panic
+func FuzzFromGeometry(f *testing.F) {
+	geojsontest.AddPolygonSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte, lng, lat float64) {
+		for _, geometry := range geojsontest.Polygons(data, lng, lat) {
+			polygon, err := FromGeometry(geometry, geojson.IgnoreSkippedRing)
+			if err != nil {
+				continue
+			}
+			// Rejecting the polygon is fine, panicking is not
+			_, _ = Cover(polygon, 2)
+		}
+	})
+}
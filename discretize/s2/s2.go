@@ -0,0 +1,218 @@
+// Package s2 converts GeoJSON polygons to S2 polygons and covers them with
+// S2 cells.
+package s2
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+)
+
+// MaxLevel is the finest S2 level
+const MaxLevel = s2.MaxLevel
+
+// FromGeometry converts a GeoJSON Polygon geometry to an S2 polygon,
+// passing holes it cannot use to skip. PolygonFromLoops works out which
+// loops are holes from how they nest and wants every loop normalized rather
+// than holes reversed, which LoopFromRing ensures whatever the winding of
+// the GeoJSON rings.
+func FromGeometry(geometry geojson.Geometry, skip geojson.SkipRingFunc) (*s2.Polygon, error) {
+	loops, err := LoopsFromGeometry(geometry, skip)
+	if err != nil {
+		return nil, err
+	}
+	polygon := s2.PolygonFromLoops(loops)
+	if err := polygon.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid polygon: %w", err)
+	}
+	return polygon, nil
+}
+
+// LoopsFromGeometry converts the rings of a GeoJSON Polygon geometry to S2
+// loops, exterior first. An exterior ring that is not a valid loop (see
+// ValidateLoop) fails the conversion; holes that are not valid, not inside
+// the exterior or overlap an earlier hole are passed to skip.
+func LoopsFromGeometry(geometry geojson.Geometry, skip geojson.SkipRingFunc) ([]*s2.Loop, error) {
+	if geometry.Type != "Polygon" {
+		return nil, fmt.Errorf("expected Polygon geometry, got %s", geometry.Type)
+	}
+
+	if err := geometry.Err(); err != nil {
+		return nil, err
+	}
+	if len(geometry.Coordinates) == 0 {
+		return nil, fmt.Errorf("polygon has no coordinates")
+	}
+
+	// Convert exterior ring to S2 loop
+	if err := geojson.CheckRing(geometry.Coordinates[0]); err != nil {
+		return nil, fmt.Errorf("exterior ring %w", err)
+	}
+	exteriorLoop := LoopFromRing(geometry.Coordinates[0])
+	if exteriorLoop == nil {
+		return nil, fmt.Errorf("failed to create exterior loop")
+	}
+	if err := ValidateLoop(exteriorLoop); err != nil {
+		return nil, fmt.Errorf("exterior ring is not a valid loop: %w", err)
+	}
+
+	// Build list of all loops (exterior + holes)
+	loops := []*s2.Loop{exteriorLoop}
+
+	// Add holes as additional loops
+	if len(geometry.Coordinates) > 1 {
+		for holeIndex, holeRing := range geometry.Coordinates[1:] {
+			if len(holeRing) < 4 {
+				if err := skip(holeIndex+1, "has fewer than 4 points"); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if err := geojson.CheckRing(holeRing); err != nil {
+				if err := skip(holeIndex+1, err.Error()); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			holeLoop := LoopFromRing(holeRing)
+			if holeLoop == nil {
+				if err := skip(holeIndex+1, "could not be converted to a loop"); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if err := ValidateLoop(holeLoop); err != nil {
+				if err := skip(holeIndex+1, "is not a valid loop: "+err.Error()); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if !exteriorLoop.Contains(holeLoop) {
+				if err := skip(holeIndex+1, "is not inside the exterior ring"); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if slices.ContainsFunc(loops[1:], holeLoop.Intersects) {
+				if err := skip(holeIndex+1, "overlaps an earlier hole"); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			loops = append(loops, holeLoop)
+		}
+	}
+
+	return loops, nil
+}
+
+// ValidateLoop checks a loop with Loop.Validate and, which golang/geo does
+// not do yet, for edges crossing each other and vertices visited twice. S2
+// covers a self-intersecting loop without complaint, but its inside is not
+// what the ring drew and H3 fills a different shape for it.
+func ValidateLoop(loop *s2.Loop) error {
+	if err := loop.Validate(); err != nil {
+		return err
+	}
+	seen := make(map[s2.Point]int, loop.NumVertices())
+	for i, v := range loop.Vertices() {
+		if j, ok := seen[v]; ok {
+			return fmt.Errorf("vertex %d repeats vertex %d", i, j)
+		}
+		seen[v] = i
+	}
+
+	index := s2.NewShapeIndex()
+	index.Add(loop)
+	query := s2.NewCrossingEdgeQuery(index)
+	for i := 0; i < loop.NumEdges(); i++ {
+		edge := loop.Edge(i)
+		for _, j := range query.Crossings(edge.V0, edge.V1, loop, s2.CrossingTypeInterior) {
+			if j != i {
+				return fmt.Errorf("edges %d and %d cross", min(i, j), max(i, j))
+			}
+		}
+	}
+	return nil
+}
+
+// LoopFromRing converts a GeoJSON ring to an S2 Loop. The closing position
+// is dropped only when it repeats the first, and the loop is normalized so
+// it encloses the smaller side whatever the ring's winding: S2 keeps the
+// region to the left of a loop, so a clockwise ring (such as an RFC 7946
+// hole) would otherwise describe everything outside it.
+func LoopFromRing(ring [][2]float64) *s2.Loop {
+	if len(ring) > 1 && ring[0] == ring[len(ring)-1] {
+		ring = ring[:len(ring)-1]
+	}
+	if len(ring) < 3 {
+		return nil
+	}
+
+	// Convert each [lon, lat] pair to S2 Point
+	// GeoJSON uses [longitude, latitude] order
+	points := make([]s2.Point, 0, len(ring))
+	for _, coord := range ring {
+		latLng := s2.LatLngFromDegrees(coord[1], coord[0])
+		points = append(points, s2.PointFromLatLng(latLng))
+	}
+
+	loop := s2.LoopFromPoints(points)
+	loop.Normalize()
+	return loop
+}
+
+// Cover covers a polygon with S2 cells fixed at a single level, the
+// coverer setup of the original level sweep
+func Cover(polygon *s2.Polygon, level int, maxCells int) []uint64 {
+	return CoverLevels(polygon, level, level, maxCells)
+}
+
+// CoverLevels covers a polygon with S2 cells from minLevel to maxLevel,
+// leaving the coverer to spend maxCells on a mix of coarse and fine cells
+func CoverLevels(polygon *s2.Polygon, minLevel, maxLevel int, maxCells int) []uint64 {
+	rc := &s2.RegionCoverer{
+		MinLevel: minLevel,
+		MaxLevel: maxLevel,
+		MaxCells: maxCells,
+		LevelMod: 1,
+	}
+	covering := rc.Covering(polygon)
+	ids := make([]uint64, len(covering))
+	for i, c := range covering {
+		ids[i] = uint64(c)
+	}
+	return ids
+}
+
+// Denormalize expands a covering to cells of a single level, for counting
+// cells the way a single-resolution H3 fill does: every coarser cell is
+// replaced by its descendants at level and every finer one by its ancestor
+// there, without duplicates
+func Denormalize(cells []uint64, level int) []uint64 {
+	union := make(s2.CellUnion, len(cells))
+	for i, c := range cells {
+		id := s2.CellID(c)
+		if id.Level() > level {
+			id = id.Parent(level)
+		}
+		union[i] = id
+	}
+	union.Denormalize(level, 1)
+	slices.Sort(union) // a coarse cell may have held one of the ancestors
+	union = slices.Compact(union)
+	ids := make([]uint64, len(union))
+	for i, c := range union {
+		ids[i] = uint64(c)
+	}
+	return ids
+}
+
+// PointCell returns the S2 cell containing a point at a level
+func PointCell(ll s2.LatLng, level int) uint64 {
+	return uint64(s2.CellIDFromLatLng(ll).Parent(level))
+}
@@ -0,0 +1,121 @@
+package s2
+
+import (
+	"math"
+	"testing"
+
+	"github.com/golang/geo/s2"
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+	"github.com/nkk36/earth-discretization-benchmark/geojson/geojsontest"
+	"pgregory.net/rapid"
+)
+
+// ringCase is a generated simple ring: a star-shaped polygon around center,
+// counter-clockwise unless reversed, closed with a repeat of the first
+// position unless open
+type ringCase struct {
+	ring     [][2]float64
+	center   [2]float64
+	vertices int // distinct positions
+	reversed bool
+	open     bool
+}
+
+// ringGen draws star-shaped rings: sorted angles around a center with
+// jittered radii, so they never self-intersect. Angle jitter is kept small
+// enough that no gap reaches 180 degrees and the center stays inside.
+var ringGen = rapid.Custom(func(t *rapid.T) ringCase {
+	c := ringCase{
+		center:   [2]float64{rapid.Float64Range(-179, 179).Draw(t, "lng"), rapid.Float64Range(-80, 80).Draw(t, "lat")},
+		vertices: rapid.IntRange(3, 64).Draw(t, "vertices"),
+		reversed: rapid.Bool().Draw(t, "reversed"),
+		open:     rapid.Bool().Draw(t, "open"),
+	}
+	radius := math.Pow(10, rapid.Float64Range(-2, 1).Draw(t, "log radius")) // 0.01 to 10 degrees
+	for i := range c.vertices {
+		a := (float64(i) + rapid.Float64Range(0, 0.4).Draw(t, "angle jitter")) * 2 * math.Pi / float64(c.vertices)
+		r := radius * rapid.Float64Range(0.5, 1).Draw(t, "radius jitter")
+		c.ring = append(c.ring, [2]float64{c.center[0] + r*math.Cos(a), c.center[1] + r*math.Sin(a)})
+	}
+	if c.reversed {
+		for i, j := 0, len(c.ring)-1; i < j; i, j = i+1, j-1 {
+			c.ring[i], c.ring[j] = c.ring[j], c.ring[i]
+		}
+	}
+	if !c.open {
+		c.ring = append(c.ring, c.ring[0])
+	}
+	return c
+})
+
+func TestLoopFromRingVertices(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		c := ringGen.Draw(t, "ring")
+		loop := LoopFromRing(c.ring)
+		if loop == nil {
+			t.Fatal("no loop")
+		}
+		if loop.NumVertices() != c.vertices {
+			t.Fatalf("%d distinct positions became %d vertices", c.vertices, loop.NumVertices())
+		}
+		for _, p := range c.ring {
+			if !loopHasVertex(loop, s2.PointFromLatLng(s2.LatLngFromDegrees(p[1], p[0]))) {
+				t.Fatalf("position %v is not a vertex", p)
+			}
+		}
+	})
+}
+
+func TestLoopFromRingInterior(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		c := ringGen.Draw(t, "ring")
+		loop := LoopFromRing(c.ring)
+		if loop == nil {
+			t.Fatal("no loop")
+		}
+		if loop.Area() > 2*math.Pi {
+			t.Fatalf("loop covers %.3g sr, more than a hemisphere", loop.Area())
+		}
+		if !loop.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(c.center[1], c.center[0]))) {
+			t.Fatal("loop does not contain the ring center")
+		}
+	})
+}
+
+func TestLoopFromRingValid(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		c := ringGen.Draw(t, "ring")
+		loop := LoopFromRing(c.ring)
+		if loop == nil {
+			t.Fatal("no loop")
+		}
+		if err := loop.Validate(); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func loopHasVertex(loop *s2.Loop, p s2.Point) bool {
+	for i := 0; i < loop.NumVertices(); i++ {
+		if loop.Vertex(i).ApproxEqual(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// FuzzFromGeometry converts the polygons geojsontest.Polygons makes of a
+// fuzzed document and covers what converts: either step may reject the
+// input but neither may panic
+func FuzzFromGeometry(f *testing.F) {
+	geojsontest.AddPolygonSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte, lng, lat float64) {
+		for _, geometry := range geojsontest.Polygons(data, lng, lat) {
+			polygon, err := FromGeometry(geometry, geojson.IgnoreSkippedRing)
+			if err != nil {
+				continue
+			}
+			Cover(polygon, 6, 8)
+		}
+	})
+}
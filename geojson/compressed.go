@@ -0,0 +1,83 @@
+package geojson
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// ReadData reads a GeoJSON file, decompressing it when it is gzipped (as
+// .geojson.gz files are) or a zip archive holding a single .geojson or
+// .json file, the way boundary datasets are usually distributed. The format
+// is detected from the file's first bytes, not its name. A path of "-"
+// reads standard input, so compressed input can be piped in as well.
+func ReadData(filePath string) ([]byte, error) {
+	var data []byte
+	var err error
+	if filePath == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(filePath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	switch {
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("error reading gzip file %s: %w", filePath, err)
+		}
+		defer gz.Close()
+		if data, err = io.ReadAll(gz); err != nil {
+			return nil, fmt.Errorf("error reading gzip file %s: %w", filePath, err)
+		}
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		if data, err = readZippedGeoJSON(data); err != nil {
+			return nil, fmt.Errorf("error reading zip file %s: %w", filePath, err)
+		}
+	}
+	return data, nil
+}
+
+// readZippedGeoJSON extracts the only GeoJSON file of a zip archive
+func readZippedGeoJSON(data []byte) ([]byte, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	var candidates []*zip.File
+	for _, file := range archive.File {
+		// Archives made on macOS carry resource forks under __MACOSX/
+		if file.FileInfo().IsDir() || strings.HasPrefix(file.Name, "__MACOSX/") {
+			continue
+		}
+		switch strings.ToLower(path.Ext(file.Name)) {
+		case ".geojson", ".json":
+			candidates = append(candidates, file)
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("no .geojson or .json file in the archive")
+	case 1:
+	default:
+		names := make([]string, len(candidates))
+		for i, file := range candidates {
+			names[i] = file.Name
+		}
+		return nil, fmt.Errorf("%d GeoJSON files in the archive (%s); extract the one to benchmark", len(candidates), strings.Join(names, ", "))
+	}
+
+	file, err := candidates[0].Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
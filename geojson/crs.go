@@ -0,0 +1,149 @@
+package geojson
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// CRS is the crs member of GeoJSON from before RFC 7946, which dropped it
+// in favour of always using WGS84. Files exported by older GIS tools still
+// carry it, e.g. {"type": "name", "properties": {"name": "EPSG:3857"}}.
+type CRS struct {
+	Type       string `json:"type"`
+	Properties struct {
+		Name string `json:"name"`
+	} `json:"properties"`
+}
+
+// CRSName returns the name in the collection's crs member, or "" when it
+// has none (and so, per RFC 7946, is WGS84)
+func (fc FeatureCollection) CRSName() string {
+	if fc.CRS == nil || fc.CRS.Type != "name" {
+		return ""
+	}
+	return fc.CRS.Properties.Name
+}
+
+// Unprojection converts a position of a coordinate reference system to
+// WGS84 longitude and latitude in degrees
+type Unprojection func(x, y float64) (lng, lat float64)
+
+// WGS84 ellipsoid
+const (
+	wgs84A = 6378137.0
+	wgs84F = 1 / 298.257223563
+)
+
+// LookupCRS returns the unprojection of a coordinate reference system, named
+// as "EPSG:3857", "urn:ogc:def:crs:EPSG::3857", "3857" or
+// "urn:ogc:def:crs:OGC:1.3:CRS84". Supported are the geographic systems
+// that match WGS84 to within a metre (EPSG:4326, CRS84, EPSG:4269 NAD83 and
+// EPSG:4258 ETRS89), Web Mercator (EPSG:3857 and its aliases 900913 and
+// 102100) and the WGS84 UTM zones (EPSG:32601-32660 north, 32701-32760
+// south). The returned bool is false for systems already in WGS84 degrees.
+// There is no datum shift: other systems need reprojecting with a full
+// PROJ install (e.g. ogr2ogr -t_srs EPSG:4326) first.
+func LookupCRS(name string) (Unprojection, bool, error) {
+	upper := strings.ToUpper(strings.TrimSpace(name))
+	if strings.HasSuffix(upper, "CRS84") {
+		return nil, false, nil
+	}
+	// The code is the last field of "EPSG:3857", "EPSG::3857" and versioned
+	// URNs like "urn:ogc:def:crs:EPSG:6.6:3857"
+	fields := strings.Split(upper, ":")
+	if len(fields) > 1 && !strings.Contains(upper, "EPSG") {
+		return nil, false, fmt.Errorf("unsupported CRS %q: only EPSG codes and CRS84 are known", name)
+	}
+	code, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil {
+		return nil, false, fmt.Errorf("unsupported CRS %q: no EPSG code", name)
+	}
+
+	switch {
+	case code == 4326 || code == 4269 || code == 4258:
+		return nil, false, nil
+	case code == 3857 || code == 900913 || code == 102100:
+		return webMercator, true, nil
+	case code >= 32601 && code <= 32660:
+		return utm(code-32600, false), true, nil
+	case code >= 32701 && code <= 32760:
+		return utm(code-32700, true), true, nil
+	}
+	return nil, false, fmt.Errorf("unsupported CRS EPSG:%d: reproject it to EPSG:4326 first", code)
+}
+
+// webMercator inverts the spherical Mercator of web maps, in metres
+func webMercator(x, y float64) (lng, lat float64) {
+	lng = x / wgs84A * 180 / math.Pi
+	lat = (2*math.Atan(math.Exp(y/wgs84A)) - math.Pi/2) * 180 / math.Pi
+	return lng, lat
+}
+
+// utm inverts the transverse Mercator of a WGS84 UTM zone with the series
+// of Snyder, "Map Projections: A Working Manual" (1987), eq. 8-12 to 8-25,
+// accurate to well under a metre within the zone
+func utm(zone int, south bool) Unprojection {
+	const k0 = 0.9996
+	e2 := wgs84F * (2 - wgs84F)
+	ep2 := e2 / (1 - e2)
+	e1 := (1 - math.Sqrt(1-e2)) / (1 + math.Sqrt(1-e2))
+	lng0 := float64((zone-1)*6-180+3) * math.Pi / 180
+
+	return func(easting, northing float64) (lng, lat float64) {
+		x := easting - 500000
+		y := northing
+		if south {
+			y -= 10000000
+		}
+		mu := y / k0 / (wgs84A * (1 - e2/4 - 3*e2*e2/64 - 5*e2*e2*e2/256))
+		phi1 := mu + (3*e1/2-27*math.Pow(e1, 3)/32)*math.Sin(2*mu) +
+			(21*e1*e1/16-55*math.Pow(e1, 4)/32)*math.Sin(4*mu) +
+			(151*math.Pow(e1, 3)/96)*math.Sin(6*mu) +
+			(1097*math.Pow(e1, 4)/512)*math.Sin(8*mu)
+
+		sin, cos, tan := math.Sin(phi1), math.Cos(phi1), math.Tan(phi1)
+		n1 := wgs84A / math.Sqrt(1-e2*sin*sin)
+		t1 := tan * tan
+		c1 := ep2 * cos * cos
+		r1 := wgs84A * (1 - e2) / math.Pow(1-e2*sin*sin, 1.5)
+		d := x / (n1 * k0)
+
+		phi := phi1 - (n1*tan/r1)*(d*d/2-
+			(5+3*t1+10*c1-4*c1*c1-9*ep2)*math.Pow(d, 4)/24+
+			(61+90*t1+298*c1+45*t1*t1-252*ep2-3*c1*c1)*math.Pow(d, 6)/720)
+		lambda := lng0 + (d-(1+2*t1+c1)*math.Pow(d, 3)/6+
+			(5-2*c1+28*t1-3*c1*c1+8*ep2+24*t1*t1)*math.Pow(d, 5)/120)/cos
+		return lambda * 180 / math.Pi, phi * 180 / math.Pi
+	}
+}
+
+// Reproject converts every position of the collection from the named
+// coordinate reference system to WGS84 longitude and latitude (see
+// LookupCRS) and drops its crs member. An empty name uses the crs member,
+// and a collection without one is left as it is.
+func (fc *FeatureCollection) Reproject(name string) error {
+	if name == "" {
+		name = fc.CRSName()
+	}
+	if name == "" {
+		return nil
+	}
+	unproject, projected, err := LookupCRS(name)
+	if err != nil {
+		return err
+	}
+	fc.CRS = nil
+	if !projected {
+		return nil
+	}
+	for _, feature := range fc.Features {
+		for _, ring := range feature.Geometry.Coordinates {
+			for i, p := range ring {
+				ring[i][0], ring[i][1] = unproject(p[0], p[1])
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+// Package geojson decodes the GeoJSON FeatureCollections the benchmark runs
+// on and checks their rings before conversion to H3 or S2.
+package geojson
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// Feature represents a single GeoJSON Feature
+type Feature struct {
+	Type       string                 `json:"type"`
+	ID         interface{}            `json:"id,omitempty"` // RFC 7946 string or number
+	Geometry   Geometry               `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// Geometry represents the geometry portion of a GeoJSON Feature. Only the
+// coordinates of Polygons are decoded, as [longitude, latitude] positions.
+type Geometry struct {
+	Type        string         `json:"type"`
+	Coordinates [][][2]float64 `json:"coordinates"`
+
+	err error // why the coordinates could not be decoded, see Err
+}
+
+// UnmarshalJSON decodes a geometry, dropping the altitude (and any further
+// values) of 3D positions. The coordinates of other geometry types than
+// Polygon are not decoded, so a Point or LineString among the polygons
+// does not fail the whole file; the converters skip those by type. A
+// Polygon with a position of fewer than two numbers decodes without its
+// coordinates and reports why from Err, rather than as a zero latitude.
+func (g *Geometry) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*g = Geometry{Type: raw.Type}
+	if raw.Type != "Polygon" || len(raw.Coordinates) == 0 || string(raw.Coordinates) == "null" {
+		return nil
+	}
+
+	var rings [][][]float64
+	if err := json.Unmarshal(raw.Coordinates, &rings); err != nil {
+		g.err = fmt.Errorf("coordinates are not an array of rings of positions: %w", err)
+		return nil
+	}
+	g.Coordinates = make([][][2]float64, len(rings))
+	for r, ring := range rings {
+		g.Coordinates[r] = make([][2]float64, len(ring))
+		for i, p := range ring {
+			if len(p) < 2 {
+				g.Coordinates, g.err = nil, fmt.Errorf("ring %d position %d has %d value(s), not longitude and latitude", r, i, len(p))
+				return nil
+			}
+			g.Coordinates[r][i] = [2]float64{p[0], p[1]}
+		}
+	}
+	return nil
+}
+
+// Err reports why the geometry's coordinates could not be decoded
+func (g Geometry) Err() error {
+	return g.err
+}
+
+// FeatureCollection represents a GeoJSON FeatureCollection
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	CRS      *CRS      `json:"crs,omitempty"`
+	Features []Feature `json:"features"`
+}
+
+// ReadFile reads and parses a GeoJSON document from disk, compressed or
+// not (see ReadData)
+func ReadFile(filePath string) (FeatureCollection, error) {
+	data, err := ReadData(filePath)
+	if err != nil {
+		return FeatureCollection{}, err
+	}
+	return Parse(data)
+}
+
+// Parse parses a GeoJSON document into a FeatureCollection. A document
+// whose top level is a single Feature or a bare Geometry, as single-geofence
+// files often are, is returned as a collection of that one feature. The
+// features of a collection are decoded in parallel, see ParallelFor.
+func Parse(data []byte) (FeatureCollection, error) {
+	// One pass decodes the members of all three kinds of document, leaving
+	// the features of a collection for the workers
+	var doc struct {
+		Type       string                 `json:"type"`
+		CRS        *CRS                   `json:"crs"`
+		Features   []json.RawMessage      `json:"features"`
+		ID         interface{}            `json:"id"`
+		Geometry   Geometry               `json:"geometry"`
+		Properties map[string]interface{} `json:"properties"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return FeatureCollection{}, fmt.Errorf("error unmarshaling GeoJSON: %w", err)
+	}
+
+	switch doc.Type {
+	case "FeatureCollection", "":
+		fc := FeatureCollection{Type: doc.Type, CRS: doc.CRS, Features: make([]Feature, len(doc.Features))}
+		errs := make([]error, len(doc.Features))
+		ParallelFor(len(doc.Features), func(i int) {
+			errs[i] = json.Unmarshal(doc.Features[i], &fc.Features[i])
+		})
+		for i, err := range errs {
+			if err != nil {
+				return FeatureCollection{}, fmt.Errorf("error unmarshaling GeoJSON feature %d: %w", i, err)
+			}
+		}
+		return fc, nil
+	case "Feature":
+		feature := Feature{Type: doc.Type, ID: doc.ID, Geometry: doc.Geometry, Properties: doc.Properties}
+		return FeatureCollection{Type: "FeatureCollection", CRS: doc.CRS, Features: []Feature{feature}}, nil
+	case "Point", "MultiPoint", "LineString", "MultiLineString", "Polygon", "MultiPolygon", "GeometryCollection":
+		var geometry Geometry
+		if err := json.Unmarshal(data, &geometry); err != nil {
+			return FeatureCollection{}, fmt.Errorf("error unmarshaling GeoJSON: %w", err)
+		}
+		feature := Feature{Type: "Feature", Geometry: geometry}
+		return FeatureCollection{Type: "FeatureCollection", CRS: doc.CRS, Features: []Feature{feature}}, nil
+	}
+	return FeatureCollection{}, fmt.Errorf("unsupported GeoJSON type %q", doc.Type)
+}
+
+// ParallelFor calls fn for every index in [0, n) on GOMAXPROCS goroutines
+// and returns when all calls have. Each index is passed to exactly one call,
+// in no particular order.
+func ParallelFor(n int, fn func(i int)) {
+	workers := min(runtime.GOMAXPROCS(0), n)
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := int(next.Add(1) - 1); i < n; i = int(next.Add(1) - 1) {
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// SourceID returns the ID a feature carries as text: its RFC 7946 "id"
+// member, or properties["id"] when it has none, and "" without either
+func SourceID(feature Feature) string {
+	id := feature.ID
+	if id == nil {
+		id = feature.Properties["id"]
+	}
+	switch v := id.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+// maxHashedID keeps hashed IDs exact in JSON numbers (float64)
+const maxHashedID = 1<<53 - 1
+
+// FeatureID returns the integer ID of the feature at position i: its
+// SourceID when that is an integer, a stable hash of it when it is any
+// other string (an RFC 7946 "id" like "way/1234"), and the 1-based position
+// i+1 when the feature has no ID. Use FeatureIDs for a whole collection,
+// whose IDs need not be unique.
+func FeatureID(i int, feature Feature) int {
+	source := SourceID(feature)
+	if source == "" {
+		return i + 1
+	}
+	if id, err := strconv.ParseInt(source, 10, 64); err == nil {
+		return int(id)
+	}
+	if f, err := strconv.ParseFloat(source, 64); err == nil && f == math.Trunc(f) && math.Abs(f) <= maxHashedID {
+		return int(f)
+	}
+	return hashID(source)
+}
+
+// FeatureIDs returns the FeatureID of every feature of a collection, made
+// unique: a feature whose ID an earlier feature already has is given a hash
+// of its SourceID and position instead. The IDs only depend on the file, so
+// they are the same on every run and results can be joined back to it.
+func FeatureIDs(fc FeatureCollection) []int {
+	ids := make([]int, len(fc.Features))
+	taken := make(map[int]bool, len(fc.Features))
+	for i, feature := range fc.Features {
+		id := FeatureID(i, feature)
+		for salt := 0; taken[id]; salt++ {
+			id = hashID(fmt.Sprintf("%s#%d#%d", SourceID(feature), i, salt))
+		}
+		ids[i] = id
+		taken[id] = true
+	}
+	return ids
+}
+
+// hashID is the 64-bit FNV-1a hash of s folded into [1, maxHashedID]
+func hashID(s string) int {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return int(h.Sum64()%maxHashedID) + 1
+}
+
+// CheckRing rejects positions neither H3 nor S2 can work with: NaN or
+// infinite values, which encoding/json cannot produce but other decoders
+// and reprojection can, and latitudes beyond the poles. S2 panics deep in
+// loop construction on NaN rather than returning an error.
+func CheckRing(ring [][2]float64) error {
+	for i, p := range ring {
+		if math.IsNaN(p[0]) || math.IsNaN(p[1]) || math.IsInf(p[0], 0) || math.IsInf(p[1], 0) {
+			return fmt.Errorf("position %d %v is not a finite number", i, p)
+		}
+		if p[1] < -90 || p[1] > 90 {
+			return fmt.Errorf("position %d has latitude %g outside [-90, 90]", i, p[1])
+		}
+	}
+	return nil
+}
+
+// ContainsPlanar is an even-odd point-in-polygon test treating longitude
+// and latitude as planar coordinates, holes included
+func (g Geometry) ContainsPlanar(lng, lat float64) bool {
+	inside := false
+	for _, ring := range g.Coordinates {
+		for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+			a, b := ring[i], ring[j]
+			if (a[1] > lat) != (b[1] > lat) && lng < (b[0]-a[0])*(lat-a[1])/(b[1]-a[1])+a[0] {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// SkipRingFunc is called by the converters for each hole they cannot use,
+// with the ring index in the GeoJSON coordinates. Returning nil drops the
+// hole and keeps converting; an error fails the whole geometry.
+type SkipRingFunc func(ring int, reason string) error
+
+// LogSkippedRing drops the hole with a warning, for single geometries that
+// have no report to go into
+func LogSkippedRing(ring int, reason string) error {
+	log.Printf("Warning: Hole %d %s, skipping", ring-1, reason)
+	return nil
+}
+
+// IgnoreSkippedRing drops the hole silently, for re-conversions of features
+// whose issues were already reported
+func IgnoreSkippedRing(int, string) error {
+	return nil
+}
@@ -0,0 +1,34 @@
+package geojson_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+	"github.com/nkk36/earth-discretization-benchmark/geojson/geojsontest"
+)
+
+// FuzzParse checks that any document either parses or returns an error,
+// and that what parses survives being written back out
+func FuzzParse(f *testing.F) {
+	for _, data := range geojsontest.Documents(f) {
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fc, err := geojson.Parse(data)
+		if err != nil {
+			return
+		}
+		out, err := json.Marshal(fc)
+		if err != nil {
+			t.Fatalf("marshalling a parsed document: %v", err)
+		}
+		again, err := geojson.Parse(out)
+		if err != nil {
+			t.Fatalf("parsing a marshalled document: %v\n%s", err, out)
+		}
+		if len(again.Features) != len(fc.Features) {
+			t.Fatalf("%d features became %d", len(fc.Features), len(again.Features))
+		}
+	})
+}
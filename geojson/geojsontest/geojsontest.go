@@ -0,0 +1,76 @@
+// Package geojsontest holds the fuzz corpus shared by the decoder and the
+// converters built on it.
+package geojsontest
+
+import (
+	"embed"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/nkk36/earth-discretization-benchmark/geojson"
+)
+
+// seeds are small FeatureCollections that reach the corners of the
+// decoder: holes, the antimeridian, a pole and a non-polygon geometry
+//
+//go:embed seeds/*.geojson
+var seeds embed.FS
+
+// Values are coordinates JSON cannot carry or no position should have,
+// reaching the converters the way other decoders can deliver them
+var Values = [][2]float64{
+	{math.NaN(), 0}, {0, math.NaN()}, {math.Inf(1), 0}, {0, math.Inf(-1)}, {1e308, -1e308},
+	{5e-324, -0.0}, {180, 90}, {-180, -90}, {0, 90 + 1e-9}, {45, 45},
+}
+
+// Documents returns the seed documents
+func Documents(tb testing.TB) [][]byte {
+	entries, err := seeds.ReadDir("seeds")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	var docs [][]byte
+	for _, entry := range entries {
+		data, err := seeds.ReadFile("seeds/" + entry.Name())
+		if err != nil {
+			tb.Fatal(err)
+		}
+		docs = append(docs, data)
+	}
+	return docs
+}
+
+// AddPolygonSeeds seeds a fuzz target taking (data []byte, lng, lat
+// float64) with every seed document paired with every one of Values
+func AddPolygonSeeds(f *testing.F) {
+	for _, data := range Documents(f) {
+		for _, v := range Values {
+			f.Add(data, v[0], v[1])
+		}
+	}
+}
+
+// Polygons parses a fuzzed document and returns its polygons with one
+// more position, (lng, lat), inserted into the middle of the first ring;
+// a document that does not parse has none
+func Polygons(data []byte, lng, lat float64) []geojson.Geometry {
+	fc, err := geojson.Parse(data)
+	if err != nil {
+		return nil
+	}
+	var polygons []geojson.Geometry
+	for _, feature := range fc.Features {
+		geometry := feature.Geometry
+		if geometry.Type != "Polygon" {
+			continue
+		}
+		if len(geometry.Coordinates) > 0 && len(geometry.Coordinates[0]) > 0 {
+			ring := slices.Clone(geometry.Coordinates[0])
+			geometry.Coordinates = slices.Clone(geometry.Coordinates)
+			geometry.Coordinates[0] = slices.Insert(ring, len(ring)/2, [2]float64{lng, lat})
+		}
+		polygons = append(polygons, geometry)
+	}
+	return polygons
+}
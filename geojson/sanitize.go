@@ -0,0 +1,199 @@
+package geojson
+
+import (
+	"fmt"
+	"math"
+)
+
+// The reason codes of degenerate rings. The first four cannot be repaired
+// and leave the ring out; the last two change no shape and are fixed when
+// repair is asked for, and otherwise leave the ring out too, since S2
+// rejects them.
+const (
+	DegenerateIdentical = "identical_points" // every position is the same, such as a ring of 4 identical points
+	DegenerateTooFew    = "too_few_points"   // fewer than 3 distinct positions
+	DegenerateZeroArea  = "zero_area"        // the distinct positions are collinear
+	DegenerateSliver    = "sliver"           // a nearly collinear ring, see SliverThreshold
+	DegenerateRepeated  = "repeated_points"  // a position repeated right after itself
+	DegenerateSpike     = "spike"            // a vertex the ring runs out to and straight back from
+)
+
+// SliverThreshold is the isoperimetric quotient 4πA/P² below which a ring
+// is a sliver. The quotient is 1 for a circle and about π·w/l for a long
+// thin strip, so the threshold flags strips some 30,000 times longer than
+// they are wide: collinear vertices nudged apart by rounding, not shapes
+// anyone drew.
+const SliverThreshold = 1e-4
+
+// zeroAreaThreshold is the quotient below which a ring's area is rounding
+// error of collinear positions
+const zeroAreaThreshold = 1e-12
+
+// RingDefect is a degenerate ring found by SanitizeGeometry. Ring is the
+// index in the geometry's coordinates, 0 for the exterior; a ring that was
+// Repaired is kept, any other is left out.
+type RingDefect struct {
+	Ring     int
+	Code     string
+	Detail   string
+	Repaired bool
+}
+
+// Repairable reports whether a reason code is one RepairRing fixes
+func Repairable(code string) bool {
+	return code == DegenerateRepeated || code == DegenerateSpike
+}
+
+// ClassifyRing returns the reason code of a degenerate ring with a short
+// description, or "" for a ring that is fine. The shape tests work in
+// degrees, with longitudes scaled by the cosine of the mean latitude.
+func ClassifyRing(ring [][2]float64) (code, detail string) {
+	open := ring
+	if len(open) > 1 && open[0] == open[len(open)-1] {
+		open = open[:len(open)-1]
+	}
+	if len(open) == 0 {
+		return DegenerateTooFew, "has no positions"
+	}
+
+	distinct := dedupeRing(open)
+	if len(distinct) == 1 {
+		return DegenerateIdentical, fmt.Sprintf("has %d identical positions", len(ring))
+	}
+	if len(distinct) < 3 {
+		return DegenerateTooFew, fmt.Sprintf("has %d distinct positions", len(distinct))
+	}
+
+	if q := isoperimetricQuotient(distinct); q < zeroAreaThreshold {
+		return DegenerateZeroArea, "encloses no area"
+	} else if q < SliverThreshold {
+		return DegenerateSliver, fmt.Sprintf("is a sliver (isoperimetric quotient %.2g)", q)
+	}
+
+	if len(distinct) < len(open) {
+		return DegenerateRepeated, fmt.Sprintf("repeats %d position(s)", len(open)-len(distinct))
+	}
+	if i := spikeVertex(distinct); i >= 0 {
+		return DegenerateSpike, fmt.Sprintf("has a spike at position %d", i)
+	}
+	return "", ""
+}
+
+// RepairRing returns the ring without repeated positions and spikes,
+// closed again; the shape it encloses is unchanged
+func RepairRing(ring [][2]float64) [][2]float64 {
+	open := ring
+	if len(open) > 1 && open[0] == open[len(open)-1] {
+		open = open[:len(open)-1]
+	}
+	out := dedupeRing(open)
+	for len(out) >= 3 {
+		i := spikeVertex(out)
+		if i < 0 {
+			break
+		}
+		// Dropping the tip leaves its two neighbours equal: drop one of them too
+		out = dedupeRing(append(out[:i:i], out[i+1:]...))
+	}
+	if len(out) == 0 {
+		return out
+	}
+	return append(out, out[0])
+}
+
+// SanitizeGeometry classifies every ring of a Polygon, repairing those it
+// can when repair is set. The returned geometry leaves out the degenerate
+// holes; a degenerate exterior is reported but kept, since leaving it out
+// means leaving out the feature, which is the caller's call.
+func SanitizeGeometry(g Geometry, repair bool) (Geometry, []RingDefect) {
+	var defects []RingDefect
+	out := Geometry{Type: g.Type}
+	for r, ring := range g.Coordinates {
+		code, detail := ClassifyRing(ring)
+		if code == "" {
+			out.Coordinates = append(out.Coordinates, ring)
+			continue
+		}
+		defect := RingDefect{Ring: r, Code: code, Detail: detail}
+		if repair && Repairable(code) {
+			repaired := RepairRing(ring)
+			if c, d := ClassifyRing(repaired); c == "" {
+				ring, defect.Repaired = repaired, true
+			} else {
+				defect.Code, defect.Detail = c, d+" after repair"
+			}
+		}
+		defects = append(defects, defect)
+		if defect.Repaired || r == 0 {
+			out.Coordinates = append(out.Coordinates, ring)
+		}
+	}
+	return out, defects
+}
+
+// dedupeRing drops the positions equal to the one before them, the last
+// position wrapping round to the first
+func dedupeRing(open [][2]float64) [][2]float64 {
+	out := make([][2]float64, 0, len(open))
+	for _, p := range open {
+		if len(out) == 0 || p != out[len(out)-1] {
+			out = append(out, p)
+		}
+	}
+	for len(out) > 1 && out[0] == out[len(out)-1] {
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+// spikeVertex returns the first vertex of an open ring whose two neighbours
+// are the same position, or -1
+func spikeVertex(open [][2]float64) int {
+	n := len(open)
+	if n < 3 {
+		return -1
+	}
+	for i := range open {
+		if open[(i+n-1)%n] == open[(i+1)%n] {
+			return i
+		}
+	}
+	return -1
+}
+
+// isoperimetricQuotient is 4πA/P² of an open ring, in degrees with the
+// longitudes scaled by the cosine of the mean latitude
+func isoperimetricQuotient(open [][2]float64) float64 {
+	var meanLat float64
+	for _, p := range open {
+		meanLat += p[1]
+	}
+	scale := math.Cos(meanLat / float64(len(open)) * math.Pi / 180)
+
+	var area, perimeter float64
+	for i := range open {
+		a, b := open[i], open[(i+1)%len(open)]
+		area += a[0]*scale*b[1] - b[0]*scale*a[1]
+		perimeter += math.Hypot((b[0]-a[0])*scale, b[1]-a[1])
+	}
+	if perimeter == 0 {
+		return 0
+	}
+	return 4 * math.Pi * math.Abs(area/2) / (perimeter * perimeter)
+}
+
+// RoundGeometry returns a copy of the geometry with every coordinate
+// rounded to the given number of decimal places, as a GeoJSON writer
+// limiting its precision would leave it. Neighbouring positions may round
+// to the same one; SanitizeGeometry repairs those.
+func RoundGeometry(g Geometry, decimals int) Geometry {
+	scale := math.Pow(10, float64(decimals))
+	out := Geometry{Type: g.Type, Coordinates: make([][][2]float64, len(g.Coordinates))}
+	for r, ring := range g.Coordinates {
+		out.Coordinates[r] = make([][2]float64, len(ring))
+		for i, p := range ring {
+			out.Coordinates[r][i] = [2]float64{math.Round(p[0]*scale) / scale, math.Round(p[1]*scale) / scale}
+		}
+	}
+	return out
+}
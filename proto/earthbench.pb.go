@@ -0,0 +1,598 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: proto/earthbench.proto
+
+package earthbenchpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type System int32
+
+const (
+	System_SYSTEM_UNSPECIFIED System = 0
+	System_SYSTEM_H3          System = 1
+	System_SYSTEM_S2          System = 2
+)
+
+// Enum value maps for System.
+var (
+	System_name = map[int32]string{
+		0: "SYSTEM_UNSPECIFIED",
+		1: "SYSTEM_H3",
+		2: "SYSTEM_S2",
+	}
+	System_value = map[string]int32{
+		"SYSTEM_UNSPECIFIED": 0,
+		"SYSTEM_H3":          1,
+		"SYSTEM_S2":          2,
+	}
+)
+
+func (x System) Enum() *System {
+	p := new(System)
+	*p = x
+	return p
+}
+
+func (x System) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (System) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_earthbench_proto_enumTypes[0].Descriptor()
+}
+
+func (System) Type() protoreflect.EnumType {
+	return &file_proto_earthbench_proto_enumTypes[0]
+}
+
+func (x System) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use System.Descriptor instead.
+func (System) EnumDescriptor() ([]byte, []int) {
+	return file_proto_earthbench_proto_rawDescGZIP(), []int{0}
+}
+
+type CoverRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// GeoJSON Polygon geometry, e.g. {"type":"Polygon","coordinates":[...]}.
+	GeometryGeojson string `protobuf:"bytes,1,opt,name=geometry_geojson,json=geometryGeojson,proto3" json:"geometry_geojson,omitempty"`
+	System          System `protobuf:"varint,2,opt,name=system,proto3,enum=earthbench.v1.System" json:"system,omitempty"`
+	// H3 resolution or S2 level.
+	Resolution int32 `protobuf:"varint,3,opt,name=resolution,proto3" json:"resolution,omitempty"`
+	// S2 RegionCoverer MaxCells; 8 when unset.
+	S2MaxCells    int32 `protobuf:"varint,4,opt,name=s2_max_cells,json=s2MaxCells,proto3" json:"s2_max_cells,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CoverRequest) Reset() {
+	*x = CoverRequest{}
+	mi := &file_proto_earthbench_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CoverRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CoverRequest) ProtoMessage() {}
+
+func (x *CoverRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_earthbench_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CoverRequest.ProtoReflect.Descriptor instead.
+func (*CoverRequest) Descriptor() ([]byte, []int) {
+	return file_proto_earthbench_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CoverRequest) GetGeometryGeojson() string {
+	if x != nil {
+		return x.GeometryGeojson
+	}
+	return ""
+}
+
+func (x *CoverRequest) GetSystem() System {
+	if x != nil {
+		return x.System
+	}
+	return System_SYSTEM_UNSPECIFIED
+}
+
+func (x *CoverRequest) GetResolution() int32 {
+	if x != nil {
+		return x.Resolution
+	}
+	return 0
+}
+
+func (x *CoverRequest) GetS2MaxCells() int32 {
+	if x != nil {
+		return x.S2MaxCells
+	}
+	return 0
+}
+
+type CoverTiming struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// GeoJSON to H3 GeoPolygon / S2 polygon conversion.
+	ConvertNs int64 `protobuf:"varint,1,opt,name=convert_ns,json=convertNs,proto3" json:"convert_ns,omitempty"`
+	// h3.PolygonToCells or RegionCoverer.Covering.
+	CoverNs       int64 `protobuf:"varint,2,opt,name=cover_ns,json=coverNs,proto3" json:"cover_ns,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CoverTiming) Reset() {
+	*x = CoverTiming{}
+	mi := &file_proto_earthbench_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CoverTiming) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CoverTiming) ProtoMessage() {}
+
+func (x *CoverTiming) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_earthbench_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CoverTiming.ProtoReflect.Descriptor instead.
+func (*CoverTiming) Descriptor() ([]byte, []int) {
+	return file_proto_earthbench_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CoverTiming) GetConvertNs() int64 {
+	if x != nil {
+		return x.ConvertNs
+	}
+	return 0
+}
+
+func (x *CoverTiming) GetCoverNs() int64 {
+	if x != nil {
+		return x.CoverNs
+	}
+	return 0
+}
+
+type CoverResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Cells []uint64               `protobuf:"varint,1,rep,packed,name=cells,proto3" json:"cells,omitempty"`
+	// H3 index strings or S2 tokens, in the same order as cells.
+	Tokens        []string     `protobuf:"bytes,2,rep,name=tokens,proto3" json:"tokens,omitempty"`
+	Timing        *CoverTiming `protobuf:"bytes,3,opt,name=timing,proto3" json:"timing,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CoverResponse) Reset() {
+	*x = CoverResponse{}
+	mi := &file_proto_earthbench_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CoverResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CoverResponse) ProtoMessage() {}
+
+func (x *CoverResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_earthbench_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CoverResponse.ProtoReflect.Descriptor instead.
+func (*CoverResponse) Descriptor() ([]byte, []int) {
+	return file_proto_earthbench_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CoverResponse) GetCells() []uint64 {
+	if x != nil {
+		return x.Cells
+	}
+	return nil
+}
+
+func (x *CoverResponse) GetTokens() []string {
+	if x != nil {
+		return x.Tokens
+	}
+	return nil
+}
+
+func (x *CoverResponse) GetTiming() *CoverTiming {
+	if x != nil {
+		return x.Timing
+	}
+	return nil
+}
+
+type BenchmarkRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// GeoJSON FeatureCollection of Polygon features.
+	DatasetGeojson string  `protobuf:"bytes,1,opt,name=dataset_geojson,json=datasetGeojson,proto3" json:"dataset_geojson,omitempty"`
+	H3Resolutions  []int32 `protobuf:"varint,2,rep,packed,name=h3_resolutions,json=h3Resolutions,proto3" json:"h3_resolutions,omitempty"`
+	S2Levels       []int32 `protobuf:"varint,3,rep,packed,name=s2_levels,json=s2Levels,proto3" json:"s2_levels,omitempty"`
+	// S2 RegionCoverer MaxCells; 8 when unset.
+	S2MaxCells int32 `protobuf:"varint,4,opt,name=s2_max_cells,json=s2MaxCells,proto3" json:"s2_max_cells,omitempty"`
+	// Per-covering timeout; coverings that take longer are reported in
+	// Measurement.timed_out_feature_ids. No timeout when unset.
+	CoverTimeoutMs int32 `protobuf:"varint,5,opt,name=cover_timeout_ms,json=coverTimeoutMs,proto3" json:"cover_timeout_ms,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *BenchmarkRequest) Reset() {
+	*x = BenchmarkRequest{}
+	mi := &file_proto_earthbench_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BenchmarkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BenchmarkRequest) ProtoMessage() {}
+
+func (x *BenchmarkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_earthbench_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BenchmarkRequest.ProtoReflect.Descriptor instead.
+func (*BenchmarkRequest) Descriptor() ([]byte, []int) {
+	return file_proto_earthbench_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BenchmarkRequest) GetDatasetGeojson() string {
+	if x != nil {
+		return x.DatasetGeojson
+	}
+	return ""
+}
+
+func (x *BenchmarkRequest) GetH3Resolutions() []int32 {
+	if x != nil {
+		return x.H3Resolutions
+	}
+	return nil
+}
+
+func (x *BenchmarkRequest) GetS2Levels() []int32 {
+	if x != nil {
+		return x.S2Levels
+	}
+	return nil
+}
+
+func (x *BenchmarkRequest) GetS2MaxCells() int32 {
+	if x != nil {
+		return x.S2MaxCells
+	}
+	return 0
+}
+
+func (x *BenchmarkRequest) GetCoverTimeoutMs() int32 {
+	if x != nil {
+		return x.CoverTimeoutMs
+	}
+	return 0
+}
+
+type Measurement struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	System            System                 `protobuf:"varint,1,opt,name=system,proto3,enum=earthbench.v1.System" json:"system,omitempty"`
+	Resolution        int32                  `protobuf:"varint,2,opt,name=resolution,proto3" json:"resolution,omitempty"`
+	Features          int32                  `protobuf:"varint,3,opt,name=features,proto3" json:"features,omitempty"`
+	Cells             int64                  `protobuf:"varint,4,opt,name=cells,proto3" json:"cells,omitempty"`
+	AverageDurationNs float64                `protobuf:"fixed64,5,opt,name=average_duration_ns,json=averageDurationNs,proto3" json:"average_duration_ns,omitempty"`
+	TotalDurationNs   int64                  `protobuf:"varint,6,opt,name=total_duration_ns,json=totalDurationNs,proto3" json:"total_duration_ns,omitempty"`
+	// Features whose covering exceeded cover_timeout_ms; they are not counted
+	// in features, cells or the durations.
+	TimedOutFeatureIds []int64 `protobuf:"varint,7,rep,packed,name=timed_out_feature_ids,json=timedOutFeatureIds,proto3" json:"timed_out_feature_ids,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *Measurement) Reset() {
+	*x = Measurement{}
+	mi := &file_proto_earthbench_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Measurement) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Measurement) ProtoMessage() {}
+
+func (x *Measurement) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_earthbench_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Measurement.ProtoReflect.Descriptor instead.
+func (*Measurement) Descriptor() ([]byte, []int) {
+	return file_proto_earthbench_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Measurement) GetSystem() System {
+	if x != nil {
+		return x.System
+	}
+	return System_SYSTEM_UNSPECIFIED
+}
+
+func (x *Measurement) GetResolution() int32 {
+	if x != nil {
+		return x.Resolution
+	}
+	return 0
+}
+
+func (x *Measurement) GetFeatures() int32 {
+	if x != nil {
+		return x.Features
+	}
+	return 0
+}
+
+func (x *Measurement) GetCells() int64 {
+	if x != nil {
+		return x.Cells
+	}
+	return 0
+}
+
+func (x *Measurement) GetAverageDurationNs() float64 {
+	if x != nil {
+		return x.AverageDurationNs
+	}
+	return 0
+}
+
+func (x *Measurement) GetTotalDurationNs() int64 {
+	if x != nil {
+		return x.TotalDurationNs
+	}
+	return 0
+}
+
+func (x *Measurement) GetTimedOutFeatureIds() []int64 {
+	if x != nil {
+		return x.TimedOutFeatureIds
+	}
+	return nil
+}
+
+type BenchmarkResponse struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Measurements []*Measurement         `protobuf:"bytes,1,rep,name=measurements,proto3" json:"measurements,omitempty"`
+	// Wall time of the whole request, including dataset conversion.
+	ElapsedNs     int64 `protobuf:"varint,2,opt,name=elapsed_ns,json=elapsedNs,proto3" json:"elapsed_ns,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BenchmarkResponse) Reset() {
+	*x = BenchmarkResponse{}
+	mi := &file_proto_earthbench_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BenchmarkResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BenchmarkResponse) ProtoMessage() {}
+
+func (x *BenchmarkResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_earthbench_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BenchmarkResponse.ProtoReflect.Descriptor instead.
+func (*BenchmarkResponse) Descriptor() ([]byte, []int) {
+	return file_proto_earthbench_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *BenchmarkResponse) GetMeasurements() []*Measurement {
+	if x != nil {
+		return x.Measurements
+	}
+	return nil
+}
+
+func (x *BenchmarkResponse) GetElapsedNs() int64 {
+	if x != nil {
+		return x.ElapsedNs
+	}
+	return 0
+}
+
+var File_proto_earthbench_proto protoreflect.FileDescriptor
+
+const file_proto_earthbench_proto_rawDesc = "" +
+	"\n" +
+	"\x16proto/earthbench.proto\x12\rearthbench.v1\"\xaa\x01\n" +
+	"\fCoverRequest\x12)\n" +
+	"\x10geometry_geojson\x18\x01 \x01(\tR\x0fgeometryGeojson\x12-\n" +
+	"\x06system\x18\x02 \x01(\x0e2\x15.earthbench.v1.SystemR\x06system\x12\x1e\n" +
+	"\n" +
+	"resolution\x18\x03 \x01(\x05R\n" +
+	"resolution\x12 \n" +
+	"\fs2_max_cells\x18\x04 \x01(\x05R\n" +
+	"s2MaxCells\"G\n" +
+	"\vCoverTiming\x12\x1d\n" +
+	"\n" +
+	"convert_ns\x18\x01 \x01(\x03R\tconvertNs\x12\x19\n" +
+	"\bcover_ns\x18\x02 \x01(\x03R\acoverNs\"q\n" +
+	"\rCoverResponse\x12\x14\n" +
+	"\x05cells\x18\x01 \x03(\x04R\x05cells\x12\x16\n" +
+	"\x06tokens\x18\x02 \x03(\tR\x06tokens\x122\n" +
+	"\x06timing\x18\x03 \x01(\v2\x1a.earthbench.v1.CoverTimingR\x06timing\"\xcb\x01\n" +
+	"\x10BenchmarkRequest\x12'\n" +
+	"\x0fdataset_geojson\x18\x01 \x01(\tR\x0edatasetGeojson\x12%\n" +
+	"\x0eh3_resolutions\x18\x02 \x03(\x05R\rh3Resolutions\x12\x1b\n" +
+	"\ts2_levels\x18\x03 \x03(\x05R\bs2Levels\x12 \n" +
+	"\fs2_max_cells\x18\x04 \x01(\x05R\n" +
+	"s2MaxCells\x12(\n" +
+	"\x10cover_timeout_ms\x18\x05 \x01(\x05R\x0ecoverTimeoutMs\"\x9d\x02\n" +
+	"\vMeasurement\x12-\n" +
+	"\x06system\x18\x01 \x01(\x0e2\x15.earthbench.v1.SystemR\x06system\x12\x1e\n" +
+	"\n" +
+	"resolution\x18\x02 \x01(\x05R\n" +
+	"resolution\x12\x1a\n" +
+	"\bfeatures\x18\x03 \x01(\x05R\bfeatures\x12\x14\n" +
+	"\x05cells\x18\x04 \x01(\x03R\x05cells\x12.\n" +
+	"\x13average_duration_ns\x18\x05 \x01(\x01R\x11averageDurationNs\x12*\n" +
+	"\x11total_duration_ns\x18\x06 \x01(\x03R\x0ftotalDurationNs\x121\n" +
+	"\x15timed_out_feature_ids\x18\a \x03(\x03R\x12timedOutFeatureIds\"r\n" +
+	"\x11BenchmarkResponse\x12>\n" +
+	"\fmeasurements\x18\x01 \x03(\v2\x1a.earthbench.v1.MeasurementR\fmeasurements\x12\x1d\n" +
+	"\n" +
+	"elapsed_ns\x18\x02 \x01(\x03R\telapsedNs*>\n" +
+	"\x06System\x12\x16\n" +
+	"\x12SYSTEM_UNSPECIFIED\x10\x00\x12\r\n" +
+	"\tSYSTEM_H3\x10\x01\x12\r\n" +
+	"\tSYSTEM_S2\x10\x022\xa1\x01\n" +
+	"\vDiscretizer\x12B\n" +
+	"\x05Cover\x12\x1b.earthbench.v1.CoverRequest\x1a\x1c.earthbench.v1.CoverResponse\x12N\n" +
+	"\tBenchmark\x12\x1f.earthbench.v1.BenchmarkRequest\x1a .earthbench.v1.BenchmarkResponseBDZBgithub.com/nkk36/earth-discretization-benchmark/proto;earthbenchpbb\x06proto3"
+
+var (
+	file_proto_earthbench_proto_rawDescOnce sync.Once
+	file_proto_earthbench_proto_rawDescData []byte
+)
+
+func file_proto_earthbench_proto_rawDescGZIP() []byte {
+	file_proto_earthbench_proto_rawDescOnce.Do(func() {
+		file_proto_earthbench_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_earthbench_proto_rawDesc), len(file_proto_earthbench_proto_rawDesc)))
+	})
+	return file_proto_earthbench_proto_rawDescData
+}
+
+var file_proto_earthbench_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_proto_earthbench_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_proto_earthbench_proto_goTypes = []any{
+	(System)(0),               // 0: earthbench.v1.System
+	(*CoverRequest)(nil),      // 1: earthbench.v1.CoverRequest
+	(*CoverTiming)(nil),       // 2: earthbench.v1.CoverTiming
+	(*CoverResponse)(nil),     // 3: earthbench.v1.CoverResponse
+	(*BenchmarkRequest)(nil),  // 4: earthbench.v1.BenchmarkRequest
+	(*Measurement)(nil),       // 5: earthbench.v1.Measurement
+	(*BenchmarkResponse)(nil), // 6: earthbench.v1.BenchmarkResponse
+}
+var file_proto_earthbench_proto_depIdxs = []int32{
+	0, // 0: earthbench.v1.CoverRequest.system:type_name -> earthbench.v1.System
+	2, // 1: earthbench.v1.CoverResponse.timing:type_name -> earthbench.v1.CoverTiming
+	0, // 2: earthbench.v1.Measurement.system:type_name -> earthbench.v1.System
+	5, // 3: earthbench.v1.BenchmarkResponse.measurements:type_name -> earthbench.v1.Measurement
+	1, // 4: earthbench.v1.Discretizer.Cover:input_type -> earthbench.v1.CoverRequest
+	4, // 5: earthbench.v1.Discretizer.Benchmark:input_type -> earthbench.v1.BenchmarkRequest
+	3, // 6: earthbench.v1.Discretizer.Cover:output_type -> earthbench.v1.CoverResponse
+	6, // 7: earthbench.v1.Discretizer.Benchmark:output_type -> earthbench.v1.BenchmarkResponse
+	6, // [6:8] is the sub-list for method output_type
+	4, // [4:6] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_proto_earthbench_proto_init() }
+func file_proto_earthbench_proto_init() {
+	if File_proto_earthbench_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_earthbench_proto_rawDesc), len(file_proto_earthbench_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_earthbench_proto_goTypes,
+		DependencyIndexes: file_proto_earthbench_proto_depIdxs,
+		EnumInfos:         file_proto_earthbench_proto_enumTypes,
+		MessageInfos:      file_proto_earthbench_proto_msgTypes,
+	}.Build()
+	File_proto_earthbench_proto = out.File
+	file_proto_earthbench_proto_goTypes = nil
+	file_proto_earthbench_proto_depIdxs = nil
+}
@@ -0,0 +1,179 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: proto/earthbench.proto
+
+package earthbenchpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Discretizer_Cover_FullMethodName     = "/earthbench.v1.Discretizer/Cover"
+	Discretizer_Benchmark_FullMethodName = "/earthbench.v1.Discretizer/Benchmark"
+)
+
+// DiscretizerClient is the client API for Discretizer service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Discretizer exposes the covering engine and the benchmark runner.
+//
+// Regenerate the Go code from the repository root with protoc (or buf):
+//
+//	protoc --go_out=. --go_opt=paths=source_relative \
+//	       --go-grpc_out=. --go-grpc_opt=paths=source_relative proto/earthbench.proto
+type DiscretizerClient interface {
+	// Cover returns the cells covering a single polygon.
+	Cover(ctx context.Context, in *CoverRequest, opts ...grpc.CallOption) (*CoverResponse, error)
+	// Benchmark times the covering of every polygon of a dataset for each
+	// requested resolution.
+	Benchmark(ctx context.Context, in *BenchmarkRequest, opts ...grpc.CallOption) (*BenchmarkResponse, error)
+}
+
+type discretizerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDiscretizerClient(cc grpc.ClientConnInterface) DiscretizerClient {
+	return &discretizerClient{cc}
+}
+
+func (c *discretizerClient) Cover(ctx context.Context, in *CoverRequest, opts ...grpc.CallOption) (*CoverResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CoverResponse)
+	err := c.cc.Invoke(ctx, Discretizer_Cover_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *discretizerClient) Benchmark(ctx context.Context, in *BenchmarkRequest, opts ...grpc.CallOption) (*BenchmarkResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BenchmarkResponse)
+	err := c.cc.Invoke(ctx, Discretizer_Benchmark_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DiscretizerServer is the server API for Discretizer service.
+// All implementations must embed UnimplementedDiscretizerServer
+// for forward compatibility.
+//
+// Discretizer exposes the covering engine and the benchmark runner.
+//
+// Regenerate the Go code from the repository root with protoc (or buf):
+//
+//	protoc --go_out=. --go_opt=paths=source_relative \
+//	       --go-grpc_out=. --go-grpc_opt=paths=source_relative proto/earthbench.proto
+type DiscretizerServer interface {
+	// Cover returns the cells covering a single polygon.
+	Cover(context.Context, *CoverRequest) (*CoverResponse, error)
+	// Benchmark times the covering of every polygon of a dataset for each
+	// requested resolution.
+	Benchmark(context.Context, *BenchmarkRequest) (*BenchmarkResponse, error)
+	mustEmbedUnimplementedDiscretizerServer()
+}
+
+// UnimplementedDiscretizerServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedDiscretizerServer struct{}
+
+func (UnimplementedDiscretizerServer) Cover(context.Context, *CoverRequest) (*CoverResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Cover not implemented")
+}
+func (UnimplementedDiscretizerServer) Benchmark(context.Context, *BenchmarkRequest) (*BenchmarkResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Benchmark not implemented")
+}
+func (UnimplementedDiscretizerServer) mustEmbedUnimplementedDiscretizerServer() {}
+func (UnimplementedDiscretizerServer) testEmbeddedByValue()                     {}
+
+// UnsafeDiscretizerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DiscretizerServer will
+// result in compilation errors.
+type UnsafeDiscretizerServer interface {
+	mustEmbedUnimplementedDiscretizerServer()
+}
+
+func RegisterDiscretizerServer(s grpc.ServiceRegistrar, srv DiscretizerServer) {
+	// If the following call pancis, it indicates UnimplementedDiscretizerServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Discretizer_ServiceDesc, srv)
+}
+
+func _Discretizer_Cover_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CoverRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DiscretizerServer).Cover(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Discretizer_Cover_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DiscretizerServer).Cover(ctx, req.(*CoverRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Discretizer_Benchmark_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BenchmarkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DiscretizerServer).Benchmark(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Discretizer_Benchmark_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DiscretizerServer).Benchmark(ctx, req.(*BenchmarkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Discretizer_ServiceDesc is the grpc.ServiceDesc for Discretizer service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Discretizer_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "earthbench.v1.Discretizer",
+	HandlerType: (*DiscretizerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Cover",
+			Handler:    _Discretizer_Cover_Handler,
+		},
+		{
+			MethodName: "Benchmark",
+			Handler:    _Discretizer_Benchmark_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/earthbench.proto",
+}
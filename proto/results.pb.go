@@ -0,0 +1,962 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: proto/results.proto
+
+package earthbenchpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Run is the measurements of one dataset with one set of covering options,
+// over one or more repetitions of a sweep.
+type Run struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Path of the dataset file.
+	Dataset string           `protobuf:"bytes,1,opt,name=dataset,proto3" json:"dataset,omitempty"`
+	Options *CoveringOptions `protobuf:"bytes,2,opt,name=options,proto3" json:"options,omitempty"`
+	Info    *RunInfo         `protobuf:"bytes,3,opt,name=info,proto3" json:"info,omitempty"`
+	// Unset when the machine was not calibrated.
+	Calibration   *Calibration           `protobuf:"bytes,4,opt,name=calibration,proto3" json:"calibration,omitempty"`
+	Measurements  []*CoveringMeasurement `protobuf:"bytes,5,rep,name=measurements,proto3" json:"measurements,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Run) Reset() {
+	*x = Run{}
+	mi := &file_proto_results_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Run) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Run) ProtoMessage() {}
+
+func (x *Run) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_results_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Run.ProtoReflect.Descriptor instead.
+func (*Run) Descriptor() ([]byte, []int) {
+	return file_proto_results_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Run) GetDataset() string {
+	if x != nil {
+		return x.Dataset
+	}
+	return ""
+}
+
+func (x *Run) GetOptions() *CoveringOptions {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+func (x *Run) GetInfo() *RunInfo {
+	if x != nil {
+		return x.Info
+	}
+	return nil
+}
+
+func (x *Run) GetCalibration() *Calibration {
+	if x != nil {
+		return x.Calibration
+	}
+	return nil
+}
+
+func (x *Run) GetMeasurements() []*CoveringMeasurement {
+	if x != nil {
+		return x.Measurements
+	}
+	return nil
+}
+
+// RunInfo identifies the code a run measured and the machine it ran on.
+type RunInfo struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Short commit hash of the benchmark, with -dirty for uncommitted changes.
+	Commit           string `protobuf:"bytes,1,opt,name=commit,proto3" json:"commit,omitempty"`
+	GoVersion        string `protobuf:"bytes,2,opt,name=go_version,json=goVersion,proto3" json:"go_version,omitempty"`
+	H3GoVersion      string `protobuf:"bytes,3,opt,name=h3_go_version,json=h3GoVersion,proto3" json:"h3_go_version,omitempty"`
+	GolangGeoVersion string `protobuf:"bytes,4,opt,name=golang_geo_version,json=golangGeoVersion,proto3" json:"golang_geo_version,omitempty"`
+	Host             string `protobuf:"bytes,5,opt,name=host,proto3" json:"host,omitempty"`
+	// GOOS and GOARCH of the benchmark binary.
+	Goos   string `protobuf:"bytes,6,opt,name=goos,proto3" json:"goos,omitempty"`
+	Goarch string `protobuf:"bytes,7,opt,name=goarch,proto3" json:"goarch,omitempty"`
+	// CPU model name, e.g. "AMD EPYC 7B13".
+	Cpu string `protobuf:"bytes,8,opt,name=cpu,proto3" json:"cpu,omitempty"`
+	// Arm core (e.g. "neoverse-n1") or x86-64 microarchitecture level (e.g.
+	// "x86-64-v3").
+	Microarch     string `protobuf:"bytes,9,opt,name=microarch,proto3" json:"microarch,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RunInfo) Reset() {
+	*x = RunInfo{}
+	mi := &file_proto_results_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RunInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunInfo) ProtoMessage() {}
+
+func (x *RunInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_results_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunInfo.ProtoReflect.Descriptor instead.
+func (*RunInfo) Descriptor() ([]byte, []int) {
+	return file_proto_results_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *RunInfo) GetCommit() string {
+	if x != nil {
+		return x.Commit
+	}
+	return ""
+}
+
+func (x *RunInfo) GetGoVersion() string {
+	if x != nil {
+		return x.GoVersion
+	}
+	return ""
+}
+
+func (x *RunInfo) GetH3GoVersion() string {
+	if x != nil {
+		return x.H3GoVersion
+	}
+	return ""
+}
+
+func (x *RunInfo) GetGolangGeoVersion() string {
+	if x != nil {
+		return x.GolangGeoVersion
+	}
+	return ""
+}
+
+func (x *RunInfo) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *RunInfo) GetGoos() string {
+	if x != nil {
+		return x.Goos
+	}
+	return ""
+}
+
+func (x *RunInfo) GetGoarch() string {
+	if x != nil {
+		return x.Goarch
+	}
+	return ""
+}
+
+func (x *RunInfo) GetCpu() string {
+	if x != nil {
+		return x.Cpu
+	}
+	return ""
+}
+
+func (x *RunInfo) GetMicroarch() string {
+	if x != nil {
+		return x.Microarch
+	}
+	return ""
+}
+
+// Calibration scores the machine against the reference machine; 2 means
+// twice as fast.
+type Calibration struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Score      float64                `protobuf:"fixed64,1,opt,name=score,proto3" json:"score,omitempty"`
+	WorkloadNs float64                `protobuf:"fixed64,2,opt,name=workload_ns,json=workloadNs,proto3" json:"workload_ns,omitempty"`
+	// The durations of the run were multiplied by score.
+	Normalized    bool `protobuf:"varint,3,opt,name=normalized,proto3" json:"normalized,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Calibration) Reset() {
+	*x = Calibration{}
+	mi := &file_proto_results_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Calibration) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Calibration) ProtoMessage() {}
+
+func (x *Calibration) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_results_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Calibration.ProtoReflect.Descriptor instead.
+func (*Calibration) Descriptor() ([]byte, []int) {
+	return file_proto_results_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Calibration) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *Calibration) GetWorkloadNs() float64 {
+	if x != nil {
+		return x.WorkloadNs
+	}
+	return 0
+}
+
+func (x *Calibration) GetNormalized() bool {
+	if x != nil {
+		return x.Normalized
+	}
+	return false
+}
+
+type CoveringOptions struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// S2 RegionCoverer MaxCells.
+	MaxCells int32 `protobuf:"varint,1,opt,name=max_cells,json=maxCells,proto3" json:"max_cells,omitempty"`
+	// Per-covering timeout; 0 for no limit.
+	TimeoutNs int64 `protobuf:"varint,2,opt,name=timeout_ns,json=timeoutNs,proto3" json:"timeout_ns,omitempty"`
+	// H3 coverings estimated above this many cells are skipped; 0 for no cap.
+	H3CellCap int64 `protobuf:"varint,3,opt,name=h3_cell_cap,json=h3CellCap,proto3" json:"h3_cell_cap,omitempty"`
+	// Interior sample points per covering checked; 0 when not verified.
+	Verify int32 `protobuf:"varint,4,opt,name=verify,proto3" json:"verify,omitempty"`
+	// H3 containment mode; empty for PolygonToCells.
+	H3Containment string `protobuf:"bytes,5,opt,name=h3_containment,json=h3Containment,proto3" json:"h3_containment,omitempty"`
+	// Each covering was timed until the 95% confidence interval of its mean
+	// was within this fraction of it, or max_samples was reached.
+	Precision  float64 `protobuf:"fixed64,6,opt,name=precision,proto3" json:"precision,omitempty"`
+	MaxSamples int32   `protobuf:"varint,7,opt,name=max_samples,json=maxSamples,proto3" json:"max_samples,omitempty"`
+	// Empty coverings were replaced with the cell of the polygon's centroid.
+	CentroidFallback bool `protobuf:"varint,8,opt,name=centroid_fallback,json=centroidFallback,proto3" json:"centroid_fallback,omitempty"`
+	// S2 coverings used levels from this many below the sweep level up to
+	// it; 0 when fixed at the sweep level.
+	S2LevelSpan   int32 `protobuf:"varint,9,opt,name=s2_level_span,json=s2LevelSpan,proto3" json:"s2_level_span,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CoveringOptions) Reset() {
+	*x = CoveringOptions{}
+	mi := &file_proto_results_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CoveringOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CoveringOptions) ProtoMessage() {}
+
+func (x *CoveringOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_results_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CoveringOptions.ProtoReflect.Descriptor instead.
+func (*CoveringOptions) Descriptor() ([]byte, []int) {
+	return file_proto_results_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CoveringOptions) GetMaxCells() int32 {
+	if x != nil {
+		return x.MaxCells
+	}
+	return 0
+}
+
+func (x *CoveringOptions) GetTimeoutNs() int64 {
+	if x != nil {
+		return x.TimeoutNs
+	}
+	return 0
+}
+
+func (x *CoveringOptions) GetH3CellCap() int64 {
+	if x != nil {
+		return x.H3CellCap
+	}
+	return 0
+}
+
+func (x *CoveringOptions) GetVerify() int32 {
+	if x != nil {
+		return x.Verify
+	}
+	return 0
+}
+
+func (x *CoveringOptions) GetH3Containment() string {
+	if x != nil {
+		return x.H3Containment
+	}
+	return ""
+}
+
+func (x *CoveringOptions) GetPrecision() float64 {
+	if x != nil {
+		return x.Precision
+	}
+	return 0
+}
+
+func (x *CoveringOptions) GetMaxSamples() int32 {
+	if x != nil {
+		return x.MaxSamples
+	}
+	return 0
+}
+
+func (x *CoveringOptions) GetCentroidFallback() bool {
+	if x != nil {
+		return x.CentroidFallback
+	}
+	return false
+}
+
+func (x *CoveringOptions) GetS2LevelSpan() int32 {
+	if x != nil {
+		return x.S2LevelSpan
+	}
+	return 0
+}
+
+// CoveringMeasurement is the covering durations of every feature at one
+// system and resolution in one repetition.
+type CoveringMeasurement struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	System System                 `protobuf:"varint,1,opt,name=system,proto3,enum=earthbench.v1.System" json:"system,omitempty"`
+	// H3 resolution or S2 level.
+	Resolution int32 `protobuf:"varint,2,opt,name=resolution,proto3" json:"resolution,omitempty"`
+	Repetition int32 `protobuf:"varint,3,opt,name=repetition,proto3" json:"repetition,omitempty"`
+	Cells      int64 `protobuf:"varint,4,opt,name=cells,proto3" json:"cells,omitempty"`
+	// Total polygon area of the features measured.
+	AreaKm2 float64 `protobuf:"fixed64,5,opt,name=area_km2,json=areaKm2,proto3" json:"area_km2,omitempty"`
+	// One per feature measured, in the order of feature_ids.
+	DurationsNs []int64 `protobuf:"varint,6,rep,packed,name=durations_ns,json=durationsNs,proto3" json:"durations_ns,omitempty"`
+	FeatureIds  []int64 `protobuf:"varint,7,rep,packed,name=feature_ids,json=featureIds,proto3" json:"feature_ids,omitempty"`
+	// Timings behind each duration when sampled to a precision.
+	Samples []int32 `protobuf:"varint,8,rep,packed,name=samples,proto3" json:"samples,omitempty"`
+	// Features whose samples did not reach the precision.
+	UnconvergedFeatureIds []int64              `protobuf:"varint,9,rep,packed,name=unconverged_feature_ids,json=unconvergedFeatureIds,proto3" json:"unconverged_feature_ids,omitempty"`
+	TimedOutFeatureIds    []int64              `protobuf:"varint,10,rep,packed,name=timed_out_feature_ids,json=timedOutFeatureIds,proto3" json:"timed_out_feature_ids,omitempty"`
+	OverCap               []*CappedFeature     `protobuf:"bytes,11,rep,name=over_cap,json=overCap,proto3" json:"over_cap,omitempty"`
+	Violations            []*CoveringViolation `protobuf:"bytes,12,rep,name=violations,proto3" json:"violations,omitempty"`
+	// Data-quality flags (load, frequency, throttled) when the machine was
+	// disturbed during the measurement.
+	Quality []string `protobuf:"bytes,13,rep,name=quality,proto3" json:"quality,omitempty"`
+	// Features whose covering came back without cells.
+	EmptyFeatureIds []int64 `protobuf:"varint,14,rep,packed,name=empty_feature_ids,json=emptyFeatureIds,proto3" json:"empty_feature_ids,omitempty"`
+	// Cells of the S2 coverings at each level, when they mix levels.
+	LevelCells    map[int32]int64 `protobuf:"bytes,15,rep,name=level_cells,json=levelCells,proto3" json:"level_cells,omitempty" protobuf_key:"varint,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CoveringMeasurement) Reset() {
+	*x = CoveringMeasurement{}
+	mi := &file_proto_results_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CoveringMeasurement) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CoveringMeasurement) ProtoMessage() {}
+
+func (x *CoveringMeasurement) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_results_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CoveringMeasurement.ProtoReflect.Descriptor instead.
+func (*CoveringMeasurement) Descriptor() ([]byte, []int) {
+	return file_proto_results_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CoveringMeasurement) GetSystem() System {
+	if x != nil {
+		return x.System
+	}
+	return System_SYSTEM_UNSPECIFIED
+}
+
+func (x *CoveringMeasurement) GetResolution() int32 {
+	if x != nil {
+		return x.Resolution
+	}
+	return 0
+}
+
+func (x *CoveringMeasurement) GetRepetition() int32 {
+	if x != nil {
+		return x.Repetition
+	}
+	return 0
+}
+
+func (x *CoveringMeasurement) GetCells() int64 {
+	if x != nil {
+		return x.Cells
+	}
+	return 0
+}
+
+func (x *CoveringMeasurement) GetAreaKm2() float64 {
+	if x != nil {
+		return x.AreaKm2
+	}
+	return 0
+}
+
+func (x *CoveringMeasurement) GetDurationsNs() []int64 {
+	if x != nil {
+		return x.DurationsNs
+	}
+	return nil
+}
+
+func (x *CoveringMeasurement) GetFeatureIds() []int64 {
+	if x != nil {
+		return x.FeatureIds
+	}
+	return nil
+}
+
+func (x *CoveringMeasurement) GetSamples() []int32 {
+	if x != nil {
+		return x.Samples
+	}
+	return nil
+}
+
+func (x *CoveringMeasurement) GetUnconvergedFeatureIds() []int64 {
+	if x != nil {
+		return x.UnconvergedFeatureIds
+	}
+	return nil
+}
+
+func (x *CoveringMeasurement) GetTimedOutFeatureIds() []int64 {
+	if x != nil {
+		return x.TimedOutFeatureIds
+	}
+	return nil
+}
+
+func (x *CoveringMeasurement) GetOverCap() []*CappedFeature {
+	if x != nil {
+		return x.OverCap
+	}
+	return nil
+}
+
+func (x *CoveringMeasurement) GetViolations() []*CoveringViolation {
+	if x != nil {
+		return x.Violations
+	}
+	return nil
+}
+
+func (x *CoveringMeasurement) GetQuality() []string {
+	if x != nil {
+		return x.Quality
+	}
+	return nil
+}
+
+func (x *CoveringMeasurement) GetEmptyFeatureIds() []int64 {
+	if x != nil {
+		return x.EmptyFeatureIds
+	}
+	return nil
+}
+
+func (x *CoveringMeasurement) GetLevelCells() map[int32]int64 {
+	if x != nil {
+		return x.LevelCells
+	}
+	return nil
+}
+
+// CappedFeature is a feature skipped because its estimated H3 cell count
+// exceeded the cap.
+type CappedFeature struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	FeatureId      int64                  `protobuf:"varint,1,opt,name=feature_id,json=featureId,proto3" json:"feature_id,omitempty"`
+	EstimatedCells int64                  `protobuf:"varint,2,opt,name=estimated_cells,json=estimatedCells,proto3" json:"estimated_cells,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *CappedFeature) Reset() {
+	*x = CappedFeature{}
+	mi := &file_proto_results_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CappedFeature) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CappedFeature) ProtoMessage() {}
+
+func (x *CappedFeature) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_results_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CappedFeature.ProtoReflect.Descriptor instead.
+func (*CappedFeature) Descriptor() ([]byte, []int) {
+	return file_proto_results_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CappedFeature) GetFeatureId() int64 {
+	if x != nil {
+		return x.FeatureId
+	}
+	return 0
+}
+
+func (x *CappedFeature) GetEstimatedCells() int64 {
+	if x != nil {
+		return x.EstimatedCells
+	}
+	return 0
+}
+
+// CoveringViolation is a feature whose covering failed verification.
+type CoveringViolation struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	FeatureId int64                  `protobuf:"varint,1,opt,name=feature_id,json=featureId,proto3" json:"feature_id,omitempty"`
+	// Sample points the covering got wrong.
+	Count         int32  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	Example       string `protobuf:"bytes,3,opt,name=example,proto3" json:"example,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CoveringViolation) Reset() {
+	*x = CoveringViolation{}
+	mi := &file_proto_results_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CoveringViolation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CoveringViolation) ProtoMessage() {}
+
+func (x *CoveringViolation) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_results_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CoveringViolation.ProtoReflect.Descriptor instead.
+func (*CoveringViolation) Descriptor() ([]byte, []int) {
+	return file_proto_results_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CoveringViolation) GetFeatureId() int64 {
+	if x != nil {
+		return x.FeatureId
+	}
+	return 0
+}
+
+func (x *CoveringViolation) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *CoveringViolation) GetExample() string {
+	if x != nil {
+		return x.Example
+	}
+	return ""
+}
+
+// Covering is the cells of one feature at one system and resolution.
+type Covering struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	FeatureId  int64                  `protobuf:"varint,1,opt,name=feature_id,json=featureId,proto3" json:"feature_id,omitempty"`
+	System     System                 `protobuf:"varint,2,opt,name=system,proto3,enum=earthbench.v1.System" json:"system,omitempty"`
+	Resolution int32                  `protobuf:"varint,3,opt,name=resolution,proto3" json:"resolution,omitempty"`
+	// H3 indexes or S2 cell IDs.
+	Cells         []uint64 `protobuf:"varint,4,rep,packed,name=cells,proto3" json:"cells,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Covering) Reset() {
+	*x = Covering{}
+	mi := &file_proto_results_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Covering) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Covering) ProtoMessage() {}
+
+func (x *Covering) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_results_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Covering.ProtoReflect.Descriptor instead.
+func (*Covering) Descriptor() ([]byte, []int) {
+	return file_proto_results_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Covering) GetFeatureId() int64 {
+	if x != nil {
+		return x.FeatureId
+	}
+	return 0
+}
+
+func (x *Covering) GetSystem() System {
+	if x != nil {
+		return x.System
+	}
+	return System_SYSTEM_UNSPECIFIED
+}
+
+func (x *Covering) GetResolution() int32 {
+	if x != nil {
+		return x.Resolution
+	}
+	return 0
+}
+
+func (x *Covering) GetCells() []uint64 {
+	if x != nil {
+		return x.Cells
+	}
+	return nil
+}
+
+// CoveringSet is the coverings of a dataset.
+type CoveringSet struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Dataset       string                 `protobuf:"bytes,1,opt,name=dataset,proto3" json:"dataset,omitempty"`
+	Coverings     []*Covering            `protobuf:"bytes,2,rep,name=coverings,proto3" json:"coverings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CoveringSet) Reset() {
+	*x = CoveringSet{}
+	mi := &file_proto_results_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CoveringSet) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CoveringSet) ProtoMessage() {}
+
+func (x *CoveringSet) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_results_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CoveringSet.ProtoReflect.Descriptor instead.
+func (*CoveringSet) Descriptor() ([]byte, []int) {
+	return file_proto_results_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CoveringSet) GetDataset() string {
+	if x != nil {
+		return x.Dataset
+	}
+	return ""
+}
+
+func (x *CoveringSet) GetCoverings() []*Covering {
+	if x != nil {
+		return x.Coverings
+	}
+	return nil
+}
+
+var File_proto_results_proto protoreflect.FileDescriptor
+
+const file_proto_results_proto_rawDesc = "" +
+	"\n" +
+	"\x13proto/results.proto\x12\rearthbench.v1\x1a\x16proto/earthbench.proto\"\x8b\x02\n" +
+	"\x03Run\x12\x18\n" +
+	"\adataset\x18\x01 \x01(\tR\adataset\x128\n" +
+	"\aoptions\x18\x02 \x01(\v2\x1e.earthbench.v1.CoveringOptionsR\aoptions\x12*\n" +
+	"\x04info\x18\x03 \x01(\v2\x16.earthbench.v1.RunInfoR\x04info\x12<\n" +
+	"\vcalibration\x18\x04 \x01(\v2\x1a.earthbench.v1.CalibrationR\vcalibration\x12F\n" +
+	"\fmeasurements\x18\x05 \x03(\v2\".earthbench.v1.CoveringMeasurementR\fmeasurements\"\x82\x02\n" +
+	"\aRunInfo\x12\x16\n" +
+	"\x06commit\x18\x01 \x01(\tR\x06commit\x12\x1d\n" +
+	"\n" +
+	"go_version\x18\x02 \x01(\tR\tgoVersion\x12\"\n" +
+	"\rh3_go_version\x18\x03 \x01(\tR\vh3GoVersion\x12,\n" +
+	"\x12golang_geo_version\x18\x04 \x01(\tR\x10golangGeoVersion\x12\x12\n" +
+	"\x04host\x18\x05 \x01(\tR\x04host\x12\x12\n" +
+	"\x04goos\x18\x06 \x01(\tR\x04goos\x12\x16\n" +
+	"\x06goarch\x18\a \x01(\tR\x06goarch\x12\x10\n" +
+	"\x03cpu\x18\b \x01(\tR\x03cpu\x12\x1c\n" +
+	"\tmicroarch\x18\t \x01(\tR\tmicroarch\"d\n" +
+	"\vCalibration\x12\x14\n" +
+	"\x05score\x18\x01 \x01(\x01R\x05score\x12\x1f\n" +
+	"\vworkload_ns\x18\x02 \x01(\x01R\n" +
+	"workloadNs\x12\x1e\n" +
+	"\n" +
+	"normalized\x18\x03 \x01(\bR\n" +
+	"normalized\"\xbc\x02\n" +
+	"\x0fCoveringOptions\x12\x1b\n" +
+	"\tmax_cells\x18\x01 \x01(\x05R\bmaxCells\x12\x1d\n" +
+	"\n" +
+	"timeout_ns\x18\x02 \x01(\x03R\ttimeoutNs\x12\x1e\n" +
+	"\vh3_cell_cap\x18\x03 \x01(\x03R\th3CellCap\x12\x16\n" +
+	"\x06verify\x18\x04 \x01(\x05R\x06verify\x12%\n" +
+	"\x0eh3_containment\x18\x05 \x01(\tR\rh3Containment\x12\x1c\n" +
+	"\tprecision\x18\x06 \x01(\x01R\tprecision\x12\x1f\n" +
+	"\vmax_samples\x18\a \x01(\x05R\n" +
+	"maxSamples\x12+\n" +
+	"\x11centroid_fallback\x18\b \x01(\bR\x10centroidFallback\x12\"\n" +
+	"\rs2_level_span\x18\t \x01(\x05R\vs2LevelSpan\"\xd3\x05\n" +
+	"\x13CoveringMeasurement\x12-\n" +
+	"\x06system\x18\x01 \x01(\x0e2\x15.earthbench.v1.SystemR\x06system\x12\x1e\n" +
+	"\n" +
+	"resolution\x18\x02 \x01(\x05R\n" +
+	"resolution\x12\x1e\n" +
+	"\n" +
+	"repetition\x18\x03 \x01(\x05R\n" +
+	"repetition\x12\x14\n" +
+	"\x05cells\x18\x04 \x01(\x03R\x05cells\x12\x19\n" +
+	"\barea_km2\x18\x05 \x01(\x01R\aareaKm2\x12!\n" +
+	"\fdurations_ns\x18\x06 \x03(\x03R\vdurationsNs\x12\x1f\n" +
+	"\vfeature_ids\x18\a \x03(\x03R\n" +
+	"featureIds\x12\x18\n" +
+	"\asamples\x18\b \x03(\x05R\asamples\x126\n" +
+	"\x17unconverged_feature_ids\x18\t \x03(\x03R\x15unconvergedFeatureIds\x121\n" +
+	"\x15timed_out_feature_ids\x18\n" +
+	" \x03(\x03R\x12timedOutFeatureIds\x127\n" +
+	"\bover_cap\x18\v \x03(\v2\x1c.earthbench.v1.CappedFeatureR\aoverCap\x12@\n" +
+	"\n" +
+	"violations\x18\f \x03(\v2 .earthbench.v1.CoveringViolationR\n" +
+	"violations\x12\x18\n" +
+	"\aquality\x18\r \x03(\tR\aquality\x12*\n" +
+	"\x11empty_feature_ids\x18\x0e \x03(\x03R\x0femptyFeatureIds\x12S\n" +
+	"\vlevel_cells\x18\x0f \x03(\v22.earthbench.v1.CoveringMeasurement.LevelCellsEntryR\n" +
+	"levelCells\x1a=\n" +
+	"\x0fLevelCellsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\x05R\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"W\n" +
+	"\rCappedFeature\x12\x1d\n" +
+	"\n" +
+	"feature_id\x18\x01 \x01(\x03R\tfeatureId\x12'\n" +
+	"\x0festimated_cells\x18\x02 \x01(\x03R\x0eestimatedCells\"b\n" +
+	"\x11CoveringViolation\x12\x1d\n" +
+	"\n" +
+	"feature_id\x18\x01 \x01(\x03R\tfeatureId\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x05R\x05count\x12\x18\n" +
+	"\aexample\x18\x03 \x01(\tR\aexample\"\x8e\x01\n" +
+	"\bCovering\x12\x1d\n" +
+	"\n" +
+	"feature_id\x18\x01 \x01(\x03R\tfeatureId\x12-\n" +
+	"\x06system\x18\x02 \x01(\x0e2\x15.earthbench.v1.SystemR\x06system\x12\x1e\n" +
+	"\n" +
+	"resolution\x18\x03 \x01(\x05R\n" +
+	"resolution\x12\x14\n" +
+	"\x05cells\x18\x04 \x03(\x04R\x05cells\"^\n" +
+	"\vCoveringSet\x12\x18\n" +
+	"\adataset\x18\x01 \x01(\tR\adataset\x125\n" +
+	"\tcoverings\x18\x02 \x03(\v2\x17.earthbench.v1.CoveringR\tcoveringsBDZBgithub.com/nkk36/earth-discretization-benchmark/proto;earthbenchpbb\x06proto3"
+
+var (
+	file_proto_results_proto_rawDescOnce sync.Once
+	file_proto_results_proto_rawDescData []byte
+)
+
+func file_proto_results_proto_rawDescGZIP() []byte {
+	file_proto_results_proto_rawDescOnce.Do(func() {
+		file_proto_results_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_results_proto_rawDesc), len(file_proto_results_proto_rawDesc)))
+	})
+	return file_proto_results_proto_rawDescData
+}
+
+var file_proto_results_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_proto_results_proto_goTypes = []any{
+	(*Run)(nil),                 // 0: earthbench.v1.Run
+	(*RunInfo)(nil),             // 1: earthbench.v1.RunInfo
+	(*Calibration)(nil),         // 2: earthbench.v1.Calibration
+	(*CoveringOptions)(nil),     // 3: earthbench.v1.CoveringOptions
+	(*CoveringMeasurement)(nil), // 4: earthbench.v1.CoveringMeasurement
+	(*CappedFeature)(nil),       // 5: earthbench.v1.CappedFeature
+	(*CoveringViolation)(nil),   // 6: earthbench.v1.CoveringViolation
+	(*Covering)(nil),            // 7: earthbench.v1.Covering
+	(*CoveringSet)(nil),         // 8: earthbench.v1.CoveringSet
+	nil,                         // 9: earthbench.v1.CoveringMeasurement.LevelCellsEntry
+	(System)(0),                 // 10: earthbench.v1.System
+}
+var file_proto_results_proto_depIdxs = []int32{
+	3,  // 0: earthbench.v1.Run.options:type_name -> earthbench.v1.CoveringOptions
+	1,  // 1: earthbench.v1.Run.info:type_name -> earthbench.v1.RunInfo
+	2,  // 2: earthbench.v1.Run.calibration:type_name -> earthbench.v1.Calibration
+	4,  // 3: earthbench.v1.Run.measurements:type_name -> earthbench.v1.CoveringMeasurement
+	10, // 4: earthbench.v1.CoveringMeasurement.system:type_name -> earthbench.v1.System
+	5,  // 5: earthbench.v1.CoveringMeasurement.over_cap:type_name -> earthbench.v1.CappedFeature
+	6,  // 6: earthbench.v1.CoveringMeasurement.violations:type_name -> earthbench.v1.CoveringViolation
+	9,  // 7: earthbench.v1.CoveringMeasurement.level_cells:type_name -> earthbench.v1.CoveringMeasurement.LevelCellsEntry
+	10, // 8: earthbench.v1.Covering.system:type_name -> earthbench.v1.System
+	7,  // 9: earthbench.v1.CoveringSet.coverings:type_name -> earthbench.v1.Covering
+	10, // [10:10] is the sub-list for method output_type
+	10, // [10:10] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
+}
+
+func init() { file_proto_results_proto_init() }
+func file_proto_results_proto_init() {
+	if File_proto_results_proto != nil {
+		return
+	}
+	file_proto_earthbench_proto_init()
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_results_proto_rawDesc), len(file_proto_results_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_proto_results_proto_goTypes,
+		DependencyIndexes: file_proto_results_proto_depIdxs,
+		MessageInfos:      file_proto_results_proto_msgTypes,
+	}.Build()
+	File_proto_results_proto = out.File
+	file_proto_results_proto_goTypes = nil
+	file_proto_results_proto_depIdxs = nil
+}
@@ -0,0 +1,98 @@
+// Package report summarises and writes benchmark results.
+package report
+
+import (
+	"math"
+	"sort"
+)
+
+// Distribution summarises a sample of values
+type Distribution struct {
+	Count  int     `json:"count"`
+	Min    float64 `json:"min"`
+	P25    float64 `json:"p25"`
+	Median float64 `json:"median"`
+	P75    float64 `json:"p75"`
+	P90    float64 `json:"p90"`
+	Max    float64 `json:"max"`
+	Mean   float64 `json:"mean"`
+}
+
+// NewDistribution computes a Distribution; values is sorted in place
+func NewDistribution(values []float64) Distribution {
+	if len(values) == 0 {
+		return Distribution{}
+	}
+	sort.Float64s(values)
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return Distribution{
+		Count:  len(values),
+		Min:    values[0],
+		P25:    Percentile(values, 25),
+		Median: Percentile(values, 50),
+		P75:    Percentile(values, 75),
+		P90:    Percentile(values, 90),
+		Max:    values[len(values)-1],
+		Mean:   sum / float64(len(values)),
+	}
+}
+
+// Percentile returns the p-th percentile (0-100) of sorted values using
+// linear interpolation between closest ranks
+func Percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	if hi >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lo] + (rank-float64(lo))*(sorted[hi]-sorted[lo])
+}
+
+// StandardError returns the standard error of the mean of values, from the
+// sample standard deviation; it is infinite for fewer than two values
+func StandardError(values []float64) float64 {
+	n := float64(len(values))
+	if n < 2 {
+		return math.Inf(1)
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / n
+	var squares float64
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(squares/(n-1)) / math.Sqrt(n)
+}
+
+// tQuantile975 are the 97.5th percentiles of Student's t distribution for
+// 1 to 30 degrees of freedom
+var tQuantile975 = []float64{
+	12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+// ConfidenceHalfWidth95 returns the half-width of the 95% confidence
+// interval of the mean of values, using Student's t up to 30 degrees of
+// freedom and the normal 1.96 beyond; it is infinite for fewer than two
+// values
+func ConfidenceHalfWidth95(values []float64) float64 {
+	t := 1.96
+	if df := len(values) - 1; df >= 1 && df <= len(tQuantile975) {
+		t = tQuantile975[df-1]
+	}
+	return t * StandardError(values)
+}